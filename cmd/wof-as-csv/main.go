@@ -4,16 +4,13 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"net/url"
 	"os"
-	"strconv"
-	"sync"
 
-	"github.com/sfomuseum/go-csvdict"
 	"github.com/sfomuseum/go-flags/multi"
-	"github.com/tidwall/gjson"
-	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	"github.com/whosonfirst/go-whosonfirst-exportify/emit"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
 )
 
@@ -24,6 +21,9 @@ func main() {
 	iterator_uri := flag.String("iterator-uri", "repo://", "")
 	flag.Var(&fields, "field", "One or more relative 'properties.FIELDNAME' paths to include the CSV output. If the fieldname is 'path' the filename of the current record will be included. If the fieldname is 'centroid' the primary centroid of the current record will be derived and included as 'latitude' and 'longitude' columns.")
 
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
 	flag.Usage = func() {
 
 		fmt.Fprintf(os.Stderr, "Export one or more WOF records as a CSV document written to STDOUT\n\n")
@@ -40,86 +40,24 @@ func main() {
 
 	ctx := context.Background()
 
-	writers := []io.Writer{
-		os.Stdout,
-	}
-
-	mw := io.MultiWriter(writers...)
-
-	var csv_wr *csvdict.Writer
-
-	mu := new(sync.RWMutex)
-
-	iter_cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
-
-		body, err := io.ReadAll(fh)
-
-		if err != nil {
-			return fmt.Errorf("Failed to read %s, %w", path, err)
-		}
-
-		out := make(map[string]string)
-
-		for _, k := range fields {
-
-			switch k {
-			case "path":
-				out[k] = path
-			case "centroid":
-
-				c, _, err := properties.Centroid(body)
-
-				if err != nil {
-					return fmt.Errorf("Failed to derive centroid for %s, %w", path, err)
-				}
-
-				out["latitude"] = strconv.FormatFloat(c.Lat(), 'f', -1, 64)
-				out["longitude"] = strconv.FormatFloat(c.Lon(), 'f', -1, 64)
-
-			default:
+	q := url.Values{}
+	q["field"] = fields
 
-				fq_k := fmt.Sprintf("properties.%s", k)
-				rsp := gjson.GetBytes(body, fq_k)
-				out[k] = rsp.String()
-			}
-		}
+	encoder_uri := fmt.Sprintf("csv://?%s", q.Encode())
 
-		if csv_wr == nil {
+	enc, err := emit.NewEncoder(ctx, encoder_uri, os.Stdout)
 
-			fieldnames := make([]string, 0)
-
-			for f, _ := range out {
-				fieldnames = append(fieldnames, f)
-			}
-
-			wr, err := csvdict.NewWriter(mw, fieldnames)
-
-			if err != nil {
-				return fmt.Errorf("Failed to create CSV writer, %w", err)
-			}
-
-			csv_wr = wr
-
-			err = csv_wr.WriteHeader()
-
-			if err != nil {
-				return fmt.Errorf("Failed to write CSV header, %w", err)
-			}
-		}
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		err = csv_wr.WriteRow(out)
-
-		if err != nil {
-			return fmt.Errorf("Failed to write row for %s, %w", path, err)
-		}
+	if err != nil {
+		log.Fatalf("Failed to create CSV encoder, %v", err)
+	}
 
-		return nil
+	emit_opts := &emit.EmitOptions{
+		Encoder: enc,
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, iter_cb)
+	iter_cb := emit.IteratorCallback(emit_opts)
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), iter_cb))
 
 	if err != nil {
 		log.Fatalf("Failed to create iterator, %v", err)
@@ -131,5 +69,9 @@ func main() {
 		log.Fatalf("Failed to iterate URIs, %v", err)
 	}
 
-	csv_wr.Flush()
+	err = enc.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close CSV encoder, %v", err)
+	}
 }
@@ -1,22 +1,20 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/url"
 	"os"
 	"strings"
 
-	"github.com/paulmach/orb"
-	"github.com/paulmach/orb/geojson"
-	"github.com/paulmach/orb/planar"
+	"github.com/whosonfirst/go-whosonfirst-exportify/emit"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/transform"
+	_ "github.com/whosonfirst/go-whosonfirst-iterate-git/v2"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/emitter"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
-	_ "github.com/whosonfirst/go-whosonfirst-iterate-git/v2"	
 	_ "github.com/whosonfirst/go-writer-featurecollection/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
@@ -47,6 +45,9 @@ func main() {
 
 	as_multipoints := flag.Bool("as-multipoints", false, "Output geometries as a MultiPoint array")
 
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
 	flag.Usage = func() {
 
 		fmt.Fprintf(os.Stderr, "Export one or more WOF records as a GeoJSON FeatureCollection\n\n")
@@ -67,6 +68,9 @@ func main() {
 
 	ctx := context.Background()
 
+	// Records are written using the featurecollection:// writer, wrapping the -writer-uri writer, as they always have
+	// been so that the output of this tool doesn't change. The wof-emit tool uses the featurecollection:// encoder instead.
+
 	q := url.Values{}
 	q.Set("writer", *writer_uri)
 
@@ -82,77 +86,28 @@ func main() {
 		log.Fatalf("Failed to create writer, %v", err)
 	}
 
-	defer wr.Close(ctx)
-
-	iter_cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
-
-		if *as_multipoints {
-
-			body, err := io.ReadAll(fh)
-
-			if err != nil {
-				return err
-			}
-
-			f, err := geojson.UnmarshalFeature(body)
-
-			if err != nil {
-				return err
-			}
-
-			geom := f.Geometry
-
-			f.Geometry = geom
-
-			switch geom.GeoJSONType() {
-			case "Point":
-
-				points := []orb.Point{
-					geom.(orb.Point),
-				}
-
-				geom = orb.MultiPoint(points)
-
-			case "MultiPoint":
-				// pass
-			case "MultiPolygon":
-
-				points := make([]orb.Point, 0)
+	enc := emit.NewWriterEncoder(ctx, wr)
 
-				for _, poly := range geom.(orb.MultiPolygon) {
-					pt, _ := planar.CentroidArea(poly)
-					points = append(points, pt)
-				}
+	transform_uris := make([]string, 0)
 
-				geom = orb.MultiPoint(points)
-
-			case "Polygon":
-
-				pt, _ := planar.CentroidArea(geom)
-				points := []orb.Point{pt}
-
-				geom = orb.MultiPoint(points)
-
-			default:
-				return fmt.Errorf("unsupported geometry type %s", geom.GeoJSONType())
-			}
-
-			f.Geometry = geom
-
-			body, err = f.MarshalJSON()
+	if *as_multipoints {
+		transform_uris = append(transform_uris, "multipoints://")
+	}
 
-			if err != nil {
-				return err
-			}
+	transforms, err := transform.NewTransformations(ctx, transform_uris...)
 
-			fh = bytes.NewReader(body)
-		}
+	if err != nil {
+		log.Fatalf("Failed to create transformations, %v", err)
+	}
 
-		_, err := wr.Write(ctx, "", fh)
-		return err
+	emit_opts := &emit.EmitOptions{
+		Encoder:         enc,
+		Transformations: transforms,
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, iter_cb)
+	iter_cb := emit.IteratorCallback(emit_opts)
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), iter_cb))
 
 	if err != nil {
 		log.Fatalf("Failed to create iterator, %v", err)
@@ -164,4 +119,9 @@ func main() {
 		log.Fatalf("Failed to iterate URIs, %v", err)
 	}
 
+	err = enc.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close writer, %v", err)
+	}
 }
@@ -16,8 +16,12 @@ import (
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
@@ -32,6 +36,9 @@ func main() {
 
 	from_stdin := flag.Bool("stdin", false, "Read target IDs from STDIN")
 
+	force := flag.Bool("force", false, "Assign the geometry to target records whose wof:controlled property contains \"wof:geometry\".")
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Assign the geometry from a given record to one or more other records.\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] target-id-(N) target-id-(N)\n\n", os.Args[0])
@@ -91,6 +98,8 @@ func main() {
 		log.Fatalf("Failed to create new writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	ex, err := export.NewExporter(ctx, *exporter_uri)
 
 	if err != nil {
@@ -111,30 +120,42 @@ func main() {
 
 	source_geom := geom_rsp.Value()
 
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	for _, id := range target_ids {
 
-		target_body, err := wof_reader.LoadBytes(ctx, r, id)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return assignGeometry(ctx, r, wr, ex, id, source_geom, controlled_opts)
+		})
 
 		if err != nil {
-			log.Fatalf("Failed to load target '%d'", id)
+			log.Fatalf("Failed to assign geometry for target '%d', %v", id, err)
 		}
+	}
+}
 
-		new_body, err := sjson.SetBytes(target_body, "geometry", source_geom)
+func assignGeometry(ctx context.Context, r reader.Reader, wr writer.Writer, ex export.Exporter, id int64, source_geom interface{}, controlled_opts *exportify.ControlledOptions) error {
 
-		if err != nil {
-			log.Fatalf("Failed to update target geometry for '%d', %v", id, err)
-		}
+	target_body, err := wof_reader.LoadBytes(ctx, r, id)
 
-		new_body, err = ex.Export(ctx, new_body)
+	if err != nil {
+		return fmt.Errorf("Failed to load target '%d', %w", id, err)
+	}
 
-		if err != nil {
-			log.Fatalf("Failed to export target '%d', %v", id, err)
-		}
+	new_body, err := sjson.SetBytes(target_body, "geometry", source_geom)
 
-		_, err = wof_writer.WriteBytes(ctx, wr, new_body)
+	if err != nil {
+		return fmt.Errorf("Failed to update target geometry for '%d', %w", id, err)
+	}
 
-		if err != nil {
-			log.Fatalf("Failed to write target '%d', %v", id, err)
-		}
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, target_body, new_body, controlled_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to export target '%d', %w", id, err)
 	}
+
+	return nil
 }
@@ -12,8 +12,12 @@ import (
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
@@ -30,6 +34,9 @@ func main() {
 
 	parent_id := flag.Int64("parent-id", 0, "A valid Who's On First ID.")
 
+	force := flag.Bool("force", false, "Assign the parent to records whose wof:controlled property lists wof:parent_id or wof:hierarchy.")
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
 	flag.Usage = func() {
 
 		fmt.Fprintf(os.Stderr, "Assign the parent ID and its hierarchy to one or more WOF records\n\n")
@@ -70,6 +77,8 @@ func main() {
 		log.Fatalf("Failed to create new writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	ex, err := export.NewExporter(ctx, *exporter_uri)
 
 	if err != nil {
@@ -97,36 +106,49 @@ func main() {
 		"properties.wof:hierarchy": parent_hierarchy,
 	}
 
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	// Okay, go
 
 	for _, id := range ids {
 
-		f, err := wof_reader.LoadBytes(ctx, r, id)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return assignParent(ctx, r, wr, ex, id, to_update, controlled_opts)
+		})
 
 		if err != nil {
-			log.Fatalf("Failed to load '%d', %v", id, err)
+			log.Fatalf("Failed to assign parent for '%d', %v", id, err)
 		}
+	}
+}
 
-		for path, v := range to_update {
+func assignParent(ctx context.Context, r reader.Reader, wr writer.Writer, ex export.Exporter, id int64, to_update map[string]interface{}, controlled_opts *exportify.ControlledOptions) error {
 
-			f, err = sjson.SetBytes(f, path, v)
+	body, err := wof_reader.LoadBytes(ctx, r, id)
 
-			if err != nil {
-				log.Fatalf("Failed to update '%s', %v", path, err)
-			}
-		}
+	if err != nil {
+		return fmt.Errorf("Failed to load '%d', %w", id, err)
+	}
 
-		f, err = ex.Export(ctx, f)
+	f := body
 
-		if err != nil {
-			log.Fatalf("Failed to export '%d', %v", id, err)
-		}
+	for path, v := range to_update {
 
-		_, err = wof_writer.WriteBytes(ctx, wr, f)
+		f, err = sjson.SetBytes(f, path, v)
 
 		if err != nil {
-			log.Fatalf("Failed to write '%d', %v", id, err)
+			return fmt.Errorf("Failed to update '%s', %w", path, err)
 		}
 	}
 
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, body, f, controlled_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to export '%d', %w", id, err)
+	}
+
+	return nil
 }
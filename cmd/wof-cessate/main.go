@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/sfomuseum/go-edtf"
@@ -18,6 +19,11 @@ import (
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
@@ -34,6 +40,7 @@ func main() {
 	writer_uri := flag.String("writer-uri", "", "A valid whosonfirst/go-writer URI. If empty the value of the -s flag will be used in combination with the fs:// scheme.")
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	id_provider_uri := flag.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
 	var ids multi.MultiInt64
 	flag.Var(&ids, "id", "One or more Who's On First IDs. If left empty the value of the -i flag will be used.")
@@ -42,6 +49,7 @@ func main() {
 	flag.Var(&superseded_by, "superseded-by", "Zero or more Who's On First IDs that the records being deprecated are superseded by.")
 
 	supersede_with_copy := flag.Bool("supersede-with-copy", false, "Supersede this record with a copy of itself.")
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
 
 	flag.Usage = func() {
 
@@ -103,16 +111,16 @@ func main() {
 
 	ctx := context.Background()
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	r, err := reader.NewReader(ctx, *reader_uri)
 
 	if err != nil {
-		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
+		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
 	}
 
-	r, err := reader.NewReader(ctx, *reader_uri)
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, r)
 
 	if err != nil {
-		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
+		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
 	}
 
 	wr, err := writer.NewWriter(ctx, *writer_uri)
@@ -121,6 +129,8 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	if *date == "" {
 		now := time.Now()
 		*date = now.Format("2006-01-02")
@@ -138,7 +148,9 @@ func main() {
 
 	for _, id := range ids {
 
-		err := cessateId(ctx, r, wr, ex, id, edtf_dt, superseded_by, *supersede_with_copy)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return cessateId(ctx, r, wr, ex, id, edtf_dt, superseded_by, *supersede_with_copy)
+		})
 
 		if err != nil {
 			log.Fatalf("Failed to cessate record for '%d', %v", id, err)
@@ -161,6 +173,11 @@ func cessateId(ctx context.Context, r reader.Reader, wr writer.Writer, ex export
 		"properties.mz:is_current":  0,
 	}
 
+	// The copy is written after the record being cessated so that a conflict writing the latter
+	// (see the -conflict-retries flag) doesn't leave an orphaned copy behind.
+
+	var copy_body []byte
+
 	if supersede_with_copy {
 
 		new_body := slices.Clone(body)
@@ -201,15 +218,11 @@ func cessateId(ctx context.Context, r reader.Reader, wr writer.Writer, ex export
 			return fmt.Errorf("Failed to derive new ID from copy, %w", err)
 		}
 
-		_, err = wof_writer.WriteBytes(ctx, wr, new_body)
-
-		if err != nil {
-			return fmt.Errorf("Failed to write new record for copy, %v", err)
-		}
-
 		superseded_by = []int64{
 			new_id,
 		}
+
+		copy_body = new_body
 	}
 
 	if len(superseded_by) > 0 {
@@ -248,5 +261,20 @@ func cessateId(ctx context.Context, r reader.Reader, wr writer.Writer, ex export
 		return err
 	}
 
-	return exportify.ExportWithWriter(ctx, ex, wr, new_body)
+	err = exportify.ExportWithWriter(ctx, ex, wr, new_body)
+
+	if err != nil {
+		return err
+	}
+
+	if copy_body != nil {
+
+		_, err = wof_writer.WriteBytes(ctx, wr, copy_body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write new record for copy, %v", err)
+		}
+	}
+
+	return nil
 }
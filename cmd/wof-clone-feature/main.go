@@ -4,17 +4,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"github.com/sfomuseum/go-flags/multi"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func main() {
@@ -25,15 +31,11 @@ func main() {
 	writer_uri := flag.String("writer-uri", "", "A valid whosonfirst/go-writer URI. If empty the value of the -s flag will be used in combination with the fs:// scheme.")
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	id_provider_uri := flag.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
-	var str_properties multi.KeyValueString
-	flag.Var(&str_properties, "string-property", "One or more {KEY}={VALUE} properties to append to the new record where {KEY} is a valid tidwall/gjson path and {VALUE} is a string value.")
-
-	var int_properties multi.KeyValueInt64
-	flag.Var(&str_properties, "int-property", "One or more {KEY}={VALUE} properties to append to the new record where {KEY} is a valid tidwall/gjson path and {VALUE} is a int(64) value.")
-
-	var float_properties multi.KeyValueFloat64
-	flag.Var(&str_properties, "float-property", "One or more {KEY}={VALUE} properties to append to the new record where {KEY} is a valid tidwall/gjson path and {VALUE} is a float(64) value.")
+	var operations exportify.Operations
+	exportify.AppendPropertyFlags(flag.CommandLine, &operations)
+	exportify.AppendOperationFlags(flag.CommandLine, &operations)
 
 	src_id := flag.Int64("id", 0, "The feature being cloned.")
 
@@ -95,16 +97,16 @@ func main() {
 
 	ctx := context.Background()
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	r, err := reader.NewReader(ctx, *reader_uri)
 
 	if err != nil {
-		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
+		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
 	}
 
-	r, err := reader.NewReader(ctx, *reader_uri)
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, r)
 
 	if err != nil {
-		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
+		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
 	}
 
 	wr, err := writer.NewWriter(ctx, *writer_uri)
@@ -113,6 +115,8 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	// Load the record being cloned
 
 	src_body, err := wof_reader.LoadBytes(ctx, r, *src_id)
@@ -131,20 +135,22 @@ func main() {
 		log.Fatalf("Failed to remove wof:id from new record, %v", err)
 	}
 
-	new_updates := make(map[string]interface{})
+	// The new record is a copy so the properties listed in the wof:controlled
+	// property of the record being cloned don't prevent it from being updated.
 
-	for _, p := range str_properties {
-		new_updates[p.Key()] = p.Value()
+	update_opts := &exportify.UpdateFeatureOptions{
+		Operations: operations,
+		Force:      true,
 	}
 
-	for _, p := range int_properties {
-		new_updates[p.Key()] = p.Value()
-	}
+	new_body, _, err = exportify.UpdateFeature(ctx, new_body, update_opts)
 
-	for _, p := range float_properties {
-		new_updates[p.Key()] = p.Value()
+	if err != nil {
+		log.Fatalf("Failed to update new record, %v", err)
 	}
 
+	new_updates := make(map[string]interface{})
+
 	if *supersedes {
 		new_updates["properties.wof:supersedes"] = []int64{*src_id}
 	}
@@ -0,0 +1,315 @@
+// wof-coerce-properties coerces the values of properties in one or more Who's On First records to the types defined
+// by a schema and exports the records whose values were changed.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-csvdict"
+	"github.com/sfomuseum/go-flags/multi"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/coerce"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-format"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+func main() {
+
+	iterator_uri := flag.String("indexer-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v2 URI.")
+	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	writer_uri := flag.String("writer-uri", "null://", "A valid whosonfirst/go-writer URI.")
+
+	schema_path := flag.String("schema", "", "The path to a JSON file mapping (tidwall/gjson) property paths, which may contain glob patterns, to the types their values should be coerced to.")
+
+	var types multi.KeyValueString
+	flag.Var(&types, "type", fmt.Sprintf("One or more {PATH}={TYPE} flags where {PATH} is a valid tidwall/gjson path, which may contain glob patterns, and {TYPE} is the type its value should be coerced to. These are added to, and take precedence over, the -schema file. Valid types are: %s. The 'array' type may be followed by ':' and another type, for example 'array:int64'.", strings.Join(coerce.Types(), ", ")))
+
+	force := flag.Bool("force", false, "Coerce properties listed in a record's wof:controlled property.")
+
+	report_path := flag.String("report", "", "The path to write a CSV report, of the values that were coerced and the values that could not be coerced, to. If \"-\" the report will be written to STDOUT.")
+	dry_run := flag.Bool("dry-run", false, "Report what would be coerced without writing any records.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Coerce the values of properties in one or more Who's On First records to the types defined by a schema.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] uri(N) uri(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -type 'properties.wof:population=int64' -type 'properties.wof:belongsto=array:int64' -report - /usr/local/data/whosonfirst-data-admin-ca\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	schema := make(coerce.Schema)
+
+	if *schema_path != "" {
+
+		s, err := coerce.ReadSchemaFile(*schema_path)
+
+		if err != nil {
+			log.Fatalf("Failed to load schema, %v", err)
+		}
+
+		schema = s
+	}
+
+	for _, kv := range types {
+		schema[kv.Key()] = kv.Value().(string)
+	}
+
+	if len(schema) == 0 {
+		log.Fatalf("Missing -schema or -type flag")
+	}
+
+	err := schema.Validate()
+
+	if err != nil {
+		log.Fatalf("Invalid schema, %v", err)
+	}
+
+	ctx := context.Background()
+
+	ex, err := export.NewExporter(ctx, *exporter_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create exporter for '%s', %v", *exporter_uri, err)
+	}
+
+	wr, err := writer.NewWriter(ctx, *writer_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
+	}
+
+	wr, versions := concurrency.GuardWriter(ctx, wr)
+
+	var report *csvdict.Writer
+
+	if *report_path != "" {
+
+		var report_wr io.Writer
+
+		if *report_path == "-" {
+			report_wr = os.Stdout
+		} else {
+
+			fh, err := os.Create(*report_path)
+
+			if err != nil {
+				log.Fatalf("Failed to create %s, %v", *report_path, err)
+			}
+
+			defer fh.Close()
+			report_wr = fh
+		}
+
+		fieldnames := []string{"path", "id", "property", "type", "old", "new", "error"}
+
+		report, err = csvdict.NewWriter(report_wr, fieldnames)
+
+		if err != nil {
+			log.Fatalf("Failed to create report writer, %v", err)
+		}
+
+		err = report.WriteHeader()
+
+		if err != nil {
+			log.Fatalf("Failed to write report header, %v", err)
+		}
+	}
+
+	coerce_opts := &coerce.CoerceOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  coerce_opts.Force,
+		Logger: coerce_opts.Logger,
+	}
+
+	// Callbacks may be invoked concurrently so access to the report and counts is serialized.
+
+	mu := new(sync.Mutex)
+
+	count_files := 0
+	count_changes := 0
+	count_failures := 0
+
+	writeRow := func(row map[string]string) error {
+
+		if report == nil {
+			return nil
+		}
+
+		return report.WriteRow(row)
+	}
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		id, uri_args, err := uri.ParseURI(path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to parse URI for %s, %w", path, err)
+		}
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		versions.TrackFile(path, body)
+
+		new_body, changes, failures, err := coerce.Coerce(body, schema, coerce_opts)
+
+		if err != nil {
+			return fmt.Errorf("Failed to coerce %s, %w", path, err)
+		}
+
+		if len(changes) > 0 && !*dry_run {
+
+			// Alternate geometry files lack the properties the exporter requires so they are
+			// only formatted.
+
+			if uri_args.IsAlternate {
+
+				new_body, err = format.FormatBytes(new_body)
+
+				if err != nil {
+					return fmt.Errorf("Failed to format %s, %w", path, err)
+				}
+
+				rel_path, err := uri.Id2RelPath(id, uri_args)
+
+				if err != nil {
+					return fmt.Errorf("Failed to derive rel_path for %d (%s), %w", id, path, err)
+				}
+
+				_, err = wr.Write(ctx, rel_path, bytes.NewReader(new_body))
+
+				if err != nil {
+					return fmt.Errorf("Failed to write %s (for %s), %w", rel_path, path, err)
+				}
+
+			} else {
+
+				_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
+
+				if err != nil {
+					return fmt.Errorf("Failed to export %s, %w", path, err)
+				}
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		str_id := strconv.FormatInt(id, 10)
+
+		for _, f := range failures {
+
+			log.Printf("Failed to coerce '%s' (%s) in %s to %s, %s\n", f.Path, f.Value, path, f.Type, f.Reason)
+
+			row := map[string]string{
+				"path":     path,
+				"id":       str_id,
+				"property": f.Path,
+				"type":     f.Type,
+				"old":      f.Value,
+				"error":    f.Reason,
+			}
+
+			err := writeRow(row)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write report row for %s, %w", path, err)
+			}
+		}
+
+		count_failures += len(failures)
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		count_files += 1
+		count_changes += len(changes)
+
+		str_changes := make([]string, len(changes))
+
+		for i, c := range changes {
+
+			str_changes[i] = fmt.Sprintf("%s=%s", c.Path, c.New)
+
+			row := map[string]string{
+				"path":     path,
+				"id":       str_id,
+				"property": c.Path,
+				"type":     c.Type,
+				"old":      c.Old,
+				"new":      c.New,
+			}
+
+			err := writeRow(row)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write report row for %s, %w", path, err)
+			}
+		}
+
+		log.Printf("Coerced %s (%s)\n", path, strings.Join(str_changes, ", "))
+		return nil
+	}
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
+
+	if err != nil {
+		log.Fatalf("Failed to create iterator, %v", err)
+	}
+
+	paths := flag.Args()
+
+	err = iter.IterateURIs(ctx, paths...)
+
+	if err != nil {
+		log.Fatalf("Failed to iterate URIs, %v", err)
+	}
+
+	if report != nil {
+
+		report.Flush()
+
+		err = report.Error()
+
+		if err != nil {
+			log.Fatalf("Failed to write report, %v", err)
+		}
+	}
+
+	err = wr.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close writer, %v", err)
+	}
+
+	log.Printf("Coerced %d values in %d files, %d values could not be coerced\n", count_changes, count_files, count_failures)
+}
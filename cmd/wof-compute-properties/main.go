@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/compute"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	_ "github.com/whosonfirst/go-whosonfirst-iterate-reader"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+	uri "github.com/whosonfirst/go-whosonfirst-uri"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+func main() {
+
+	iterator_uri := flag.String("indexer-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v2 URI.")
+	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	writer_uri := flag.String("writer-uri", "null://", "A valid whosonfirst/go-writer URI.")
+
+	var rules compute.Rules
+	flag.Var(&rules, "compute", "One or more {PATH}={EXPRESSION} flags where {PATH} is a valid tidwall/gjson path and {EXPRESSION} is an expression whose result will be assigned to it. Rules are applied in the order they are passed.")
+
+	force := flag.Bool("force", false, "Update properties listed in a record's wof:controlled property.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Assign properties derived from other properties to one or more Who's On First records.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] uri(N) uri(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -compute 'properties.lbl:latitude=round({properties.lbl:latitude}, 6)' /usr/local/data/whosonfirst-data-admin-ca\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid functions are: %s\n\n", strings.Join(compute.FunctionNames(), ", "))
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if len(rules) == 0 {
+		log.Fatalf("Missing -compute flag")
+	}
+
+	ctx := context.Background()
+
+	ex, err := export.NewExporter(ctx, *exporter_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create exporter for '%s', %v", *exporter_uri, err)
+	}
+
+	wr, err := writer.NewWriter(ctx, *writer_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
+	}
+
+	wr, versions := concurrency.GuardWriter(ctx, wr)
+
+	apply_opts := &compute.ApplyOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		_, uri_args, err := uri.ParseURI(path)
+
+		if err != nil {
+			return err
+		}
+
+		if uri_args.IsAlternate {
+			log.Printf("Alternate files (%s) are not supported yet, skipping\n", path)
+			return nil
+		}
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return err
+		}
+
+		versions.TrackFile(path, body)
+
+		new_body, changed, err := compute.Apply(ctx, body, rules, apply_opts)
+
+		if err != nil {
+			return fmt.Errorf("Failed to compute properties for %s, %w", path, err)
+		}
+
+		if len(changed) == 0 {
+			return nil
+		}
+
+		written, err := exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
+
+		if err != nil {
+			return err
+		}
+
+		if !written {
+			return nil
+		}
+
+		log.Printf("Updated %s (%s)\n", path, strings.Join(changed, ", "))
+		return nil
+	}
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paths := flag.Args()
+
+	err = iter.IterateURIs(ctx, paths...)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/sfomuseum/go-flags/flagset"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
-	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	"github.com/whosonfirst/go-whosonfirst-exportify/shapefile"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wofReader "github.com/whosonfirst/go-whosonfirst-reader"
 	wofWriter "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
@@ -23,9 +29,11 @@ func main() {
 	parentID := fs.Int64("parent-wof-id", -1, "An optional WOF ID which the created record should be parented by")
 	writerURI := fs.String("writer-uri", "", "A valid whosonfirst/go-writer URI")
 	exporterURI := fs.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	idProviderURI := fs.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -parent-reader-uri reader, if set, are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Create a new WOF record from a partially prepared record. Useful when you have a new record, but need to give it an ID, place it into the hierarchy and write it into a repo.\n\n")
+		fmt.Fprintf(os.Stderr, "Create a new WOF record from a partially prepared record. Useful when you have a new record, but need to give it an ID, place it into the hierarchy and write it into a repo.\n")
+		fmt.Fprintf(os.Stderr, "If a file ends in \".shp\" (or \".zip\") it is read as an ESRI Shapefile and a new record is created for each of its features.\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] file [file ...]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Valid options are:\n")
 		fs.PrintDefaults()
@@ -41,8 +49,19 @@ func main() {
 
 	ctx := context.Background()
 
-	// Setup the exporter
-	ex, err := export.NewExporter(ctx, *exporterURI)
+	// Setup the exporter, checking minted IDs against the parent reader (if there is one)
+	checkReaderURI := *parentReaderURI
+
+	if checkReaderURI == "" {
+		checkReaderURI = "null://"
+	}
+
+	checkReader, err := reader.NewReader(ctx, checkReaderURI)
+	if err != nil {
+		log.Fatalf("Failed to create reader for '%s', %v", checkReaderURI, err)
+	}
+
+	ex, err := provider.NewExporter(ctx, *exporterURI, *idProviderURI, checkReader)
 	if err != nil {
 		log.Fatalf("Failed create exporter for '%s', %v", *exporterURI, err)
 	}
@@ -58,51 +77,69 @@ func main() {
 	for _, file := range files {
 		log.Print(file)
 
-		bytes, err := os.ReadFile(file)
+		records, err := readRecords(ctx, file)
 		if err != nil {
 			log.Fatalf("Unable to read file: %s, %v", file, err)
 		}
 
-		// If -parent-id is provided, attempt to set the hierarchy and other details from the parent
-		if *parentID > 0 {
-			if *parentReaderURI == "" {
-				log.Fatalf("No parent reader URI provided")
-			}
+		for _, bytes := range records {
+			// If -parent-id is provided, attempt to set the hierarchy and other details from the parent
+			if *parentID > 0 {
+				if *parentReaderURI == "" {
+					log.Fatalf("No parent reader URI provided")
+				}
 
-			parentReader, err := reader.NewReader(ctx, *parentReaderURI)
-			if err != nil {
-				log.Fatalf("Failed to create reader for '%s', %v", *parentReaderURI, err)
-			}
+				parentReader, err := reader.NewReader(ctx, *parentReaderURI)
+				if err != nil {
+					log.Fatalf("Failed to create reader for '%s', %v", *parentReaderURI, err)
+				}
 
-			parentBytes, err := wofReader.LoadBytes(ctx, parentReader, *parentID)
-			if err != nil {
-				log.Fatalf("Failed to load parent record (%d), %v", *parentID, err)
-			}
+				parentBytes, err := wofReader.LoadBytes(ctx, parentReader, *parentID)
+				if err != nil {
+					log.Fatalf("Failed to load parent record (%d), %v", *parentID, err)
+				}
 
-			to_copy := []string{
-				"properties.wof:hierarchy",
-				"properties.wof:country",
-			}
+				to_copy := []string{
+					"properties.wof:hierarchy",
+					"properties.wof:country",
+				}
 
-			for _, path := range to_copy {
-				rsp := gjson.GetBytes(parentBytes, path)
-				bytes, err = sjson.SetBytes(bytes, path, rsp.Value())
+				for _, path := range to_copy {
+					rsp := gjson.GetBytes(parentBytes, path)
+					bytes, err = sjson.SetBytes(bytes, path, rsp.Value())
 
-				if err != nil {
-					log.Fatalf("Failed to copy '%s' parent value, %v", path, err)
+					if err != nil {
+						log.Fatalf("Failed to copy '%s' parent value, %v", path, err)
+					}
 				}
 			}
-		}
 
-		exportBytes, err := ex.Export(ctx, bytes)
-		if err != nil {
-			log.Fatalf("Failed to export '%s', %v", file, err)
+			exportBytes, err := ex.Export(ctx, bytes)
+			if err != nil {
+				log.Fatalf("Failed to export '%s', %v", file, err)
+			}
+
+			_, err = wofWriter.WriteBytes(ctx, wr, exportBytes)
+			if err != nil {
+				log.Fatalf("Failed to write '%s', %v", file, err)
+			}
 		}
+	}
+
+}
 
-		_, err = wofWriter.WriteBytes(ctx, wr, exportBytes)
+// readRecords returns the record contained in 'file' or, if 'file' is an ESRI Shapefile (or a zipped Shapefile),
+// each of the features it contains.
+func readRecords(ctx context.Context, file string) ([][]byte, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".shp", ".zip":
+		return shapefile.ReadFeatures(ctx, file)
+	default:
+		bytes, err := os.ReadFile(file)
 		if err != nil {
-			log.Fatalf("Failed to write '%s', %v", file, err)
+			return nil, err
 		}
-	}
 
+		return [][]byte{bytes}, nil
+	}
 }
@@ -9,15 +9,18 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-flags/flagset"
-	"github.com/sfomuseum/go-flags/multi"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
-	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	hierarchy "github.com/whosonfirst/go-whosonfirst-spatial/hierarchy"
 	hierarchy_filter "github.com/whosonfirst/go-whosonfirst-spatial/hierarchy/filter"
@@ -41,17 +44,13 @@ func main() {
 	writer_uri := fs.String("writer-uri", "", "A valid whosonfirst/go-writer URI. If empty the value of the -s fs will be used in combination with the fs:// scheme.")
 
 	exporter_uri := fs.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	id_provider_uri := fs.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint the new record's ID. Minted IDs that can already be read from the -parent-reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
 	spatial_database_uri := fs.String("spatial-database-uri", "", "A valid whosonfirst/go-whosonfirst-spatial/database URI.")
 
-	var str_properties multi.KeyValueString
-	fs.Var(&str_properties, "string-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a string value.")
-
-	var int_properties multi.KeyValueInt64
-	fs.Var(&int_properties, "int-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a int(64) value.")
-
-	var float_properties multi.KeyValueFloat64
-	fs.Var(&float_properties, "float-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a float(64) value.")
+	var operations exportify.Operations
+	exportify.AppendPropertyFlags(fs, &operations)
+	exportify.AppendOperationFlags(fs, &operations)
 
 	str_geom := fs.String("geometry", "", "A valid GeoJSON geometry")
 
@@ -107,7 +106,20 @@ func main() {
 
 	ctx := context.Background()
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	var id_r reader.Reader
+
+	if *id_provider_uri != "" {
+
+		r, err := reader.NewReader(ctx, *parent_reader_uri)
+
+		if err != nil {
+			log.Fatalf("Failed to create reader for '%s', %v", *parent_reader_uri, err)
+		}
+
+		id_r = r
+	}
+
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, id_r)
 
 	if err != nil {
 		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
@@ -126,10 +138,8 @@ func main() {
 	}
 
 	opts := &exportify.UpdateFeatureOptions{
-		StringProperties:  str_properties,
-		Int64Properties:   int_properties,
-		Float64Properties: float_properties,
-		Geometry:          geom,
+		Operations: operations,
+		Geometry:   geom,
 	}
 
 	body, _, err := exportify.UpdateFeature(ctx, stub, opts)
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sfomuseum/go-flags/multi"
@@ -14,6 +15,11 @@ import (
 	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
@@ -28,6 +34,9 @@ func main() {
 	writer_uri := flag.String("writer-uri", "", "A valid whosonfirst/go-writer URI. If empty the value of the -s flag will be used in combination with the fs:// scheme.")
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	id_provider_uri := flag.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
+
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
 
 	var str_properties multi.KeyValueString
 	flag.Var(&str_properties, "string-property", "One or more {KEY}={VALUE} properties to append to the new record where {KEY} is a valid tidwall/gjson path and {VALUE} is a string value.")
@@ -101,16 +110,16 @@ func main() {
 
 	ctx := context.Background()
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	r, err := reader.NewReader(ctx, *reader_uri)
 
 	if err != nil {
-		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
+		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
 	}
 
-	r, err := reader.NewReader(ctx, *reader_uri)
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, r)
 
 	if err != nil {
-		log.Fatalf("Failed to create reader for '%s', %v", *reader_uri, err)
+		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
 	}
 
 	wr, err := writer.NewWriter(ctx, *writer_uri)
@@ -119,6 +128,8 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	props := make([]multi.KeyValueFlag, 0)
 
 	for _, p := range str_properties {
@@ -135,7 +146,19 @@ func main() {
 
 	for _, old_id := range ids {
 
-		new_id, err := replaceId(ctx, r, wr, ex, old_id, props...)
+		var new_id int64
+
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+
+			id, err := replaceId(ctx, r, wr, ex, old_id, props...)
+
+			if err != nil {
+				return err
+			}
+
+			new_id = id
+			return nil
+		})
 
 		if err != nil {
 			log.Fatalf("Failed to export record for '%d', %v", old_id, err)
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/whosonfirst/go-whosonfirst-exportify/emit"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/transform"
+	_ "github.com/whosonfirst/go-whosonfirst-iterate-git/v2"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/emitter"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+)
+
+func main() {
+
+	emitter_schemes := strings.Join(emitter.Schemes(), ", ")
+	emitter_desc := fmt.Sprintf("A valid whosonfirst/go-whosonfirst-iterate/v2 URI. Supported emitter URI schemes are: %s", emitter_schemes)
+
+	encoder_schemes := strings.Join(emit.Schemes(), ", ")
+	encoder_desc := fmt.Sprintf("A valid go-whosonfirst-exportify/emit URI. Supported encoder URI schemes are: %s", encoder_schemes)
+
+	transform_schemes := strings.Join(transform.Schemes(), ", ")
+	transform_desc := fmt.Sprintf("Zero or more go-whosonfirst-exportify/transform URIs to apply, in order, to each record before it is encoded. Supported transformation URI schemes are: %s", transform_schemes)
+
+	iterator_uri := flag.String("iterator-uri", "repo://", emitter_desc)
+	encoder_uri := flag.String("encoder-uri", "geojsonl://", encoder_desc)
+
+	var transformations multi.MultiString
+	flag.Var(&transformations, "transform", transform_desc)
+
+	output := flag.String("output", "-", "The path to write encoded records to. If \"-\" then records will be written to STDOUT.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Emit one or more WOF records in a variety of output formats.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] path-(N) path-(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -encoder-uri featurecollection:// -iterator-uri 'repo://?include=properties.mz:is_current=1' /usr/local/data/sfomuseum-data-publicart/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s -encoder-uri 'csv://?field=wof:id&field=wof:name&field=centroid' /usr/local/data/sfomuseum-data-publicart/\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	uris := flag.Args()
+
+	ctx := context.Background()
+
+	var wr io.Writer
+
+	switch *output {
+	case "-":
+		wr = os.Stdout
+	default:
+
+		fh, err := os.OpenFile(*output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+
+		if err != nil {
+			log.Fatalf("Failed to open '%s' for writing, %v", *output, err)
+		}
+
+		defer fh.Close()
+		wr = fh
+	}
+
+	enc, err := emit.NewEncoder(ctx, *encoder_uri, wr)
+
+	if err != nil {
+		log.Fatalf("Failed to create encoder, %v", err)
+	}
+
+	transforms, err := transform.NewTransformations(ctx, transformations...)
+
+	if err != nil {
+		log.Fatalf("Failed to create transformations, %v", err)
+	}
+
+	emit_opts := &emit.EmitOptions{
+		Encoder:         enc,
+		Transformations: transforms,
+	}
+
+	iter_cb := emit.IteratorCallback(emit_opts)
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), iter_cb))
+
+	if err != nil {
+		log.Fatalf("Failed to create iterator, %v", err)
+	}
+
+	err = iter.IterateURIs(ctx, uris...)
+
+	if err != nil {
+		log.Fatalf("Failed to iterate URIs, %v", err)
+	}
+
+	err = enc.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close encoder, %v", err)
+	}
+}
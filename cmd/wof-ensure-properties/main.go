@@ -3,18 +3,21 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"log/slog"
+	"strings"
 	
-	"github.com/sfomuseum/go-flags/multi"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/rules"
 	_ "github.com/whosonfirst/go-whosonfirst-iterate-reader"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
 	uri "github.com/whosonfirst/go-whosonfirst-uri"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
@@ -24,14 +27,16 @@ func main() {
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
 	writer_uri := flag.String("writer-uri", "null://", "A valid whosonfirst/go-writer URI.")
 
-	var str_properties multi.KeyValueString
-	flag.Var(&str_properties, "string-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a string value.")
+	var operations exportify.Operations
+	exportify.AppendPropertyFlags(flag.CommandLine, &operations)
+	exportify.AppendOperationFlags(flag.CommandLine, &operations)
 
-	var int_properties multi.KeyValueInt64
-	flag.Var(&int_properties, "int-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a int(64) value.")
+	force := flag.Bool("force", false, "Update properties listed in a record's wof:controlled property.")
 
-	var float_properties multi.KeyValueFloat64
-	flag.Var(&float_properties, "float-property", "One or more {KEY}={VALUE} flags where {KEY} is a valid tidwall/gjson path and {VALUE} is a float(64) value.")
+	rules_path := flag.String("rules", "", "The path to an optional JSON file containing a list of conditional rules to apply to each record, after any other changes.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
 
 	flag.Parse()
 
@@ -39,6 +44,19 @@ func main() {
 	
 	ctx := context.Background()
 
+	var conditional_rules rules.Rules
+
+	if *rules_path != "" {
+
+		r, err := rules.ReadRulesFile(*rules_path)
+
+		if err != nil {
+			log.Fatalf("Failed to load rules, %v", err)
+		}
+
+		conditional_rules = r
+	}
+
 	ex, err := export.NewExporter(ctx, *exporter_uri)
 
 	if err != nil {
@@ -51,6 +69,13 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	wr, versions := concurrency.GuardWriter(ctx, wr)
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
 
 		_, uri_args, err := uri.ParseURI(path)
@@ -70,39 +95,56 @@ func main() {
 			return err
 		}
 
+		versions.TrackFile(path, body)
+
 		opts := &exportify.UpdateFeatureOptions{
-			StringProperties:  str_properties,
-			Int64Properties:   int_properties,
-			Float64Properties: float_properties,
+			Operations: operations,
+			Force:      *force,
+			Logger:     log.Default(),
 		}
 
-		new_body, changed, err := exportify.UpdateFeature(ctx, body, opts)
+		new_body, changed, err := exportify.UpdateFeatureWithChanges(ctx, body, opts)
 
 		if err != nil {
 			return err
 		}
 
-		if !changed {
+		if len(conditional_rules) > 0 {
+
+			rules_opts := &rules.ApplyOptions{
+				Force:  *force,
+				Logger: log.Default(),
+			}
+
+			rules_body, rules_changed, err := conditional_rules.Apply(ctx, new_body, rules_opts)
+
+			if err != nil {
+				return fmt.Errorf("Failed to apply rules to %s, %w", path, err)
+			}
+
+			new_body = rules_body
+			changed = append(changed, rules_changed...)
+		}
+
+		if len(changed) == 0 {
 			return nil
 		}
 
-		new_body, err = ex.Export(ctx, new_body)
+		written, err := exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
 
 		if err != nil {
 			return err
 		}
 
-		_, err = wof_writer.WriteBytes(ctx, wr, new_body)
-
-		if err != nil {
-			return err
+		if !written {
+			return nil
 		}
 
-		log.Printf("Updated %s\n", path)
+		log.Printf("Updated %s (%s)\n", path, strings.Join(changed, ", "))
 		return nil
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, cb)
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
 
 	if err != nil {
 		log.Fatal(err)
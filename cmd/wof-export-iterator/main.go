@@ -10,6 +10,11 @@ import (
 	"log"
 
 	"github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/flatgeobuf"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
 	"github.com/whosonfirst/go-whosonfirst-uri"
 	"github.com/whosonfirst/go-writer/v3"
@@ -20,6 +25,9 @@ func main() {
 	writer_uri := flag.String("writer-uri", "stdout://", "")
 	iterator_uri := flag.String("iterator-uri", "repo://", "")
 
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
 	flag.Parse()
 
 	ctx := context.Background()
@@ -82,7 +90,7 @@ func main() {
 		return nil
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, iter_cb)
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), iter_cb))
 
 	if err != nil {
 		log.Fatalf("Failed to create iterator, %v", err)
@@ -94,5 +102,11 @@ func main() {
 		log.Fatalf("Failed to iterate URIs, %v", err)
 	}
 
-	wr.Close(ctx)
+	// Some writers, like flatgeobuf://, don't write anything until they are closed.
+
+	err = wr.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close writer, %v", err)
+	}
 }
@@ -12,6 +12,10 @@ import (
 	"github.com/sfomuseum/go-flags/multi"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
@@ -27,6 +31,8 @@ func main() {
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
 
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
 	var ids multi.MultiInt64
 	flag.Var(&ids, "id", "One or more Who's On First IDs. If left empty the value of the -i flag will be used.")
 
@@ -113,6 +119,8 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	// please to be exposing reader.STDIN_SCHEME
 
 	if *reader_uri == "stdin://" {
@@ -128,7 +136,9 @@ func main() {
 
 	for _, id := range ids {
 
-		err := exportId(ctx, r, wr, ex, id)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return exportId(ctx, r, wr, ex, id)
+		})
 
 		if err != nil {
 			log.Fatalf("Failed to export record for '%d', %v", id, err)
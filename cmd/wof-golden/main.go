@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/whosonfirst/go-whosonfirst-exportify/golden"
+)
+
+func main() {
+
+	cases_path := flag.String("cases", "testdata/golden/cases.json", "The path to a JSON file containing the list of golden-file test cases to run.")
+	bin_dir := flag.String("bin", "bin", "The path to the directory containing the tools that test cases run.")
+	update := flag.Bool("update", false, "Rewrite the golden file for each test case with the records it produces rather than comparing them.")
+
+	var names multi.MultiString
+	flag.Var(&names, "case", "Zero or more test case names to run. If empty all the test cases are run.")
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Run tools against fixture records and compare the records they produce with golden files.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -cases testdata/golden/cases.json -bin bin\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s -case wof-deprecate -update\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	ctx := context.Background()
+
+	cases, err := golden.LoadCases(*cases_path)
+
+	if err != nil {
+		log.Fatalf("Failed to load test cases, %v", err)
+	}
+
+	abs_bin, err := filepath.Abs(*bin_dir)
+
+	if err != nil {
+		log.Fatalf("Failed to derive absolute path for '%s', %v", *bin_dir, err)
+	}
+
+	failures := 0
+
+	for _, c := range cases {
+
+		if len(names) > 0 && !slices.Contains(names, c.Name) {
+			continue
+		}
+
+		if c.Command == "" {
+			log.Fatalf("Test case '%s' is missing a command", c.Name)
+		}
+
+		op := golden.StoreCommandOperation(c.Store, filepath.Join(abs_bin, c.Command), c.Args...)
+
+		err := golden.Run(ctx, c, op, *update)
+
+		if err != nil {
+			fmt.Printf("FAIL\t%s\n\t%v\n", c.Name, err)
+			failures += 1
+			continue
+		}
+
+		if *update {
+			fmt.Printf("UPDATED\t%s\n", c.Name)
+		} else {
+			fmt.Printf("ok\t%s\n", c.Name)
+		}
+	}
+
+	if failures > 0 {
+		log.Fatalf("%d test case(s) failed", failures)
+	}
+}
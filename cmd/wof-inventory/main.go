@@ -0,0 +1,108 @@
+// wof-inventory reports the properties used by one or more Who's On First records with their JSON types,
+// frequencies, example values and placetype distribution.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/inventory"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+func main() {
+
+	iterator_uri := flag.String("iterator-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v2 URI.")
+	format := flag.String("format", "json", "The format of the inventory written to STDOUT. Valid options are: json, csv.")
+	examples := flag.Int("examples", 3, "The maximum number of unique example values to include for each type of each property.")
+	inconsistent_only := flag.Bool("inconsistent-only", false, "Only include properties whose values have more than one (non-null) JSON type.")
+	include_alt := flag.Bool("include-alt-files", false, "Include alternate geometry files in the inventory.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Report the properties used by one or more Who's On First records, with their JSON types, frequencies, example values and placetype distribution, to STDOUT.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] uri(N) uri(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -format csv -inconsistent-only /usr/local/data/whosonfirst-data-admin-ca\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	switch *format {
+	case "json", "csv":
+		// pass
+	default:
+		log.Fatalf("Invalid -format flag '%s'", *format)
+	}
+
+	ctx := context.Background()
+
+	inv := inventory.NewInventory(*examples)
+
+	iter_cb := func(ctx context.Context, path string, r io.ReadSeeker, args ...interface{}) error {
+
+		_, uri_args, err := uri.ParseURI(path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to parse URI for %s, %w", path, err)
+		}
+
+		if uri_args.IsAlternate && !*include_alt {
+			return nil
+		}
+
+		body, err := io.ReadAll(r)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		err = inv.Add(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to add %s to inventory, %w", path, err)
+		}
+
+		return nil
+	}
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), iter_cb))
+
+	if err != nil {
+		log.Fatalf("Failed to create iterator, %v", err)
+	}
+
+	uris := flag.Args()
+
+	err = iter.IterateURIs(ctx, uris...)
+
+	if err != nil {
+		log.Fatalf("Failed to iterate URIs, %v", err)
+	}
+
+	switch *format {
+	case "csv":
+		err = inv.WriteCSV(os.Stdout, *inconsistent_only)
+	default:
+		err = inv.WriteJSON(os.Stdout, *inconsistent_only)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to write inventory, %v", err)
+	}
+
+	for _, p := range inv.List(true) {
+		log.Printf("Property '%s' has inconsistent types (%s)\n", p.Name, inventory.FormatCounts(p.Types))
+	}
+}
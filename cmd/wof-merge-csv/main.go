@@ -13,8 +13,12 @@ import (
 	"github.com/sfomuseum/go-flags/multi"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
@@ -36,6 +40,9 @@ func main() {
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI")
 
+	force := flag.Bool("force", false, "Update properties listed in a record's wof:controlled property.")
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
 	flag.Usage = func() {
 
 		fmt.Fprintf(os.Stderr, "Upate one or more Who's On First records with matching entries in a CSV file.\n\n")
@@ -68,8 +75,15 @@ func main() {
 		log.Fatalf("Failed to create new writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	paths := flag.Args()
 
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	for _, path := range paths {
 
 		csv_r, err := csvdict.NewReaderFromPath(path)
@@ -102,13 +116,6 @@ func main() {
 				log.Fatalf("Failed to parse '%s' as WOF Id, %v", str_id, err)
 			}
 
-			body, err := wof_reader.LoadBytes(ctx, r, wof_id)
-
-			if err != nil {
-				log.Printf("Failed to load '%d', %v. Skipping", wof_id, err)
-				continue
-			}
-
 			updates := make(map[string]interface{})
 
 			for _, field := range str_fields {
@@ -162,29 +169,43 @@ func main() {
 				updates[path] = v
 			}
 
-			has_changed, new_body, err := export.AssignPropertiesIfChanged(ctx, body, updates)
+			err = concurrency.Retry(ctx, *conflict_retries, func() error {
+				return mergeRow(ctx, r, wr, ex, wof_id, updates, controlled_opts)
+			})
 
 			if err != nil {
-				log.Fatalf("Failed to assign new properties for %d, %v", wof_id, err)
+				log.Fatalf("Failed to export record for '%d', %v", wof_id, err)
 			}
 
-			if !has_changed {
-				continue
-			}
+		}
 
-			new_body, err = ex.Export(ctx, new_body)
+	}
+}
 
-			if err != nil {
-				log.Fatalf("Failed to export new record for '%d', %v", wof_id, err)
-			}
+func mergeRow(ctx context.Context, r reader.Reader, wr writer.Writer, ex export.Exporter, wof_id int64, updates map[string]interface{}, controlled_opts *exportify.ControlledOptions) error {
 
-			_, err = wof_writer.WriteBytes(ctx, wr, new_body)
+	body, err := wof_reader.LoadBytes(ctx, r, wof_id)
 
-			if err != nil {
-				log.Fatalf("Failed to write record for '%d', %v", wof_id, err)
-			}
+	if err != nil {
+		log.Printf("Failed to load '%d', %v. Skipping", wof_id, err)
+		return nil
+	}
 
-		}
+	has_changed, new_body, err := export.AssignPropertiesIfChanged(ctx, body, updates)
 
+	if err != nil {
+		return fmt.Errorf("Failed to assign new properties, %w", err)
 	}
+
+	if !has_changed {
+		return nil
+	}
+
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
@@ -10,15 +9,26 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	query "github.com/aaronland/go-json-query"
 	"github.com/sfomuseum/go-flags/multi"
 	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/geopackage"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/merge"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	"github.com/whosonfirst/go-whosonfirst-exportify/shapefile"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
+	"github.com/whosonfirst/go-whosonfirst-exportify/transform"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
@@ -38,6 +48,31 @@ func open(ctx context.Context, path string) ([]byte, error) {
 	return ioutil.ReadAll(fh)
 }
 
+const ACTION_MERGED string = "merged"
+
+const ACTION_CREATED string = "created"
+
+const ACTION_DEPRECATED string = "deprecated"
+
+// REPORT_NEW_ID is the ID reported for records that would be created during a dry run.
+const REPORT_NEW_ID string = "new"
+
+// MergeReport records the action taken, and the paths that were changed, for a given WOF record.
+type MergeReport struct {
+	// Id is the WOF ID of the record, or REPORT_NEW_ID for records that would be created during a dry run.
+	Id      interface{} `json:"wof:id"`
+	Action  string      `json:"action"`
+	Changed []string    `json:"changed,omitempty"`
+}
+
+// requiredPaths are the paths that are always copied to records created from new features, in addition to
+// those specified by the -path flag, because the exporter can not create a record without them.
+var requiredPaths = []string{
+	"geometry",
+	"properties.wof:name",
+	"properties.wof:placetype",
+}
+
 func main() {
 
 	reader_uri := flag.String("reader-uri", "", "A valid whosonfirst/go-reader URI")
@@ -59,16 +94,44 @@ func main() {
 	query_mode := flag.String("include-mode", query.QUERYSET_MODE_ALL, desc_query_modes)
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI")
+	id_provider_uri := flag.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
 	var to_append multi.MultiString
 	flag.Var(&to_append, "path", "One or more valid tidwall/gjson paths. These will be copied from the source GeoJSON feature to the corresponding WOF record.")
 
+	valid_strategies := strings.Join(merge.Strategies(), ", ")
+
+	var strategies multi.KeyValueString
+	flag.Var(&strategies, "strategy", fmt.Sprintf("Zero or more {PATH}={STRATEGY} flags used to assign a merge strategy to a specific path. Valid strategies are: %s", valid_strategies))
+
+	default_strategy := flag.String("default-strategy", merge.STRATEGY_OVERWRITE, fmt.Sprintf("The merge strategy to use for paths without an explicit -strategy flag. Valid strategies are: %s", valid_strategies))
+
+	geometry_tolerance := flag.Float64("geometry-tolerance", 0.0, "The maximum distance (in coordinate units) between two vertices for them to be considered equal when comparing geometries. This is useful for ignoring floating point noise introduced by GIS applications. It applies to the geometry path and any path inside it.")
+
+	var transformations multi.MultiString
+	flag.Var(&transformations, "transform", fmt.Sprintf("Zero or more go-whosonfirst-exportify/transform URIs to apply, in order, to each feature being merged before it is compared to its corresponding WOF record. Supported transformation URI schemes are: %s", strings.Join(transform.Schemes(), ", ")))
+
+	original := flag.String("original", "", "The path to the original (unedited) GeoJSON FeatureCollection file that the features being merged were derived from. If set, the features being merged will be reconciled against this file: Features without a wof:id property will be created as new records, features that are absent will optionally be deprecated and only those paths that have been modified will be merged.")
+
+	deprecate_missing := flag.Bool("deprecate-missing", false, "If true, and the -original flag is set, deprecate records that are present in the original FeatureCollection but absent from the features being merged.")
+
+	layer := flag.String("layer", "", "The name of the features table (layer) to read when merging GeoPackage (.gpkg) files. If empty, and the GeoPackage file contains a single features table, that table will be used.")
+
+	dry_run := flag.Bool("dry-run", false, "Go through the motions but do not write any changes.")
+
+	force := flag.Bool("force", false, "Merge paths that are listed in a record's wof:controlled property (or the geometry if it contains \"wof:geometry\") and update controlled properties when deprecating records.")
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
+	report := flag.String("report", "", "An optional path to write a JSON-encoded report listing the action taken, and the paths that were changed, for each record. If \"-\" then the report will be written to STDOUT.")
+
 	flag.Usage = func() {
 
-		fmt.Fprintf(os.Stderr, "Upate one or more Who's On First records with matching entries in a GeoJSON FeatureCollection file.\n\n")
+		fmt.Fprintf(os.Stderr, "Upate one or more Who's On First records with matching entries in a GeoJSON FeatureCollection file.\n")
+		fmt.Fprintf(os.Stderr, "Features may also be read from a layer in a GeoPackage file if its filename ends in \".gpkg\" or from an ESRI Shapefile if its filename ends in \".shp\" (or \".zip\" for a zipped Shapefile).\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] path(N) path(N)\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "For example:\n")
-		fmt.Fprintf(os.Stderr, "\t%s -reader-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -path geometry -path 'properties.example:property' /usr/local/data/updates.geojson\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s -reader-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -path geometry -path 'properties.example:property' /usr/local/data/updates.geojson\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s -reader-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -path geometry -original /usr/local/data/export.geojson -deprecate-missing -dry-run /usr/local/data/export-edited.geojson\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Valid options are:\n")
 		flag.PrintDefaults()
 	}
@@ -77,6 +140,31 @@ func main() {
 
 	ctx := context.Background()
 
+	merge_opts := &merge.MergeOptions{
+		Paths:             to_append,
+		Strategies:        make(map[string]string),
+		DefaultStrategy:   *default_strategy,
+		GeometryTolerance: *geometry_tolerance,
+	}
+
+	for _, kv := range strategies {
+		merge_opts.Strategies[kv.Key()] = kv.Value().(string)
+	}
+
+	err := merge_opts.Validate()
+
+	if err != nil {
+		log.Fatalf("Invalid merge options, %v", err)
+	}
+
+	transforms, err := transform.NewTransformations(ctx, transformations...)
+
+	if err != nil {
+		log.Fatalf("Failed to create transformations, %v", err)
+	}
+
+	reports := make([]*MergeReport, 0)
+
 	lookup_map := make(map[string]int64)
 	lookup_mu := new(sync.RWMutex)
 
@@ -162,16 +250,16 @@ func main() {
 		*/
 	}
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	r, err := reader.NewReader(ctx, *reader_uri)
 
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to create new reader for '%s', %v", *reader_uri, err)
 	}
 
-	r, err := reader.NewReader(ctx, *reader_uri)
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, r)
 
 	if err != nil {
-		log.Fatalf("Failed to create new reader for '%s', %v", *reader_uri, err)
+		log.Fatal(err)
 	}
 
 	wr, err := writer.NewWriter(ctx, *writer_uri)
@@ -180,108 +268,490 @@ func main() {
 		log.Fatalf("Failed to create new writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	paths := flag.Args()
 
-	for _, path := range paths {
+	if *original != "" {
 
-		fc_b, err := open(ctx, path)
+		if *lookup_key != "" {
+			log.Fatalf("The -lookup-key flag is not supported when reconciling features (-original)")
+		}
+
+		original_features, err := readFeatures(ctx, *original, *layer, transforms...)
 
 		if err != nil {
-			log.Fatalf("Failed to open '%s', %v", path, err)
+			log.Fatalf("Failed to read features from '%s', %v", *original, err)
 		}
 
-		f_rsp := gjson.GetBytes(fc_b, "features")
+		edited_features := make([]gjson.Result, 0)
+
+		for _, path := range paths {
 
-		for idx, qgis_f := range f_rsp.Array() {
+			features, err := readFeatures(ctx, path, *layer, transforms...)
+
+			if err != nil {
+				log.Fatalf("Failed to read features from '%s', %v", path, err)
+			}
 
-			var wof_id int64
+			edited_features = append(edited_features, features...)
+		}
 
-			if *lookup_key != "" {
+		reconcile_opts := &merge.ReconcileOptions{
+			Paths:             to_append,
+			GeometryTolerance: *geometry_tolerance,
+		}
 
-				key_rsp := qgis_f.Get(*lookup_key)
+		rec, err := merge.Reconcile(ctx, original_features, edited_features, reconcile_opts)
 
-				if !key_rsp.Exists() {
-					log.Fatalf("Missing '%s' property for updated feature '%s'", *lookup_key, "PATH")
-				}
+		if err != nil {
+			log.Fatalf("Failed to reconcile features, %v", err)
+		}
 
-				key := key_rsp.String()
+		for idx, f := range rec.New {
 
-				id, exists := lookup_map[key]
+			new_id, changed, err := createRecord(ctx, wr, ex, f, merge_opts, *dry_run)
 
-				if !exists {
-					log.Fatalf("Missing key '%s'", key)
-				}
+			if err != nil {
+				log.Fatalf("Failed to create new record for feature at offset %d, %v", idx, err)
+			}
+
+			var report_id interface{} = new_id
+
+			if *dry_run {
+				report_id = REPORT_NEW_ID
+			}
 
-				wof_id = id
+			reports = append(reports, &MergeReport{
+				Id:      report_id,
+				Action:  ACTION_CREATED,
+				Changed: changed,
+			})
+		}
+
+		for _, m := range rec.Modified {
+
+			opts := *merge_opts
+			opts.Paths = m.Paths
+
+			var changed []string
+			loaded := true
 
-			} else {
+			err := concurrency.Retry(ctx, *conflict_retries, func() error {
 
-				id_rsp := qgis_f.Get("properties.wof:id")
+				wof_f, err := wof_reader.LoadBytes(ctx, r, m.Id)
 
-				if !id_rsp.Exists() {
-					log.Fatalf("Missing wof:id property for updated feature '%d'", idx)
+				if err != nil {
+					log.Printf("Failed to load '%d', %v. Skipping", m.Id, err)
+					loaded = false
+					return nil
 				}
 
-				wof_id = id_rsp.Int()
+				changed, err = mergeRecord(ctx, wr, ex, wof_f, m.Feature, &opts, *force, *dry_run)
+				return err
+			})
+
+			if !loaded {
+				continue
+			}
+
+			if err != nil {
+				log.Fatalf("Failed to merge updated feature for '%d', %v", m.Id, err)
+			}
+
+			if len(changed) == 0 {
+				continue
+			}
+
+			reports = append(reports, &MergeReport{
+				Id:      m.Id,
+				Action:  ACTION_MERGED,
+				Changed: changed,
+			})
+		}
+
+		deprecated := 0
+
+		for _, id := range rec.Deleted {
+
+			if !*deprecate_missing {
+				log.Printf("Record %d is absent from the edited features but -deprecate-missing is false, skipping\n", id)
+				continue
 			}
 
-			wof_f, err := wof_reader.LoadBytes(ctx, r, wof_id)
+			var ok bool
+
+			err := concurrency.Retry(ctx, *conflict_retries, func() error {
+
+				var err error
+				ok, err = deprecateRecord(ctx, r, wr, ex, id, *force, *dry_run)
+				return err
+			})
 
 			if err != nil {
-				log.Printf("Failed to load '%d', %v. Skipping", wof_id, err)
+				log.Fatalf("Failed to deprecate record %d, %v", id, err)
+			}
+
+			if !ok {
 				continue
 			}
 
-			changed := false
+			reports = append(reports, &MergeReport{
+				Id:     id,
+				Action: ACTION_DEPRECATED,
+			})
+
+			deprecated += 1
+		}
 
-			for _, path := range to_append {
+		log.Printf("Reconciled features: %d new, %d modified, %d unchanged, %d missing (%d deprecated)\n", len(rec.New), len(rec.Modified), len(rec.Unchanged), len(rec.Deleted), deprecated)
 
-				v := qgis_f.Get(path)
+	} else {
 
-				if !v.Exists() {
-					log.Printf("Missing '%s' path in updated feature for '%d', skipping", path, wof_id)
-					continue
-				}
+		for _, path := range paths {
+
+			features, err := readFeatures(ctx, path, *layer, transforms...)
+
+			if err != nil {
+				log.Fatalf("Failed to read features from '%s', %v", path, err)
+			}
+
+			for idx, qgis_f := range features {
+
+				var wof_id int64
+
+				if *lookup_key != "" {
+
+					key_rsp := qgis_f.Get(*lookup_key)
+
+					if !key_rsp.Exists() {
+						log.Fatalf("Missing '%s' property for updated feature '%s'", *lookup_key, "PATH")
+					}
+
+					key := key_rsp.String()
+
+					id, exists := lookup_map[key]
+
+					if !exists {
+						log.Fatalf("Missing key '%s'", key)
+					}
+
+					wof_id = id
+
+				} else {
+
+					id_rsp := qgis_f.Get("properties.wof:id")
 
-				wof_v := gjson.GetBytes(wof_f, path)
+					if !id_rsp.Exists() {
+						log.Fatalf("Missing wof:id property for updated feature '%d'", idx)
+					}
 
-				if wof_v.Exists() {
+					wof_id = id_rsp.Int()
+				}
 
-					enc_old, _ := json.Marshal(wof_v.Value())
-					enc_new, _ := json.Marshal(v.Value())
+				for _, path := range to_append {
 
-					if bytes.Equal(enc_old, enc_new) {
-						continue
+					if !qgis_f.Get(path).Exists() {
+						log.Printf("Missing '%s' path in updated feature for '%d', skipping", path, wof_id)
 					}
 				}
 
-				wof_f, err = sjson.SetBytes(wof_f, path, v.Value())
+				var changed []string
+				loaded := true
+
+				err := concurrency.Retry(ctx, *conflict_retries, func() error {
+
+					wof_f, err := wof_reader.LoadBytes(ctx, r, wof_id)
+
+					if err != nil {
+						log.Printf("Failed to load '%d', %v. Skipping", wof_id, err)
+						loaded = false
+						return nil
+					}
+
+					changed, err = mergeRecord(ctx, wr, ex, wof_f, qgis_f, merge_opts, *force, *dry_run)
+					return err
+				})
+
+				if !loaded {
+					continue
+				}
 
 				if err != nil {
-					log.Fatalf("Failed to set '%s' for '%d', %v", path, wof_id, err)
+					log.Fatalf("Failed to merge updated feature for '%d', %v", wof_id, err)
 				}
 
-				changed = true
-			}
+				if len(changed) == 0 {
+					log.Printf("Nothing changed for %d, skipping\n", wof_id)
+					continue
+				}
 
-			if !changed {
-				log.Printf("Nothing changed for %d, skipping\n", wof_id)
-				continue
+				reports = append(reports, &MergeReport{
+					Id:      wof_id,
+					Action:  ACTION_MERGED,
+					Changed: changed,
+				})
 			}
+		}
+	}
+
+	if *report != "" {
+
+		var report_wr io.Writer
+
+		switch *report {
+		case "-":
+			report_wr = os.Stdout
+		default:
 
-			wof_f, err = ex.Export(ctx, wof_f)
+			report_fh, err := os.OpenFile(*report, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 
 			if err != nil {
-				log.Fatalf("Failed to export new record for '%d', %v", wof_id, err)
+				log.Fatalf("Failed to open '%s' for writing, %v", *report, err)
 			}
 
-			_, err = wof_writer.WriteBytes(ctx, wr, wof_f)
+			defer report_fh.Close()
+			report_wr = report_fh
+		}
 
-			if err != nil {
-				log.Fatalf("Failed to write record for '%d', %v", wof_id, err)
+		enc := json.NewEncoder(report_wr)
+		enc.SetIndent("", "  ")
+
+		err = enc.Encode(reports)
+
+		if err != nil {
+			log.Fatalf("Failed to write report, %v", err)
+		}
+	}
+}
+
+func readFeatures(ctx context.Context, path string, layer string, transforms ...transform.Transformation) ([]gjson.Result, error) {
+
+	var f_rsp []gjson.Result
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpkg":
+
+		gpkg_features, err := geopackage.ReadFeatures(ctx, path, layer)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read GeoPackage features, %w", err)
+		}
+
+		f_rsp = make([]gjson.Result, len(gpkg_features))
+
+		for idx, body := range gpkg_features {
+			f_rsp[idx] = gjson.ParseBytes(body)
+		}
+
+	case ".shp", ".zip":
+
+		shp_features, err := shapefile.ReadFeatures(ctx, path)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read Shapefile features, %w", err)
+		}
+
+		f_rsp = make([]gjson.Result, len(shp_features))
+
+		for idx, body := range shp_features {
+			f_rsp[idx] = gjson.ParseBytes(body)
+		}
+
+	default:
+
+		fc_b, err := open(ctx, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rsp := gjson.GetBytes(fc_b, "features")
+
+		if !rsp.Exists() {
+			return nil, fmt.Errorf("Missing features property")
+		}
+
+		f_rsp = rsp.Array()
+	}
+
+	if len(transforms) == 0 {
+		return f_rsp, nil
+	}
+
+	features := make([]gjson.Result, 0)
+
+	for idx, f := range f_rsp {
+
+		body, ok, err := transform.TransformFeature(ctx, []byte(f.Raw), transforms...)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to transform feature at offset %d, %w", idx, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		features = append(features, gjson.ParseBytes(body))
+	}
+
+	return features, nil
+}
+
+func mergeRecord(ctx context.Context, wr writer.Writer, ex export.Exporter, wof_f []byte, f gjson.Result, opts *merge.MergeOptions, force bool, dry_run bool) ([]string, error) {
+
+	new_f, changed, err := merge.MergeFeature(ctx, wof_f, []byte(f.Raw), opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  force,
+		Logger: log.Default(),
+	}
+
+	new_f, reverted, err := exportify.RevertControlledChanges(wof_f, new_f, controlled_opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Only report the paths whose changes survived reverting changes to controlled properties.
+
+	if len(reverted) > 0 {
+
+		merged := make([]string, 0)
+
+		for _, path := range changed {
+
+			if gjson.GetBytes(wof_f, path).Raw != gjson.GetBytes(new_f, path).Raw {
+				merged = append(merged, path)
 			}
+		}
+
+		changed = merged
+	}
+
+	if len(changed) == 0 || dry_run {
+		return changed, nil
+	}
+
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, wof_f, new_f, controlled_opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// createRecord creates a new record from the values of the -path flags (and the paths required to create a record)
+// in 'f'. It returns the ID of the new record, which is 0 if 'dry_run' is true, and the list of paths that were copied.
+func createRecord(ctx context.Context, wr writer.Writer, ex export.Exporter, f gjson.Result, opts *merge.MergeOptions, dry_run bool) (int64, []string, error) {
+
+	create_opts := *opts
+	create_opts.Paths = make([]string, 0)
+
+	seen := make(map[string]bool)
 
+	for _, path := range append(requiredPaths, opts.Paths...) {
+
+		if seen[path] || path == "properties.wof:id" {
+			continue
 		}
 
+		seen[path] = true
+		create_opts.Paths = append(create_opts.Paths, path)
+	}
+
+	body := []byte(`{"type":"Feature","properties":{}}`)
+
+	body, changed, err := merge.MergeFeature(ctx, body, []byte(f.Raw), &create_opts)
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to copy paths to new record, %w", err)
+	}
+
+	if dry_run {
+		log.Printf("Create new record for feature named '%s' (dry run)\n", f.Get("properties.wof:name").String())
+		return 0, changed, nil
+	}
+
+	body, err = ex.Export(ctx, body)
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to export new record, %w", err)
+	}
+
+	_, err = wof_writer.WriteBytes(ctx, wr, body)
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to write new record, %w", err)
+	}
+
+	new_id := gjson.GetBytes(body, "properties.wof:id").Int()
+
+	log.Printf("Created new record %d\n", new_id)
+	return new_id, changed, nil
+}
+
+func deprecateRecord(ctx context.Context, r reader.Reader, wr writer.Writer, ex export.Exporter, id int64, force bool, dry_run bool) (bool, error) {
+
+	body, err := wof_reader.LoadBytes(ctx, r, id)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to load record, %w", err)
+	}
+
+	deprecated_rsp := gjson.GetBytes(body, "properties.edtf:deprecated")
+
+	switch deprecated_rsp.String() {
+	case "", "uuuu":
+		// pass
+	default:
+		log.Printf("Record %d is already deprecated, skipping\n", id)
+		return false, nil
+	}
+
+	to_update := map[string]interface{}{
+		"properties.edtf:deprecated": time.Now().Format("2006-01-02"),
+		"properties.mz:is_current":   0,
+	}
+
+	new_body, err := export.AssignProperties(ctx, body, to_update)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to assign deprecated properties, %w", err)
+	}
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  force,
+		Logger: log.Default(),
+	}
+
+	new_body, _, err = exportify.RevertControlledChanges(body, new_body, controlled_opts)
+
+	if err != nil {
+		return false, err
+	}
+
+	if gjson.GetBytes(new_body, "properties.edtf:deprecated").String() == deprecated_rsp.String() {
+		log.Printf("Record %d can not be deprecated because edtf:deprecated is a controlled property, skipping\n", id)
+		return false, nil
+	}
+
+	if dry_run {
+		log.Printf("Deprecate record %d (dry run)\n", id)
+		return true, nil
+	}
+
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to deprecate record, %w", err)
 	}
+
+	return true, nil
 }
@@ -0,0 +1,321 @@
+// wof-migrate-namespace renames the properties in one or more Who's On First records, and their alternate geometry
+// files, whose names match a prefix or a regular expression.
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-csvdict"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/migrate"
+	"github.com/whosonfirst/go-whosonfirst-format"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+func main() {
+
+	iterator_uri := flag.String("indexer-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v2 URI.")
+	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	writer_uri := flag.String("writer-uri", "null://", "A valid whosonfirst/go-writer URI.")
+
+	var renames migrate.Renames
+
+	flag.Func("prefix", "One or more {OLD_PREFIX}={NEW_PREFIX} flags. Properties whose names start with {OLD_PREFIX} will have it replaced by {NEW_PREFIX}. For example: 'sfomuseum:*=sfo:*'.", func(str string) error {
+
+		r, err := migrate.ParsePrefixRename(str)
+
+		if err != nil {
+			return err
+		}
+
+		renames = append(renames, r)
+		return nil
+	})
+
+	flag.Func("regexp", "One or more {PATTERN}={REPLACEMENT} flags. The first match of the regular expression {PATTERN} in property names will be replaced by {REPLACEMENT}, which may contain '$1' style references to capture groups. For example: '^misc:foo_(.*)$=foo:$1'.", func(str string) error {
+
+		r, err := migrate.ParseRegexpRename(str)
+
+		if err != nil {
+			return err
+		}
+
+		renames = append(renames, r)
+		return nil
+	})
+
+	collision := flag.String("collision", migrate.COLLISION_FAIL, fmt.Sprintf("The strategy to use when a property is renamed to a property that already exists. Valid options are: %s.", strings.Join(migrate.CollisionStrategies(), ", ")))
+
+	report_path := flag.String("report", "", "The path to write a CSV migration report to. If \"-\" the report will be written to STDOUT.")
+	dry_run := flag.Bool("dry-run", false, "Report what would be renamed without writing any records.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Rename the properties whose names match a prefix or a regular expression in one or more Who's On First records, and their alternate geometry files.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] uri(N) uri(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -writer-uri fs:///usr/local/data/sfomuseum-data-architecture/data -prefix 'sfomuseum:*=sfo:*' -report - /usr/local/data/sfomuseum-data-architecture\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Renames are applied in the order they are passed and each property is renamed by the first one it matches. Matching entries in wof:controlled are renamed too.\n\n")
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if len(renames) == 0 {
+		log.Fatalf("Missing -prefix or -regexp flag")
+	}
+
+	if !slices.Contains(migrate.CollisionStrategies(), *collision) {
+		log.Fatalf("Invalid -collision flag '%s'", *collision)
+	}
+
+	migrate_opts := &migrate.MigrateOptions{
+		Renames:   renames,
+		Collision: *collision,
+	}
+
+	ctx := context.Background()
+
+	ex, err := export.NewExporter(ctx, *exporter_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create exporter for '%s', %v", *exporter_uri, err)
+	}
+
+	wr, err := writer.NewWriter(ctx, *writer_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
+	}
+
+	wr, versions := concurrency.GuardWriter(ctx, wr)
+
+	var report *csvdict.Writer
+
+	if *report_path != "" {
+
+		var report_wr io.Writer
+
+		if *report_path == "-" {
+			report_wr = os.Stdout
+		} else {
+
+			fh, err := os.Create(*report_path)
+
+			if err != nil {
+				log.Fatalf("Failed to create %s, %v", *report_path, err)
+			}
+
+			defer fh.Close()
+			report_wr = fh
+		}
+
+		fieldnames := []string{"path", "id", "old", "new", "collision", "error"}
+
+		report, err = csvdict.NewWriter(report_wr, fieldnames)
+
+		if err != nil {
+			log.Fatalf("Failed to create report writer, %v", err)
+		}
+
+		err = report.WriteHeader()
+
+		if err != nil {
+			log.Fatalf("Failed to write report header, %v", err)
+		}
+	}
+
+	// Callbacks may be invoked concurrently so access to the report and counts is serialized.
+
+	mu := new(sync.Mutex)
+
+	count_files := 0
+	count_properties := 0
+	count_failed := 0
+
+	writeRow := func(row map[string]string) error {
+
+		if report == nil {
+			return nil
+		}
+
+		return report.WriteRow(row)
+	}
+
+	// Migrating a property renames its entry in wof:controlled too so controlled properties are migrated
+	// without requiring a -force flag.
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force: true,
+	}
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		id, uri_args, err := uri.ParseURI(path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to parse URI for %s, %w", path, err)
+		}
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		versions.TrackFile(path, body)
+
+		new_body, changes, err := migrate.Migrate(body, migrate_opts)
+
+		if err != nil {
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			log.Printf("Failed to migrate %s, %v\n", path, err)
+			count_failed += 1
+
+			row := map[string]string{
+				"path":      path,
+				"id":        strconv.FormatInt(id, 10),
+				"collision": *collision,
+				"error":     err.Error(),
+			}
+
+			var collision_err *migrate.CollisionError
+
+			if errors.As(err, &collision_err) {
+				row["old"] = collision_err.Old
+				row["new"] = collision_err.New
+			}
+
+			return writeRow(row)
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if !*dry_run {
+
+			// Alternate geometry files lack the properties the exporter requires so they are
+			// only formatted.
+
+			if uri_args.IsAlternate {
+
+				new_body, err = format.FormatBytes(new_body)
+
+				if err != nil {
+					return fmt.Errorf("Failed to format %s, %w", path, err)
+				}
+
+				rel_path, err := uri.Id2RelPath(id, uri_args)
+
+				if err != nil {
+					return fmt.Errorf("Failed to derive rel_path for %d (%s), %w", id, path, err)
+				}
+
+				_, err = wr.Write(ctx, rel_path, bytes.NewReader(new_body))
+
+				if err != nil {
+					return fmt.Errorf("Failed to write %s (for %s), %w", rel_path, path, err)
+				}
+
+			} else {
+
+				_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, body, new_body, controlled_opts)
+
+				if err != nil {
+					return fmt.Errorf("Failed to export %s, %w", path, err)
+				}
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		count_files += 1
+		count_properties += len(changes)
+
+		str_changes := make([]string, len(changes))
+
+		for i, c := range changes {
+
+			str_changes[i] = fmt.Sprintf("%s=%s", c.Old, c.New)
+
+			row := map[string]string{
+				"path":      path,
+				"id":        strconv.FormatInt(id, 10),
+				"old":       c.Old,
+				"new":       c.New,
+				"collision": c.Collision,
+			}
+
+			err := writeRow(row)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write report row for %s, %w", path, err)
+			}
+		}
+
+		log.Printf("Migrated %s (%s)\n", path, strings.Join(str_changes, ", "))
+		return nil
+	}
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
+
+	if err != nil {
+		log.Fatalf("Failed to create iterator, %v", err)
+	}
+
+	paths := flag.Args()
+
+	err = iter.IterateURIs(ctx, paths...)
+
+	if err != nil {
+		log.Fatalf("Failed to iterate URIs, %v", err)
+	}
+
+	if report != nil {
+
+		report.Flush()
+
+		err = report.Error()
+
+		if err != nil {
+			log.Fatalf("Failed to write report, %v", err)
+		}
+	}
+
+	err = wr.Close(ctx)
+
+	if err != nil {
+		log.Fatalf("Failed to close writer, %v", err)
+	}
+
+	log.Printf("Migrated %d properties in %d files\n", count_properties, count_files)
+
+	if count_failed > 0 {
+		log.Fatalf("Failed to migrate %d files", count_failed)
+	}
+}
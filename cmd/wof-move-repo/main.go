@@ -0,0 +1,648 @@
+// wof-move-repo moves one or more Who's On First records, including their alternate geometry files, from one
+// repository checkout to another updating their `wof:repo` property.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// move is a single file being moved from one checkout to another.
+type move struct {
+	// The path of the file relative to the source checkout's "data" directory.
+	RelPath string
+	// The body of the file with an updated `wof:repo` property. Records are exported; alternate geometry files,
+	// which lack the properties the exporter requires, are left otherwise unchanged.
+	Body []byte
+}
+
+func main() {
+
+	from := flag.String("from", "", "The path to the root directory of the Who's On First data repository that records are being moved from.")
+	to := flag.String("to", "", "The path to the root directory of the Who's On First data repository that records are being moved to.")
+	repo := flag.String("repo", "", "The new value of the wof:repo property. If empty the name of the -to directory will be used.")
+
+	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+
+	var ids multi.MultiInt64
+	flag.Var(&ids, "id", "One or more Who's On First IDs to move.")
+
+	use_git := flag.Bool("git", false, "Stage the moved files using 'git add' in the -to checkout and remove them using 'git rm' in the -from checkout.")
+	update_meta := flag.Bool("update-meta", false, "Move the rows for each record from the CSV files in the -from checkout's meta directory to the corresponding files in the -to checkout's meta directory. If false the rows are only reported.")
+	check_references := flag.Bool("check-references", true, "Report other records in the -from checkout that reference the records being moved.")
+	dry_run := flag.Bool("dry-run", false, "Report what would be done without changing anything.")
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Move one or more Who's On First records, including alternate geometry files, from one repository to another.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -from /usr/local/data/whosonfirst-data-admin-xy -to /usr/local/data/whosonfirst-data-admin-ca -id 1234 -git -update-meta\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *from == "" || *to == "" {
+		log.Fatalf("Missing -from or -to flag")
+	}
+
+	if len(ids) == 0 {
+		log.Fatalf("Missing -id flag")
+	}
+
+	// Moving the same record twice would fail half-way through, after the first move has been written.
+
+	unique_ids := make([]int64, 0, len(ids))
+
+	for _, id := range ids {
+
+		if !slices.Contains(unique_ids, id) {
+			unique_ids = append(unique_ids, id)
+		}
+	}
+
+	ids = unique_ids
+
+	abs_from, err := filepath.Abs(*from)
+
+	if err != nil {
+		log.Fatalf("Failed to derive absolute path for '%s', %v", *from, err)
+	}
+
+	abs_to, err := filepath.Abs(*to)
+
+	if err != nil {
+		log.Fatalf("Failed to derive absolute path for '%s', %v", *to, err)
+	}
+
+	if abs_from == abs_to {
+		log.Fatalf("-from and -to must be different checkouts")
+	}
+
+	if *repo == "" {
+		*repo = filepath.Base(abs_to)
+	}
+
+	from_data := filepath.Join(abs_from, "data")
+	to_data := filepath.Join(abs_to, "data")
+
+	for _, path := range []string{from_data, to_data} {
+
+		info, err := os.Stat(path)
+
+		if err != nil {
+			log.Fatalf("Failed to stat %s, %v", path, err)
+		}
+
+		if !info.IsDir() {
+			log.Fatalf("%s is not a directory", path)
+		}
+	}
+
+	ctx := context.Background()
+
+	ex, err := export.NewExporter(ctx, *exporter_uri)
+
+	if err != nil {
+		log.Fatalf("Failed create exporter for '%s', %v", *exporter_uri, err)
+	}
+
+	// Read, and update, everything before changing anything so that a missing record or a
+	// conflict in the destination checkout doesn't leave a move half-finished.
+
+	// The version of each file is tracked so that files which are changed by another process before they are
+	// moved aren't overwritten, or removed, with the version that was read.
+
+	versions := concurrency.NewVersions()
+
+	moves := make([]*move, 0)
+	old_repos := make(map[string]bool)
+	planned := make(map[string]int64)
+
+	for _, id := range ids {
+
+		id_moves, old_repo, err := planMoves(ctx, ex, versions, from_data, to_data, id, *repo)
+
+		if err != nil {
+			log.Fatalf("Failed to plan move for %d, %v", id, err)
+		}
+
+		for _, m := range id_moves {
+
+			other_id, exists := planned[m.RelPath]
+
+			if exists {
+				log.Fatalf("Failed to plan move for %d, %s is already being moved for %d", id, m.RelPath, other_id)
+			}
+
+			planned[m.RelPath] = id
+		}
+
+		moves = append(moves, id_moves...)
+		old_repos[old_repo] = true
+	}
+
+	for _, m := range moves {
+		log.Printf("Move %s from %s to %s\n", m.RelPath, abs_from, abs_to)
+	}
+
+	if !*dry_run {
+
+		err := applyMoves(ctx, abs_from, abs_to, moves, versions, *use_git)
+
+		if err != nil {
+			log.Fatalf("Failed to move records, %v", err)
+		}
+	}
+
+	for old_repo := range old_repos {
+
+		err := moveMetaRows(ctx, abs_from, abs_to, old_repo, *repo, ids, *update_meta && !*dry_run, *use_git)
+
+		if err != nil {
+			log.Fatalf("Failed to process meta files, %v", err)
+		}
+	}
+
+	if *check_references {
+
+		err := reportReferences(from_data, ids)
+
+		if err != nil {
+			log.Fatalf("Failed to check references, %v", err)
+		}
+	}
+}
+
+// planMoves returns the list of files (the record and its alternate geometry files) to move for 'id', with
+// their "wof:repo" property assigned to 'repo', and the record's current "wof:repo" property. Nothing is
+// written so any errors are reported before files in either checkout are changed.
+func planMoves(ctx context.Context, ex export.Exporter, versions *concurrency.Versions, from_data string, to_data string, id int64, repo string) ([]*move, string, error) {
+
+	rel_path, err := uri.Id2RelPath(id)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to derive path, %w", err)
+	}
+
+	rel_dir := filepath.Dir(rel_path)
+
+	alt_paths, err := filepath.Glob(filepath.Join(from_data, rel_dir, fmt.Sprintf("%d-alt-*.geojson", id)))
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to find alternate geometry files, %w", err)
+	}
+
+	rel_paths := []string{rel_path}
+
+	for _, path := range alt_paths {
+		rel_paths = append(rel_paths, filepath.Join(rel_dir, filepath.Base(path)))
+	}
+
+	moves := make([]*move, 0)
+	old_repo := ""
+
+	for idx, p := range rel_paths {
+
+		body, err := os.ReadFile(filepath.Join(from_data, p))
+
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to read %s, %w", p, err)
+		}
+
+		versions.Track(filepath.Join(from_data, p), body)
+
+		if idx == 0 {
+
+			old_repo = gjson.GetBytes(body, "properties.wof:repo").String()
+
+			if old_repo == repo {
+				return nil, "", fmt.Errorf("Record already has wof:repo=%s", repo)
+			}
+		}
+
+		_, err = os.Stat(filepath.Join(to_data, p))
+
+		if err == nil {
+			return nil, "", fmt.Errorf("%s already exists in destination checkout", p)
+		}
+
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", fmt.Errorf("Failed to stat %s in destination checkout, %w", p, err)
+		}
+
+		body, err = sjson.SetBytes(body, "properties.wof:repo", repo)
+
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to assign wof:repo to %s, %w", p, err)
+		}
+
+		if idx == 0 {
+
+			body, err = ex.Export(ctx, body)
+
+			if err != nil {
+				return nil, "", fmt.Errorf("Failed to export %s, %w", p, err)
+			}
+		}
+
+		m := &move{
+			RelPath: p,
+			Body:    body,
+		}
+
+		moves = append(moves, m)
+	}
+
+	return moves, old_repo, nil
+}
+
+// applyMoves writes 'moves' to the "data" directory of the 'to' checkout and removes them from the "data" directory of
+// the 'from' checkout, using git if 'use_git' is true. The lock files for both checkouts are held while files are
+// written and removed and an error wrapping `concurrency.ErrConflict` is returned, before anything is changed, if any
+// of the files being moved have been changed since they were read.
+func applyMoves(ctx context.Context, abs_from string, abs_to string, moves []*move, versions *concurrency.Versions, use_git bool) error {
+
+	from_data := filepath.Join(abs_from, "data")
+	to_data := filepath.Join(abs_to, "data")
+
+	// Acquire the locks in a consistent order so that processes moving records in opposite directions
+	// don't wait on each other.
+
+	roots := []string{abs_from, abs_to}
+	slices.Sort(roots)
+
+	for _, root := range roots {
+
+		unlock, err := concurrency.LockRepo(ctx, root)
+
+		if err != nil {
+			return fmt.Errorf("Failed to acquire lock for %s, %w", root, err)
+		}
+
+		defer unlock()
+	}
+
+	for _, m := range moves {
+
+		err := versions.Check(filepath.Join(from_data, m.RelPath))
+
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Stat(filepath.Join(to_data, m.RelPath))
+
+		if err == nil {
+			return fmt.Errorf("%s has been created in destination checkout since it was checked", m.RelPath)
+		}
+	}
+
+	wr, err := writer.NewWriter(ctx, fmt.Sprintf("fs://%s", to_data))
+
+	if err != nil {
+		return fmt.Errorf("Failed to create writer for %s, %w", to_data, err)
+	}
+
+	for _, m := range moves {
+
+		_, err := wr.Write(ctx, m.RelPath, bytes.NewReader(m.Body))
+
+		if err != nil {
+			return fmt.Errorf("Failed to write %s, %w", m.RelPath, err)
+		}
+	}
+
+	err = wr.Close(ctx)
+
+	if err != nil {
+		return fmt.Errorf("Failed to close writer, %w", err)
+	}
+
+	if !use_git {
+
+		for _, m := range moves {
+
+			err := os.Remove(filepath.Join(from_data, m.RelPath))
+
+			if err != nil {
+				return fmt.Errorf("Failed to remove %s, %w", m.RelPath, err)
+			}
+		}
+
+		return nil
+	}
+
+	git_paths := make([]string, len(moves))
+
+	for idx, m := range moves {
+		git_paths[idx] = filepath.Join("data", m.RelPath)
+	}
+
+	err = runGit(ctx, abs_to, append([]string{"add", "--"}, git_paths...)...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add moved files, %w", err)
+	}
+
+	err = runGit(ctx, abs_from, append([]string{"rm", "-q", "-f", "--"}, git_paths...)...)
+
+	if err != nil {
+		return fmt.Errorf("Failed to remove moved files, %w", err)
+	}
+
+	return nil
+}
+
+// moveMetaRows reports the rows for 'ids' in the CSV files in the "meta" directory of the 'from' checkout. If 'update'
+// is true the rows are removed from those files and appended to the files with the same name, with 'old_repo' replaced
+// by 'new_repo', in the "meta" directory of the 'to' checkout. If present the "wof_repo" column of moved rows is
+// assigned 'new_repo'.
+func moveMetaRows(ctx context.Context, from string, to string, old_repo string, new_repo string, ids []int64, update bool, use_git bool) error {
+
+	meta_paths, err := filepath.Glob(filepath.Join(from, "meta", "*.csv"))
+
+	if err != nil {
+		return err
+	}
+
+	str_ids := make([]string, len(ids))
+
+	for idx, id := range ids {
+		str_ids[idx] = strconv.FormatInt(id, 10)
+	}
+
+	for _, meta_path := range meta_paths {
+
+		header, rows, err := readCSV(meta_path)
+
+		if err != nil {
+			return err
+		}
+
+		id_idx := slices.Index(header, "id")
+
+		if id_idx == -1 {
+			continue
+		}
+
+		keep := make([][]string, 0)
+		moved := make([][]string, 0)
+
+		for _, row := range rows {
+
+			if id_idx < len(row) && slices.Contains(str_ids, row[id_idx]) {
+				moved = append(moved, row)
+			} else {
+				keep = append(keep, row)
+			}
+		}
+
+		if len(moved) == 0 {
+			continue
+		}
+
+		fname := filepath.Base(meta_path)
+
+		if old_repo != "" {
+			fname = strings.Replace(fname, old_repo, new_repo, 1)
+		}
+
+		dest_path := filepath.Join(to, "meta", fname)
+
+		repo_idx := slices.Index(header, "wof_repo")
+
+		for _, row := range moved {
+
+			log.Printf("Meta file %s references %s, move to %s\n", meta_path, row[id_idx], dest_path)
+
+			if repo_idx != -1 && repo_idx < len(row) {
+				row[repo_idx] = new_repo
+			}
+		}
+
+		if !update {
+			continue
+		}
+
+		err = writeCSV(meta_path, header, keep)
+
+		if err != nil {
+			return err
+		}
+
+		err = appendCSV(dest_path, header, moved)
+
+		if err != nil {
+			return err
+		}
+
+		if use_git {
+
+			err = runGit(ctx, from, "add", "--", filepath.Join("meta", filepath.Base(meta_path)))
+
+			if err != nil {
+				return err
+			}
+
+			err = runGit(ctx, to, "add", "--", filepath.Join("meta", fname))
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reportReferences reports any records in 'data_dir' whose "wof:parent_id", "wof:belongsto", "wof:supersedes",
+// "wof:superseded_by" or "wof:hierarchy" properties reference 'ids'. The records in 'ids' themselves are skipped.
+func reportReferences(data_dir string, ids []int64) error {
+
+	paths := []string{
+		"properties.wof:parent_id",
+		"properties.wof:belongsto",
+		"properties.wof:supersedes",
+		"properties.wof:superseded_by",
+		"properties.wof:hierarchy.#.@values",
+	}
+
+	walk_func := func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".geojson" {
+			return nil
+		}
+
+		body, err := os.ReadFile(path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		if slices.Contains(ids, gjson.GetBytes(body, "properties.wof:id").Int()) {
+			return nil
+		}
+
+		for _, p := range paths {
+
+			for _, v := range flattenInts(gjson.GetBytes(body, p)) {
+
+				if slices.Contains(ids, v) {
+					log.Printf("%s references %d (%s)\n", path, v, strings.TrimPrefix(p, "properties."))
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return filepath.WalkDir(data_dir, walk_func)
+}
+
+func flattenInts(rsp gjson.Result) []int64 {
+
+	if !rsp.IsArray() {
+		return []int64{rsp.Int()}
+	}
+
+	values := make([]int64, 0)
+
+	for _, r := range rsp.Array() {
+		values = append(values, flattenInts(r)...)
+	}
+
+	return values
+}
+
+func readCSV(path string) ([]string, [][]string, error) {
+
+	fh, err := os.Open(path)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer fh.Close()
+
+	csv_r := csv.NewReader(fh)
+	csv_r.FieldsPerRecord = -1
+
+	header, err := csv_r.Read()
+
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("Failed to read header for %s, %w", path, err)
+	}
+
+	rows, err := csv_r.ReadAll()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	return header, rows, nil
+}
+
+func writeCSV(path string, header []string, rows [][]string) error {
+
+	fh, err := os.Create(path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create %s, %w", path, err)
+	}
+
+	csv_wr := csv.NewWriter(fh)
+	csv_wr.Write(header)
+	csv_wr.WriteAll(rows)
+
+	err = csv_wr.Error()
+
+	if err != nil {
+		fh.Close()
+		return fmt.Errorf("Failed to write %s, %w", path, err)
+	}
+
+	return fh.Close()
+}
+
+// appendCSV appends 'rows' to the CSV file at 'path', creating it with 'header' if necessary. If the file already
+// exists rows are reordered to match its header; columns missing from 'header' are left empty.
+func appendCSV(path string, header []string, rows [][]string) error {
+
+	existing_header, existing_rows, err := readCSV(path)
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if existing_header == nil {
+
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create %s, %w", filepath.Dir(path), err)
+		}
+
+		return writeCSV(path, header, rows)
+	}
+
+	for _, row := range rows {
+
+		new_row := make([]string, len(existing_header))
+
+		for idx, col := range existing_header {
+
+			src_idx := slices.Index(header, col)
+
+			if src_idx != -1 && src_idx < len(row) {
+				new_row[idx] = row[src_idx]
+			}
+		}
+
+		existing_rows = append(existing_rows, new_row)
+	}
+
+	return writeCSV(path, existing_header, existing_rows)
+}
+
+func runGit(ctx context.Context, dir string, args ...string) error {
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("Failed to run git %s in %s, %w\n%s", strings.Join(args, " "), dir, err, out)
+	}
+
+	return nil
+}
@@ -1,3 +1,5 @@
+// wof-remove-properties removes one or more properties, which may be glob patterns, from one or more Who's On First
+// records and exports the results.
 package main
 
 import (
@@ -6,35 +8,111 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/sfomuseum/go-flags/multi"
 	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/rules"
 	_ "github.com/whosonfirst/go-whosonfirst-iterate-reader"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
 	uri "github.com/whosonfirst/go-whosonfirst-uri"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
 func main() {
 
 	iterator_uri := flag.String("indexer-uri", "repo://", "A valid whosonfirst/go-whosonfirst-iterate/v2 URI.")
+	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
 	writer_uri := flag.String("writer-uri", "null://", "A valid whosonfirst/go-writer URI.")
 
 	var properties multi.MultiString
-	flag.Var(&properties, "property", "One or more (fully-qualified) properties to remove")
+	flag.Var(&properties, "property", "One or more (fully-qualified) properties to remove. Properties may contain glob patterns, for example 'properties.misc:*' or 'properties.name:*_x_colloquial'.")
+
+	force := flag.Bool("force", false, "Remove properties listed in a record's wof:controlled property and properties, other than wof:id, that are assigned by the exporter if they are named explicitly.")
+
+	rules_path := flag.String("rules", "", "The path to an optional JSON file containing a list of conditional rules to apply to each record, after removing -property properties.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Remove one or more properties from one or more Who's On First records.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] uri(N) uri(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -writer-uri fs:///usr/local/data/whosonfirst-data-admin-ca/data -property 'properties.misc:*' -property 'properties.name:*_x_colloquial' /usr/local/data/whosonfirst-data-admin-ca\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
 
 	flag.Parse()
 
+	if len(properties) == 0 && *rules_path == "" {
+		log.Fatalf("Missing -property or -rules flag")
+	}
+
+	for _, p := range properties {
+
+		err := exportify.ValidatePathPattern(p)
+
+		if err != nil {
+			log.Fatalf("Invalid -property flag, %v", err)
+		}
+
+		// Removing wof:id (or all the properties) would cause the exporter to mint a new ID for the record.
+
+		if p == "properties" || p == "properties.wof:id" {
+			log.Fatalf("Invalid -property flag, '%s' can not be removed", p)
+		}
+
+		if exportify.IsProtectedPath(p) && exportify.IsPathPattern(p) {
+			log.Fatalf("Invalid -property flag, '%s' matches properties assigned by the exporter which can only be removed by name", p)
+		}
+
+		if exportify.IsProtectedPath(p) && !*force {
+			log.Fatalf("Invalid -property flag, '%s' is assigned by the exporter, use -force to override", p)
+		}
+	}
+
+	var conditional_rules rules.Rules
+
+	if *rules_path != "" {
+
+		r, err := rules.ReadRulesFile(*rules_path)
+
+		if err != nil {
+			log.Fatalf("Failed to load rules, %v", err)
+		}
+
+		conditional_rules = r
+	}
+
 	ctx := context.Background()
 
+	ex, err := export.NewExporter(ctx, *exporter_uri)
+
+	if err != nil {
+		log.Fatalf("Failed to create exporter for '%s', %v", *exporter_uri, err)
+	}
+
 	wr, err := writer.NewWriter(ctx, *writer_uri)
 
 	if err != nil {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	wr, versions := concurrency.GuardWriter(ctx, wr)
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
 
 		_, uri_args, err := uri.ParseURI(path)
@@ -54,42 +132,115 @@ func main() {
 			return err
 		}
 
+		versions.TrackFile(path, body)
+
+		original_body := body
 		changed := false
 
-		for _, path := range properties {
+		// Expand all the patterns against the original record. Controlled properties are checked against
+		// the original record too so that removing wof:controlled itself doesn't unlock any of the properties
+		// it lists.
 
-			rsp := gjson.GetBytes(body, path)
+		to_remove := make([]string, 0)
+		seen := make(map[string]bool)
 
-			if !rsp.Exists() {
-				continue
+		for _, p := range properties {
+
+			matches, err := exportify.ExpandPath(body, p)
+
+			if err != nil {
+				return fmt.Errorf("Failed to expand '%s' for %s, %w", p, path, err)
 			}
 
-			var err error
+			for _, m := range matches {
+
+				if seen[m] {
+					continue
+				}
+
+				seen[m] = true
+
+				// Properties assigned by the exporter are only removed if they are named explicitly, which
+				// has already been checked, rather than matched by a glob pattern.
+
+				if m != p && exportify.IsProtectedPath(m) {
+					return fmt.Errorf("Pattern '%s' matches '%s' in %s which is assigned by the exporter and can only be removed by name", p, m, path)
+				}
+
+				to_remove = append(to_remove, m)
+			}
+		}
 
-			body, err = sjson.DeleteBytes(body, path)
+		if len(to_remove) > 0 {
+
+			body, err = export.RemoveProperties(ctx, body, to_remove)
 
 			if err != nil {
-				return fmt.Errorf("failed to delete %s, %w", path, err)
+				return fmt.Errorf("Failed to remove properties from %s, %w", path, err)
 			}
 
 			changed = true
 		}
 
+		if len(conditional_rules) > 0 {
+
+			rules_opts := &rules.ApplyOptions{
+				Force:  controlled_opts.Force,
+				Logger: controlled_opts.Logger,
+			}
+
+			new_body, rules_changed, err := conditional_rules.Apply(ctx, body, rules_opts)
+
+			if err != nil {
+				return fmt.Errorf("Failed to apply rules to %s, %w", path, err)
+			}
+
+			body = new_body
+
+			if len(rules_changed) > 0 {
+				changed = true
+			}
+		}
+
 		if !changed {
 			return nil
 		}
 
-		_, err = wof_writer.WriteBytes(ctx, wr, body)
+		body, _, err = exportify.RevertControlledChanges(original_body, body, controlled_opts)
 
 		if err != nil {
-			return err
+			return fmt.Errorf("Failed to check controlled properties for %s, %w", path, err)
+		}
+
+		removed := make([]string, 0)
+
+		for _, m := range to_remove {
+
+			if !gjson.GetBytes(body, m).Exists() {
+				removed = append(removed, m)
+			}
+		}
+
+		written, err := exportify.ExportChangesWithWriter(ctx, ex, wr, original_body, body, controlled_opts)
+
+		if err != nil {
+			return fmt.Errorf("Failed to export %s, %w", path, err)
+		}
+
+		if !written {
+			return nil
+		}
+
+		if len(removed) > 0 {
+			log.Printf("Updated %s (removed %s)\n", path, strings.Join(removed, ", "))
+		} else {
+			log.Printf("Updated %s\n", path)
 		}
 
-		log.Printf("Updated %s\n", path)
 		return nil
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, cb)
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
 
 	if err != nil {
 		log.Fatal(err)
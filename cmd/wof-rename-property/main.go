@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -11,8 +12,11 @@ import (
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+	"github.com/whosonfirst/go-whosonfirst-exportify/rules"
 	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
-	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
 )
 
@@ -25,8 +29,36 @@ func main() {
 	old_property := flag.String("old-property", "", "The fully qualified path of the property to rename.")
 	new_property := flag.String("new-property", "", "The fully qualified path of the property to be (re)named.")
 
+	force := flag.Bool("force", false, "Rename properties in records whose wof:controlled property lists either the old or new property.")
+
+	rules_path := flag.String("rules", "", "The path to an optional JSON file containing a list of conditional rules to apply to each record, after renaming -old-property.")
+
+	var filters filter.Filters
+	filter.AppendFlags(flag.CommandLine, &filters)
+
 	flag.Parse()
 
+	if *old_property == "" && *rules_path == "" {
+		log.Fatalf("Missing -old-property or -rules flag")
+	}
+
+	if *old_property != "" && *new_property == "" {
+		log.Fatalf("Missing -new-property flag")
+	}
+
+	var conditional_rules rules.Rules
+
+	if *rules_path != "" {
+
+		r, err := rules.ReadRulesFile(*rules_path)
+
+		if err != nil {
+			log.Fatalf("Failed to load rules, %v", err)
+		}
+
+		conditional_rules = r
+	}
+
 	ctx := context.Background()
 
 	ex, err := export.NewExporter(ctx, *exporter_uri)
@@ -41,49 +73,115 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
-	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+	wr, versions := concurrency.GuardWriter(ctx, wr)
 
-		body, err := ioutil.ReadAll(fh)
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
 
-		if err != nil {
-			return err
-		}
+	// rename renames *old_property to *new_property in 'body' and returns the updated record along with
+	// a boolean value indicating whether it was changed.
+
+	rename := func(body []byte) ([]byte, bool, error) {
 
 		old_rsp := gjson.GetBytes(body, *old_property)
 
 		if !old_rsp.Exists() {
-			return nil
+			return body, false, nil
 		}
 
-		body, err = sjson.SetBytes(body, *new_property, old_rsp.Value())
+		new_body, err := sjson.SetBytes(body, *new_property, old_rsp.Value())
 
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 
-		body, err = sjson.DeleteBytes(body, *old_property)
+		new_body, err = sjson.DeleteBytes(new_body, *old_property)
 
 		if err != nil {
-			return err
+			return nil, false, err
 		}
 
-		new_body, err := ex.Export(ctx, body)
+		// Skip the rename in its entirety, rather than reverting only one half of it, if it changes a controlled property.
+
+		_, reverted, err := exportify.RevertControlledChanges(body, new_body, controlled_opts)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(reverted) > 0 {
+			return body, false, nil
+		}
+
+		return new_body, true, nil
+	}
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		body, err := ioutil.ReadAll(fh)
 
 		if err != nil {
 			return err
 		}
 
-		_, err = wof_writer.WriteBytes(ctx, wr, new_body)
+		versions.TrackFile(path, body)
+
+		original_body := body
+		changed := false
+
+		if *old_property != "" {
+
+			new_body, renamed, err := rename(body)
+
+			if err != nil {
+				return err
+			}
+
+			body = new_body
+			changed = renamed
+		}
+
+		if len(conditional_rules) > 0 {
+
+			rules_opts := &rules.ApplyOptions{
+				Force:  controlled_opts.Force,
+				Logger: controlled_opts.Logger,
+			}
+
+			new_body, rules_changed, err := conditional_rules.Apply(ctx, body, rules_opts)
+
+			if err != nil {
+				return fmt.Errorf("Failed to apply rules to %s, %w", path, err)
+			}
+
+			body = new_body
+
+			if len(rules_changed) > 0 {
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		written, err := exportify.ExportChangesWithWriter(ctx, ex, wr, original_body, body, controlled_opts)
 
 		if err != nil {
 			return err
 		}
 
+		if !written {
+			return nil
+		}
+
 		log.Printf("Updated %s\n", path)
 		return nil
 	}
 
-	iter, err := iterator.NewIterator(ctx, *iterator_uri, cb)
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, filter.IteratorCallback(filters, log.Default(), cb))
 
 	if err != nil {
 		log.Fatal(err)
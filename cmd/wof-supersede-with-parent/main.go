@@ -6,11 +6,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/sfomuseum/go-flags/multi"
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/provider"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	"github.com/whosonfirst/go-writer/v3"
 )
@@ -22,12 +28,17 @@ func main() {
 	parent_reader_uri := flag.String("parent-reader-uri", "", "A valid whosonfirst/go-reader URI. If empty the value of the -reader-uri flag will be assumed.")
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
+	id_provider_uri := flag.String("id-provider-uri", "", fmt.Sprintf("An optional go-whosonfirst-exportify/provider URI used to mint new record IDs. Minted IDs that can already be read from the -reader-uri reader are skipped. If empty the exporter's default provider is used. Supported ID provider URI schemes are: %s", strings.Join(provider.Schemes(), ", ")))
 
 	var ids multi.MultiInt64
 	flag.Var(&ids, "id", "One or more valid Who's On First ID.")
 
 	parent_id := flag.Int64("parent-id", 0, "A valid Who's On First ID.")
 
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
+	force := flag.Bool("force", false, "Supersede records whose wof:controlled property lists any of the properties that superseding a record changes.")
+
 	flag.Usage = func() {
 
 		fmt.Fprintf(os.Stderr, "Supersede one or more WOF records with a known parent ID (and hierarchy)\n\n")
@@ -68,41 +79,76 @@ func main() {
 		log.Fatalf("Failed to create new writer for '%s', %v", *writer_uri, err)
 	}
 
-	ex, err := export.NewExporter(ctx, *exporter_uri)
+	r, wr = concurrency.Guard(ctx, r, wr)
+
+	ex, err := provider.NewExporter(ctx, *exporter_uri, *id_provider_uri, r)
 
 	if err != nil {
 		log.Fatalf("Failed to create new exporter for '%s', %v", *exporter_uri, err)
 	}
 
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  *force,
+		Logger: log.Default(),
+	}
+
 	// Parent stuff we only need to set up once
 
 	parent_f, err := wof_reader.LoadBytes(ctx, parent_r, *parent_id)
 
+	if err != nil {
+		log.Fatalf("Failed to load '%d', %v", *parent_id, err)
+	}
+
 	for _, id := range ids {
 
-		f, err := wof_reader.LoadBytes(ctx, r, id)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return supersedeId(ctx, r, wr, ex, id, parent_f, controlled_opts)
+		})
 
 		if err != nil {
-			log.Fatalf("Failed to load '%d', %v", id, err)
+			log.Fatalf("Failed to supersede record %d, %v", id, err)
 		}
+	}
+}
 
-		old_f, new_f, err := export.SupersedeRecordWithParent(ctx, ex, f, parent_f)
+// supersedeId supersedes the record 'id' with a new record whose parent is 'parent_f'. The record is skipped, rather than
+// superseded by a record that it doesn't reference, if superseding it would change a controlled property.
+func supersedeId(ctx context.Context, r reader.Reader, wr writer.Writer, ex export.Exporter, id int64, parent_f []byte, controlled_opts *exportify.ControlledOptions) error {
 
-		if err != nil {
-			log.Fatalf("Failed to supersede record %d, %v", id, err)
-		}
+	f, err := wof_reader.LoadBytes(ctx, r, id)
 
-		err = exportify.ExportWithWriter(ctx, ex, wr, old_f)
+	if err != nil {
+		return fmt.Errorf("Failed to load '%d', %w", id, err)
+	}
 
-		if err != nil {
-			log.Fatalf("Failed to export '%d', %v", id, err)
-		}
+	old_f, new_f, err := export.SupersedeRecordWithParent(ctx, ex, f, parent_f)
 
-		err = exportify.ExportWithWriter(ctx, ex, wr, new_f)
+	if err != nil {
+		return fmt.Errorf("Failed to supersede record, %w", err)
+	}
 
-		if err != nil {
-			log.Fatalf("Failed to export new feature '%d', %v", id, err)
-		}
+	_, reverted, err := exportify.RevertControlledChanges(f, old_f, controlled_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to check controlled properties, %w", err)
+	}
+
+	if len(reverted) > 0 {
+		return nil
+	}
+
+	_, err = exportify.ExportChangesWithWriter(ctx, ex, wr, f, old_f, controlled_opts)
+
+	if err != nil {
+		return fmt.Errorf("Failed to export '%d', %w", id, err)
+	}
+
+	err = exportify.ExportWithWriter(ctx, ex, wr, new_f)
+
+	if err != nil {
+		return fmt.Errorf("Failed to export new feature for '%d', %w", id, err)
 	}
 
+	return nil
 }
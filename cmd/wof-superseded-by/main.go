@@ -15,6 +15,10 @@ import (
 	"github.com/whosonfirst/go-reader"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/routing"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
 	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
 	"github.com/whosonfirst/go-writer/v3"
 )
@@ -29,6 +33,8 @@ func main() {
 
 	exporter_uri := flag.String("exporter-uri", "whosonfirst://", "A valid whosonfirst/go-whosonfirst-export URI.")
 
+	conflict_retries := flag.Int("conflict-retries", 0, "The number of times to re-apply changes to a record that has been modified by another process since it was read. If 0 then a conflicting change is an error.")
+
 	var ids multi.MultiInt64
 	flag.Var(&ids, "id", "One or more Who's On First IDs. If left empty the value of the -i flag will be used.")
 
@@ -112,15 +118,21 @@ func main() {
 		log.Fatalf("Failed to create writer for '%s', %v", *writer_uri, err)
 	}
 
+	r, wr = concurrency.Guard(ctx, r, wr)
+
 	for _, id := range ids {
 
-		err := supersededById(ctx, r, wr, ex, id, superseded_by)
+		err := concurrency.Retry(ctx, *conflict_retries, func() error {
+			return supersededById(ctx, r, wr, ex, id, superseded_by)
+		})
 
 		if err != nil {
 			log.Fatalf("Failed to supersede record for '%d', %v", id, err)
 		}
 
-		err = supersedesId(ctx, r, wr, ex, superseded_by, id)
+		err = concurrency.Retry(ctx, *conflict_retries, func() error {
+			return supersedesId(ctx, r, wr, ex, superseded_by, id)
+		})
 
 		if err != nil {
 			log.Fatalf("Failed to update wof:supersedes properties for superseding records, %v", err)
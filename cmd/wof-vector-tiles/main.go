@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb/maptile"
+	"github.com/sfomuseum/go-flags/multi"
+	"github.com/whosonfirst/go-whosonfirst-exportify/emit"
+	"github.com/whosonfirst/go-whosonfirst-exportify/tiles"
+	"github.com/whosonfirst/go-whosonfirst-exportify/transform"
+	_ "github.com/whosonfirst/go-whosonfirst-iterate-git/v2"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/emitter"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/iterator"
+)
+
+// tileEncoder implements the `emit.Encoder` interface by adding each record to a `tiles.Builder` instance.
+type tileEncoder struct {
+	emit.Encoder
+	builder *tiles.Builder
+}
+
+func (enc *tileEncoder) Encode(ctx context.Context, path string, body []byte) error {
+	return enc.builder.AddFeature(ctx, body)
+}
+
+func (enc *tileEncoder) Close(ctx context.Context) error {
+	return nil
+}
+
+func main() {
+
+	emitter_schemes := strings.Join(emitter.Schemes(), ", ")
+	emitter_desc := fmt.Sprintf("A valid whosonfirst/go-whosonfirst-iterate/v2 URI. Supported emitter URI schemes are: %s", emitter_schemes)
+
+	writer_schemes := strings.Join(tiles.DirectoryWriterSchemes(), ", ")
+	writer_desc := fmt.Sprintf("A valid whosonfirst/go-writer/v3 URI used to write tiles as a {Z}/{X}/{Y}.mvt directory tree, along with a metadata.json file. Supported writer URI schemes are: %s", writer_schemes)
+
+	transform_schemes := strings.Join(transform.Schemes(), ", ")
+	transform_desc := fmt.Sprintf("Zero or more go-whosonfirst-exportify/transform URIs to apply, in order, to each record before it is added to the tiles. Supported transformation URI schemes are: %s", transform_schemes)
+
+	iterator_uri := flag.String("iterator-uri", "repo://", emitter_desc)
+	writer_uri := flag.String("writer-uri", "", writer_desc)
+	pmtiles_path := flag.String("pmtiles", "", "The path of a PMTiles archive to write tiles to. This flag is mutually exclusive with the -writer-uri flag.")
+
+	var transformations multi.MultiString
+	flag.Var(&transformations, "transform", transform_desc)
+
+	var properties multi.MultiString
+	flag.Var(&properties, "property", "Zero or more (relative) property paths to include as feature attributes. For example \"wof:name\".")
+
+	layer := flag.String("layer", tiles.DEFAULT_LAYER, "The name of the layer that features are added to.")
+	min_zoom := flag.Uint("min-zoom", 0, "The minimum zoom level to generate tiles for.")
+	max_zoom := flag.Uint("max-zoom", 12, "The maximum zoom level to generate tiles for.")
+	tolerance := flag.Float64("simplify", tiles.DEFAULT_TOLERANCE, "The Douglas-Peucker simplification threshold, in tile extent (4096) units, applied to geometries at every zoom level. If 0 geometries are not simplified.")
+	buffer := flag.Float64("buffer", tiles.DEFAULT_BUFFER, "The size, in tile extent (4096) units, of the buffer around each tile that features are clipped to.")
+
+	var zoom_tolerances multi.KeyValueString
+	flag.Var(&zoom_tolerances, "zoom-simplify", "Zero or more {ZOOM}={THRESHOLD} flags overriding the -simplify flag for specific zoom levels.")
+
+	flag.Usage = func() {
+
+		fmt.Fprintf(os.Stderr, "Generate Mapbox Vector Tiles (MVT) for one or more WOF records and write them to a directory tree or a single PMTiles archive.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\t %s [options] path-(N) path-(N)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For example:\n")
+		fmt.Fprintf(os.Stderr, "\t%s -max-zoom 14 -property wof:name -property wof:placetype -pmtiles publicart.pmtiles /usr/local/data/sfomuseum-data-publicart/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\t%s -writer-uri fs:///usr/local/tiles -zoom-simplify 14=0 /usr/local/data/sfomuseum-data-architecture/\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Valid options are:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	uris := flag.Args()
+
+	ctx := context.Background()
+
+	switch {
+	case *writer_uri == "" && *pmtiles_path == "":
+		log.Fatalf("One of -writer-uri or -pmtiles must be set")
+	case *writer_uri != "" && *pmtiles_path != "":
+		log.Fatalf("-writer-uri and -pmtiles are mutually exclusive")
+	}
+
+	opts := tiles.DefaultBuilderOptions()
+	opts.Layer = *layer
+	opts.Properties = properties
+	opts.MinZoom = maptile.Zoom(*min_zoom)
+	opts.MaxZoom = maptile.Zoom(*max_zoom)
+	opts.Tolerance = *tolerance
+	opts.Buffer = *buffer
+
+	for _, kv := range zoom_tolerances {
+
+		z, err := strconv.ParseUint(kv.Key(), 10, 8)
+
+		if err != nil {
+			log.Fatalf("Invalid zoom level '%s' for -zoom-simplify flag, %v", kv.Key(), err)
+		}
+
+		tol, err := strconv.ParseFloat(kv.Value().(string), 64)
+
+		if err != nil {
+			log.Fatalf("Invalid threshold '%s' for -zoom-simplify flag, %v", kv.Value(), err)
+		}
+
+		opts.ZoomTolerances[maptile.Zoom(z)] = tol
+	}
+
+	b, err := tiles.NewBuilder(ctx, opts)
+
+	if err != nil {
+		log.Fatalf("Failed to create tile builder, %v", err)
+	}
+
+	var tile_wr tiles.TileWriter
+
+	switch {
+	case *pmtiles_path != "":
+
+		fh, err := os.OpenFile(*pmtiles_path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+
+		if err != nil {
+			log.Fatalf("Failed to open '%s' for writing, %v", *pmtiles_path, err)
+		}
+
+		defer fh.Close()
+
+		tile_wr, err = tiles.NewPMTilesWriter(ctx, fh)
+
+		if err != nil {
+			log.Fatalf("Failed to create PMTiles writer, %v", err)
+		}
+
+	default:
+
+		tile_wr, err = tiles.NewDirectoryTileWriterWithURI(ctx, *writer_uri)
+
+		if err != nil {
+			log.Fatalf("Failed to create directory tile writer, %v", err)
+		}
+	}
+
+	transforms, err := transform.NewTransformations(ctx, transformations...)
+
+	if err != nil {
+		log.Fatalf("Failed to create transformations, %v", err)
+	}
+
+	emit_opts := &emit.EmitOptions{
+		Encoder:         &tileEncoder{builder: b},
+		Transformations: transforms,
+	}
+
+	iter_cb := emit.IteratorCallback(emit_opts)
+
+	iter, err := iterator.NewIterator(ctx, *iterator_uri, iter_cb)
+
+	if err != nil {
+		log.Fatalf("Failed to create iterator, %v", err)
+	}
+
+	err = iter.IterateURIs(ctx, uris...)
+
+	if err != nil {
+		log.Fatalf("Failed to iterate URIs, %v", err)
+	}
+
+	err = b.Build(ctx, tile_wr)
+
+	if err != nil {
+		log.Fatalf("Failed to build tiles, %v", err)
+	}
+}
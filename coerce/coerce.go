@@ -0,0 +1,483 @@
+// Package coerce provides methods for coercing the values of Who's On First properties to the types defined by a
+// schema, for example numeric strings to numbers or "1" and "0" to booleans.
+//
+// A schema maps tidwall/gjson paths to types. Paths may contain glob patterns (see `exportify.ExpandPath`). For
+// example:
+//
+//	{
+//		"properties.wof:population": "int64",
+//		"properties.mz:is_funky": "bool",
+//		"properties.wof:belongsto": "array:int64",
+//		"properties.name:*": "array:string"
+//	}
+//
+// Values are only coerced when no information is lost. Values that can't be coerced are reported and left unchanged.
+// Null values are always left unchanged.
+package coerce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/inventory"
+)
+
+// TYPE_STRING coerces numbers and booleans to strings.
+const TYPE_STRING string = "string"
+
+// TYPE_INT64 coerces numeric strings, and numbers without a fractional part, to integers no larger (in absolute terms)
+// than MAX_SAFE_INTEGER.
+const TYPE_INT64 string = "int64"
+
+// MAX_SAFE_INTEGER is the largest integer, in absolute terms, that values will be coerced to. Larger integers can not
+// be represented exactly by the float64 values that numbers are decoded as when records are exported.
+const MAX_SAFE_INTEGER int64 = 1 << 53
+
+// TYPE_FLOAT64 coerces numeric strings to numbers.
+const TYPE_FLOAT64 string = "float64"
+
+// TYPE_BOOL coerces the numbers 1 and 0 and the strings "1", "0", "true" and "false" to booleans.
+const TYPE_BOOL string = "bool"
+
+// TYPE_ARRAY coerces single values to single-element arrays. It may be followed by ":" and the type to coerce
+// each element to, for example "array:int64".
+const TYPE_ARRAY string = "array"
+
+// Types returns the list of valid types. TYPE_ARRAY may also be followed by ":" and any of the other types.
+func Types() []string {
+	return []string{TYPE_STRING, TYPE_INT64, TYPE_FLOAT64, TYPE_BOOL, TYPE_ARRAY}
+}
+
+// Schema maps tidwall/gjson paths, which may contain glob patterns, to the types their values should be coerced to.
+type Schema map[string]string
+
+// ReadSchemaFile reads and validates the JSON-encoded schema at 'path'.
+func ReadSchemaFile(path string) (Schema, error) {
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer r.Close()
+
+	schema, err := ReadSchema(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read schema from %s, %w", path, err)
+	}
+
+	return schema, nil
+}
+
+// ReadSchema reads and validates a JSON-encoded schema from 'r'.
+func ReadSchema(r io.Reader) (Schema, error) {
+
+	var schema Schema
+
+	err := json.NewDecoder(r).Decode(&schema)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode schema, %w", err)
+	}
+
+	err = schema.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// Validate returns an error if any of the paths or types in 'schema' are invalid.
+func (schema Schema) Validate() error {
+
+	for path, t := range schema {
+
+		if path == "" {
+			return fmt.Errorf("Schema contains an empty path")
+		}
+
+		err := exportify.ValidatePathPattern(path)
+
+		if err != nil {
+			return err
+		}
+
+		err = validateType(t)
+
+		if err != nil {
+			return fmt.Errorf("Invalid type for '%s', %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+func validateType(t string) error {
+
+	switch t {
+	case TYPE_STRING, TYPE_INT64, TYPE_FLOAT64, TYPE_BOOL, TYPE_ARRAY:
+		return nil
+	}
+
+	element_type, ok := strings.CutPrefix(t, TYPE_ARRAY+":")
+
+	if !ok || strings.HasPrefix(element_type, TYPE_ARRAY) {
+		return fmt.Errorf("Invalid type '%s'", t)
+	}
+
+	return validateType(element_type)
+}
+
+// Change describes a value that was coerced.
+type Change struct {
+	// Path is the tidwall/gjson path of the value.
+	Path string
+	// Old is the original (JSON-encoded) value.
+	Old string
+	// New is the coerced (JSON-encoded) value.
+	New string
+	// Type is the type the value was coerced to.
+	Type string
+}
+
+// Failure describes a value that could not be coerced.
+type Failure struct {
+	// Path is the tidwall/gjson path of the value.
+	Path string
+	// Value is the (JSON-encoded) value.
+	Value string
+	// Type is the type the value could not be coerced to.
+	Type string
+	// Reason is the reason the value could not be coerced.
+	Reason string
+}
+
+// Error returns a description of 'f'.
+func (f *Failure) Error() string {
+	return fmt.Sprintf("Can not coerce '%s' (%s) to %s, %s", f.Path, f.Value, f.Type, f.Reason)
+}
+
+// CoerceOptions defines options for the `Coerce` method.
+type CoerceOptions struct {
+	// Force allows properties listed in a record's "wof:controlled" property to be coerced.
+	Force bool
+	// Logger is an optional logger used to report changes to controlled properties that have been skipped.
+	Logger *log.Logger
+}
+
+// Coerce coerces the values of 'body' to the types defined by 'schema' and returns the updated record along with the
+// list of values that were coerced and the list of values that could not be coerced. Paths are processed in sorted
+// order.
+func Coerce(body []byte, schema Schema, opts *CoerceOptions) ([]byte, []*Change, []*Failure, error) {
+
+	patterns := make([]string, 0, len(schema))
+
+	for p := range schema {
+		patterns = append(patterns, p)
+	}
+
+	sort.Strings(patterns)
+
+	// Check controlled properties against the original record so that coercing wof:controlled
+	// itself can't unlock any of the properties it lists.
+
+	controlled_body := body
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  opts.Force,
+		Logger: opts.Logger,
+	}
+
+	changes := make([]*Change, 0)
+	failures := make([]*Failure, 0)
+
+	for _, pattern := range patterns {
+
+		t := schema[pattern]
+
+		paths, err := exportify.ExpandPath(body, pattern)
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		for _, path := range paths {
+
+			rsp := gjson.GetBytes(body, path)
+
+			new_raw, err := coerceValue(rsp, t)
+
+			if err != nil {
+
+				f := &Failure{
+					Path:   path,
+					Value:  compact(rsp.Raw),
+					Type:   t,
+					Reason: err.Error(),
+				}
+
+				failures = append(failures, f)
+				continue
+			}
+
+			if new_raw == rsp.Raw {
+				continue
+			}
+
+			new_body, err := sjson.SetRawBytes(body, path, []byte(new_raw))
+
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("Failed to assign '%s', %w", path, err)
+			}
+
+			_, reverted, err := exportify.RevertControlledChanges(controlled_body, new_body, controlled_opts)
+
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			if len(reverted) > 0 {
+				continue
+			}
+
+			body = new_body
+
+			c := &Change{
+				Path: path,
+				Old:  compact(rsp.Raw),
+				New:  new_raw,
+				Type: t,
+			}
+
+			changes = append(changes, c)
+		}
+	}
+
+	return body, changes, failures, nil
+}
+
+// coerceValue returns the (JSON-encoded) value of 'rsp' coerced to the type 't'. If 'rsp' is already of type 't', or
+// is null, its raw value is returned unchanged.
+func coerceValue(rsp gjson.Result, t string) (string, error) {
+
+	if rsp.Type == gjson.Null {
+		return rsp.Raw, nil
+	}
+
+	switch t {
+	case TYPE_STRING:
+		return coerceString(rsp)
+	case TYPE_INT64:
+		return coerceInt64(rsp)
+	case TYPE_FLOAT64:
+		return coerceFloat64(rsp)
+	case TYPE_BOOL:
+		return coerceBool(rsp)
+	}
+
+	element_type, _ := strings.CutPrefix(t, TYPE_ARRAY)
+	element_type = strings.TrimPrefix(element_type, ":")
+
+	return coerceArray(rsp, element_type)
+}
+
+func coerceString(rsp gjson.Result) (string, error) {
+
+	switch rsp.Type {
+	case gjson.String:
+		return rsp.Raw, nil
+	case gjson.Number, gjson.True, gjson.False:
+		return encode(rsp.Raw)
+	default:
+		return "", fmt.Errorf("%s values can not be coerced to strings", inventory.TypeOf(rsp))
+	}
+}
+
+func coerceInt64(rsp gjson.Result) (string, error) {
+
+	var str_value string
+
+	switch rsp.Type {
+	case gjson.Number:
+		str_value = rsp.Raw
+	case gjson.String:
+		str_value = strings.TrimSpace(rsp.String())
+	default:
+		return "", fmt.Errorf("%s values can not be coerced to integers", inventory.TypeOf(rsp))
+	}
+
+	i, err := strconv.ParseInt(str_value, 10, 64)
+
+	if err == nil {
+
+		// Numbers are decoded as float64 values when records are exported so larger integers would be silently
+		// changed.
+
+		if i > MAX_SAFE_INTEGER || i < -MAX_SAFE_INTEGER {
+			return "", fmt.Errorf("not safely representable")
+		}
+
+		return strconv.FormatInt(i, 10), nil
+	}
+
+	// Numbers like 12.0 or 1e3
+
+	f, err := strconv.ParseFloat(str_value, 64)
+
+	if err != nil {
+		return "", fmt.Errorf("not a number")
+	}
+
+	if f != math.Trunc(f) {
+		return "", fmt.Errorf("not an integer")
+	}
+
+	if math.Abs(f) > float64(MAX_SAFE_INTEGER) {
+		return "", fmt.Errorf("not safely representable")
+	}
+
+	return strconv.FormatInt(int64(f), 10), nil
+}
+
+func coerceFloat64(rsp gjson.Result) (string, error) {
+
+	switch rsp.Type {
+	case gjson.Number:
+		return rsp.Raw, nil
+	case gjson.String:
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(rsp.String()), 64)
+
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("not a number")
+		}
+
+		return encode(f)
+
+	default:
+		return "", fmt.Errorf("%s values can not be coerced to numbers", inventory.TypeOf(rsp))
+	}
+}
+
+func coerceBool(rsp gjson.Result) (string, error) {
+
+	switch rsp.Type {
+	case gjson.True, gjson.False:
+		return rsp.Raw, nil
+	case gjson.Number, gjson.String:
+
+		switch strings.ToLower(strings.TrimSpace(rsp.String())) {
+		case "1", "true":
+			return "true", nil
+		case "0", "false":
+			return "false", nil
+		}
+
+		return "", fmt.Errorf("not 1, 0, true or false")
+
+	default:
+		return "", fmt.Errorf("%s values can not be coerced to booleans", inventory.TypeOf(rsp))
+	}
+}
+
+// coerceArray wraps single values in an array and, if 'element_type' is not empty, coerces each element of the array
+// to that type. If any element can not be coerced the array is not changed.
+func coerceArray(rsp gjson.Result, element_type string) (string, error) {
+
+	if rsp.IsObject() {
+		return "", fmt.Errorf("object values can not be coerced to arrays")
+	}
+
+	elements := []gjson.Result{rsp}
+
+	if rsp.IsArray() {
+		elements = rsp.Array()
+	}
+
+	raw_elements := make([]json.RawMessage, len(elements))
+
+	for i, e := range elements {
+
+		raw := e.Raw
+
+		if element_type != "" {
+
+			v, err := coerceValue(e, element_type)
+
+			if err != nil {
+				return "", fmt.Errorf("element %d (%s), %w", i, e.Raw, err)
+			}
+
+			raw = v
+		}
+
+		raw_elements[i] = json.RawMessage(raw)
+	}
+
+	// Preserve the original formatting of arrays whose elements haven't changed.
+
+	if rsp.IsArray() {
+
+		changed := false
+
+		for i, e := range elements {
+
+			if string(raw_elements[i]) != e.Raw {
+				changed = true
+				break
+			}
+		}
+
+		if !changed {
+			return rsp.Raw, nil
+		}
+	}
+
+	return encode(raw_elements)
+}
+
+// compact returns the compacted form of the raw JSON value 'raw'.
+func compact(raw string) string {
+
+	var buf bytes.Buffer
+
+	err := json.Compact(&buf, []byte(raw))
+
+	if err != nil {
+		return raw
+	}
+
+	return buf.String()
+}
+
+// encode returns the JSON encoding of 'v' without escaping HTML characters.
+func encode(v interface{}) (string, error) {
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(v)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
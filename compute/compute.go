@@ -0,0 +1,239 @@
+// Package compute provides a small expression language for deriving the values of Who's On First properties from
+// other properties in the same record.
+//
+// Expressions reference the values of a record using tidwall/gjson paths enclosed in curly braces, for example
+// `{properties.wof:name}`. Paths that do not exist evaluate to null. Expressions support string, number, boolean,
+// null and array literals, the arithmetic operators "+", "-", "*", "/" and "%" (the "+" operator also concatenates
+// strings and arrays), the comparison operators "==", "!=", "<", "<=", ">" and ">=", the logical operators "&&", "||"
+// and "!", the conditional operator "cond ? a : b", indexing ("a[0]") and a set of built-in functions (see
+// `FunctionNames`). Arithmetic and most functions evaluate to null if any of their operands are null. In addition to
+// general purpose string, numeric, conditional and array functions there are functions for comparing placetypes
+// (using whosonfirst/go-whosonfirst-placetypes), EDTF dates (using sfomuseum/go-edtf) and geometries.
+//
+// A `Rule` assigns the result of an expression to a path. Rules whose expressions evaluate to null leave the record unchanged.
+package compute
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+)
+
+// Expression is a parsed expression.
+type Expression struct {
+	source string
+	root   node
+}
+
+// ParseExpression parses 'expr' and returns a new `Expression` instance.
+func ParseExpression(expr string) (*Expression, error) {
+
+	root, err := parse(expr)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse expression '%s', %w", expr, err)
+	}
+
+	e := &Expression{
+		source: expr,
+		root:   root,
+	}
+
+	return e, nil
+}
+
+// Evaluate evaluates 'e' against the record 'body'. The return value is one of nil, bool, float64, string,
+// []interface{} or map[string]interface{} or an int64 for integers larger than 2^53, which can not be represented
+// exactly as a float64.
+func (e *Expression) Evaluate(body []byte) (interface{}, error) {
+
+	v, err := e.root.eval(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to evaluate expression '%s', %w", e.source, err)
+	}
+
+	return v, nil
+}
+
+// Matches evaluates 'e' against the record 'body' and returns a boolean value indicating whether the result is
+// "truthy". Null, false, zero, empty strings and empty arrays and objects are not truthy; everything else is.
+func (e *Expression) Matches(body []byte) (bool, error) {
+
+	v, err := e.Evaluate(body)
+
+	if err != nil {
+		return false, err
+	}
+
+	return truthy(v), nil
+}
+
+// String returns the source of 'e'.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Rule assigns the result of an expression to a path.
+type Rule struct {
+	// Path is the tidwall/gjson path the result of Expression is assigned to.
+	Path       string
+	Expression *Expression
+}
+
+// NewRule returns a new `Rule` instance which assigns the result of 'expr' to 'path'.
+func NewRule(path string, expr string) (*Rule, error) {
+
+	path = strings.TrimSpace(path)
+
+	if path == "" {
+		return nil, fmt.Errorf("Missing path")
+	}
+
+	e, err := ParseExpression(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rule{
+		Path:       path,
+		Expression: e,
+	}
+
+	return r, nil
+}
+
+// ParseRule parses a rule in the form of "{PATH}={EXPRESSION}".
+func ParseRule(str_rule string) (*Rule, error) {
+
+	path, expr, ok := strings.Cut(str_rule, "=")
+
+	if !ok {
+		return nil, fmt.Errorf("Invalid rule '%s', expected {PATH}={EXPRESSION}", str_rule)
+	}
+
+	return NewRule(path, expr)
+}
+
+// Operation evaluates 'r' against the record 'body' and returns an `exportify.Operation` which assigns the result
+// to r.Path, or nil if the expression evaluates to null.
+func (r *Rule) Operation(body []byte) (*exportify.Operation, error) {
+
+	v, err := r.Expression.Evaluate(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	op := &exportify.Operation{
+		Kind:  exportify.OPERATION_SET_JSON,
+		Path:  r.Path,
+		Value: v,
+	}
+
+	return op, nil
+}
+
+// String returns 'r' in the form of "{PATH}={EXPRESSION}".
+func (r *Rule) String() string {
+	return fmt.Sprintf("%s=%s", r.Path, r.Expression)
+}
+
+// Rules is an ordered list of `Rule` instances. It implements the `flag.Value` interface.
+type Rules []*Rule
+
+// String returns the list of rules as a comma-separated string.
+func (rules *Rules) String() string {
+
+	str_rules := make([]string, len(*rules))
+
+	for i, r := range *rules {
+		str_rules[i] = r.String()
+	}
+
+	return strings.Join(str_rules, ",")
+}
+
+// Set parses 'value' as a "{PATH}={EXPRESSION}" rule and appends it to the list.
+func (rules *Rules) Set(value string) error {
+
+	r, err := ParseRule(value)
+
+	if err != nil {
+		return err
+	}
+
+	*rules = append(*rules, r)
+	return nil
+}
+
+// ApplyOptions defines options for applying rules to a record.
+type ApplyOptions struct {
+	// Force allows properties listed in a record's "wof:controlled" property to be updated.
+	Force bool
+	// Logger is an optional logger used to report updates to controlled properties that have been skipped.
+	Logger *log.Logger
+}
+
+// Apply applies each of 'rules', in order, to 'body' and returns the updated record along with the list of paths
+// whose values were changed. Each rule is evaluated against the record as updated by the rules before it.
+func Apply(ctx context.Context, body []byte, rules Rules, opts *ApplyOptions) ([]byte, []string, error) {
+
+	changed := make([]string, 0)
+
+	for _, r := range rules {
+
+		op, err := r.Operation(body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if op == nil {
+			continue
+		}
+
+		update_opts := &exportify.UpdateFeatureOptions{
+			Operations: exportify.Operations{op},
+			Force:      opts.Force,
+			Logger:     opts.Logger,
+		}
+
+		new_body, op_changed, err := exportify.UpdateFeatureWithChanges(ctx, body, update_opts)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body = new_body
+
+		for _, path := range op_changed {
+
+			if !contains(changed, path) {
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	return body, changed, nil
+}
+
+func contains(paths []string, path string) bool {
+
+	for _, p := range paths {
+
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
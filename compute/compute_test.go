@@ -0,0 +1,68 @@
+package compute
+
+import (
+	"reflect"
+	"testing"
+)
+
+// evaluateTest is an expression, the record it is evaluated against and the value it is expected to evaluate to.
+type evaluateTest struct {
+	expr     string
+	body     string
+	expected interface{}
+}
+
+// runEvaluateTests parses and evaluates each of 'tests' and ensures it returns the expected value.
+func runEvaluateTests(t *testing.T, tests []evaluateTest) {
+
+	t.Helper()
+
+	for _, test := range tests {
+
+		e, err := ParseExpression(test.expr)
+
+		if err != nil {
+			t.Errorf("Failed to parse '%s', %v", test.expr, err)
+			continue
+		}
+
+		body := test.body
+
+		if body == "" {
+			body = "{}"
+		}
+
+		v, err := e.Evaluate([]byte(body))
+
+		if err != nil {
+			t.Errorf("Failed to evaluate '%s', %v", test.expr, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("Expected '%s' to evaluate to %#v, got %#v", test.expr, test.expected, v)
+		}
+	}
+}
+
+// runErrorTests ensures that each of 'exprs' parses but fails to evaluate against 'body'.
+func runErrorTests(t *testing.T, body string, exprs []string) {
+
+	t.Helper()
+
+	for _, expr := range exprs {
+
+		e, err := ParseExpression(expr)
+
+		if err != nil {
+			t.Errorf("Failed to parse '%s', %v", expr, err)
+			continue
+		}
+
+		v, err := e.Evaluate([]byte(body))
+
+		if err == nil {
+			t.Errorf("Expected '%s' to fail to evaluate, got %#v", expr, v)
+		}
+	}
+}
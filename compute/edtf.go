@@ -0,0 +1,123 @@
+package compute
+
+import (
+	"time"
+
+	"github.com/sfomuseum/go-edtf"
+	edtf_parser "github.com/sfomuseum/go-edtf/parser"
+)
+
+func init() {
+	functions["edtf_before"] = &function{2, 2, true, edtfBeforeFunction}
+	functions["edtf_after"] = &function{2, 2, true, edtfAfterFunction}
+	functions["edtf_between"] = &function{3, 3, true, edtfRangeFunction(false)}
+	functions["edtf_overlaps"] = &function{3, 3, true, edtfRangeFunction(true)}
+	functions["today"] = &function{0, 0, false, todayFunction}
+}
+
+// edtfBounds returns the earliest and latest times of the EDTF date string 'v'. It returns false if 'v' is not a
+// valid EDTF date or is an open or unknown date.
+func edtfBounds(v interface{}) (*time.Time, *time.Time, bool) {
+
+	str_date := toString(v)
+
+	if edtf.IsOpen(str_date) || edtf.IsUnknown(str_date) {
+		return nil, nil, false
+	}
+
+	d, err := edtf_parser.ParseString(str_date)
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	lower, err := d.Lower()
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	upper, err := d.Upper()
+
+	if err != nil {
+		return nil, nil, false
+	}
+
+	return lower, upper, true
+}
+
+// edtfBeforeFunction returns true if the latest time of an EDTF date is before the earliest time of another EDTF date.
+// Invalid, open and unknown dates evaluate to null.
+func edtfBeforeFunction(args []interface{}) (interface{}, error) {
+
+	_, upper, ok := edtfBounds(args[0])
+
+	if !ok {
+		return nil, nil
+	}
+
+	other_lower, _, ok := edtfBounds(args[1])
+
+	if !ok {
+		return nil, nil
+	}
+
+	return upper.Before(*other_lower), nil
+}
+
+// edtfAfterFunction returns true if the earliest time of an EDTF date is after the latest time of another EDTF date.
+// Invalid, open and unknown dates evaluate to null.
+func edtfAfterFunction(args []interface{}) (interface{}, error) {
+
+	lower, _, ok := edtfBounds(args[0])
+
+	if !ok {
+		return nil, nil
+	}
+
+	_, other_upper, ok := edtfBounds(args[1])
+
+	if !ok {
+		return nil, nil
+	}
+
+	return lower.After(*other_upper), nil
+}
+
+// edtfRangeFunction returns a function which returns true if an EDTF date falls entirely between ('overlaps' is false),
+// or overlaps, the range from the earliest time of a start date to the latest time of an end date. Invalid, open and
+// unknown dates evaluate to null.
+func edtfRangeFunction(overlaps bool) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		lower, upper, ok := edtfBounds(args[0])
+
+		if !ok {
+			return nil, nil
+		}
+
+		start, _, ok := edtfBounds(args[1])
+
+		if !ok {
+			return nil, nil
+		}
+
+		_, end, ok := edtfBounds(args[2])
+
+		if !ok {
+			return nil, nil
+		}
+
+		if overlaps {
+			return !upper.Before(*start) && !lower.After(*end), nil
+		}
+
+		return !lower.Before(*start) && !upper.After(*end), nil
+	}
+}
+
+// todayFunction returns the current (UTC) date as a YYYY-MM-DD string.
+func todayFunction(args []interface{}) (interface{}, error) {
+	return time.Now().UTC().Format("2006-01-02"), nil
+}
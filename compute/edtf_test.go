@@ -0,0 +1,61 @@
+package compute
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestEDTFFunctions(t *testing.T) {
+
+	tests := []evaluateTest{
+		{expr: `edtf_before("1990", "2000")`, expected: true},
+		{expr: `edtf_before("2000-06", "2000")`, expected: false},
+		{expr: `edtf_before("1999-12-31", "2000-01")`, expected: true},
+		{expr: `edtf_after("2020-01-02", "2020-01-01")`, expected: true},
+		// Uncertain and approximate dates, and ranges, use their widest bounds.
+		{expr: `edtf_after("2021~", "2020")`, expected: true},
+		{expr: `edtf_after("2020-06", "2020")`, expected: false},
+		{expr: `edtf_before("1990/1995", "1996")`, expected: true},
+		{expr: `edtf_between("2005", "2000", "2010")`, expected: true},
+		{expr: `edtf_between("2000/2011", "2000", "2010")`, expected: false},
+		{expr: `edtf_between("2010-12-31", "2000", "2010")`, expected: true},
+		{expr: `edtf_overlaps("2009/2012", "2000", "2010")`, expected: true},
+		{expr: `edtf_overlaps("2011/2012", "2000", "2010")`, expected: false},
+		{expr: `edtf_overlaps("1990/2020", "2000", "2010")`, expected: true},
+		// Open, unknown and invalid dates, and missing properties, evaluate to null.
+		{expr: `edtf_before("..", "2000")`, expected: nil},
+		{expr: `edtf_after("2000", "")`, expected: nil},
+		{expr: `edtf_before("uuuu", "2000")`, expected: nil},
+		{expr: `edtf_between("2005", "2000", "not a date")`, expected: nil},
+		{expr: `edtf_after({properties.edtf:cessation}, "2020")`, body: `{"properties":{}}`, expected: nil},
+		{expr: `edtf_after({properties.edtf:cessation}, "2020")`, body: `{"properties":{"edtf:cessation":"2021-03-04"}}`, expected: true},
+		{expr: `!exists({properties.edtf:cessation}) || edtf_after({properties.edtf:cessation}, "2020")`, body: `{"properties":{}}`, expected: true},
+	}
+
+	runEvaluateTests(t, tests)
+}
+
+func TestToday(t *testing.T) {
+
+	e, err := ParseExpression(`today()`)
+
+	if err != nil {
+		t.Fatalf("Failed to parse expression, %v", err)
+	}
+
+	v, err := e.Evaluate([]byte(`{}`))
+
+	if err != nil {
+		t.Fatalf("Failed to evaluate expression, %v", err)
+	}
+
+	str_v, ok := v.(string)
+
+	if !ok || !regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`).MatchString(str_v) {
+		t.Fatalf("Expected today() to return a YYYY-MM-DD string, got %#v", v)
+	}
+
+	runEvaluateTests(t, []evaluateTest{
+		{expr: `edtf_before("2000", today())`, expected: true},
+	})
+}
@@ -0,0 +1,552 @@
+package compute
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// node is a node in the syntax tree of an expression. Values are represented using the same types as
+// `encoding/json`: nil, bool, float64, string, []interface{} and map[string]interface{}. The exception is integers which
+// can not be represented exactly as a float64 (for example IDs larger than 2^53) which are represented as int64 values
+// so that they can be compared exactly. See `number`.
+type node interface {
+	eval(body []byte) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(body []byte) (interface{}, error) {
+	return n.value, nil
+}
+
+// pathNode evaluates to the value of a tidwall/gjson path, or nil if the path does not exist.
+type pathNode struct {
+	path string
+}
+
+func (n *pathNode) eval(body []byte) (interface{}, error) {
+	return lookup(body, n.path), nil
+}
+
+// dynamicPathNode evaluates to the value of the tidwall/gjson path that its argument evaluates to.
+type dynamicPathNode struct {
+	path node
+}
+
+func (n *dynamicPathNode) eval(body []byte) (interface{}, error) {
+
+	v, err := n.path.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	path, ok := v.(string)
+
+	if !ok {
+		return nil, fmt.Errorf("Path must be a string, not %s", typeOf(v))
+	}
+
+	return lookup(body, path), nil
+}
+
+type arrayNode struct {
+	items []node
+}
+
+func (n *arrayNode) eval(body []byte) (interface{}, error) {
+
+	values := make([]interface{}, len(n.items))
+
+	for i, item := range n.items {
+
+		v, err := item.eval(body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+type conditionalNode struct {
+	cond      node
+	then      node
+	otherwise node
+}
+
+func (n *conditionalNode) eval(body []byte) (interface{}, error) {
+
+	v, err := n.cond.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if truthy(v) {
+		return n.then.eval(body)
+	}
+
+	return n.otherwise.eval(body)
+}
+
+// logicalNode implements the short-circuiting "&&" and "||" operators. It always evaluates to a boolean value.
+type logicalNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *logicalNode) eval(body []byte) (interface{}, error) {
+
+	left, err := n.left.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "&&" && !truthy(left) {
+		return false, nil
+	}
+
+	if n.op == "||" && truthy(left) {
+		return true, nil
+	}
+
+	right, err := n.right.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return truthy(right), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(body []byte) (interface{}, error) {
+
+	v, err := n.operand.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		return !truthy(v), nil
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	if i, ok := v.(int64); ok {
+		return -i, nil
+	}
+
+	f, ok := toFloat(v)
+
+	if !ok {
+		return nil, fmt.Errorf("Can not negate %s", typeOf(v))
+	}
+
+	return -f, nil
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(body []byte) (interface{}, error) {
+
+	left, err := n.left.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equals(left, right), nil
+	case "!=":
+		return !equals(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	}
+
+	// Arithmetic involving a missing (null) value evaluates to null.
+
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	if n.op == "+" {
+
+		left_arr, left_ok := left.([]interface{})
+		right_arr, right_ok := right.([]interface{})
+
+		if left_ok && right_ok {
+			values := make([]interface{}, 0, len(left_arr)+len(right_arr))
+			values = append(values, left_arr...)
+			return append(values, right_arr...), nil
+		}
+
+		_, left_str := left.(string)
+		_, right_str := right.(string)
+
+		if left_str || right_str {
+			return toString(left) + toString(right), nil
+		}
+	}
+
+	// Large (int64) integers are converted to float64 values, and may lose precision, for arithmetic.
+
+	a, a_ok := toFloat(left)
+	b, b_ok := toFloat(right)
+
+	if !a_ok || !b_ok {
+		return nil, fmt.Errorf("Invalid operands for '%s' operator, %s and %s", n.op, typeOf(left), typeOf(right))
+	}
+
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+
+		if b == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+
+		return a / b, nil
+
+	case "%":
+
+		if b == 0 {
+			return nil, fmt.Errorf("Division by zero")
+		}
+
+		return math.Mod(a, b), nil
+	}
+
+	return nil, fmt.Errorf("Invalid operator '%s'", n.op)
+}
+
+// indexNode evaluates to the element of an array (or the character of a string) at a given index. Negative indices
+// count backwards from the end. Indices that are out of range evaluate to null.
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(body []byte) (interface{}, error) {
+
+	target, err := n.target.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := n.index.eval(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if target == nil || index == nil {
+		return nil, nil
+	}
+
+	if obj, ok := target.(map[string]interface{}); ok {
+
+		k, ok := index.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("Object key must be a string, not %s", typeOf(index))
+		}
+
+		return obj[k], nil
+	}
+
+	f, ok := toFloat(index)
+
+	if !ok {
+		return nil, fmt.Errorf("Index must be a number, not %s", typeOf(index))
+	}
+
+	return at(target, int(f))
+}
+
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+}
+
+func (n *callNode) eval(body []byte) (interface{}, error) {
+
+	args := make([]interface{}, len(n.args))
+
+	for i, arg := range n.args {
+
+		v, err := arg.eval(body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	v, err := n.fn.call(args)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s(), %w", n.name, err)
+	}
+
+	return v, nil
+}
+
+// lookup returns the value of 'path' in 'body' or nil if it does not exist.
+func lookup(body []byte, path string) interface{} {
+
+	rsp := gjson.GetBytes(body, path)
+
+	if !rsp.Exists() {
+		return nil
+	}
+
+	if rsp.Type == gjson.Number {
+		return number(rsp.Raw, rsp.Num)
+	}
+
+	return rsp.Value()
+}
+
+// maxExactInteger is the integer, in absolute terms, below which every integer can be represented exactly as a
+// float64 value. Larger integers, including those which are rounded to it, may not be.
+const maxExactInteger float64 = 1 << 53
+
+// number returns the value of the JSON number 'raw', which has already been parsed as the float64 value 'f'. Integers
+// that are too large to be represented exactly as a float64 value are returned as int64 values instead.
+func number(raw string, f float64) interface{} {
+
+	if math.Abs(f) < maxExactInteger || strings.ContainsAny(raw, ".eE") {
+		return f
+	}
+
+	i, err := strconv.ParseInt(raw, 10, 64)
+
+	if err != nil {
+		return f
+	}
+
+	return i
+}
+
+// toFloat returns the float64 value of 'v' and true if it is a number.
+func toFloat(v interface{}) (float64, bool) {
+
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	default:
+		return 0, false
+	}
+}
+
+// compareNumbers returns -1, 0 or 1 if the number 'a' is less than, equal to or greater than the number 'b'. Numbers
+// are compared exactly, even when one of them is an int64 value which can't be represented as a float64 value.
+func compareNumbers(a interface{}, b interface{}) int {
+
+	a_i, a_int := a.(int64)
+	b_i, b_int := b.(int64)
+
+	if a_int && b_int {
+
+		switch {
+		case a_i < b_i:
+			return -1
+		case a_i > b_i:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	bigFloat := func(v interface{}) *big.Float {
+
+		if i, ok := v.(int64); ok {
+			return new(big.Float).SetInt64(i)
+		}
+
+		f, _ := toFloat(v)
+		return big.NewFloat(f)
+	}
+
+	return bigFloat(a).Cmp(bigFloat(b))
+}
+
+// truthy returns false for null, false, zero, empty strings and empty arrays and objects and true for everything else.
+func truthy(v interface{}) bool {
+
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case int64:
+		return t != 0
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	default:
+		return true
+	}
+}
+
+// equals returns true if 'a' and 'b' have the same JSON encoding. Numbers are compared exactly.
+func equals(a interface{}, b interface{}) bool {
+
+	_, a_number := toFloat(a)
+	_, b_number := toFloat(b)
+
+	if a_number && b_number {
+		return compareNumbers(a, b) == 0
+	}
+
+	return encode(a) == encode(b)
+}
+
+// compare compares two numbers or two strings. Comparisons involving null are always false. Comparisons between
+// other types, or values of different types, are errors.
+func compare(op string, a interface{}, b interface{}) (interface{}, error) {
+
+	if a == nil || b == nil {
+		return false, nil
+	}
+
+	var c int
+
+	switch a_v := a.(type) {
+	case float64, int64:
+
+		_, ok := toFloat(b)
+
+		if !ok {
+			return nil, fmt.Errorf("Can not compare %s and %s", typeOf(a), typeOf(b))
+		}
+
+		c = compareNumbers(a, b)
+
+	case string:
+
+		b_v, ok := b.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("Can not compare %s and %s", typeOf(a), typeOf(b))
+		}
+
+		switch {
+		case a_v < b_v:
+			c = -1
+		case a_v > b_v:
+			c = 1
+		}
+
+	default:
+		return nil, fmt.Errorf("Can not compare %s and %s", typeOf(a), typeOf(b))
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// toString returns the string representation of 'v'. Numbers are formatted without trailing zeros and arrays and
+// objects are JSON-encoded.
+func toString(v interface{}) string {
+
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return encode(t)
+	}
+}
+
+func typeOf(v interface{}) string {
+
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, int64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func encode(v interface{}) string {
+	enc, _ := json.Marshal(v)
+	return string(enc)
+}
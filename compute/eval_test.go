@@ -0,0 +1,93 @@
+package compute
+
+import (
+	"testing"
+)
+
+func TestComparisons(t *testing.T) {
+
+	body := `{"properties":{"wof:id":101736545,"wof:name":"Montreal","mz:is_current":1,"wof:tags":[],"src:geom":"quattroshapes"}}`
+
+	tests := []evaluateTest{
+		{expr: `{properties.wof:id} == 101736545`, body: body, expected: true},
+		{expr: `{properties.wof:id} == 101736545.0`, body: body, expected: true},
+		{expr: `{properties.wof:id} != "101736545"`, body: body, expected: true},
+		{expr: `{properties.wof:name} == "Montreal"`, body: body, expected: true},
+		{expr: `{properties.wof:name} < "Quebec"`, body: body, expected: true},
+		{expr: `"10" < "9"`, expected: true},
+		{expr: `10 < 9`, expected: false},
+		{expr: `2 >= 2 && 2 <= 2`, expected: true},
+		{expr: `[1, "a"] == [1, "a"]`, expected: true},
+		{expr: `[1, "a"] == ["a", 1]`, expected: false},
+		{expr: `{properties.wof:tags} == []`, body: body, expected: true},
+		{expr: `true == 1`, expected: false},
+		// Comparisons involving null are always false but null equals null.
+		{expr: `{properties.missing} == null`, body: body, expected: true},
+		{expr: `{properties.missing} != 0`, body: body, expected: true},
+		{expr: `{properties.missing} < 1`, body: body, expected: false},
+		{expr: `{properties.missing} >= 1`, body: body, expected: false},
+		{expr: `null <= null`, expected: false},
+		{expr: `!{properties.missing}`, body: body, expected: true},
+		// Arithmetic with null evaluates to null.
+		{expr: `{properties.missing} + 1`, body: body, expected: nil},
+		{expr: `-{properties.missing}`, body: body, expected: nil},
+		{expr: `{properties.missing}[0]`, body: body, expected: nil},
+		// Mixed-type addition concatenates strings.
+		{expr: `"wof:" + {properties.wof:id}`, body: body, expected: "wof:101736545"},
+		{expr: `1 + "a" + true`, expected: "1atrue"},
+		{expr: `[1] + [2]`, expected: []interface{}{1.0, 2.0}},
+		// Logical operators always evaluate to booleans.
+		{expr: `{properties.src:geom} && 0`, body: body, expected: false},
+		{expr: `{properties.wof:tags} || ""`, body: body, expected: false},
+	}
+
+	runEvaluateTests(t, tests)
+
+	errors := []string{
+		`{properties.wof:id} < "101736545"`,
+		`{properties.wof:name} > 1`,
+		`true < false`,
+		`[1] < [2]`,
+		`{properties.wof:tags} >= 0`,
+		`1 - "a"`,
+		`{properties.wof:name} * 2`,
+		`-"a"`,
+		`1 / 0`,
+		`1 % 0`,
+		`[1, 2]["a"]`,
+		`get(1)`,
+	}
+
+	runErrorTests(t, body, errors)
+}
+
+// TestLargeIntegers ensures that integers which can't be represented exactly as float64 values are compared exactly.
+func TestLargeIntegers(t *testing.T) {
+
+	body := `{"properties":{"wof:id":9007199254740993,"wof:parent_id":9007199254740992,"neg":-9007199254740993,"float":9007199254740993.0}}`
+
+	tests := []evaluateTest{
+		{expr: `{properties.wof:id}`, body: body, expected: int64(9007199254740993)},
+		{expr: `{properties.wof:id} == 9007199254740993`, body: body, expected: true},
+		{expr: `{properties.wof:id} == 9007199254740992`, body: body, expected: false},
+		{expr: `{properties.wof:id} != {properties.wof:parent_id}`, body: body, expected: true},
+		{expr: `{properties.wof:id} > {properties.wof:parent_id}`, body: body, expected: true},
+		{expr: `{properties.wof:parent_id} < {properties.wof:id}`, body: body, expected: true},
+		{expr: `{properties.neg} < -9007199254740992`, body: body, expected: true},
+		{expr: `-{properties.wof:id} == {properties.neg}`, body: body, expected: true},
+		{expr: `{properties.wof:id} == "9007199254740993"`, body: body, expected: false},
+		{expr: `string({properties.wof:id})`, body: body, expected: "9007199254740993"},
+		{expr: `number("9007199254740993") == {properties.wof:id}`, body: body, expected: true},
+		{expr: `sort([9007199254740993, 9007199254740992, 1])`, expected: []interface{}{1.0, int64(9007199254740992), int64(9007199254740993)}},
+		// Numbers with a fractional part or an exponent are always float64 values so compare with the rounded value.
+		{expr: `{properties.float} == {properties.wof:parent_id}`, body: body, expected: true},
+		{expr: `{properties.float} < {properties.wof:id}`, body: body, expected: true},
+		{expr: `{properties.wof:id} == 9.007199254740993e15`, body: body, expected: false},
+		// Integers small enough to be represented exactly are float64 values.
+		{expr: `{properties.count}`, body: `{"properties":{"count":9007199254740991}}`, expected: 9007199254740991.0},
+		// Arithmetic converts large integers to float64 values.
+		{expr: `{properties.wof:id} + 0`, body: body, expected: 9007199254740992.0},
+	}
+
+	runEvaluateTests(t, tests)
+}
@@ -0,0 +1,593 @@
+package compute
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// function is a built-in function that can be called from an expression.
+type function struct {
+	min_args int
+	// max_args is the maximum number of arguments or -1 if the function is variadic.
+	max_args int
+	// propagate_null causes the function to evaluate to null, without being called, if any of its arguments are null.
+	propagate_null bool
+	fn             func(args []interface{}) (interface{}, error)
+}
+
+func (f *function) call(args []interface{}) (interface{}, error) {
+
+	if f.propagate_null {
+
+		for _, v := range args {
+
+			if v == nil {
+				return nil, nil
+			}
+		}
+	}
+
+	return f.fn(args)
+}
+
+// functions is the table of built-in functions. Other files in this package add functions to it in their init functions.
+var functions = map[string]*function{
+	// Strings
+	"upper":       {1, 1, true, stringFunction(strings.ToUpper)},
+	"lower":       {1, 1, true, stringFunction(strings.ToLower)},
+	"trim":        {1, 1, true, stringFunction(strings.TrimSpace)},
+	"concat":      {1, -1, true, concatFunction},
+	"join":        {2, 2, true, joinFunction},
+	"split":       {2, 2, true, splitFunction},
+	"replace":     {3, 3, true, replaceFunction},
+	"substr":      {2, 3, true, substrFunction},
+	"starts_with": {2, 2, true, stringPredicate(strings.HasPrefix)},
+	"ends_with":   {2, 2, true, stringPredicate(strings.HasSuffix)},
+	"contains":    {2, 2, true, containsFunction},
+	"len":         {1, 1, true, lenFunction},
+	"string":      {1, 1, true, stringConversionFunction},
+	"number":      {1, 1, true, numberFunction},
+	// Numbers
+	"int":   {1, 1, true, numericFunction(math.Trunc)},
+	"floor": {1, 1, true, numericFunction(math.Floor)},
+	"ceil":  {1, 1, true, numericFunction(math.Ceil)},
+	"abs":   {1, 1, true, numericFunction(math.Abs)},
+	"round": {1, 2, true, roundFunction},
+	"min":   {1, -1, false, extremeFunction(-1)},
+	"max":   {1, -1, false, extremeFunction(1)},
+	// Conditionals
+	"coalesce": {1, -1, false, coalesceFunction},
+	"exists":   {1, 1, false, existsFunction},
+	// Arrays
+	"first":   {1, 1, true, firstFunction},
+	"last":    {1, 1, true, lastFunction},
+	"at":      {2, 2, true, atFunction},
+	"append":  {2, -1, false, appendFunction},
+	"unique":  {1, 1, true, uniqueFunction},
+	"sort":    {1, 1, true, sortFunction},
+	"slice":   {2, 3, true, sliceFunction},
+	"compact": {1, 1, true, compactFunction},
+}
+
+// FunctionNames returns the sorted list of functions that can be called from an expression.
+func FunctionNames() []string {
+
+	names := []string{"get", "if"}
+
+	for name := range functions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+
+	s, ok := args[i].(string)
+
+	if !ok {
+		return "", fmt.Errorf("Argument %d must be a string, not %s", i+1, typeOf(args[i]))
+	}
+
+	return s, nil
+}
+
+func numberArg(args []interface{}, i int) (float64, error) {
+
+	f, ok := toFloat(args[i])
+
+	if !ok {
+		return 0, fmt.Errorf("Argument %d must be a number, not %s", i+1, typeOf(args[i]))
+	}
+
+	return f, nil
+}
+
+func arrayArg(args []interface{}, i int) ([]interface{}, error) {
+
+	arr, ok := args[i].([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("Argument %d must be an array, not %s", i+1, typeOf(args[i]))
+	}
+
+	return arr, nil
+}
+
+func stringFunction(fn func(string) string) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+		return fn(toString(args[0])), nil
+	}
+}
+
+func stringPredicate(fn func(string, string) bool) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+		return fn(toString(args[0]), toString(args[1])), nil
+	}
+}
+
+func numericFunction(fn func(float64) float64) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		f, err := numberArg(args, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return fn(f), nil
+	}
+}
+
+func concatFunction(args []interface{}) (interface{}, error) {
+
+	var sb strings.Builder
+
+	for _, v := range args {
+		sb.WriteString(toString(v))
+	}
+
+	return sb.String(), nil
+}
+
+// joinFunction joins the (non-null) elements of an array with a separator.
+func joinFunction(args []interface{}) (interface{}, error) {
+
+	arr, err := arrayArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sep := toString(args[1])
+	parts := make([]string, 0, len(arr))
+
+	for _, v := range arr {
+
+		if v != nil {
+			parts = append(parts, toString(v))
+		}
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+func splitFunction(args []interface{}) (interface{}, error) {
+
+	parts := strings.Split(toString(args[0]), toString(args[1]))
+	values := make([]interface{}, len(parts))
+
+	for i, p := range parts {
+		values[i] = p
+	}
+
+	return values, nil
+}
+
+func replaceFunction(args []interface{}) (interface{}, error) {
+	return strings.ReplaceAll(toString(args[0]), toString(args[1]), toString(args[2])), nil
+}
+
+// substrFunction returns the substring starting at a (character) offset, optionally limited to a given length.
+// Negative offsets count backwards from the end of the string.
+func substrFunction(args []interface{}) (interface{}, error) {
+
+	runes := []rune(toString(args[0]))
+
+	start, err := numberArg(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := clamp(int(start), len(runes))
+	j := len(runes)
+
+	if len(args) == 3 {
+
+		length, err := numberArg(args, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if length < 0 {
+			return nil, fmt.Errorf("Length must not be negative")
+		}
+
+		j = int(math.Min(float64(i)+length, float64(len(runes))))
+	}
+
+	return string(runes[i:j]), nil
+}
+
+// containsFunction returns true if a string contains a substring or an array contains a value.
+func containsFunction(args []interface{}) (interface{}, error) {
+
+	switch t := args[0].(type) {
+	case string:
+		return strings.Contains(t, toString(args[1])), nil
+	case []interface{}:
+
+		for _, v := range t {
+
+			if equals(v, args[1]) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+
+	default:
+		return nil, fmt.Errorf("Argument 1 must be a string or an array, not %s", typeOf(args[0]))
+	}
+}
+
+// lenFunction returns the number of characters in a string or the number of elements in an array or object.
+func lenFunction(args []interface{}) (interface{}, error) {
+
+	switch t := args[0].(type) {
+	case string:
+		return float64(len([]rune(t))), nil
+	case []interface{}:
+		return float64(len(t)), nil
+	case map[string]interface{}:
+		return float64(len(t)), nil
+	default:
+		return nil, fmt.Errorf("Argument 1 must be a string, an array or an object, not %s", typeOf(args[0]))
+	}
+}
+
+func stringConversionFunction(args []interface{}) (interface{}, error) {
+	return toString(args[0]), nil
+}
+
+// numberFunction converts a string or boolean to a number. Strings that can not be parsed evaluate to null.
+func numberFunction(args []interface{}) (interface{}, error) {
+
+	switch t := args[0].(type) {
+	case float64, int64:
+		return t, nil
+	case bool:
+
+		if t {
+			return float64(1), nil
+		}
+
+		return float64(0), nil
+
+	case string:
+
+		str_number := strings.TrimSpace(t)
+		f, err := strconv.ParseFloat(str_number, 64)
+
+		if err != nil {
+			return nil, nil
+		}
+
+		return number(str_number, f), nil
+
+	default:
+		return nil, fmt.Errorf("Can not convert %s to a number", typeOf(args[0]))
+	}
+}
+
+// roundFunction rounds a number to the nearest integer or, if present, a number of decimal places.
+func roundFunction(args []interface{}) (interface{}, error) {
+
+	f, err := numberArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 1 {
+		return math.Round(f), nil
+	}
+
+	places, err := numberArg(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	factor := math.Pow(10, math.Trunc(places))
+	return math.Round(f*factor) / factor, nil
+}
+
+// extremeFunction returns a function which returns the smallest ('sign' < 0) or largest number in its arguments, or
+// in the elements of a single array argument. Null values are ignored.
+func extremeFunction(sign float64) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		if len(args) == 1 {
+
+			arr, ok := args[0].([]interface{})
+
+			if ok {
+				args = arr
+			}
+		}
+
+		var extreme interface{}
+
+		for i, v := range args {
+
+			if v == nil {
+				continue
+			}
+
+			f, err := numberArg(args, i)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if extreme == nil || (f-extreme.(float64))*sign > 0 {
+				extreme = f
+			}
+		}
+
+		return extreme, nil
+	}
+}
+
+// coalesceFunction returns the first argument that is not null.
+func coalesceFunction(args []interface{}) (interface{}, error) {
+
+	for _, v := range args {
+
+		if v != nil {
+			return v, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func existsFunction(args []interface{}) (interface{}, error) {
+	return args[0] != nil, nil
+}
+
+func firstFunction(args []interface{}) (interface{}, error) {
+	return at(args[0], 0)
+}
+
+func lastFunction(args []interface{}) (interface{}, error) {
+	return at(args[0], -1)
+}
+
+func atFunction(args []interface{}) (interface{}, error) {
+
+	i, err := numberArg(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return at(args[0], int(i))
+}
+
+// appendFunction appends one or more values to an array. If the array is null a new array is created.
+func appendFunction(args []interface{}) (interface{}, error) {
+
+	values := make([]interface{}, 0)
+
+	if args[0] != nil {
+
+		arr, err := arrayArg(args, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, arr...)
+	}
+
+	return append(values, args[1:]...), nil
+}
+
+// uniqueFunction removes duplicate elements from an array, preserving the order of their first occurrence.
+func uniqueFunction(args []interface{}) (interface{}, error) {
+
+	arr, err := arrayArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	values := make([]interface{}, 0, len(arr))
+
+	for _, v := range arr {
+
+		enc := encode(v)
+
+		if seen[enc] {
+			continue
+		}
+
+		seen[enc] = true
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// sortFunction sorts an array of numbers or an array of strings.
+func sortFunction(args []interface{}) (interface{}, error) {
+
+	arr, err := arrayArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(arr))
+	copy(values, arr)
+
+	var sort_err error
+
+	sort.SliceStable(values, func(i, j int) bool {
+
+		lt, err := compare("<", values[i], values[j])
+
+		if err != nil {
+			sort_err = err
+			return false
+		}
+
+		return lt.(bool)
+	})
+
+	if sort_err != nil {
+		return nil, sort_err
+	}
+
+	return values, nil
+}
+
+// sliceFunction returns the elements of an array from a start index up to (but not including) an optional end index.
+// Negative indices count backwards from the end of the array.
+func sliceFunction(args []interface{}) (interface{}, error) {
+
+	arr, err := arrayArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := numberArg(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := clamp(int(start), len(arr))
+	j := len(arr)
+
+	if len(args) == 3 {
+
+		end, err := numberArg(args, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		j = clamp(int(end), len(arr))
+	}
+
+	if j < i {
+		j = i
+	}
+
+	values := make([]interface{}, j-i)
+	copy(values, arr[i:j])
+
+	return values, nil
+}
+
+// compactFunction removes null values and empty strings from an array.
+func compactFunction(args []interface{}) (interface{}, error) {
+
+	arr, err := arrayArg(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, len(arr))
+
+	for _, v := range arr {
+
+		if v == nil || v == "" {
+			continue
+		}
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// at returns the element of an array, or the character of a string, at index 'i'. Negative indices count backwards
+// from the end. Indices that are out of range return nil.
+func at(v interface{}, i int) (interface{}, error) {
+
+	switch t := v.(type) {
+	case []interface{}:
+
+		if i < 0 {
+			i = len(t) + i
+		}
+
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+
+		return t[i], nil
+
+	case string:
+
+		runes := []rune(t)
+
+		if i < 0 {
+			i = len(runes) + i
+		}
+
+		if i < 0 || i >= len(runes) {
+			return nil, nil
+		}
+
+		return string(runes[i]), nil
+
+	default:
+		return nil, fmt.Errorf("Can not index %s", typeOf(v))
+	}
+}
+
+// clamp converts a (possibly negative) index in to an offset between 0 and 'length'.
+func clamp(i int, length int) int {
+
+	if i < 0 {
+		i = length + i
+	}
+
+	if i < 0 {
+		return 0
+	}
+
+	if i > length {
+		return length
+	}
+
+	return i
+}
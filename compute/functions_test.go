@@ -0,0 +1,68 @@
+package compute
+
+import (
+	"testing"
+)
+
+func TestFunctions(t *testing.T) {
+
+	body := `{"properties":{"wof:name":" Montréal ","wof:lang":["fra","eng"],"wof:population":1762949}}`
+
+	tests := []evaluateTest{
+		{expr: `upper(trim({properties.wof:name}))`, body: body, expected: "MONTRÉAL"},
+		{expr: `len(trim({properties.wof:name}))`, body: body, expected: 8.0},
+		{expr: `concat("a", 1, true)`, expected: "a1true"},
+		{expr: `join({properties.wof:lang}, ";")`, body: body, expected: "fra;eng"},
+		{expr: `split("a,b", ",")`, expected: []interface{}{"a", "b"}},
+		{expr: `replace("a-b-c", "-", "_")`, expected: "a_b_c"},
+		{expr: `substr("Montréal", 4, 3)`, expected: "réa"},
+		{expr: `substr("Montréal", 4)`, expected: "réal"},
+		{expr: `starts_with("Montréal", "Mont")`, expected: true},
+		{expr: `contains({properties.wof:lang}, "eng")`, body: body, expected: true},
+		{expr: `number("1.5") + number(true)`, expected: 2.5},
+		{expr: `number("one")`, expected: nil},
+		{expr: `round({properties.wof:population} / 1000000, 2)`, body: body, expected: 1.76},
+		{expr: `int(-1.5)`, expected: -1.0},
+		{expr: `floor(-1.5)`, expected: -2.0},
+		{expr: `max(1, null, 3)`, expected: 3.0},
+		{expr: `min([4, 2, 8])`, expected: 2.0},
+		{expr: `max(null, {properties.missing})`, expected: nil},
+		{expr: `coalesce({properties.missing}, null, "default")`, expected: "default"},
+		{expr: `exists({properties.wof:name})`, body: body, expected: true},
+		{expr: `exists({properties.missing})`, body: body, expected: false},
+		{expr: `first({properties.wof:lang})`, body: body, expected: "fra"},
+		{expr: `last([])`, expected: nil},
+		{expr: `at({properties.wof:lang}, -1)`, body: body, expected: "eng"},
+		{expr: `append({properties.wof:lang}, "spa")`, body: body, expected: []interface{}{"fra", "eng", "spa"}},
+		{expr: `unique([1, 2, 1, "1"])`, expected: []interface{}{1.0, 2.0, "1"}},
+		{expr: `sort(["b", "a"])`, expected: []interface{}{"a", "b"}},
+		{expr: `slice([1, 2, 3, 4], 1, 3)`, expected: []interface{}{2.0, 3.0}},
+		{expr: `compact([1, null, "", "a"])`, expected: []interface{}{1.0, "a"}},
+		{expr: `get("properties.wof:" + "population")`, body: body, expected: 1762949.0},
+		{expr: `if({properties.missing}, 1, 2)`, expected: 2.0},
+		// Functions evaluate to null if any of their arguments are null, unless they handle null themselves.
+		{expr: `upper({properties.missing})`, expected: nil},
+		{expr: `substr("abc", {properties.missing})`, expected: nil},
+		{expr: `concat("a", null)`, expected: nil},
+	}
+
+	runEvaluateTests(t, tests)
+
+	// Arguments of the wrong type are errors when the expression is evaluated.
+
+	errors := []string{
+		`round("a")`,
+		`round(1.5, "a")`,
+		`abs({properties.wof:lang})`,
+		`substr("abc", "1")`,
+		`join("abc", ",")`,
+		`first("abc" == "abc")`,
+		`slice({properties.wof:population}, 1)`,
+		`sort([1, "a"])`,
+		`max(1, "a")`,
+		`number([1])`,
+		`at({properties.wof:lang}, "a")`,
+	}
+
+	runErrorTests(t, body, errors)
+}
@@ -0,0 +1,212 @@
+package compute
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenPath
+	tokenIdent
+	tokenOperator
+)
+
+// token is a single lexical token in an expression. 'pos' is the (byte) offset of the token in the expression.
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenPath:
+		return fmt.Sprintf("{%s}", t.text)
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return t.text
+	}
+}
+
+// operators is the list of operators, longest first so that "==" is matched before "=".
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", "[", "]", ",",
+}
+
+// lex splits 'expr' in to a list of tokens terminated by a tokenEOF token.
+func lex(expr string) ([]token, error) {
+
+	tokens := make([]token, 0)
+	i := 0
+
+	for i < len(expr) {
+
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i += 1
+
+		case c == '{':
+
+			// Paths may contain any character, including balanced braces for gjson multipaths and queries.
+
+			depth := 0
+			j := i
+
+			for ; j < len(expr); j++ {
+
+				if expr[j] == '{' {
+					depth += 1
+				} else if expr[j] == '}' {
+					depth -= 1
+
+					if depth == 0 {
+						break
+					}
+				}
+			}
+
+			if j == len(expr) {
+				return nil, fmt.Errorf("Unterminated path at position %d", i)
+			}
+
+			path := strings.TrimSpace(expr[i+1 : j])
+
+			if path == "" {
+				return nil, fmt.Errorf("Empty path at position %d", i)
+			}
+
+			tokens = append(tokens, token{kind: tokenPath, text: path, pos: i})
+			i = j + 1
+
+		case c == '"' || c == '\'':
+
+			str, n, err := lexString(expr[i:])
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid string at position %d, %w", i, err)
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: str, value: str, pos: i})
+			i += n
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+
+			j := i
+
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j += 1
+			}
+
+			if j < len(expr) && (expr[j] == 'e' || expr[j] == 'E') {
+
+				j += 1
+
+				if j < len(expr) && (expr[j] == '+' || expr[j] == '-') {
+					j += 1
+				}
+
+				for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+					j += 1
+				}
+			}
+
+			v, err := strconv.ParseFloat(expr[i:j], 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid number '%s' at position %d", expr[i:j], i)
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[i:j], value: number(expr[i:j], v), pos: i})
+			i = j
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+
+			j := i
+
+			for j < len(expr) && (expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				j += 1
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:j], pos: i})
+			i = j
+
+		default:
+
+			matched := false
+
+			for _, op := range operators {
+
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return nil, fmt.Errorf("Unexpected character '%c' at position %d", c, i)
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(expr)})
+	return tokens, nil
+}
+
+// lexString reads a single or double quoted string, with backslash escapes, from the start of 'expr' and returns
+// its (unquoted) value and the number of bytes it occupied.
+func lexString(expr string) (string, int, error) {
+
+	quote := expr[0]
+
+	var sb strings.Builder
+
+	for i := 1; i < len(expr); i++ {
+
+		c := expr[i]
+
+		switch c {
+		case quote:
+			return sb.String(), i + 1, nil
+		case '\\':
+
+			i += 1
+
+			if i == len(expr) {
+				return "", 0, fmt.Errorf("Unterminated escape sequence")
+			}
+
+			switch expr[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			default:
+				sb.WriteByte(expr[i])
+			}
+
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("Unterminated string")
+}
@@ -0,0 +1,111 @@
+package compute
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+
+	tests := []struct {
+		expr     string
+		expected []token
+	}{
+		{
+			expr: `{properties.wof:name} == "Montréal"`,
+			expected: []token{
+				{kind: tokenPath, text: "properties.wof:name", pos: 0},
+				{kind: tokenOperator, text: "==", pos: 22},
+				{kind: tokenString, text: "Montréal", value: "Montréal", pos: 25},
+				{kind: tokenEOF, pos: 36},
+			},
+		},
+		{
+			// Paths may contain balanced braces and whitespace around them is trimmed.
+			expr: `{ properties.wof:hierarchy.#(region_id==85633111)#.locality_id }`,
+			expected: []token{
+				{kind: tokenPath, text: "properties.wof:hierarchy.#(region_id==85633111)#.locality_id", pos: 0},
+				{kind: tokenEOF, pos: 64},
+			},
+		},
+		{
+			expr: `{properties.name:{lang}}`,
+			expected: []token{
+				{kind: tokenPath, text: "properties.name:{lang}", pos: 0},
+				{kind: tokenEOF, pos: 24},
+			},
+		},
+		{
+			expr: `1.5e3 <= .5 && !x_1(-2)`,
+			expected: []token{
+				{kind: tokenNumber, text: "1.5e3", value: 1500.0, pos: 0},
+				{kind: tokenOperator, text: "<=", pos: 6},
+				{kind: tokenNumber, text: ".5", value: 0.5, pos: 9},
+				{kind: tokenOperator, text: "&&", pos: 12},
+				{kind: tokenOperator, text: "!", pos: 15},
+				{kind: tokenIdent, text: "x_1", pos: 16},
+				{kind: tokenOperator, text: "(", pos: 19},
+				{kind: tokenOperator, text: "-", pos: 20},
+				{kind: tokenNumber, text: "2", value: 2.0, pos: 21},
+				{kind: tokenOperator, text: ")", pos: 22},
+				{kind: tokenEOF, pos: 23},
+			},
+		},
+		{
+			expr: `'it\'s' + "a\tb\\"`,
+			expected: []token{
+				{kind: tokenString, text: "it's", value: "it's", pos: 0},
+				{kind: tokenOperator, text: "+", pos: 8},
+				{kind: tokenString, text: "a\tb\\", value: "a\tb\\", pos: 10},
+				{kind: tokenEOF, pos: 18},
+			},
+		},
+		{
+			// Integers which can't be represented exactly as a float64 are lexed as int64 values.
+			expr: `9007199254740993 9007199254740992 9007199254740993.0`,
+			expected: []token{
+				{kind: tokenNumber, text: "9007199254740993", value: int64(9007199254740993), pos: 0},
+				{kind: tokenNumber, text: "9007199254740992", value: int64(9007199254740992), pos: 17},
+				{kind: tokenNumber, text: "9007199254740993.0", value: 9007199254740992.0, pos: 34},
+				{kind: tokenEOF, pos: 52},
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		tokens, err := lex(test.expr)
+
+		if err != nil {
+			t.Errorf("Failed to lex '%s', %v", test.expr, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(tokens, test.expected) {
+			t.Errorf("Unexpected tokens for '%s', %#v", test.expr, tokens)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+
+	invalid := []string{
+		`{properties.wof:name`,
+		`{ }`,
+		`"unterminated`,
+		`'escape\`,
+		`1.2.3`,
+		`{properties.wof:id} = 1`,
+		`a & b`,
+		`#`,
+	}
+
+	for _, expr := range invalid {
+
+		_, err := lex(expr)
+
+		if err == nil {
+			t.Errorf("Expected '%s' to fail to lex", expr)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package compute
+
+import (
+	"fmt"
+)
+
+// parser is a recursive descent parser for expressions. In order of increasing precedence the grammar is:
+//
+//	conditional = or [ "?" conditional ":" conditional ]
+//	or          = and { "||" and }
+//	and         = equality { "&&" equality }
+//	equality    = comparison { ( "==" | "!=" ) comparison }
+//	comparison  = additive { ( "<" | "<=" | ">" | ">=" ) additive }
+//	additive    = multiplicative { ( "+" | "-" ) multiplicative }
+//	multiplicative = unary { ( "*" | "/" | "%" ) unary }
+//	unary       = ( "!" | "-" ) unary | postfix
+//	postfix     = primary { "[" conditional "]" }
+//	primary     = NUMBER | STRING | PATH | "true" | "false" | "null" | IDENT "(" [ args ] ")" | "(" conditional ")" | "[" [ args ] "]"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses 'expr' and returns the root node of its syntax tree.
+func parse(expr string) (node, error) {
+
+	tokens, err := lex(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		tokens: tokens,
+	}
+
+	n, err := p.conditional()
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+
+	if t.kind != tokenEOF {
+		return nil, fmt.Errorf("Unexpected %s at position %d", t, t.pos)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != tokenEOF {
+		p.pos += 1
+	}
+
+	return t
+}
+
+// accept consumes the next token and returns true if it is the operator 'op'.
+func (p *parser) accept(op string) bool {
+
+	t := p.peek()
+
+	if t.kind == tokenOperator && t.text == op {
+		p.pos += 1
+		return true
+	}
+
+	return false
+}
+
+// expect consumes the next token and returns an error if it is not the operator 'op'.
+func (p *parser) expect(op string) error {
+
+	t := p.peek()
+
+	if !p.accept(op) {
+		return fmt.Errorf("Expected '%s' but found %s at position %d", op, t, t.pos)
+	}
+
+	return nil
+}
+
+func (p *parser) conditional() (node, error) {
+
+	cond, err := p.or()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept("?") {
+		return cond, nil
+	}
+
+	then, err := p.conditional()
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(":")
+
+	if err != nil {
+		return nil, err
+	}
+
+	otherwise, err := p.conditional()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conditionalNode{cond, then, otherwise}, nil
+}
+
+// binary parses a left-associative sequence of operands, parsed by 'operand', separated by any of 'ops'.
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+
+	left, err := operand()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+
+		t := p.peek()
+		matched := false
+
+		for _, op := range ops {
+
+			if p.accept(op) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return left, nil
+		}
+
+		right, err := operand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch t.text {
+		case "&&", "||":
+			left = &logicalNode{t.text, left, right}
+		default:
+			left = &binaryNode{t.text, left, right}
+		}
+	}
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.equality, "&&")
+}
+
+func (p *parser) equality() (node, error) {
+	return p.binary(p.comparison, "==", "!=")
+}
+
+func (p *parser) comparison() (node, error) {
+	return p.binary(p.additive, "<=", ">=", "<", ">")
+}
+
+func (p *parser) additive() (node, error) {
+	return p.binary(p.multiplicative, "+", "-")
+}
+
+func (p *parser) multiplicative() (node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+func (p *parser) unary() (node, error) {
+
+	t := p.peek()
+
+	if p.accept("!") || p.accept("-") {
+
+		operand, err := p.unary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryNode{t.text, operand}, nil
+	}
+
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+
+	n, err := p.primary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("[") {
+
+		index, err := p.conditional()
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect("]")
+
+		if err != nil {
+			return nil, err
+		}
+
+		n = &indexNode{n, index}
+	}
+
+	return n, nil
+}
+
+func (p *parser) primary() (node, error) {
+
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{t.value}, nil
+	case tokenPath:
+		return &pathNode{t.text}, nil
+	case tokenIdent:
+
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+
+		err := p.expect("(")
+
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := p.list(")")
+
+		if err != nil {
+			return nil, err
+		}
+
+		// if() is evaluated lazily, like the ternary operator, so that only the chosen branch is evaluated
+		// and get() needs access to the record being evaluated so neither are defined in 'functions'.
+
+		switch t.text {
+		case "if":
+
+			if len(args) != 3 {
+				return nil, fmt.Errorf("Invalid number of arguments (%d) for function 'if' at position %d", len(args), t.pos)
+			}
+
+			return &conditionalNode{args[0], args[1], args[2]}, nil
+
+		case "get":
+
+			if len(args) != 1 {
+				return nil, fmt.Errorf("Invalid number of arguments (%d) for function 'get' at position %d", len(args), t.pos)
+			}
+
+			return &dynamicPathNode{args[0]}, nil
+		}
+
+		fn, exists := functions[t.text]
+
+		if !exists {
+			return nil, fmt.Errorf("Unknown function '%s' at position %d", t.text, t.pos)
+		}
+
+		if len(args) < fn.min_args || (fn.max_args >= 0 && len(args) > fn.max_args) {
+			return nil, fmt.Errorf("Invalid number of arguments (%d) for function '%s' at position %d", len(args), t.text, t.pos)
+		}
+
+		return &callNode{t.text, fn, args}, nil
+
+	case tokenOperator:
+
+		switch t.text {
+		case "(":
+
+			n, err := p.conditional()
+
+			if err != nil {
+				return nil, err
+			}
+
+			err = p.expect(")")
+
+			if err != nil {
+				return nil, err
+			}
+
+			return n, nil
+
+		case "[":
+
+			items, err := p.list("]")
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &arrayNode{items}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unexpected %s at position %d", t, t.pos)
+}
+
+// list parses a (possibly empty) comma-separated list of expressions terminated by the operator 'end'.
+func (p *parser) list(end string) ([]node, error) {
+
+	items := make([]node, 0)
+
+	if p.accept(end) {
+		return items, nil
+	}
+
+	for {
+
+		n, err := p.conditional()
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, n)
+
+		if p.accept(end) {
+			return items, nil
+		}
+
+		err = p.expect(",")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
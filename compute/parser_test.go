@@ -0,0 +1,93 @@
+package compute
+
+import (
+	"testing"
+)
+
+func TestPrecedence(t *testing.T) {
+
+	tests := []evaluateTest{
+		{expr: `1 + 2 * 3`, expected: 7.0},
+		{expr: `(1 + 2) * 3`, expected: 9.0},
+		{expr: `10 - 4 - 3`, expected: 3.0},
+		{expr: `24 / 4 / 2`, expected: 3.0},
+		{expr: `7 % 4 * 2`, expected: 6.0},
+		{expr: `-2 * 3`, expected: -6.0},
+		{expr: `--2`, expected: 2.0},
+		{expr: `1 + 2 < 4`, expected: true},
+		{expr: `1 < 2 == 2 < 3`, expected: true},
+		{expr: `!0 == true`, expected: true},
+		{expr: `!(1 == 1)`, expected: false},
+		{expr: `true || false && false`, expected: true},
+		{expr: `(true || false) && false`, expected: false},
+		{expr: `1 == 2 || 2 == 2 && 3 == 3`, expected: true},
+		// The conditional operator is right-associative and binds more loosely than everything else.
+		{expr: `false ? 1 : true ? 2 : 3`, expected: 2.0},
+		{expr: `1 > 2 || 3 > 2 ? "yes" : "no"`, expected: "yes"},
+		{expr: `true ? 1 + 1 : 0`, expected: 2.0},
+		{expr: `[1, 2, 3][1] * 2`, expected: 4.0},
+		{expr: `-[1, 2, 3][-1]`, expected: -3.0},
+		{expr: `upper("a" + "b")[1]`, expected: "B"},
+		{expr: `{properties.names}[0][1]`, body: `{"properties":{"names":[["a","b"]]}}`, expected: "b"},
+	}
+
+	runEvaluateTests(t, tests)
+}
+
+func TestParseErrors(t *testing.T) {
+
+	invalid := []string{
+		``,
+		`1 +`,
+		`(1 + 2`,
+		`1 + 2)`,
+		`[1, 2`,
+		`{properties.a}[0`,
+		`true ? 1`,
+		`1 2`,
+		`upper`,
+		`upper("a"`,
+		`unknown("a")`,
+		// Arity is checked when the expression is parsed.
+		`upper()`,
+		`upper("a", "b")`,
+		`substr("abc")`,
+		`substr("abc", 1, 2, 3)`,
+		`concat()`,
+		`today(1)`,
+		`if(true, 1)`,
+		`get()`,
+		`get("a", "b")`,
+		`edtf_between("2000", "2001")`,
+		`within_bbox({geometry}, 1, 2, 3)`,
+	}
+
+	for _, expr := range invalid {
+
+		_, err := ParseExpression(expr)
+
+		if err == nil {
+			t.Errorf("Expected '%s' to fail to parse", expr)
+		}
+	}
+
+	// Variadic functions accept any number of arguments above their minimum.
+
+	valid := []string{
+		`concat("a")`,
+		`concat("a", "b", "c", "d")`,
+		`max(1, 2, 3, 4, 5)`,
+		`append([1], 2, 3, 4)`,
+		`substr("abc", 1)`,
+		`substr("abc", 1, 1)`,
+	}
+
+	for _, expr := range valid {
+
+		_, err := ParseExpression(expr)
+
+		if err != nil {
+			t.Errorf("Failed to parse '%s', %v", expr, err)
+		}
+	}
+}
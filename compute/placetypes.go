@@ -0,0 +1,37 @@
+package compute
+
+import (
+	"github.com/whosonfirst/go-whosonfirst-placetypes"
+)
+
+func init() {
+	functions["descendant_of"] = &function{2, 2, true, placetypeFunction(false)}
+	functions["ancestor_of"] = &function{2, 2, true, placetypeFunction(true)}
+}
+
+// placetypeFunction returns a function which returns true if the placetype named by its first argument is a descendant
+// of ('ancestor' is false), or an ancestor of, the placetype named by its second argument. Unknown placetypes are never
+// related to other placetypes.
+func placetypeFunction(ancestor bool) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		a, err := placetypes.GetPlacetypeByName(toString(args[0]))
+
+		if err != nil {
+			return false, nil
+		}
+
+		b, err := placetypes.GetPlacetypeByName(toString(args[1]))
+
+		if err != nil {
+			return false, nil
+		}
+
+		if ancestor {
+			return placetypes.IsAncestor(b, a), nil
+		}
+
+		return placetypes.IsAncestor(a, b), nil
+	}
+}
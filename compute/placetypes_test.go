@@ -0,0 +1,25 @@
+package compute
+
+import (
+	"testing"
+)
+
+func TestPlacetypeFunctions(t *testing.T) {
+
+	tests := []evaluateTest{
+		{expr: `descendant_of("locality", "region")`, expected: true},
+		{expr: `descendant_of("neighbourhood", "country")`, expected: true},
+		{expr: `descendant_of("region", "locality")`, expected: false},
+		{expr: `ancestor_of("region", "locality")`, expected: true},
+		{expr: `ancestor_of("locality", "region")`, expected: false},
+		// A placetype is neither its own ancestor nor its own descendant.
+		{expr: `descendant_of("region", "region")`, expected: false},
+		// Unknown placetypes are never related to other placetypes.
+		{expr: `descendant_of("borough-ish", "region")`, expected: false},
+		{expr: `ancestor_of("region", "")`, expected: false},
+		{expr: `descendant_of({properties.wof:placetype}, "region")`, body: `{"properties":{"wof:placetype":"locality"}}`, expected: true},
+		{expr: `descendant_of({properties.wof:placetype}, "region")`, body: `{"properties":{}}`, expected: nil},
+	}
+
+	runEvaluateTests(t, tests)
+}
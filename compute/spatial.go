@@ -0,0 +1,409 @@
+package compute
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+func init() {
+	functions["within_bbox"] = &function{5, 5, true, bboxFunction(true)}
+	functions["intersects_bbox"] = &function{5, 5, true, bboxFunction(false)}
+	functions["within"] = &function{2, 2, true, polygonFunction(true)}
+	functions["intersects"] = &function{2, 2, true, polygonFunction(false)}
+}
+
+// polygons caches the polygons parsed from GeoJSON strings, since the same string literal is usually passed
+// for every record.
+var polygons = new(sync.Map)
+
+// bboxFunction returns a function which returns true if a GeoJSON geometry is entirely within ('within' is true), or
+// intersects, the bounding box defined by its minx, miny, maxx and maxy arguments.
+func bboxFunction(within bool) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		geom, err := toGeometry(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		coords := make([]float64, 4)
+
+		for i := 0; i < 4; i++ {
+
+			f, err := numberArg(args, i+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			coords[i] = f
+		}
+
+		bbox := orb.Bound{
+			Min: orb.Point{coords[0], coords[1]},
+			Max: orb.Point{coords[2], coords[3]},
+		}
+
+		mp := orb.MultiPolygon{bbox.ToPolygon()}
+
+		if within {
+			return isWithin(geom, mp), nil
+		}
+
+		return intersects(geom, mp), nil
+	}
+}
+
+// polygonFunction returns a function which returns true if a GeoJSON geometry is entirely within ('within' is true),
+// or intersects, a GeoJSON (Multi)Polygon geometry or Feature. The latter may be passed as an object or a string.
+func polygonFunction(within bool) func([]interface{}) (interface{}, error) {
+
+	return func(args []interface{}) (interface{}, error) {
+
+		geom, err := toGeometry(args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		mp, err := toMultiPolygon(args[1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		if within {
+			return isWithin(geom, mp), nil
+		}
+
+		return intersects(geom, mp), nil
+	}
+}
+
+// toGeometry converts a GeoJSON geometry, or Feature, to an `orb.Geometry` instance. 'v' may be an object
+// (for example the value of a record's "geometry" path) or a JSON-encoded string.
+func toGeometry(v interface{}) (orb.Geometry, error) {
+
+	var body []byte
+
+	switch t := v.(type) {
+	case string:
+		body = []byte(t)
+	case map[string]interface{}:
+
+		enc, err := json.Marshal(t)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal geometry, %w", err)
+		}
+
+		body = enc
+
+	default:
+		return nil, fmt.Errorf("Geometry must be an object or a string, not %s", typeOf(v))
+	}
+
+	var probe struct {
+		Type string `json:"type"`
+	}
+
+	err := json.Unmarshal(body, &probe)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse geometry, %w", err)
+	}
+
+	if probe.Type == "Feature" {
+
+		f, err := geojson.UnmarshalFeature(body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse feature, %w", err)
+		}
+
+		return f.Geometry, nil
+	}
+
+	geom, err := geojson.UnmarshalGeometry(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse geometry, %w", err)
+	}
+
+	return geom.Geometry(), nil
+}
+
+// toMultiPolygon converts a GeoJSON Polygon or MultiPolygon geometry (or Feature) to an `orb.MultiPolygon` instance.
+// Polygons passed as strings are cached.
+func toMultiPolygon(v interface{}) (orb.MultiPolygon, error) {
+
+	str_v, is_string := v.(string)
+
+	if is_string {
+
+		cached, ok := polygons.Load(str_v)
+
+		if ok {
+			return cached.(orb.MultiPolygon), nil
+		}
+	}
+
+	geom, err := toGeometry(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var mp orb.MultiPolygon
+
+	switch g := geom.(type) {
+	case orb.Polygon:
+		mp = orb.MultiPolygon{g}
+	case orb.MultiPolygon:
+		mp = g
+	default:
+		return nil, fmt.Errorf("Invalid geometry type '%s', must be a Polygon or MultiPolygon", geom.GeoJSONType())
+	}
+
+	if is_string {
+		polygons.Store(str_v, mp)
+	}
+
+	return mp, nil
+}
+
+// intersects returns true if 'geom' and 'mp' share any points.
+func intersects(geom orb.Geometry, mp orb.MultiPolygon) bool {
+
+	if !geom.Bound().Intersects(mp.Bound()) {
+		return false
+	}
+
+	for _, pt := range vertices(geom) {
+
+		if planar.MultiPolygonContains(mp, pt) {
+			return true
+		}
+	}
+
+	// Polygons can intersect without either containing any of the other's vertices (for example two overlapping
+	// rectangles forming a cross) or one can contain the other entirely.
+
+	for _, pt := range vertices(mp) {
+
+		if geometryContains(geom, pt) {
+			return true
+		}
+	}
+
+	return segmentsCross(geom, mp, false)
+}
+
+// isWithin returns true if every point in 'geom' is inside (or on the boundary of) 'mp'.
+func isWithin(geom orb.Geometry, mp orb.MultiPolygon) bool {
+
+	if !mp.Bound().Contains(geom.Bound().Min) || !mp.Bound().Contains(geom.Bound().Max) {
+		return false
+	}
+
+	for _, pt := range vertices(geom) {
+
+		if !planar.MultiPolygonContains(mp, pt) {
+			return false
+		}
+	}
+
+	// All the vertices being inside a concave polygon doesn't mean the segments between them are.
+
+	return !segmentsCross(geom, mp, true)
+}
+
+// geometryContains returns true if 'geom' is a (Multi)Polygon that contains 'pt'.
+func geometryContains(geom orb.Geometry, pt orb.Point) bool {
+
+	switch g := geom.(type) {
+	case orb.Polygon:
+		return planar.PolygonContains(g, pt)
+	case orb.MultiPolygon:
+		return planar.MultiPolygonContains(g, pt)
+	case orb.Bound:
+		return g.Contains(pt)
+	case orb.Collection:
+
+		for _, child := range g {
+
+			if geometryContains(child, pt) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// vertices returns all the points in 'geom'.
+func vertices(geom orb.Geometry) []orb.Point {
+
+	points := make([]orb.Point, 0)
+
+	switch g := geom.(type) {
+	case orb.Point:
+		points = append(points, g)
+	case orb.MultiPoint:
+		points = append(points, g...)
+	case orb.LineString:
+		points = append(points, g...)
+	case orb.MultiLineString:
+
+		for _, ls := range g {
+			points = append(points, ls...)
+		}
+
+	case orb.Ring:
+		points = append(points, g...)
+	case orb.Polygon:
+
+		for _, r := range g {
+			points = append(points, r...)
+		}
+
+	case orb.MultiPolygon:
+
+		for _, p := range g {
+			points = append(points, vertices(p)...)
+		}
+
+	case orb.Bound:
+		points = append(points, g.ToRing()...)
+	case orb.Collection:
+
+		for _, child := range g {
+			points = append(points, vertices(child)...)
+		}
+	}
+
+	return points
+}
+
+// segments returns all the line segments in 'geom'.
+func segments(geom orb.Geometry) [][2]orb.Point {
+
+	segs := make([][2]orb.Point, 0)
+
+	appendLine := func(points []orb.Point) {
+
+		for i := 1; i < len(points); i++ {
+			segs = append(segs, [2]orb.Point{points[i-1], points[i]})
+		}
+	}
+
+	switch g := geom.(type) {
+	case orb.LineString:
+		appendLine(g)
+	case orb.MultiLineString:
+
+		for _, ls := range g {
+			appendLine(ls)
+		}
+
+	case orb.Ring:
+		appendLine(g)
+	case orb.Polygon:
+
+		for _, r := range g {
+			appendLine(r)
+		}
+
+	case orb.MultiPolygon:
+
+		for _, p := range g {
+			segs = append(segs, segments(p)...)
+		}
+
+	case orb.Bound:
+		appendLine(g.ToRing())
+	case orb.Collection:
+
+		for _, child := range g {
+			segs = append(segs, segments(child)...)
+		}
+	}
+
+	return segs
+}
+
+// segmentsCross returns true if any of the segments in 'a' intersect any of the segments in 'b'. If 'proper' is true
+// only segments that cross each other, rather than touching or overlapping, are considered.
+func segmentsCross(a orb.Geometry, b orb.Geometry, proper bool) bool {
+
+	b_segs := segments(b)
+
+	for _, s := range segments(a) {
+
+		s_bound := orb.MultiPoint{s[0], s[1]}.Bound()
+
+		for _, t := range b_segs {
+
+			if !s_bound.Intersects(orb.MultiPoint{t[0], t[1]}.Bound()) {
+				continue
+			}
+
+			if segmentsIntersect(s[0], s[1], t[0], t[1], proper) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// segmentsIntersect returns true if the segment p1-p2 intersects the segment q1-q2. If 'proper' is true only
+// segments that cross at a single point interior to both segments are considered to intersect.
+func segmentsIntersect(p1 orb.Point, p2 orb.Point, q1 orb.Point, q2 orb.Point, proper bool) bool {
+
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+
+	if proper {
+		return false
+	}
+
+	return (d1 == 0 && onSegment(q1, q2, p1)) ||
+		(d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) ||
+		(d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation returns the sign of the cross product of (b - a) and (c - a): positive if a, b, c turn
+// counter-clockwise, negative if they turn clockwise and zero if they are collinear.
+func orientation(a orb.Point, b orb.Point, c orb.Point) float64 {
+
+	v := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// onSegment returns true if 'pt', which is known to be collinear with 'a' and 'b', lies between them.
+func onSegment(a orb.Point, b orb.Point, pt orb.Point) bool {
+	return orb.MultiPoint{a, b}.Bound().Contains(pt)
+}
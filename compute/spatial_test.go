@@ -0,0 +1,57 @@
+package compute
+
+import (
+	"testing"
+)
+
+func TestSpatialFunctions(t *testing.T) {
+
+	point := `{"geometry":{"type":"Point","coordinates":[-73.6,45.5]}}`
+	line := `{"geometry":{"type":"LineString","coordinates":[[-74.5,45.5],[-73.6,45.5]]}}`
+	square := `{"geometry":{"type":"Polygon","coordinates":[[[-73.9,45.4],[-73.4,45.4],[-73.4,45.7],[-73.9,45.7],[-73.9,45.4]]]}}`
+
+	// An L-shaped polygon covering (0,0)-(2,1) and (0,1)-(1,2) but not the square (1,1)-(2,2).
+	concave := `{\"type\":\"Polygon\",\"coordinates\":[[[0,0],[2,0],[2,1],[1,1],[1,2],[0,2],[0,0]]]}`
+
+	// A line whose end points are both inside the L but which crosses the missing square.
+	shortcut := `{"geometry":{"type":"LineString","coordinates":[[1.5,0.5],[0.5,1.9]]}}`
+
+	// Two rectangles forming a cross: neither contains any of the other's vertices.
+	cross := `{"geometry":{"type":"Polygon","coordinates":[[[-1,2],[5,2],[5,3],[-1,3],[-1,2]]]}}`
+	bar := `{\"type\":\"Feature\",\"properties\":{},\"geometry\":{\"type\":\"Polygon\",\"coordinates\":[[[1,0],[2,0],[2,5],[1,5],[1,0]]]}}`
+
+	tests := []evaluateTest{
+		{expr: `within_bbox({geometry}, -74.0, 45.4, -73.4, 45.7)`, body: point, expected: true},
+		{expr: `within_bbox({geometry}, -73.5, 45.4, -73.4, 45.7)`, body: point, expected: false},
+		{expr: `intersects_bbox({geometry}, -74.0, 45.4, -73.4, 45.7)`, body: line, expected: true},
+		{expr: `within_bbox({geometry}, -74.0, 45.4, -73.4, 45.7)`, body: line, expected: false},
+		{expr: `intersects_bbox({geometry}, -75.0, 44.0, -74.9, 44.1)`, body: line, expected: false},
+		{expr: `within_bbox({geometry}, -74.0, 45.0, -73.0, 46.0)`, body: square, expected: true},
+		{expr: `intersects_bbox({geometry}, -73.5, 45.5, -73.0, 46.0)`, body: square, expected: true},
+		// A bounding box inside the polygon shares no vertices with it.
+		{expr: `intersects_bbox({geometry}, -73.7, 45.5, -73.6, 45.6)`, body: square, expected: true},
+		{expr: `intersects_bbox({geometry}, -73.0, 45.5, -72.0, 46.0)`, body: square, expected: false},
+		{expr: `within({geometry}, "` + concave + `")`, body: `{"geometry":{"type":"Point","coordinates":[0.5,0.5]}}`, expected: true},
+		{expr: `within({geometry}, "` + concave + `")`, body: `{"geometry":{"type":"Point","coordinates":[1.5,1.5]}}`, expected: false},
+		{expr: `intersects({geometry}, "` + concave + `")`, body: shortcut, expected: true},
+		{expr: `within({geometry}, "` + concave + `")`, body: shortcut, expected: false},
+		// Points on the boundary are within the polygon.
+		{expr: `within({geometry}, "` + concave + `")`, body: `{"geometry":{"type":"LineString","coordinates":[[0,0],[2,0],[2,1]]}}`, expected: true},
+		{expr: `intersects({geometry}, "` + bar + `")`, body: cross, expected: true},
+		{expr: `within({geometry}, "` + bar + `")`, body: cross, expected: false},
+		// Functions whose arguments are null evaluate to null.
+		{expr: `within_bbox({geometry}, -74.0, 45.4, -73.4, 45.7)`, body: `{}`, expected: nil},
+		{expr: `intersects({geometry}, {properties.polygon})`, body: point, expected: nil},
+	}
+
+	runEvaluateTests(t, tests)
+
+	errors := []string{
+		`within_bbox({properties.wof:name}, -74.0, 45.4, -73.4, 45.7)`,
+		`within_bbox({geometry}, "west", 45.4, -73.4, 45.7)`,
+		`within({geometry}, "{\"type\":\"Point\",\"coordinates\":[0,0]}")`,
+		`intersects({geometry}, "not json")`,
+	}
+
+	runErrorTests(t, `{"properties":{"wof:name":"Montreal"},"geometry":{"type":"Point","coordinates":[-73.6,45.5]}}`, errors)
+}
@@ -0,0 +1,187 @@
+// Package concurrency provides whosonfirst/go-reader and whosonfirst/go-writer implementations for detecting when a
+// record has been changed by another process between the time it was read and the time it is written back to disk.
+//
+// A `GuardedReader` records the version (its "wof:lastmodified" property and a hash of its contents) of each record it
+// reads from a local file. Before writing a record back to the same file a `GuardedWriter` acquires an advisory lock
+// file for the record's repository and compares the version of the file on disk with the version that was read. If they
+// differ the write fails with an error wrapping `ErrConflict`, rather than overwriting the other process's changes.
+package concurrency
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// ErrConflict is returned (wrapped) when a record has been changed since it was read.
+var ErrConflict = errors.New("Write conflict")
+
+// Version identifies the state of a record at a point in time.
+type Version struct {
+	// LastModified is the value of the record's "wof:lastmodified" property.
+	LastModified int64
+	// Hash is the SHA-256 hash of the record's contents.
+	Hash string
+}
+
+// NewVersion returns the `Version` of 'body'.
+func NewVersion(body []byte) *Version {
+
+	v := &Version{
+		LastModified: gjson.GetBytes(body, "properties.wof:lastmodified").Int(),
+		Hash:         fmt.Sprintf("%x", sha256.Sum256(body)),
+	}
+
+	return v
+}
+
+// Equals returns a boolean value indicating whether 'v' and 'other' are the same version.
+func (v *Version) Equals(other *Version) bool {
+	return v.LastModified == other.LastModified && v.Hash == other.Hash
+}
+
+// Versions tracks the versions of the records that have been read, keyed by their absolute paths.
+type Versions struct {
+	versions map[string]*Version
+	mu       *sync.RWMutex
+}
+
+// NewVersions returns a new, empty, `Versions` instance.
+func NewVersions() *Versions {
+
+	v := &Versions{
+		versions: make(map[string]*Version),
+		mu:       new(sync.RWMutex),
+	}
+
+	return v
+}
+
+// Track records the version of 'body' as the version of the record at 'abs_path'.
+func (v *Versions) Track(abs_path string, body []byte) {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.versions[abs_path] = NewVersion(body)
+}
+
+// Get returns the version of the record at 'abs_path' and a boolean value indicating whether it has been tracked.
+func (v *Versions) Get(abs_path string) (*Version, bool) {
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	version, exists := v.versions[abs_path]
+	return version, exists
+}
+
+// TrackFile records the version of 'body' as the version of the record read from the local file 'path', which may be
+// relative. It is used by tools that read records with a whosonfirst/go-whosonfirst-iterate iterator, rather than a
+// `GuardedReader`. Paths which are not local files (for example the paths emitted by iterators that read records from
+// a database) are not tracked.
+func (v *Versions) TrackFile(path string, body []byte) {
+
+	abs_path, err := filepath.Abs(path)
+
+	if err != nil {
+		return
+	}
+
+	info, err := os.Stat(abs_path)
+
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	v.Track(abs_path, body)
+}
+
+// Check compares the version of the record on disk at 'abs_path' with its tracked version and returns an error
+// wrapping `ErrConflict` if they differ, or if the record has been removed. Records that have not been tracked are
+// not checked.
+func (v *Versions) Check(abs_path string) error {
+
+	loaded, exists := v.Get(abs_path)
+
+	if !exists {
+		return nil
+	}
+
+	current_body, err := os.ReadFile(abs_path)
+
+	if err != nil {
+
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s has been removed since it was read, %w", abs_path, ErrConflict)
+		}
+
+		return fmt.Errorf("Failed to read %s, %w", abs_path, err)
+	}
+
+	current := NewVersion(current_body)
+
+	if !current.Equals(loaded) {
+		return fmt.Errorf("%s has been modified since it was read (wof:lastmodified was %d, is now %d), %w", abs_path, loaded.LastModified, current.LastModified, ErrConflict)
+	}
+
+	return nil
+}
+
+// Guard returns new `GuardedReader` and `GuardedWriter` instances, sharing the same `Versions`, wrapping 'r' and 'wr'.
+// This is how tools that update existing records refuse to overwrite records that are modified by another process
+// after they are read: the reader records the version of each record it reads and the writer fails with an error
+// wrapping `ErrConflict`, rather than writing the record, if the file on disk no longer matches that version. Pass
+// a function that reads, updates and writes a record to `Retry` to re-apply its changes to the latest version instead.
+func Guard(ctx context.Context, r reader.Reader, wr writer.Writer) (reader.Reader, writer.Writer) {
+
+	versions := NewVersions()
+
+	guarded_r := NewGuardedReader(r, versions)
+	guarded_wr := NewGuardedWriter(wr, versions)
+
+	return guarded_r, guarded_wr
+}
+
+// GuardWriter returns a new `GuardedWriter` instance wrapping 'wr' and the `Versions` instance it checks. It is the
+// equivalent of `Guard` for tools that read records with a whosonfirst/go-whosonfirst-iterate iterator rather than a
+// reader: each record the iterator emits must be recorded with the `Versions.TrackFile` method before it is written.
+// Since an iterator only reads each record once these tools can't re-read a conflicting record and so don't support
+// `Retry`. Running the tool again re-applies its changes to the latest version of each record.
+func GuardWriter(ctx context.Context, wr writer.Writer) (writer.Writer, *Versions) {
+
+	versions := NewVersions()
+	guarded_wr := NewGuardedWriter(wr, versions)
+
+	return guarded_wr, versions
+}
+
+// Retry invokes 'fn' and, if it returns an error wrapping `ErrConflict`, invokes it again up to 'retries' more times.
+// Since each invocation re-reads the records it changes this has the effect of re-applying those changes to the
+// latest version of each record.
+func Retry(ctx context.Context, retries int, fn func() error) error {
+
+	for i := 0; ; i++ {
+
+		err := fn()
+
+		if err == nil || !errors.Is(err, ErrConflict) || i >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// pass
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+const TEST_PATH string = "101/736/545/101736545.geojson"
+
+const TEST_RECORD string = `{"type":"Feature","properties":{"wof:id":101736545,"wof:name":"Montreal","wof:lastmodified":1700000000},"geometry":{"type":"Point","coordinates":[-73.5,45.5]}}`
+
+// setupRepo creates a temporary repository containing TEST_RECORD and returns the path of its "data" directory.
+func setupRepo(t *testing.T) string {
+
+	data := filepath.Join(t.TempDir(), "data")
+	path := filepath.Join(data, TEST_PATH)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create %s, %v", filepath.Dir(path), err)
+	}
+
+	err = os.WriteFile(path, []byte(TEST_RECORD), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	return data
+}
+
+func setupGuard(t *testing.T, ctx context.Context, data string) (reader.Reader, writer.Writer) {
+
+	r, err := reader.NewReader(ctx, "fs://"+data)
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	wr, err := writer.NewWriter(ctx, "fs://"+data)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	return Guard(ctx, r, wr)
+}
+
+// readAndWrite reads TEST_PATH using 'r', invokes 'between' and then writes the record back using 'wr' with its name
+// replaced by 'name'.
+func readAndWrite(ctx context.Context, r reader.Reader, wr writer.Writer, name string, between func()) error {
+
+	fh, err := r.Read(ctx, TEST_PATH)
+
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(fh)
+	fh.Close()
+
+	if err != nil {
+		return err
+	}
+
+	if between != nil {
+		between()
+	}
+
+	new_body := strings.Replace(string(body), "Montreal", name, 1)
+
+	_, err = wr.Write(ctx, TEST_PATH, strings.NewReader(new_body))
+	return err
+}
+
+// modifyRecord changes the record at TEST_PATH in 'data' the way another process would.
+func modifyRecord(t *testing.T, data string) {
+
+	path := filepath.Join(data, TEST_PATH)
+	body := strings.Replace(TEST_RECORD, "1700000000", "1700000001", 1)
+
+	err := os.WriteFile(path, []byte(body), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to modify %s, %v", path, err)
+	}
+}
+
+func readName(t *testing.T, data string) string {
+
+	body, err := os.ReadFile(filepath.Join(data, TEST_PATH))
+
+	if err != nil {
+		t.Fatalf("Failed to read record, %v", err)
+	}
+
+	switch {
+	case strings.Contains(string(body), "Montréal"):
+		return "Montréal"
+	case strings.Contains(string(body), "Montreal"):
+		return "Montreal"
+	default:
+		return ""
+	}
+}
+
+func TestGuardWrite(t *testing.T) {
+
+	ctx := context.Background()
+	data := setupRepo(t)
+
+	r, wr := setupGuard(t, ctx, data)
+
+	err := readAndWrite(ctx, r, wr, "Montréal", nil)
+
+	if err != nil {
+		t.Fatalf("Expected unchanged record to be written, %v", err)
+	}
+
+	if readName(t, data) != "Montréal" {
+		t.Fatalf("Record was not written")
+	}
+
+	// The version written is tracked so the record can be written again without being re-read.
+
+	_, err = wr.Write(ctx, TEST_PATH, strings.NewReader(TEST_RECORD))
+
+	if err != nil {
+		t.Fatalf("Expected second write to succeed, %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(data), LOCK_FILENAME))
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected lock file to be removed after writing, %v", err)
+	}
+}
+
+func TestGuardConflict(t *testing.T) {
+
+	ctx := context.Background()
+	data := setupRepo(t)
+
+	r, wr := setupGuard(t, ctx, data)
+
+	err := readAndWrite(ctx, r, wr, "Montréal", func() { modifyRecord(t, data) })
+
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected write conflict, got %v", err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(data, TEST_PATH))
+
+	if err != nil {
+		t.Fatalf("Failed to read record, %v", err)
+	}
+
+	if !strings.Contains(string(body), "1700000001") || readName(t, data) != "Montreal" {
+		t.Fatalf("Expected other process's changes to be preserved, got %s", body)
+	}
+}
+
+func TestGuardRemoved(t *testing.T) {
+
+	ctx := context.Background()
+	data := setupRepo(t)
+
+	r, wr := setupGuard(t, ctx, data)
+
+	remove := func() {
+		os.Remove(filepath.Join(data, TEST_PATH))
+	}
+
+	err := readAndWrite(ctx, r, wr, "Montréal", remove)
+
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected write conflict for removed record, got %v", err)
+	}
+}
+
+func TestGuardWriterTrackFile(t *testing.T) {
+
+	ctx := context.Background()
+	data := setupRepo(t)
+
+	fs_wr, err := writer.NewWriter(ctx, "fs://"+data)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	wr, versions := GuardWriter(ctx, fs_wr)
+
+	path := filepath.Join(data, TEST_PATH)
+	versions.TrackFile(path, []byte(TEST_RECORD))
+
+	modifyRecord(t, data)
+
+	_, err = wr.Write(ctx, TEST_PATH, strings.NewReader(TEST_RECORD))
+
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected write conflict, got %v", err)
+	}
+
+	// Paths which are not local files are not tracked (or checked).
+
+	versions.TrackFile(filepath.Join(data, "999/999/999999.geojson"), []byte(TEST_RECORD))
+
+	_, exists := versions.Get(filepath.Join(data, "999/999/999999.geojson"))
+
+	if exists {
+		t.Fatalf("Expected missing file not to be tracked")
+	}
+}
+
+func TestRetry(t *testing.T) {
+
+	ctx := context.Background()
+	data := setupRepo(t)
+
+	r, wr := setupGuard(t, ctx, data)
+
+	attempts := 0
+
+	fn := func() error {
+
+		attempts += 1
+
+		// Another process changes the record between the first read and write, but not the second.
+
+		var between func()
+
+		if attempts == 1 {
+			between = func() { modifyRecord(t, data) }
+		}
+
+		return readAndWrite(ctx, r, wr, "Montréal", between)
+	}
+
+	err := Retry(ctx, 0, fn)
+
+	if !errors.Is(err, ErrConflict) || attempts != 1 {
+		t.Fatalf("Expected a single attempt to fail with a conflict, got %v after %d attempts", err, attempts)
+	}
+
+	data = setupRepo(t)
+	r, wr = setupGuard(t, ctx, data)
+	attempts = 0
+
+	err = Retry(ctx, 2, fn)
+
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, %v", err)
+	}
+
+	if attempts != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", attempts)
+	}
+
+	body, err := os.ReadFile(filepath.Join(data, TEST_PATH))
+
+	if err != nil {
+		t.Fatalf("Failed to read record, %v", err)
+	}
+
+	// The change is re-applied to the other process's version of the record.
+
+	if !strings.Contains(string(body), "1700000001") || readName(t, data) != "Montréal" {
+		t.Fatalf("Expected change to be re-applied to the latest version, got %s", body)
+	}
+}
+
+func TestRetryOtherErrors(t *testing.T) {
+
+	ctx := context.Background()
+	attempts := 0
+
+	fn := func() error {
+		attempts += 1
+		return errors.New("Not a conflict")
+	}
+
+	err := Retry(ctx, 3, fn)
+
+	if err == nil || attempts != 1 {
+		t.Fatalf("Expected other errors not to be retried, got %v after %d attempts", err, attempts)
+	}
+}
@@ -0,0 +1,89 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LOCK_FILENAME is the name of the advisory lock file created in the root of a repository while a record is being written to it.
+const LOCK_FILENAME string = ".exportify.lock"
+
+// LOCK_TIMEOUT is the maximum amount of time a `GuardedWriter` will wait to acquire a repository's lock file.
+const LOCK_TIMEOUT time.Duration = 30 * time.Second
+
+// LOCK_INTERVAL is the amount of time a `GuardedWriter` will wait between attempts to acquire a repository's lock file.
+const LOCK_INTERVAL time.Duration = 50 * time.Millisecond
+
+// AcquireLock creates the lock file 'path', waiting up to 'timeout' (checking every 'interval') for any existing
+// lock file to be removed, and returns a function to remove it.
+func AcquireLock(ctx context.Context, path string, timeout time.Duration, interval time.Duration) (func(), error) {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+
+		fh, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+		if err == nil {
+
+			fmt.Fprintf(fh, "%d\n", os.Getpid())
+			fh.Close()
+
+			unlock := func() {
+				os.Remove(path)
+			}
+
+			return unlock, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("Failed to create lock file %s, %w", path, err)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timed out waiting for lock file %s, remove it if it is stale", path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+			// pass
+		}
+	}
+}
+
+// LockRepo acquires the lock file for the repository whose root directory is 'root', waiting up to `LOCK_TIMEOUT` for
+// any existing lock file to be removed, and returns a function to remove it. This is the same lock file that a
+// `GuardedWriter` acquires while writing records to the repository.
+func LockRepo(ctx context.Context, root string) (func(), error) {
+	return AcquireLock(ctx, filepath.Join(root, LOCK_FILENAME), LOCK_TIMEOUT, LOCK_INTERVAL)
+}
+
+// LockPath returns the path of the lock file for the repository containing the record at 'abs_path'. This is
+// `LOCK_FILENAME` in the parent of the record's (last) "data" directory or, if there isn't one, in the same
+// directory as the record.
+func LockPath(abs_path string) string {
+
+	sep := string(filepath.Separator)
+	data := sep + "data" + sep
+
+	idx := strings.LastIndex(abs_path, data)
+
+	if idx == -1 {
+		return filepath.Join(filepath.Dir(abs_path), LOCK_FILENAME)
+	}
+
+	root := abs_path[:idx]
+
+	if root == "" {
+		root = sep
+	}
+
+	return filepath.Join(root, LOCK_FILENAME)
+}
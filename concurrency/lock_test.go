@@ -0,0 +1,134 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), LOCK_FILENAME)
+
+	unlock, err := AcquireLock(ctx, path, time.Second, 10*time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Failed to acquire lock, %v", err)
+	}
+
+	_, err = AcquireLock(ctx, path, 50*time.Millisecond, 10*time.Millisecond)
+
+	if err == nil {
+		t.Fatalf("Expected second lock to time out")
+	}
+
+	// Release the lock while another caller is waiting for it.
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock()
+	}()
+
+	unlock, err = AcquireLock(ctx, path, time.Second, 10*time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Expected lock to be acquired once released, %v", err)
+	}
+
+	unlock()
+
+	_, err = os.Stat(path)
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected lock file to be removed, %v", err)
+	}
+}
+
+func TestAcquireLockCancel(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), LOCK_FILENAME)
+
+	unlock, err := AcquireLock(context.Background(), path, time.Second, 10*time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Failed to acquire lock, %v", err)
+	}
+
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = AcquireLock(ctx, path, time.Second, 10*time.Millisecond)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancelled context error, got %v", err)
+	}
+}
+
+func TestLockPath(t *testing.T) {
+
+	sep := string(filepath.Separator)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     filepath.Join(sep, "usr", "local", "data", "whosonfirst-data-admin-ca", "data", "101", "736", "545", "101736545.geojson"),
+			expected: filepath.Join(sep, "usr", "local", "data", "whosonfirst-data-admin-ca", LOCK_FILENAME),
+		},
+		{
+			// The last "data" directory is the repository's.
+			path:     filepath.Join(sep, "data", "whosonfirst-data-admin-ca", "data", "101", "736", "545", "101736545.geojson"),
+			expected: filepath.Join(sep, "data", "whosonfirst-data-admin-ca", LOCK_FILENAME),
+		},
+		{
+			path:     filepath.Join(sep, "data", "101", "736", "545", "101736545.geojson"),
+			expected: filepath.Join(sep, LOCK_FILENAME),
+		},
+		{
+			path:     filepath.Join(sep, "tmp", "records", "101736545.geojson"),
+			expected: filepath.Join(sep, "tmp", "records", LOCK_FILENAME),
+		},
+	}
+
+	for _, test := range tests {
+
+		actual := LockPath(test.path)
+
+		if actual != test.expected {
+			t.Errorf("Expected lock path for %s to be %s, got %s", test.path, test.expected, actual)
+		}
+	}
+}
+
+func TestLockRepo(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	unlock, err := LockRepo(ctx, root)
+
+	if err != nil {
+		t.Fatalf("Failed to lock repository, %v", err)
+	}
+
+	_, err = os.Stat(filepath.Join(root, LOCK_FILENAME))
+
+	if err != nil {
+		t.Fatalf("Expected lock file to exist, %v", err)
+	}
+
+	// A guarded writer uses the same lock file.
+
+	if LockPath(filepath.Join(root, "data", "101", "736", "545", "101736545.geojson")) != filepath.Join(root, LOCK_FILENAME) {
+		t.Fatalf("Expected writers to use the repository lock file")
+	}
+
+	unlock()
+}
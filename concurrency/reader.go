@@ -0,0 +1,63 @@
+package concurrency
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/whosonfirst/go-ioutil"
+	"github.com/whosonfirst/go-reader"
+)
+
+// GuardedReader implements the `whosonfirst/go-reader.Reader` interface, wrapping another reader, and records
+// the version of each record it reads from a local file.
+type GuardedReader struct {
+	reader.Reader
+	reader   reader.Reader
+	versions *Versions
+}
+
+// NewGuardedReader returns a new `GuardedReader` instance which reads records using 'r' and records their
+// versions in 'versions'. Records whose `ReaderURI` is not an absolute path are read but not tracked.
+func NewGuardedReader(r reader.Reader, versions *Versions) reader.Reader {
+
+	guarded_r := &GuardedReader{
+		reader:   r,
+		versions: versions,
+	}
+
+	return guarded_r
+}
+
+// Read returns the record at 'path' using the underlying reader and records its version.
+func (r *GuardedReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+
+	fh, err := r.reader.Read(ctx, path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fh.Close()
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	abs_path := r.reader.ReaderURI(ctx, path)
+
+	if filepath.IsAbs(abs_path) {
+		r.versions.Track(abs_path, body)
+	}
+
+	return ioutil.NewReadSeekCloser(bytes.NewReader(body))
+}
+
+// ReaderURI returns the value of the underlying reader's `ReaderURI` method.
+func (r *GuardedReader) ReaderURI(ctx context.Context, path string) string {
+	return r.reader.ReaderURI(ctx, path)
+}
@@ -0,0 +1,102 @@
+package concurrency
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// GuardedWriter implements the `whosonfirst/go-writer.Writer` interface, wrapping another writer, and refuses to
+// overwrite records that have changed since they were read.
+type GuardedWriter struct {
+	writer.Writer
+	writer   writer.Writer
+	versions *Versions
+}
+
+// NewGuardedWriter returns a new `GuardedWriter` instance which writes records using 'wr' and compares the version of
+// each record on disk with its version in 'versions' before writing it. Records whose `WriterURI` is not an absolute
+// path, or which have not been tracked in 'versions', are written without being checked.
+func NewGuardedWriter(wr writer.Writer, versions *Versions) writer.Writer {
+
+	guarded_wr := &GuardedWriter{
+		writer:   wr,
+		versions: versions,
+	}
+
+	return guarded_wr
+}
+
+// Write writes the record contained in 'fh' to 'key' using the underlying writer. If the record was read using a
+// `GuardedReader` and has since been changed (or removed) on disk an error wrapping `ErrConflict` is returned. The
+// check and the write are performed while holding the lock file for the record's repository (see `LockPath`).
+func (wr *GuardedWriter) Write(ctx context.Context, key string, fh io.ReadSeeker) (int64, error) {
+
+	abs_path := wr.writer.WriterURI(ctx, key)
+
+	if !filepath.IsAbs(abs_path) {
+		return wr.writer.Write(ctx, key, fh)
+	}
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read filehandle, %w", err)
+	}
+
+	lock_path := LockPath(abs_path)
+
+	_, err = os.Stat(filepath.Dir(lock_path))
+
+	if err == nil {
+
+		unlock, err := AcquireLock(ctx, lock_path, LOCK_TIMEOUT, LOCK_INTERVAL)
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to acquire lock for %s, %w", key, err)
+		}
+
+		defer unlock()
+	}
+
+	err = wr.versions.Check(abs_path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := wr.writer.Write(ctx, key, bytes.NewReader(body))
+
+	if err != nil {
+		return n, err
+	}
+
+	wr.versions.Track(abs_path, body)
+	return n, nil
+}
+
+// WriterURI returns the value of the underlying writer's `WriterURI` method.
+func (wr *GuardedWriter) WriterURI(ctx context.Context, key string) string {
+	return wr.writer.WriterURI(ctx, key)
+}
+
+// Flush flushes the underlying writer.
+func (wr *GuardedWriter) Flush(ctx context.Context) error {
+	return wr.writer.Flush(ctx)
+}
+
+// Close closes the underlying writer.
+func (wr *GuardedWriter) Close(ctx context.Context) error {
+	return wr.writer.Close(ctx)
+}
+
+// SetLogger assigns 'logger' to the underlying writer.
+func (wr *GuardedWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return wr.writer.SetLogger(ctx, logger)
+}
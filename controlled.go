@@ -0,0 +1,116 @@
+package exportify
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CONTROLLED_GEOMETRY is the value in a record's "wof:controlled" property indicating that its geometry
+// should not be changed by automated tools.
+const CONTROLLED_GEOMETRY string = "wof:geometry"
+
+// ControlledProperties returns the list of property names in the "wof:controlled" property of 'body'.
+func ControlledProperties(body []byte) []string {
+
+	controlled := make([]string, 0)
+
+	for _, r := range gjson.GetBytes(body, "properties.wof:controlled").Array() {
+
+		name := r.String()
+
+		if name != "" {
+			controlled = append(controlled, name)
+		}
+	}
+
+	return controlled
+}
+
+// ControlledOptions defines options for the `RevertControlledChanges` and `ExportChanges` methods.
+type ControlledOptions struct {
+	// Force allows properties (and the geometry) listed in a record's "wof:controlled" property to be changed.
+	Force bool
+	// Logger is an optional logger used to report changes to controlled properties that have been reverted.
+	Logger *log.Logger
+}
+
+// ControlledPaths returns the list of tidwall/gjson paths for the properties (and the geometry) listed in the
+// "wof:controlled" property of 'body'.
+func ControlledPaths(body []byte) []string {
+
+	paths := make([]string, 0)
+
+	for _, name := range ControlledProperties(body) {
+
+		if name == CONTROLLED_GEOMETRY {
+			paths = append(paths, "geometry")
+			continue
+		}
+
+		paths = append(paths, "properties."+gjson.Escape(name))
+	}
+
+	return paths
+}
+
+// RevertControlledChanges compares 'new_body' with 'old_body', the record as it was read, and restores the values of
+// any of the properties (or the geometry) listed in the "wof:controlled" property of 'old_body' which have been changed.
+// It returns the updated record along with the list of paths that were restored. Each restored path is reported to
+// opts.Logger. If opts.Force is true 'new_body' is returned unchanged.
+func RevertControlledChanges(old_body []byte, new_body []byte, opts *ControlledOptions) ([]byte, []string, error) {
+
+	reverted := make([]string, 0)
+
+	if opts.Force {
+		return new_body, reverted, nil
+	}
+
+	for _, path := range ControlledPaths(old_body) {
+
+		old_rsp := gjson.GetBytes(old_body, path)
+		new_rsp := gjson.GetBytes(new_body, path)
+
+		if equalResults(old_rsp, new_rsp) {
+			continue
+		}
+
+		var err error
+
+		if old_rsp.Exists() {
+			new_body, err = sjson.SetRawBytes(new_body, path, []byte(old_rsp.Raw))
+		} else {
+			new_body, err = sjson.DeleteBytes(new_body, path)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to restore controlled property '%s', %w", path, err)
+		}
+
+		if opts.Logger != nil {
+			id := gjson.GetBytes(old_body, "properties.wof:id").Int()
+			opts.Logger.Printf("Skipping change to controlled property '%s' for %d, use -force to override\n", path, id)
+		}
+
+		reverted = append(reverted, path)
+	}
+
+	return new_body, reverted, nil
+}
+
+// equalResults returns a boolean value indicating whether 'a' and 'b' have the same (decoded) value.
+func equalResults(a gjson.Result, b gjson.Result) bool {
+
+	if a.Exists() != b.Exists() {
+		return false
+	}
+
+	if a.Raw == b.Raw {
+		return true
+	}
+
+	return reflect.DeepEqual(a.Value(), b.Value())
+}
@@ -0,0 +1,145 @@
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/sfomuseum/go-csvdict"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+)
+
+// CSV_FIELD_PATH is a special field name used to include the path of the record being encoded.
+const CSV_FIELD_PATH string = "path"
+
+// CSV_FIELD_CENTROID is a special field name used to include the primary centroid of the record being
+// encoded as 'latitude' and 'longitude' columns.
+const CSV_FIELD_CENTROID string = "centroid"
+
+// CSVEncoder implements the `Encoder` interface for encoding records as CSV rows.
+type CSVEncoder struct {
+	Encoder
+	fields []string
+	csv_wr *csvdict.Writer
+	mu     *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "csv", NewCSVEncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewCSVEncoder returns a new `CSVEncoder` instance configured by 'uri' in the form of:
+//
+//	csv://?field={FIELD}&field={FIELD}
+//
+// Where each {FIELD} is a relative 'properties.FIELDNAME' path to include in the CSV output. If {FIELD} is
+// "path" the path of the record will be included. If {FIELD} is "centroid" the primary centroid of the record
+// will be derived and included as 'latitude' and 'longitude' columns.
+func NewCSVEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	fields := q["field"]
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Missing ?field= parameter")
+	}
+
+	fieldnames := make([]string, 0)
+
+	for _, f := range fields {
+
+		switch f {
+		case CSV_FIELD_CENTROID:
+			fieldnames = append(fieldnames, "latitude", "longitude")
+		default:
+			fieldnames = append(fieldnames, f)
+		}
+	}
+
+	csv_wr, err := csvdict.NewWriter(wr, fieldnames)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create CSV writer, %w", err)
+	}
+
+	err = csv_wr.WriteHeader()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write CSV header, %w", err)
+	}
+
+	enc := &CSVEncoder{
+		fields: fields,
+		csv_wr: csv_wr,
+		mu:     new(sync.Mutex),
+	}
+
+	return enc, nil
+}
+
+// Encode writes the fields defined when 'enc' was created as a single CSV row.
+func (enc *CSVEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	out := make(map[string]string)
+
+	for _, k := range enc.fields {
+
+		switch k {
+		case CSV_FIELD_PATH:
+			out[k] = path
+		case CSV_FIELD_CENTROID:
+
+			c, _, err := properties.Centroid(body)
+
+			if err != nil {
+				return fmt.Errorf("Failed to derive centroid for %s, %w", path, err)
+			}
+
+			out["latitude"] = strconv.FormatFloat(c.Lat(), 'f', -1, 64)
+			out["longitude"] = strconv.FormatFloat(c.Lon(), 'f', -1, 64)
+
+		default:
+
+			fq_k := fmt.Sprintf("properties.%s", k)
+			rsp := gjson.GetBytes(body, fq_k)
+			out[k] = rsp.String()
+		}
+	}
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	err := enc.csv_wr.WriteRow(out)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write row for %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close flushes the underlying CSV writer.
+func (enc *CSVEncoder) Close(ctx context.Context) error {
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	return enc.csv_wr.Flush()
+}
@@ -0,0 +1,242 @@
+package emit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-whosonfirst-feature/alt"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+)
+
+// ELASTICSEARCH_DEFAULT_INDEX is the default name of the index that records are added to by the `ElasticsearchEncoder`.
+const ELASTICSEARCH_DEFAULT_INDEX string = "whosonfirst"
+
+// ELASTICSEARCH_DEFAULT_ID_FIELD is the default property used to derive the document ID for records.
+const ELASTICSEARCH_DEFAULT_ID_FIELD string = "wof:id"
+
+// ELASTICSEARCH_DEFAULT_GEOMETRY_FIELD is the default name of the document field containing a record's geometry.
+const ELASTICSEARCH_DEFAULT_GEOMETRY_FIELD string = "geometry"
+
+// ELASTICSEARCH_GEO_SHAPE signals that a record's geometry should be encoded as a GeoJSON geometry, suitable for
+// a field with a "geo_shape" mapping.
+const ELASTICSEARCH_GEO_SHAPE string = "geo_shape"
+
+// ELASTICSEARCH_GEO_POINT signals that a record's centroid should be encoded as a {"lat":, "lon":} object, suitable
+// for a field with a "geo_point" mapping.
+const ELASTICSEARCH_GEO_POINT string = "geo_point"
+
+// ELASTICSEARCH_GEO_NONE signals that no geometry should be included in documents.
+const ELASTICSEARCH_GEO_NONE string = "none"
+
+// ElasticsearchEncoder implements the `Encoder` interface for encoding records as an Elasticsearch (or OpenSearch)
+// bulk API request body. Each record is written as an "index" action line followed by a document line containing
+// the record's properties and, optionally, its geometry.
+type ElasticsearchEncoder struct {
+	Encoder
+	writer         io.Writer
+	index          string
+	id_field       string
+	geometry       string
+	geometry_field string
+	mu             *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	for _, scheme := range []string{"elasticsearch", "opensearch"} {
+
+		err := RegisterEncoder(ctx, scheme, NewElasticsearchEncoder)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewElasticsearchEncoder returns a new `ElasticsearchEncoder` instance configured by 'uri' in the form of:
+//
+//	elasticsearch://?index={INDEX}&id={PROPERTY}&geometry={MAPPING}&geometry-field={FIELD}
+//
+// Where:
+// * {INDEX} is the name of the index to add documents to. Default is "whosonfirst".
+// * {PROPERTY} is the (gjson) path of the property used to derive document IDs. Default is "wof:id". Alternate geometry
+// files have their alternate geometry label appended to the ID, for example "101736545-alt-quattroshapes".
+// * {MAPPING} is one of "geo_shape" (the record's GeoJSON geometry), "geo_point" (the record's centroid) or "none".
+// Default is "geo_shape".
+// * {FIELD} is the (sjson) path of the document field to store the geometry in. Default is "geometry".
+func NewElasticsearchEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	index := ELASTICSEARCH_DEFAULT_INDEX
+	id_field := ELASTICSEARCH_DEFAULT_ID_FIELD
+	geometry := ELASTICSEARCH_GEO_SHAPE
+	geometry_field := ELASTICSEARCH_DEFAULT_GEOMETRY_FIELD
+
+	if q.Has("index") {
+		index = q.Get("index")
+	}
+
+	if index == "" {
+		return nil, fmt.Errorf("Invalid ?index= parameter")
+	}
+
+	if q.Has("id") {
+		id_field = q.Get("id")
+	}
+
+	if id_field == "" {
+		return nil, fmt.Errorf("Invalid ?id= parameter")
+	}
+
+	if q.Has("geometry") {
+		geometry = q.Get("geometry")
+	}
+
+	switch geometry {
+	case ELASTICSEARCH_GEO_SHAPE, ELASTICSEARCH_GEO_POINT, ELASTICSEARCH_GEO_NONE:
+		// pass
+	default:
+		return nil, fmt.Errorf("Invalid ?geometry= parameter '%s', must be one of: %s, %s, %s", geometry, ELASTICSEARCH_GEO_SHAPE, ELASTICSEARCH_GEO_POINT, ELASTICSEARCH_GEO_NONE)
+	}
+
+	if q.Has("geometry-field") {
+		geometry_field = q.Get("geometry-field")
+	}
+
+	if geometry_field == "" {
+		return nil, fmt.Errorf("Invalid ?geometry-field= parameter")
+	}
+
+	enc := &ElasticsearchEncoder{
+		writer:         wr,
+		index:          index,
+		id_field:       id_field,
+		geometry:       geometry,
+		geometry_field: geometry_field,
+		mu:             new(sync.Mutex),
+	}
+
+	return enc, nil
+}
+
+// Encode writes an "index" action line, and a document line, for 'body'.
+func (enc *ElasticsearchEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	props_rsp := gjson.GetBytes(body, "properties")
+
+	if !props_rsp.IsObject() {
+		return fmt.Errorf("%s is missing properties", path)
+	}
+
+	id_rsp := props_rsp.Get(enc.id_field)
+
+	if !id_rsp.Exists() || id_rsp.String() == "" {
+		return fmt.Errorf("%s is missing '%s' property", path, enc.id_field)
+	}
+
+	doc_id := id_rsp.String()
+
+	if alt.IsAlt(body) {
+
+		alt_label, err := properties.AltLabel(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive alt label for %s, %w", path, err)
+		}
+
+		doc_id = fmt.Sprintf("%s-alt-%s", doc_id, alt_label)
+	}
+
+	doc := []byte(props_rsp.Raw)
+
+	switch enc.geometry {
+	case ELASTICSEARCH_GEO_SHAPE:
+
+		geom_rsp := gjson.GetBytes(body, "geometry")
+
+		if geom_rsp.IsObject() {
+
+			v, err := sjson.SetRawBytes(doc, enc.geometry_field, []byte(geom_rsp.Raw))
+
+			if err != nil {
+				return fmt.Errorf("Failed to assign geometry for %s, %w", path, err)
+			}
+
+			doc = v
+		}
+
+	case ELASTICSEARCH_GEO_POINT:
+
+		pt, _, err := properties.Centroid(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive centroid for %s, %w", path, err)
+		}
+
+		geo_point := map[string]float64{
+			"lat": pt.Lat(),
+			"lon": pt.Lon(),
+		}
+
+		v, err := sjson.SetBytes(doc, enc.geometry_field, geo_point)
+
+		if err != nil {
+			return fmt.Errorf("Failed to assign centroid for %s, %w", path, err)
+		}
+
+		doc = v
+	}
+
+	action := map[string]any{
+		"index": map[string]string{
+			"_index": enc.index,
+			"_id":    doc_id,
+		},
+	}
+
+	enc_action, err := json.Marshal(action)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal action for %s, %w", path, err)
+	}
+
+	var buf bytes.Buffer
+
+	buf.Write(enc_action)
+	buf.WriteString("\n")
+
+	err = json.Compact(&buf, doc)
+
+	if err != nil {
+		return fmt.Errorf("Failed to compact document for %s, %w", path, err)
+	}
+
+	buf.WriteString("\n")
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	_, err = enc.writer.Write(buf.Bytes())
+	return err
+}
+
+// Close is a no-op to conform to the `Encoder` interface and returns nil.
+func (enc *ElasticsearchEncoder) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package emit provides a common interface, and a registry of implementations, for encoding
+// Who's On First records in a variety of output formats.
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+	"github.com/whosonfirst/go-whosonfirst-exportify/transform"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/emitter"
+)
+
+// Encoder is an interface for encoding Who's On First records in a specific output format.
+type Encoder interface {
+	// Encode encodes a GeoJSON Feature read from a given path. Implementations must be safe to call from multiple goroutines.
+	Encode(context.Context, string, []byte) error
+	// Close writes any outstanding data and finalizes the output.
+	Close(context.Context) error
+}
+
+// EncoderInitializationFunc is a function defined by individual encoder package and used to create
+// an instance of that encoder
+type EncoderInitializationFunc func(ctx context.Context, uri string, wr io.Writer) (Encoder, error)
+
+var encoder_roster roster.Roster
+
+// RegisterEncoder registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `Encoder` instances by the `NewEncoder` method.
+func RegisterEncoder(ctx context.Context, scheme string, init_func EncoderInitializationFunc) error {
+
+	err := ensureEncoderRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return encoder_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureEncoderRoster() error {
+
+	if encoder_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		encoder_roster = r
+	}
+
+	return nil
+}
+
+// NewEncoder returns a new `Encoder` instance, that writes to 'wr', configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `EncoderInitializationFunc`
+// function used to instantiate the new `Encoder`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterEncoder` method.
+func NewEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := encoder_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(EncoderInitializationFunc)
+	return init_func(ctx, uri, wr)
+}
+
+// Schemes returns the list of schemes that have been registered.
+func Schemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureEncoderRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range encoder_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
+
+// EmitOptions defines configuration options for the `IteratorCallback` method.
+type EmitOptions struct {
+	// Encoder is the `Encoder` instance used to encode each record.
+	Encoder Encoder
+	// Transformations is an optional list of `transform.Transformation` instances to apply to each record before it is encoded.
+	Transformations []transform.Transformation
+}
+
+// IteratorCallback returns a `emitter.EmitterCallbackFunc` function that will transform and encode each
+// record it is passed using the options defined in 'opts'.
+func IteratorCallback(opts *EmitOptions) emitter.EmitterCallbackFunc {
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		body, ok, err := transform.TransformFeature(ctx, body, opts.Transformations...)
+
+		if err != nil {
+			return fmt.Errorf("Failed to transform %s, %w", path, err)
+		}
+
+		if !ok {
+			return nil
+		}
+
+		err = opts.Encoder.Encode(ctx, path, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to encode %s, %w", path, err)
+		}
+
+		return nil
+	}
+
+	return cb
+}
@@ -0,0 +1,81 @@
+package emit
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// FeatureCollectionEncoder implements the `Encoder` interface for encoding records as a GeoJSON FeatureCollection.
+type FeatureCollectionEncoder struct {
+	Encoder
+	writer io.Writer
+	mu     *sync.Mutex
+	count  int64
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "featurecollection", NewFeatureCollectionEncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewFeatureCollectionEncoder returns a new `FeatureCollectionEncoder` instance configured by 'uri' in the form of:
+//
+//	featurecollection://
+func NewFeatureCollectionEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	enc := &FeatureCollectionEncoder{
+		writer: wr,
+		mu:     new(sync.Mutex),
+	}
+
+	return enc, nil
+}
+
+// Encode appends 'body' to the list of features in the FeatureCollection.
+func (enc *FeatureCollectionEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	var err error
+
+	if enc.count == 0 {
+		_, err = enc.writer.Write([]byte(`{"type":"FeatureCollection", "features":[`))
+	} else {
+		_, err = enc.writer.Write([]byte(`,`))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = enc.writer.Write(body)
+
+	if err != nil {
+		return err
+	}
+
+	enc.count += 1
+	return nil
+}
+
+// Close closes the FeatureCollection.
+func (enc *FeatureCollectionEncoder) Close(ctx context.Context) error {
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	if enc.count == 0 {
+		_, err := enc.writer.Write([]byte(`{"type":"FeatureCollection", "features":[]}`))
+		return err
+	}
+
+	_, err := enc.writer.Write([]byte(`]}`))
+	return err
+}
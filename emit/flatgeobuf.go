@@ -0,0 +1,79 @@
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/flatgeobuf"
+)
+
+// FlatGeobufEncoder implements the `Encoder` interface for encoding records as a FlatGeobuf file.
+type FlatGeobufEncoder struct {
+	Encoder
+	writer  io.Writer
+	builder *flatgeobuf.Builder
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "flatgeobuf", NewFlatGeobufEncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewFlatGeobufEncoder returns a new `FlatGeobufEncoder` instance configured by 'uri' in the form of:
+//
+//	flatgeobuf://?property={PROPERTY}&name={NAME}&index-node-size={SIZE}
+//
+// Where each {PROPERTY} is a relative 'properties.FIELDNAME' path to store as a typed column, {NAME} is the
+// name of the layer (default "whosonfirst") and {SIZE} is the number of items per node in the spatial index,
+// or 0 to disable the index (default 16).
+func NewFlatGeobufEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	opts, err := flatgeobuf.BuilderOptionsFromQuery(u.Query())
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := flatgeobuf.NewBuilder(ctx, opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create FlatGeobuf builder, %w", err)
+	}
+
+	enc := &FlatGeobufEncoder{
+		writer:  wr,
+		builder: b,
+	}
+
+	return enc, nil
+}
+
+// Encode adds 'body' to the list of features to be written. Nothing is written until the `Close` method is invoked.
+func (enc *FlatGeobufEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	err := enc.builder.AddFeature(ctx, body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close writes the FlatGeobuf file.
+func (enc *FlatGeobufEncoder) Close(ctx context.Context) error {
+	return enc.builder.Write(ctx, enc.writer)
+}
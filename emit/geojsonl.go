@@ -0,0 +1,69 @@
+package emit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// GeoJSONLEncoder implements the `Encoder` interface for encoding records as line-separated GeoJSON.
+type GeoJSONLEncoder struct {
+	Encoder
+	writer io.Writer
+	mu     *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	for _, scheme := range []string{"geojsonl", "jsonl"} {
+
+		err := RegisterEncoder(ctx, scheme, NewGeoJSONLEncoder)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewGeoJSONLEncoder returns a new `GeoJSONLEncoder` instance configured by 'uri' in the form of:
+//
+//	geojsonl://
+func NewGeoJSONLEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	enc := &GeoJSONLEncoder{
+		writer: wr,
+		mu:     new(sync.Mutex),
+	}
+
+	return enc, nil
+}
+
+// Encode writes 'body' as a single line of JSON.
+func (enc *GeoJSONLEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	var buf bytes.Buffer
+
+	err := json.Compact(&buf, body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to compact body, %w", err)
+	}
+
+	buf.WriteString("\n")
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	_, err = enc.writer.Write(buf.Bytes())
+	return err
+}
+
+// Close is a no-op to conform to the `Encoder` interface and returns nil.
+func (enc *GeoJSONLEncoder) Close(ctx context.Context) error {
+	return nil
+}
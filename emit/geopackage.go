@@ -0,0 +1,83 @@
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/geopackage"
+)
+
+// GeoPackageEncoder implements the `Encoder` interface for encoding records as a GeoPackage file.
+type GeoPackageEncoder struct {
+	Encoder
+	writer  io.Writer
+	builder *geopackage.Builder
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	for _, scheme := range []string{"geopackage", "gpkg"} {
+
+		err := RegisterEncoder(ctx, scheme, NewGeoPackageEncoder)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewGeoPackageEncoder returns a new `GeoPackageEncoder` instance configured by 'uri' in the form of:
+//
+//	geopackage://?property={PROPERTY}&name={NAME}
+//
+// Where each {PROPERTY} is a relative 'properties.FIELDNAME' path to store as a typed column (default "wof:id" and
+// "wof:name") and {NAME} is the name of the features table (default "whosonfirst"). The "gpkg://" scheme is an
+// alias for "geopackage://".
+func NewGeoPackageEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	opts, err := geopackage.BuilderOptionsFromQuery(u.Query())
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := geopackage.NewBuilder(ctx, opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create GeoPackage builder, %w", err)
+	}
+
+	enc := &GeoPackageEncoder{
+		writer:  wr,
+		builder: b,
+	}
+
+	return enc, nil
+}
+
+// Encode adds 'body' to the list of features to be written. Nothing is written until the `Close` method is invoked.
+func (enc *GeoPackageEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	err := enc.builder.AddFeature(ctx, body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close writes the GeoPackage file.
+func (enc *GeoPackageEncoder) Close(ctx context.Context) error {
+	return enc.builder.Write(ctx, enc.writer)
+}
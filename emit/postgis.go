@@ -0,0 +1,222 @@
+package emit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/paulmach/orb/encoding/ewkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-whosonfirst-feature/alt"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+)
+
+// POSTGIS_DEFAULT_TABLE is the default name of the table created by the `PostGISEncoder`.
+const POSTGIS_DEFAULT_TABLE string = "whosonfirst"
+
+// POSTGIS_SRID is the spatial reference identifier assigned to all the geometries written by the `PostGISEncoder`.
+const POSTGIS_SRID int = 4326
+
+var re_postgis_table = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PostGISEncoder implements the `Encoder` interface for encoding records as a SQL file that creates, and populates
+// using a COPY statement, a PostGIS table. Each row contains the record's ID, alternate geometry label, properties
+// (as JSONB) and geometry (as EWKB).
+type PostGISEncoder struct {
+	Encoder
+	writer io.Writer
+	table  string
+	mu     *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "postgis", NewPostGISEncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewPostGISEncoder returns a new `PostGISEncoder` instance configured by 'uri' in the form of:
+//
+//	postgis://?table={TABLE}&drop-table={BOOLEAN}
+//
+// Where {TABLE} is the (optionally schema-qualified) name of the table to create (default "whosonfirst") and
+// {BOOLEAN} is whether to drop any existing table with the same name first (default false).
+func NewPostGISEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	table := POSTGIS_DEFAULT_TABLE
+
+	if q.Has("table") {
+		table = q.Get("table")
+	}
+
+	if !re_postgis_table.MatchString(table) {
+		return nil, fmt.Errorf("Invalid ?table= parameter '%s'", table)
+	}
+
+	drop_table := false
+
+	if q.Has("drop-table") {
+
+		v, err := strconv.ParseBool(q.Get("drop-table"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?drop-table= parameter, %w", err)
+		}
+
+		drop_table = v
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("BEGIN;\n\n")
+
+	if drop_table {
+		buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS %s;\n\n", table))
+	}
+
+	buf.WriteString(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id BIGINT NOT NULL,
+	alt_label TEXT NOT NULL DEFAULT '',
+	properties JSONB,
+	geom geometry(Geometry, %d),
+	PRIMARY KEY (id, alt_label)
+);
+`, table, POSTGIS_SRID))
+
+	buf.WriteString(fmt.Sprintf("\nCOPY %s (id, alt_label, properties, geom) FROM stdin;\n", table))
+
+	_, err = wr.Write(buf.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write table definition, %w", err)
+	}
+
+	enc := &PostGISEncoder{
+		writer: wr,
+		table:  table,
+		mu:     new(sync.Mutex),
+	}
+
+	return enc, nil
+}
+
+// Encode writes 'body' as a single row of the COPY statement.
+func (enc *PostGISEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	id, err := properties.Id(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive ID for %s, %w", path, err)
+	}
+
+	alt_label := ""
+
+	if alt.IsAlt(body) {
+
+		alt_label, err = properties.AltLabel(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive alt label for %s, %w", path, err)
+		}
+	}
+
+	str_props := `\N`
+
+	props_rsp := gjson.GetBytes(body, "properties")
+
+	if props_rsp.Exists() {
+
+		var buf bytes.Buffer
+
+		err := json.Compact(&buf, []byte(props_rsp.Raw))
+
+		if err != nil {
+			return fmt.Errorf("Failed to compact properties for %s, %w", path, err)
+		}
+
+		str_props = copyEscape(buf.String())
+	}
+
+	str_geom := `\N`
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal %s, %w", path, err)
+	}
+
+	if f.Geometry != nil {
+
+		enc_geom, err := ewkb.MarshalToHex(f.Geometry, POSTGIS_SRID)
+
+		if err != nil {
+			return fmt.Errorf("Failed to encode geometry for %s, %w", path, err)
+		}
+
+		str_geom = enc_geom
+	}
+
+	row := strings.Join([]string{
+		strconv.FormatInt(id, 10),
+		copyEscape(alt_label),
+		str_props,
+		str_geom,
+	}, "\t")
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	_, err = enc.writer.Write([]byte(row + "\n"))
+	return err
+}
+
+// Close ends the COPY statement and writes the statements to create a spatial index and commit the transaction.
+func (enc *PostGISEncoder) Close(ctx context.Context) error {
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	index_name := strings.ReplaceAll(enc.table, ".", "_") + "_geom_idx"
+
+	var buf bytes.Buffer
+
+	buf.WriteString("\\.\n\n")
+	buf.WriteString(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIST (geom);\n\n", index_name, enc.table))
+	buf.WriteString("COMMIT;\n")
+
+	_, err := enc.writer.Write(buf.Bytes())
+	return err
+}
+
+// copyEscape escapes 'str' for use as a column value in the text format of a PostgreSQL COPY statement.
+func copyEscape(str string) string {
+
+	r := strings.NewReplacer(
+		"\\", "\\\\",
+		"\t", "\\t",
+		"\n", "\\n",
+		"\r", "\\r",
+	)
+
+	return r.Replace(str)
+}
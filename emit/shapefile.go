@@ -0,0 +1,83 @@
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/shapefile"
+)
+
+// ShapefileEncoder implements the `Encoder` interface for encoding records as a zipped ESRI Shapefile.
+type ShapefileEncoder struct {
+	Encoder
+	writer  io.Writer
+	builder *shapefile.Builder
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	for _, scheme := range []string{"shapefile", "shp"} {
+
+		err := RegisterEncoder(ctx, scheme, NewShapefileEncoder)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewShapefileEncoder returns a new `ShapefileEncoder` instance configured by 'uri' in the form of:
+//
+//	shapefile://?property={PROPERTY}&name={NAME}
+//
+// Where each {PROPERTY} is a relative 'properties.FIELDNAME' path to store as a DBF field and {NAME} is the
+// base name of the files in the Shapefile (default "whosonfirst"). The output is a ZIP archive containing the
+// Shapefile's component files. The "shp://" scheme is an alias for "shapefile://".
+func NewShapefileEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	opts, err := shapefile.BuilderOptionsFromQuery(u.Query())
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := shapefile.NewBuilder(ctx, opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Shapefile builder, %w", err)
+	}
+
+	enc := &ShapefileEncoder{
+		writer:  wr,
+		builder: b,
+	}
+
+	return enc, nil
+}
+
+// Encode adds 'body' to the list of features to be written. Nothing is written until the `Close` method is invoked.
+func (enc *ShapefileEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	err := enc.builder.AddFeature(ctx, body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to add %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close writes the zipped Shapefile.
+func (enc *ShapefileEncoder) Close(ctx context.Context) error {
+	return enc.builder.Write(ctx, enc.writer)
+}
@@ -0,0 +1,250 @@
+package emit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-csvdict"
+	"github.com/sfomuseum/go-edtf"
+	"github.com/whosonfirst/go-whosonfirst-feature/alt"
+	"github.com/whosonfirst/go-whosonfirst-flags"
+	"github.com/whosonfirst/go-whosonfirst-spr/v2"
+)
+
+// SPR_FORMAT_JSONL is the format for encoding standard places responses as line-separated JSON.
+const SPR_FORMAT_JSONL string = "jsonl"
+
+// SPR_FORMAT_CSV is the format for encoding standard places responses as CSV rows.
+const SPR_FORMAT_CSV string = "csv"
+
+// SPR_CSV_FIELDS are the columns, in order, written when standard places responses are encoded as CSV rows.
+// The column names match those used by the "spr" table in whosonfirst/go-whosonfirst-database.
+var SPR_CSV_FIELDS = []string{
+	"id",
+	"parent_id",
+	"name",
+	"placetype",
+	"country",
+	"repo",
+	"path",
+	"uri",
+	"inception",
+	"cessation",
+	"latitude",
+	"longitude",
+	"min_latitude",
+	"min_longitude",
+	"max_latitude",
+	"max_longitude",
+	"is_current",
+	"is_ceased",
+	"is_deprecated",
+	"is_superseded",
+	"is_superseding",
+	"superseded_by",
+	"supersedes",
+	"belongsto",
+	"lastmodified",
+}
+
+// SPREncoder implements the `Encoder` interface for encoding records as line-separated JSON-encoded
+// "standard places responses" (SPR) or as CSV rows.
+type SPREncoder struct {
+	Encoder
+	writer io.Writer
+	csv_wr *csvdict.Writer
+	mu     *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "spr", NewSPREncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSPREncoder returns a new `SPREncoder` instance configured by 'uri' in the form of:
+//
+//	spr://?format={FORMAT}
+//
+// Where {FORMAT} is "jsonl" (the default) or "csv". CSV output contains the columns defined in `SPR_CSV_FIELDS`.
+func NewSPREncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	enc := &SPREncoder{
+		writer: wr,
+		mu:     new(sync.Mutex),
+	}
+
+	format := u.Query().Get("format")
+
+	switch format {
+	case "", SPR_FORMAT_JSONL:
+		// pass
+	case SPR_FORMAT_CSV:
+
+		csv_wr, err := csvdict.NewWriter(wr, SPR_CSV_FIELDS)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create CSV writer, %w", err)
+		}
+
+		err = csv_wr.WriteHeader()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to write CSV header, %w", err)
+		}
+
+		enc.csv_wr = csv_wr
+
+	default:
+		return nil, fmt.Errorf("Invalid ?format= parameter '%s'", format)
+	}
+
+	return enc, nil
+}
+
+// Encode derives a standard places response from 'body' and writes it as a single line of JSON or a single CSV row.
+func (enc *SPREncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	s, err := deriveSPR(body)
+
+	if err != nil {
+		return err
+	}
+
+	if enc.csv_wr != nil {
+
+		enc.mu.Lock()
+		defer enc.mu.Unlock()
+
+		err := enc.csv_wr.WriteRow(sprRow(s))
+
+		if err != nil {
+			return fmt.Errorf("Failed to write row for %s, %w", path, err)
+		}
+
+		return nil
+	}
+
+	enc_s, err := json.Marshal(s)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal SPR, %w", err)
+	}
+
+	enc_s = append(enc_s, '\n')
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	_, err = enc.writer.Write(enc_s)
+	return err
+}
+
+// Close flushes the underlying CSV writer, if present, and returns nil otherwise.
+func (enc *SPREncoder) Close(ctx context.Context) error {
+
+	if enc.csv_wr == nil {
+		return nil
+	}
+
+	enc.mu.Lock()
+	defer enc.mu.Unlock()
+
+	return enc.csv_wr.Flush()
+}
+
+func deriveSPR(body []byte) (spr.StandardPlacesResult, error) {
+
+	var s spr.StandardPlacesResult
+	var err error
+
+	if alt.IsAlt(body) {
+		s, err = spr.WhosOnFirstAltSPR(body)
+	} else {
+		s, err = spr.WhosOnFirstSPR(body)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive SPR, %w", err)
+	}
+
+	return s, nil
+}
+
+// sprRow returns the values of 's' keyed by the column names in `SPR_CSV_FIELDS`.
+func sprRow(s spr.StandardPlacesResult) map[string]string {
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	formatFlag := func(f flags.ExistentialFlag) string {
+		return strconv.FormatInt(f.Flag(), 10)
+	}
+
+	formatDate := func(d *edtf.EDTFDate) string {
+
+		if d == nil {
+			return ""
+		}
+
+		return d.EDTF
+	}
+
+	formatIds := func(ids []int64) string {
+
+		str_ids := make([]string, len(ids))
+
+		for idx, id := range ids {
+			str_ids[idx] = strconv.FormatInt(id, 10)
+		}
+
+		return strings.Join(str_ids, ",")
+	}
+
+	row := map[string]string{
+		"id":             s.Id(),
+		"parent_id":      s.ParentId(),
+		"name":           s.Name(),
+		"placetype":      s.Placetype(),
+		"country":        s.Country(),
+		"repo":           s.Repo(),
+		"path":           s.Path(),
+		"uri":            s.URI(),
+		"inception":      formatDate(s.Inception()),
+		"cessation":      formatDate(s.Cessation()),
+		"latitude":       formatFloat(s.Latitude()),
+		"longitude":      formatFloat(s.Longitude()),
+		"min_latitude":   formatFloat(s.MinLatitude()),
+		"min_longitude":  formatFloat(s.MinLongitude()),
+		"max_latitude":   formatFloat(s.MaxLatitude()),
+		"max_longitude":  formatFloat(s.MaxLongitude()),
+		"is_current":     formatFlag(s.IsCurrent()),
+		"is_ceased":      formatFlag(s.IsCeased()),
+		"is_deprecated":  formatFlag(s.IsDeprecated()),
+		"is_superseded":  formatFlag(s.IsSuperseded()),
+		"is_superseding": formatFlag(s.IsSuperseding()),
+		"superseded_by":  formatIds(s.SupersededBy()),
+		"supersedes":     formatIds(s.Supersedes()),
+		"belongsto":      formatIds(s.BelongsTo()),
+		"lastmodified":   strconv.FormatInt(s.LastModified(), 10),
+	}
+
+	return row
+}
@@ -0,0 +1,115 @@
+package emit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
+)
+
+// SQLiteEncoder implements the `Encoder` interface for encoding records as a SQLite database using the
+// tables defined by whosonfirst/go-whosonfirst-database.
+type SQLiteEncoder struct {
+	Encoder
+	writer   io.Writer
+	path     string
+	database *sqlite.Database
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterEncoder(ctx, "sqlite", NewSQLiteEncoder)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSQLiteEncoder returns a new `SQLiteEncoder` instance configured by 'uri' in the form of:
+//
+//	sqlite://?table={TABLE}&index-alt-files={BOOLEAN}
+//
+// Where each {TABLE} is the name of a table to create and populate (default is all the tables in `sqlite.DEFAULT_TABLES`)
+// and {BOOLEAN} is whether to index alternate geometry files (default is true).
+func NewSQLiteEncoder(ctx context.Context, uri string, wr io.Writer) (Encoder, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	opts, err := sqlite.DatabaseOptionsFromQuery(u.Query())
+
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "exportify-*.db")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	tmp_path := tmp.Name()
+	tmp.Close()
+
+	d, err := sqlite.OpenDatabase(ctx, tmp_path, opts)
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return nil, fmt.Errorf("Failed to open database, %w", err)
+	}
+
+	enc := &SQLiteEncoder{
+		writer:   wr,
+		path:     tmp_path,
+		database: d,
+	}
+
+	return enc, nil
+}
+
+// Encode adds 'body' to the (temporary) database.
+func (enc *SQLiteEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	err := enc.database.IndexFeature(ctx, body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to index %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close closes the temporary database, copies it to the underlying writer and then removes it.
+func (enc *SQLiteEncoder) Close(ctx context.Context) error {
+
+	defer os.Remove(enc.path)
+
+	err := enc.database.Close(ctx)
+
+	if err != nil {
+		return fmt.Errorf("Failed to close database, %w", err)
+	}
+
+	fh, err := os.Open(enc.path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s, %w", enc.path, err)
+	}
+
+	defer fh.Close()
+
+	_, err = io.Copy(enc.writer, fh)
+
+	if err != nil {
+		return fmt.Errorf("Failed to copy database, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package emit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// WriterEncoder implements the `Encoder` interface for writing records to a `whosonfirst/go-writer.Writer` instance.
+// Each record is passed to the writer, unaltered, with an empty key so it is expected to be a writer which encodes a
+// stream of records itself, for example the `jsonl://` or `featurecollection://` writers wrapping another writer.
+type WriterEncoder struct {
+	Encoder
+	writer writer.Writer
+}
+
+// NewWriterEncoder returns a new `WriterEncoder` instance which writes records to 'wr'.
+func NewWriterEncoder(ctx context.Context, wr writer.Writer) Encoder {
+
+	enc := &WriterEncoder{
+		writer: wr,
+	}
+
+	return enc
+}
+
+// Encode writes 'body' to the underlying `writer.Writer` instance.
+func (enc *WriterEncoder) Encode(ctx context.Context, path string, body []byte) error {
+
+	_, err := enc.writer.Write(ctx, "", bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying `writer.Writer` instance.
+func (enc *WriterEncoder) Close(ctx context.Context) error {
+	return enc.writer.Close(ctx)
+}
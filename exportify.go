@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/tidwall/gjson"
 	export "github.com/whosonfirst/go-whosonfirst-export/v2"
 	wof_writer "github.com/whosonfirst/go-whosonfirst-writer/v3"
 	"github.com/whosonfirst/go-writer/v3"
@@ -27,3 +28,51 @@ func ExportWithWriter(ctx context.Context, ex export.Exporter, wr writer.Writer,
 
 	return nil
 }
+
+// ExportChanges reverts any changes to the controlled properties of 'old_body', the record as it was read, in 'new_body'
+// (see `RevertControlledChanges`) and exports the result. It returns the exported record along with a boolean value
+// indicating whether it was changed. If, once any changes to controlled properties have been reverted, 'new_body' is
+// the same as 'old_body' then it is not exported and 'old_body' is returned.
+func ExportChanges(ctx context.Context, ex export.Exporter, old_body []byte, new_body []byte, opts *ControlledOptions) ([]byte, bool, error) {
+
+	new_body, _, err := RevertControlledChanges(old_body, new_body, opts)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if equalResults(gjson.ParseBytes(old_body), gjson.ParseBytes(new_body)) {
+		return old_body, false, nil
+	}
+
+	new_body, err = ex.Export(ctx, new_body)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to export body, %w", err)
+	}
+
+	return new_body, true, nil
+}
+
+// ExportChangesWithWriter exports 'new_body' using `ExportChanges` and, if it was changed, writes it to 'wr'. It returns
+// a boolean value indicating whether the record was written.
+func ExportChangesWithWriter(ctx context.Context, ex export.Exporter, wr writer.Writer, old_body []byte, new_body []byte, opts *ControlledOptions) (bool, error) {
+
+	new_body, changed, err := ExportChanges(ctx, ex, old_body, new_body, opts)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	_, err = wof_writer.WriteBytes(ctx, wr, new_body)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to write bytes, %w", err)
+	}
+
+	return true, nil
+}
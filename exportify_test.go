@@ -0,0 +1,93 @@
+package exportify_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/golden"
+	_ "github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	wof_reader "github.com/whosonfirst/go-whosonfirst-reader"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files with the records produced rather than comparing them.")
+
+// TestExportChangesWithWriter exports changes to a record whose wof:controlled property lists one of the changed
+// properties and ensures that change is reverted while the others are written.
+func TestExportChangesWithWriter(t *testing.T) {
+
+	ctx := context.Background()
+
+	c := &golden.Case{
+		Name:     "exportify-export-changes",
+		Fixtures: "testdata/golden/fixtures-controlled.geojson",
+		Golden:   "testdata/golden/exportify-export-changes.geojson",
+	}
+
+	op := func(ctx context.Context, reader_uri string, writer_uri string) error {
+
+		r, err := reader.NewReader(ctx, reader_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create reader, %w", err)
+		}
+
+		wr, err := writer.NewWriter(ctx, writer_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create writer, %w", err)
+		}
+
+		ex, err := export.NewExporter(ctx, "whosonfirst://")
+
+		if err != nil {
+			return fmt.Errorf("Failed to create exporter, %w", err)
+		}
+
+		old_body, err := wof_reader.LoadBytes(ctx, r, 101736547)
+
+		if err != nil {
+			return fmt.Errorf("Failed to load record, %w", err)
+		}
+
+		updates := map[string]any{
+			"properties.misc:note":  "north shore",
+			"properties.misc:level": 3,
+		}
+
+		new_body := old_body
+
+		for path, v := range updates {
+
+			new_body, err = sjson.SetBytes(new_body, path, v)
+
+			if err != nil {
+				return fmt.Errorf("Failed to set %s, %w", path, err)
+			}
+		}
+
+		changed, err := exportify.ExportChangesWithWriter(ctx, ex, wr, old_body, new_body, &exportify.ControlledOptions{})
+
+		if err != nil {
+			return fmt.Errorf("Failed to export changes, %w", err)
+		}
+
+		if !changed {
+			return fmt.Errorf("Expected record to be changed")
+		}
+
+		return nil
+	}
+
+	err := golden.Run(ctx, c, op, *update)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package filter provides a common selection layer for deciding which records iterator-based tools should process.
+// Filters are expressions, defined by the compute package, which select the records for which they evaluate to a
+// "truthy" value. For example:
+//
+//	{properties.mz:is_current} == 1 && descendant_of({properties.wof:placetype}, "region")
+//	!exists({properties.edtf:cessation}) || edtf_after({properties.edtf:cessation}, "2020")
+//	intersects_bbox({geometry}, -74.0, 45.4, -73.4, 45.7)
+package filter
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/compute"
+	"github.com/whosonfirst/go-whosonfirst-iterate/v2/emitter"
+)
+
+// Filter selects the records for which an expression evaluates to a truthy value.
+type Filter struct {
+	expression *compute.Expression
+}
+
+// NewFilter returns a new `Filter` instance for the expression 'expr'.
+func NewFilter(expr string) (*Filter, error) {
+
+	e, err := compute.ParseExpression(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{
+		expression: e,
+	}
+
+	return f, nil
+}
+
+// Matches returns a boolean value indicating whether the record 'body' is selected by 'f'.
+func (f *Filter) Matches(body []byte) (bool, error) {
+	return f.expression.Matches(body)
+}
+
+// String returns the expression for 'f'.
+func (f *Filter) String() string {
+	return f.expression.String()
+}
+
+// Filters is a list of `Filter` instances, all of which must match a record for it to be selected. It implements
+// the `flag.Value` interface.
+type Filters []*Filter
+
+// String returns the list of filter expressions as a comma-separated string.
+func (filters *Filters) String() string {
+
+	exprs := make([]string, len(*filters))
+
+	for i, f := range *filters {
+		exprs[i] = f.String()
+	}
+
+	return strings.Join(exprs, ",")
+}
+
+// Set parses 'value' as a filter expression and appends it to the list.
+func (filters *Filters) Set(value string) error {
+
+	f, err := NewFilter(value)
+
+	if err != nil {
+		return err
+	}
+
+	*filters = append(*filters, f)
+	return nil
+}
+
+// Matches returns a boolean value indicating whether the record 'body' is selected by all of 'filters'.
+func (filters Filters) Matches(body []byte) (bool, error) {
+
+	for _, f := range filters {
+
+		ok, err := f.Matches(body)
+
+		if err != nil {
+			return false, err
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// AppendFlags assigns a "-filter" flag to 'fs' that appends to 'filters'.
+func AppendFlags(fs *flag.FlagSet, filters *Filters) {
+	fs.Var(filters, "filter", "Zero or more go-whosonfirst-exportify/compute expressions used to select the records to process. Records are only processed if all the expressions evaluate to a truthy value. For example: '{properties.mz:is_current} == 1 && descendant_of({properties.wof:placetype}, \"region\")'")
+}
+
+// IteratorCallback returns a `emitter.EmitterCallbackFunc` function which invokes 'cb' for each record that is
+// selected by 'filters'. If 'filters' is empty 'cb' is returned unchanged. Records for which 'filters' can not be
+// evaluated, for example because a property being compared to a number is a string, are not selected. The reason is
+// reported to 'logger', if it is not nil, rather than stopping the iteration since syntax errors have already been
+// caught when the filters were parsed.
+func IteratorCallback(filters Filters, logger *log.Logger, cb emitter.EmitterCallbackFunc) emitter.EmitterCallbackFunc {
+
+	if len(filters) == 0 {
+		return cb
+	}
+
+	filter_cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+		ok, err := filters.Matches(body)
+
+		if err != nil {
+
+			if logger != nil {
+				logger.Printf("Skipping %s because filters could not be evaluated, %v", path, err)
+			}
+
+			return nil
+		}
+
+		if !ok {
+			return nil
+		}
+
+		return cb(ctx, path, bytes.NewReader(body), args...)
+	}
+
+	return filter_cb
+}
@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNewFilter(t *testing.T) {
+
+	valid := []string{
+		`{properties.mz:is_current} == 1`,
+		`descendant_of({properties.wof:placetype}, "region") && !exists({properties.edtf:cessation})`,
+	}
+
+	for _, expr := range valid {
+
+		_, err := NewFilter(expr)
+
+		if err != nil {
+			t.Errorf("Failed to parse '%s', %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		`{properties.mz:is_current} ==`,
+		`descendant_of({properties.wof:placetype})`,
+		`unknown_function(1)`,
+	}
+
+	for _, expr := range invalid {
+
+		_, err := NewFilter(expr)
+
+		if err == nil {
+			t.Errorf("Expected '%s' to fail to parse", expr)
+		}
+	}
+}
+
+func TestIteratorCallback(t *testing.T) {
+
+	ctx := context.Background()
+
+	records := map[string]string{
+		"current.geojson":    `{"properties":{"mz:is_current":1,"wof:placetype":"locality"}}`,
+		"deprecated.geojson": `{"properties":{"mz:is_current":0,"wof:placetype":"locality"}}`,
+		"county.geojson":     `{"properties":{"mz:is_current":1,"wof:placetype":"county"}}`,
+		// Comparing a string to a number can't be evaluated.
+		"invalid.geojson": `{"properties":{"mz:is_current":"yes","wof:placetype":"locality"}}`,
+	}
+
+	var filters Filters
+
+	for _, expr := range []string{`{properties.mz:is_current} > 0`, `{properties.wof:placetype} == "locality"`} {
+
+		err := filters.Set(expr)
+
+		if err != nil {
+			t.Fatalf("Failed to set filter, %v", err)
+		}
+	}
+
+	selected := make([]string, 0)
+
+	cb := func(ctx context.Context, path string, fh io.ReadSeeker, args ...interface{}) error {
+
+		body, err := io.ReadAll(fh)
+
+		if err != nil {
+			return err
+		}
+
+		if string(body) != records[path] {
+			t.Errorf("Unexpected body for %s, %s", path, body)
+		}
+
+		selected = append(selected, path)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+
+	filter_cb := IteratorCallback(filters, logger, cb)
+
+	for _, path := range []string{"current.geojson", "deprecated.geojson", "county.geojson", "invalid.geojson"} {
+
+		err := filter_cb(ctx, path, strings.NewReader(records[path]))
+
+		if err != nil {
+			t.Fatalf("Expected %s not to stop the iteration, %v", path, err)
+		}
+	}
+
+	if len(selected) != 1 || selected[0] != "current.geojson" {
+		t.Fatalf("Unexpected records selected, %v", selected)
+	}
+
+	if !strings.Contains(buf.String(), "invalid.geojson") || strings.Contains(buf.String(), "deprecated.geojson") {
+		t.Fatalf("Expected only the record which could not be evaluated to be logged, got %s", buf.String())
+	}
+
+	// Without filters the callback is invoked for every record.
+
+	selected = make([]string, 0)
+	filter_cb = IteratorCallback(nil, nil, cb)
+
+	for _, path := range []string{"current.geojson", "invalid.geojson"} {
+
+		err := filter_cb(ctx, path, strings.NewReader(records[path]))
+
+		if err != nil {
+			t.Fatalf("Failed to invoke callback, %v", err)
+		}
+	}
+
+	if len(selected) != 2 {
+		t.Fatalf("Expected all records to be selected, got %v", selected)
+	}
+}
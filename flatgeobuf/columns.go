@@ -0,0 +1,163 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ColumnType is a FlatGeobuf column (attribute) type.
+type ColumnType uint8
+
+const (
+	COLUMN_BYTE ColumnType = iota
+	COLUMN_UBYTE
+	COLUMN_BOOL
+	COLUMN_SHORT
+	COLUMN_USHORT
+	COLUMN_INT
+	COLUMN_UINT
+	COLUMN_LONG
+	COLUMN_ULONG
+	COLUMN_FLOAT
+	COLUMN_DOUBLE
+	COLUMN_STRING
+	COLUMN_JSON
+	COLUMN_DATETIME
+	COLUMN_BINARY
+)
+
+// Field ids for the FlatGeobuf `Column` table.
+const (
+	column_name = 0
+	column_type = 1
+	column_len  = 2
+)
+
+// Column defines a FlatGeobuf column derived from a WOF property.
+type Column struct {
+	// The name of the column. This is the same as the (relative) path of the property it is derived from.
+	Name string
+	// The FlatGeobuf type of the column.
+	Type ColumnType
+	// Whether a type has been assigned to the column yet.
+	typed bool
+}
+
+// inferColumnTypes assigns a type to each column in 'columns' derived from the property values
+// in 'features'. Integers are encoded as `Long` columns (or `Double` columns if they are mixed with
+// floating point numbers), booleans as `Bool` columns, strings as `String` columns and objects or
+// arrays as `Json` columns. Columns with otherwise mixed types are encoded as `String` columns unless
+// they contain objects or arrays in which case they are encoded as `Json` columns. Columns with no
+// values at all are encoded as `String` columns.
+func inferColumnTypes(columns []*Column, features []*feature) {
+
+	for idx, c := range columns {
+
+		for _, f := range features {
+
+			v := f.properties[idx]
+
+			if !v.Exists() || v.Type == gjson.Null {
+				continue
+			}
+
+			t := valueType(v)
+
+			switch {
+			case !c.typed:
+				c.Type = t
+				c.typed = true
+			case c.Type == t:
+				// pass
+			case isNumeric(c.Type) && isNumeric(t):
+				c.Type = COLUMN_DOUBLE
+			case c.Type == COLUMN_JSON || t == COLUMN_JSON:
+				c.Type = COLUMN_JSON
+			default:
+				c.Type = COLUMN_STRING
+			}
+		}
+
+		if !c.typed {
+			c.Type = COLUMN_STRING
+			c.typed = true
+		}
+	}
+}
+
+func valueType(v gjson.Result) ColumnType {
+
+	switch v.Type {
+	case gjson.True, gjson.False:
+		return COLUMN_BOOL
+	case gjson.Number:
+
+		if !strings.ContainsAny(v.Raw, ".eE") {
+
+			_, err := strconv.ParseInt(v.Raw, 10, 64)
+
+			if err == nil {
+				return COLUMN_LONG
+			}
+		}
+
+		return COLUMN_DOUBLE
+
+	case gjson.String:
+		return COLUMN_STRING
+	default:
+		return COLUMN_JSON
+	}
+}
+
+func isNumeric(t ColumnType) bool {
+	return t == COLUMN_LONG || t == COLUMN_DOUBLE
+}
+
+// encodeProperties encodes 'values' as a FlatGeobuf properties buffer: a sequence of little-endian
+// (column index, value) pairs. Missing and null values are omitted.
+func encodeProperties(columns []*Column, values []gjson.Result) []byte {
+
+	buf := make([]byte, 0)
+
+	for idx, c := range columns {
+
+		v := values[idx]
+
+		if !v.Exists() || v.Type == gjson.Null {
+			continue
+		}
+
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(idx))
+
+		switch c.Type {
+		case COLUMN_BOOL:
+
+			if v.Bool() {
+				buf = append(buf, 1)
+			} else {
+				buf = append(buf, 0)
+			}
+
+		case COLUMN_LONG:
+			buf = binary.LittleEndian.AppendUint64(buf, uint64(v.Int()))
+		case COLUMN_DOUBLE:
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Float()))
+		case COLUMN_JSON:
+			buf = appendString(buf, v.Raw)
+		default:
+			buf = appendString(buf, v.String())
+		}
+	}
+
+	return buf
+}
+
+func appendString(buf []byte, str string) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(str)))
+	return append(buf, str...)
+}
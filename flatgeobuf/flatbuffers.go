@@ -0,0 +1,235 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// This file implements the minimal subset of the FlatBuffers wire format needed to encode FlatGeobuf
+// headers and features. Tables are described as a list of (optional) field values, indexed by their
+// field id in the FlatGeobuf schema, and serialized front-to-back: each table is preceded by its vtable
+// and followed by any strings, vectors or sub-tables it references. Unsigned offsets therefore always
+// point forward, as the format requires. Alignment is relative to the start of the size-prefixed buffer.
+
+// fbTable is a FlatBuffers table whose fields are indexed by their schema field id. Nil fields are omitted.
+type fbTable []interface{}
+
+type fbUint8 uint8
+
+type fbBool bool
+
+type fbUint16 uint16
+
+type fbInt32 int32
+
+type fbUint64 uint64
+
+type fbString string
+
+type fbBytes []byte
+
+type fbUint32s []uint32
+
+type fbFloat64s []float64
+
+type fbTables []fbTable
+
+type fbBuilder struct {
+	buf []byte
+}
+
+// encodeSizePrefixed encodes 't' as a size-prefixed FlatBuffers root table.
+func encodeSizePrefixed(t fbTable) []byte {
+
+	b := &fbBuilder{
+		buf: make([]byte, 8, 1024),
+	}
+
+	pos := b.table(t)
+
+	binary.LittleEndian.PutUint32(b.buf[4:], uint32(pos-4))
+
+	b.pad(4)
+	binary.LittleEndian.PutUint32(b.buf[0:], uint32(len(b.buf)-4))
+
+	return b.buf
+}
+
+func (b *fbBuilder) pad(align int) {
+
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) grow(n int) int {
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, n)...)
+	return pos
+}
+
+func (b *fbBuilder) patchOffset(at int, target int) {
+	binary.LittleEndian.PutUint32(b.buf[at:], uint32(target-at))
+}
+
+// table writes 't' (and everything it references) and returns the position of the table.
+func (b *fbBuilder) table(t fbTable) int {
+
+	// Work out the inline layout first: a 4-byte soffset to the vtable followed by
+	// each field aligned to its own size.
+
+	offsets := make([]int, len(t))
+	size := 4
+
+	for idx, v := range t {
+
+		sz := inlineSize(v)
+
+		if sz == 0 {
+			continue
+		}
+
+		for size%sz != 0 {
+			size += 1
+		}
+
+		offsets[idx] = size
+		size += sz
+	}
+
+	b.pad(2)
+	vt_pos := b.grow(4 + 2*len(t))
+
+	binary.LittleEndian.PutUint16(b.buf[vt_pos:], uint16(4+2*len(t)))
+	binary.LittleEndian.PutUint16(b.buf[vt_pos+2:], uint16(size))
+
+	for idx, offset := range offsets {
+		binary.LittleEndian.PutUint16(b.buf[vt_pos+4+2*idx:], uint16(offset))
+	}
+
+	b.pad(8)
+	tbl_pos := b.grow(size)
+
+	binary.LittleEndian.PutUint32(b.buf[tbl_pos:], uint32(int32(tbl_pos-vt_pos)))
+
+	for idx, v := range t {
+
+		if v == nil {
+			continue
+		}
+
+		at := tbl_pos + offsets[idx]
+
+		switch v := v.(type) {
+		case fbUint8:
+			b.buf[at] = uint8(v)
+		case fbBool:
+			if v {
+				b.buf[at] = 1
+			}
+		case fbUint16:
+			binary.LittleEndian.PutUint16(b.buf[at:], uint16(v))
+		case fbInt32:
+			binary.LittleEndian.PutUint32(b.buf[at:], uint32(v))
+		case fbUint64:
+			binary.LittleEndian.PutUint64(b.buf[at:], uint64(v))
+		}
+	}
+
+	// Now write the referenced objects and patch their offsets.
+
+	for idx, v := range t {
+
+		if !isReference(v) {
+			continue
+		}
+
+		at := tbl_pos + offsets[idx]
+		b.patchOffset(at, b.reference(v))
+	}
+
+	return tbl_pos
+}
+
+// reference writes a string, vector or table and returns the position an offset should point to.
+func (b *fbBuilder) reference(v interface{}) int {
+
+	switch v := v.(type) {
+	case fbString:
+		pos := b.vector(len(v), 1)
+		copy(b.buf[pos+4:], v)
+		b.buf = append(b.buf, 0)
+		return pos
+	case fbBytes:
+		pos := b.vector(len(v), 1)
+		copy(b.buf[pos+4:], v)
+		return pos
+	case fbUint32s:
+		pos := b.vector(len(v), 4)
+		for i, n := range v {
+			binary.LittleEndian.PutUint32(b.buf[pos+4+i*4:], n)
+		}
+		return pos
+	case fbFloat64s:
+		pos := b.vector(len(v), 8)
+		for i, n := range v {
+			binary.LittleEndian.PutUint64(b.buf[pos+4+i*8:], math.Float64bits(n))
+		}
+		return pos
+	case fbTables:
+		pos := b.vector(len(v), 4)
+		for i, t := range v {
+			at := pos + 4 + i*4
+			b.patchOffset(at, b.table(t))
+		}
+		return pos
+	case fbTable:
+		return b.table(v)
+	}
+
+	return 0
+}
+
+// vector reserves space for a length-prefixed vector of 'count' elements of 'size' bytes, making sure
+// the elements themselves are aligned, and returns the position of the length prefix.
+func (b *fbBuilder) vector(count int, size int) int {
+
+	b.pad(4)
+
+	for (len(b.buf)+4)%size != 0 {
+		b.buf = append(b.buf, 0)
+	}
+
+	pos := b.grow(4 + count*size)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(count))
+
+	return pos
+}
+
+func inlineSize(v interface{}) int {
+
+	switch v.(type) {
+	case fbUint8, fbBool:
+		return 1
+	case fbUint16:
+		return 2
+	case fbInt32:
+		return 4
+	case fbUint64:
+		return 8
+	case fbString, fbBytes, fbUint32s, fbFloat64s, fbTables, fbTable:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func isReference(v interface{}) bool {
+
+	switch v.(type) {
+	case fbString, fbBytes, fbUint32s, fbFloat64s, fbTables, fbTable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,274 @@
+// Package flatgeobuf provides methods for encoding WOF records as FlatGeobuf files, with a packed
+// Hilbert R-tree spatial index and a configurable list of properties stored as typed columns.
+package flatgeobuf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/tidwall/gjson"
+)
+
+// MAGIC_BYTES are the bytes that every FlatGeobuf file starts with (version 3.0).
+var MAGIC_BYTES = []byte{0x66, 0x67, 0x62, 0x03, 0x66, 0x67, 0x62, 0x00}
+
+// DEFAULT_NAME is the default name of the layer (dataset) in a FlatGeobuf file.
+const DEFAULT_NAME string = "whosonfirst"
+
+// Field ids for the FlatGeobuf `Header` table.
+const (
+	header_name            = 0
+	header_envelope        = 1
+	header_geometry_type   = 2
+	header_columns         = 7
+	header_features_count  = 8
+	header_index_node_size = 9
+	header_crs             = 10
+	header_len             = 11
+)
+
+// Field ids for the FlatGeobuf `Crs` table.
+const (
+	crs_org  = 0
+	crs_code = 1
+	crs_len  = 2
+)
+
+// Field ids for the FlatGeobuf `Feature` table.
+const (
+	feature_geometry   = 0
+	feature_properties = 1
+	feature_len        = 2
+)
+
+// BuilderOptions defines configuration options for a `Builder` instance.
+type BuilderOptions struct {
+	// The name of the layer (dataset) in the FlatGeobuf file.
+	Name string
+	// The list of (relative) property paths to store as typed columns. For example "wof:name".
+	Properties []string
+	// The number of items per node in the packed Hilbert R-tree index. If 0 then no index will be written.
+	IndexNodeSize uint16
+}
+
+type feature struct {
+	geometry   orb.Geometry
+	bound      orb.Bound
+	properties []gjson.Result
+}
+
+// Builder accumulates WOF records in memory and writes them as a FlatGeobuf file. FlatGeobuf files
+// store their spatial index ahead of the features themselves so all the features need to be known
+// before anything can be written.
+type Builder struct {
+	options  *BuilderOptions
+	features []*feature
+	mu       *sync.Mutex
+}
+
+// DefaultBuilderOptions returns a `BuilderOptions` instance with a default layer name and index node size
+// and no properties.
+func DefaultBuilderOptions() *BuilderOptions {
+
+	opts := &BuilderOptions{
+		Name:          DEFAULT_NAME,
+		Properties:    make([]string, 0),
+		IndexNodeSize: DEFAULT_INDEX_NODE_SIZE,
+	}
+
+	return opts
+}
+
+// NewBuilder returns a new `Builder` instance configured by 'opts'.
+func NewBuilder(ctx context.Context, opts *BuilderOptions) (*Builder, error) {
+
+	if opts.IndexNodeSize == 1 {
+		return nil, fmt.Errorf("Invalid index node size, must be 0 or greater than 1")
+	}
+
+	b := &Builder{
+		options:  opts,
+		features: make([]*feature, 0),
+		mu:       new(sync.Mutex),
+	}
+
+	return b, nil
+}
+
+// AddFeature adds the GeoJSON Feature 'body' to the list of features to be written.
+func (b *Builder) AddFeature(ctx context.Context, body []byte) error {
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal feature, %w", err)
+	}
+
+	if f.Geometry == nil {
+		return fmt.Errorf("Feature is missing a geometry")
+	}
+
+	_, err = geometryType(f.Geometry)
+
+	if err != nil {
+		return err
+	}
+
+	properties := make([]gjson.Result, len(b.options.Properties))
+
+	for idx, path := range b.options.Properties {
+		properties[idx] = gjson.GetBytes(body, "properties."+gjson.Escape(path))
+	}
+
+	fgb_f := &feature{
+		geometry:   f.Geometry,
+		bound:      f.Geometry.Bound(),
+		properties: properties,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.features = append(b.features, fgb_f)
+	return nil
+}
+
+// Write writes all the features added so far to 'wr' as a FlatGeobuf file.
+func (b *Builder) Write(ctx context.Context, wr io.Writer) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	columns := make([]*Column, len(b.options.Properties))
+
+	for idx, path := range b.options.Properties {
+		columns[idx] = &Column{
+			Name: path,
+		}
+	}
+
+	inferColumnTypes(columns, b.features)
+
+	node_size := b.options.IndexNodeSize
+
+	if len(b.features) == 0 {
+		node_size = 0
+	}
+
+	var extent orb.Bound
+	geom_type := GEOMETRY_UNKNOWN
+
+	for idx, f := range b.features {
+
+		t, _ := geometryType(f.geometry)
+
+		if idx == 0 {
+			extent = f.bound
+			geom_type = t
+			continue
+		}
+
+		extent = extent.Union(f.bound)
+
+		if t != geom_type {
+			geom_type = GEOMETRY_UNKNOWN
+		}
+	}
+
+	if node_size > 0 {
+		hilbertSort(b.features, extent)
+	}
+
+	header := b.header(columns, extent, geom_type, node_size)
+
+	_, err := wr.Write(MAGIC_BYTES)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write magic bytes, %w", err)
+	}
+
+	_, err = wr.Write(encodeSizePrefixed(header))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write header, %w", err)
+	}
+
+	encoded := make([][]byte, len(b.features))
+	sizes := make([]int, len(b.features))
+
+	for idx, f := range b.features {
+
+		geom, err := encodeGeometry(f.geometry)
+
+		if err != nil {
+			return err
+		}
+
+		t := make(fbTable, feature_len)
+		t[feature_geometry] = geom
+
+		if len(columns) > 0 {
+			t[feature_properties] = fbBytes(encodeProperties(columns, f.properties))
+		}
+
+		encoded[idx] = encodeSizePrefixed(t)
+		sizes[idx] = len(encoded[idx])
+	}
+
+	if node_size > 0 {
+
+		err = writeIndex(wr, b.features, sizes, node_size)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write index, %w", err)
+		}
+	}
+
+	for _, body := range encoded {
+
+		_, err := wr.Write(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write feature, %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Builder) header(columns []*Column, extent orb.Bound, geom_type GeometryType, node_size uint16) fbTable {
+
+	fb_columns := make(fbTables, len(columns))
+
+	for idx, c := range columns {
+		t := make(fbTable, column_len)
+		t[column_name] = fbString(c.Name)
+		t[column_type] = fbUint8(c.Type)
+		fb_columns[idx] = t
+	}
+
+	crs := make(fbTable, crs_len)
+	crs[crs_org] = fbString("EPSG")
+	crs[crs_code] = fbInt32(4326)
+
+	t := make(fbTable, header_len)
+	t[header_name] = fbString(b.options.Name)
+	t[header_geometry_type] = fbUint8(geom_type)
+	t[header_features_count] = fbUint64(len(b.features))
+	t[header_index_node_size] = fbUint16(node_size)
+	t[header_crs] = crs
+
+	if len(b.features) > 0 {
+		t[header_envelope] = fbFloat64s{extent.Min.X(), extent.Min.Y(), extent.Max.X(), extent.Max.Y()}
+	}
+
+	if len(fb_columns) > 0 {
+		t[header_columns] = fb_columns
+	}
+
+	return t
+}
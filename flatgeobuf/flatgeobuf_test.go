@@ -0,0 +1,544 @@
+package flatgeobuf
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// The tests in this file read FlatGeobuf files back using the google/flatbuffers package and the field ids, and
+// packed Hilbert R-tree layout, defined by the FlatGeobuf specification rather than any of the encoder's own code.
+
+var test_features = []string{
+	`{"type":"Feature","properties":{"wof:id":102527513,"wof:name":"SFO","geom:latitude":37.616356,"mz:is_current":1},"geometry":{"type":"Point","coordinates":[-122.386166,37.616356]}}`,
+	`{"type":"Feature","properties":{"wof:id":102526437,"wof:name":"OAK","geom:latitude":37.712632,"mz:is_current":1},"geometry":{"type":"Point","coordinates":[-122.21461,37.712632]}}`,
+	`{"type":"Feature","properties":{"wof:id":102534365,"wof:name":"JFK","geom:latitude":40.642335,"mz:is_current":0},"geometry":{"type":"Point","coordinates":[-73.78817,40.642335]}}`,
+	`{"type":"Feature","properties":{"wof:id":102535149,"wof:name":"YUL","geom:latitude":45.468331},"geometry":{"type":"Point","coordinates":[-73.741392,45.468331]}}`,
+	`{"type":"Feature","properties":{"wof:id":85922583,"wof:name":"San Francisco","geom:latitude":37.759715,"mz:is_current":1},"geometry":{"type":"Polygon","coordinates":[[[-122.5149,37.7081],[-122.3569,37.7081],[-122.3569,37.8324],[-122.5149,37.8324],[-122.5149,37.7081]]]}}`,
+	`{"type":"Feature","properties":{"wof:id":101736545,"wof:name":"Montréal","geom:latitude":45.512265,"mz:is_current":1},"geometry":{"type":"MultiPolygon","coordinates":[[[[-73.9747,45.4102],[-73.4741,45.4102],[-73.4741,45.7051],[-73.9747,45.7051],[-73.9747,45.4102]]],[[[-73.6,45.3],[-73.5,45.3],[-73.5,45.35],[-73.6,45.35],[-73.6,45.3]]]]}}`,
+	`{"type":"Feature","properties":{"wof:id":85977539,"wof:name":"New York","geom:latitude":40.68295,"mz:is_current":1},"geometry":{"type":"Polygon","coordinates":[[[-74.2591,40.4774],[-73.7004,40.4774],[-73.7004,40.9176],[-74.2591,40.9176],[-74.2591,40.4774]]]}}`,
+}
+
+type testHeader struct {
+	name            string
+	envelope        []float64
+	geometry_type   byte
+	columns         map[string]byte
+	column_names    []string
+	features_count  uint64
+	index_node_size uint16
+	crs_org         string
+	crs_code        int32
+}
+
+type testFeature struct {
+	geometry_type byte
+	bbox          []float64
+	properties    map[string]interface{}
+}
+
+// testField returns the absolute position of field 'id' in 't', or 0 if it is not present.
+func testField(t *flatbuffers.Table, id int) flatbuffers.UOffsetT {
+
+	o := flatbuffers.UOffsetT(t.Offset(flatbuffers.VOffsetT(4 + 2*id)))
+
+	if o == 0 {
+		return 0
+	}
+
+	return t.Pos + o
+}
+
+func testString(t *flatbuffers.Table, id int) string {
+
+	pos := testField(t, id)
+
+	if pos == 0 {
+		return ""
+	}
+
+	return string(t.ByteVector(pos))
+}
+
+func testFloat64s(t *flatbuffers.Table, id int) []float64 {
+
+	pos := testField(t, id)
+
+	if pos == 0 {
+		return nil
+	}
+
+	v := t.Vector(pos - t.Pos)
+	values := make([]float64, t.VectorLen(pos-t.Pos))
+
+	for i := range values {
+		values[i] = t.GetFloat64(v + flatbuffers.UOffsetT(i*8))
+	}
+
+	return values
+}
+
+func testTables(t *flatbuffers.Table, id int) []*flatbuffers.Table {
+
+	pos := testField(t, id)
+
+	if pos == 0 {
+		return nil
+	}
+
+	v := t.Vector(pos - t.Pos)
+	tables := make([]*flatbuffers.Table, t.VectorLen(pos-t.Pos))
+
+	for i := range tables {
+		tables[i] = &flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(v + flatbuffers.UOffsetT(i*4))}
+	}
+
+	return tables
+}
+
+// readTestHeader reads the magic bytes and header of the FlatGeobuf file 'body' and returns the header along
+// with the offset of the first byte after it.
+func readTestHeader(body []byte) (*testHeader, int, error) {
+
+	if !bytes.HasPrefix(body, []byte("fgb\x03fgb\x00")) {
+		return nil, 0, fmt.Errorf("Missing magic bytes")
+	}
+
+	size := int(binary.LittleEndian.Uint32(body[8:]))
+	buf := body[12 : 12+size]
+
+	t := &flatbuffers.Table{Bytes: buf, Pos: flatbuffers.GetUOffsetT(buf)}
+
+	// Header fields: name (0), envelope (1), geometry_type (2), columns (7), features_count (8),
+	// index_node_size (9, default 16) and crs (10).
+
+	h := &testHeader{
+		name:            testString(t, 0),
+		envelope:        testFloat64s(t, 1),
+		columns:         make(map[string]byte),
+		column_names:    make([]string, 0),
+		index_node_size: 16,
+	}
+
+	if pos := testField(t, 2); pos != 0 {
+		h.geometry_type = t.GetByte(pos)
+	}
+
+	if pos := testField(t, 8); pos != 0 {
+		h.features_count = t.GetUint64(pos)
+	}
+
+	if pos := testField(t, 9); pos != 0 {
+		h.index_node_size = t.GetUint16(pos)
+	}
+
+	// Column fields: name (0) and type (1).
+
+	for _, c := range testTables(t, 7) {
+
+		name := testString(c, 0)
+		h.column_names = append(h.column_names, name)
+
+		if pos := testField(c, 1); pos != 0 {
+			h.columns[name] = c.GetByte(pos)
+		} else {
+			h.columns[name] = 0
+		}
+	}
+
+	// Crs fields: org (0) and code (1).
+
+	if pos := testField(t, 10); pos != 0 {
+
+		crs := &flatbuffers.Table{Bytes: buf, Pos: t.Indirect(pos)}
+		h.crs_org = testString(crs, 0)
+
+		if pos := testField(crs, 1); pos != 0 {
+			h.crs_code = crs.GetInt32(pos)
+		}
+	}
+
+	return h, 12 + size, nil
+}
+
+// readTestFeature reads the size-prefixed feature at 'offset' in 'body'.
+func readTestFeature(body []byte, offset int, h *testHeader) (*testFeature, error) {
+
+	if offset+4 > len(body) {
+		return nil, fmt.Errorf("Feature at %d is out of range", offset)
+	}
+
+	size := int(binary.LittleEndian.Uint32(body[offset:]))
+	buf := body[offset+4 : offset+4+size]
+
+	t := &flatbuffers.Table{Bytes: buf, Pos: flatbuffers.GetUOffsetT(buf)}
+
+	f := &testFeature{
+		bbox:       []float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)},
+		properties: make(map[string]interface{}),
+	}
+
+	// Feature fields: geometry (0) and properties (1). Geometry fields: xy (1), type (6) and parts (7).
+
+	pos := testField(t, 0)
+
+	if pos == 0 {
+		return nil, fmt.Errorf("Feature at %d is missing a geometry", offset)
+	}
+
+	geom := &flatbuffers.Table{Bytes: buf, Pos: t.Indirect(pos)}
+
+	if pos := testField(geom, 6); pos != 0 {
+		f.geometry_type = geom.GetByte(pos)
+	}
+
+	xy := testFloat64s(geom, 1)
+
+	for _, part := range testTables(geom, 7) {
+		xy = append(xy, testFloat64s(part, 1)...)
+	}
+
+	for i := 0; i+1 < len(xy); i += 2 {
+		f.bbox[0] = min(f.bbox[0], xy[i])
+		f.bbox[1] = min(f.bbox[1], xy[i+1])
+		f.bbox[2] = max(f.bbox[2], xy[i])
+		f.bbox[3] = max(f.bbox[3], xy[i+1])
+	}
+
+	var props []byte
+
+	if pos := testField(t, 1); pos != 0 {
+		props = t.ByteVector(pos)
+	}
+
+	for i := 0; i < len(props); {
+
+		name := h.column_names[binary.LittleEndian.Uint16(props[i:])]
+		i += 2
+
+		switch h.columns[name] {
+		case 2: // Bool
+			f.properties[name] = props[i] != 0
+			i += 1
+		case 7: // Long
+			f.properties[name] = int64(binary.LittleEndian.Uint64(props[i:]))
+			i += 8
+		case 10: // Double
+			f.properties[name] = math.Float64frombits(binary.LittleEndian.Uint64(props[i:]))
+			i += 8
+		case 11, 12: // String, Json
+			sz := int(binary.LittleEndian.Uint32(props[i:]))
+			f.properties[name] = string(props[i+4 : i+4+sz])
+			i += 4 + sz
+		default:
+			return nil, fmt.Errorf("Unexpected column type %d for %s", h.columns[name], name)
+		}
+	}
+
+	return f, nil
+}
+
+// testLevelBounds returns the number of nodes in a packed R-tree for 'count' items with 'node_size' items per node
+// and the [start, end) node indices for each of its levels, starting with the leaves.
+func testLevelBounds(count int, node_size int) (int, [][2]int) {
+
+	n := count
+	num_nodes := n
+	level_num_nodes := []int{n}
+
+	for {
+		n = (n + node_size - 1) / node_size
+		num_nodes += n
+		level_num_nodes = append(level_num_nodes, n)
+
+		if n == 1 {
+			break
+		}
+	}
+
+	bounds := make([][2]int, len(level_num_nodes))
+	n = num_nodes
+
+	for i, size := range level_num_nodes {
+		bounds[i] = [2]int{n - size, n}
+		n -= size
+	}
+
+	return num_nodes, bounds
+}
+
+// searchTestIndex returns the offsets, relative to the start of the features, of the features whose bounding boxes
+// intersect 'bbox' by searching the packed R-tree 'index'.
+func searchTestIndex(index []byte, count int, node_size int, bbox []float64) []uint64 {
+
+	num_nodes, bounds := testLevelBounds(count, node_size)
+
+	type item struct {
+		node  int
+		level int
+	}
+
+	queue := []item{{0, len(bounds) - 1}}
+	results := make([]uint64, 0)
+
+	for len(queue) > 0 {
+
+		it := queue[0]
+		queue = queue[1:]
+
+		is_leaf := it.node >= num_nodes-count
+		end := min(it.node+node_size, bounds[it.level][1])
+
+		for pos := it.node; pos < end; pos++ {
+
+			b := index[pos*40:]
+			min_x := math.Float64frombits(binary.LittleEndian.Uint64(b[0:]))
+			min_y := math.Float64frombits(binary.LittleEndian.Uint64(b[8:]))
+			max_x := math.Float64frombits(binary.LittleEndian.Uint64(b[16:]))
+			max_y := math.Float64frombits(binary.LittleEndian.Uint64(b[24:]))
+			offset := binary.LittleEndian.Uint64(b[32:])
+
+			if bbox[2] < min_x || bbox[3] < min_y || bbox[0] > max_x || bbox[1] > max_y {
+				continue
+			}
+
+			if is_leaf {
+				results = append(results, offset)
+			} else {
+				queue = append(queue, item{int(offset), it.level - 1})
+			}
+		}
+	}
+
+	return results
+}
+
+func buildTestFile(t *testing.T, node_size uint16) []byte {
+
+	ctx := context.Background()
+
+	opts := DefaultBuilderOptions()
+	opts.Name = "airports"
+	opts.Properties = []string{"wof:name", "wof:id", "geom:latitude", "mz:is_current"}
+	opts.IndexNodeSize = node_size
+
+	b, err := NewBuilder(ctx, opts)
+
+	if err != nil {
+		t.Fatalf("Failed to create builder, %v", err)
+	}
+
+	for _, body := range test_features {
+
+		err := b.AddFeature(ctx, []byte(body))
+
+		if err != nil {
+			t.Fatalf("Failed to add feature, %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err = b.Write(ctx, &buf)
+
+	if err != nil {
+		t.Fatalf("Failed to write FlatGeobuf file, %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// TestBuilder reads back the header and features of a FlatGeobuf file and ensures that searching its spatial index
+// returns the same features as comparing the bounding box of every feature.
+func TestBuilder(t *testing.T) {
+
+	body := buildTestFile(t, 2)
+
+	h, offset, err := readTestHeader(body)
+
+	if err != nil {
+		t.Fatalf("Failed to read header, %v", err)
+	}
+
+	if h.name != "airports" {
+		t.Fatalf("Unexpected name '%s'", h.name)
+	}
+
+	if h.features_count != uint64(len(test_features)) {
+		t.Fatalf("Unexpected features count %d", h.features_count)
+	}
+
+	if h.index_node_size != 2 {
+		t.Fatalf("Unexpected index node size %d", h.index_node_size)
+	}
+
+	if h.geometry_type != 0 {
+		t.Fatalf("Expected mixed geometries to have the Unknown (0) geometry type, got %d", h.geometry_type)
+	}
+
+	if h.crs_org != "EPSG" || h.crs_code != 4326 {
+		t.Fatalf("Unexpected CRS %s:%d", h.crs_org, h.crs_code)
+	}
+
+	if !slices.Equal(h.envelope, []float64{-122.5149, 37.616356, -73.4741, 45.7051}) {
+		t.Fatalf("Unexpected envelope %v", h.envelope)
+	}
+
+	expected_columns := map[string]byte{
+		"wof:name":      11, // String
+		"wof:id":        7,  // Long
+		"geom:latitude": 10, // Double
+		"mz:is_current": 7,  // Long
+	}
+
+	if !slices.Equal(h.column_names, []string{"wof:name", "wof:id", "geom:latitude", "mz:is_current"}) {
+		t.Fatalf("Unexpected columns %v", h.column_names)
+	}
+
+	for name, column_type := range expected_columns {
+
+		if h.columns[name] != column_type {
+			t.Fatalf("Unexpected type %d for column %s", h.columns[name], name)
+		}
+	}
+
+	num_nodes, _ := testLevelBounds(len(test_features), 2)
+
+	index := body[offset : offset+num_nodes*40]
+	features_offset := offset + num_nodes*40
+
+	// Read every feature, in order, recording its offset relative to the start of the features.
+
+	features := make(map[uint64]*testFeature)
+	names := make(map[string]bool)
+
+	for pos := features_offset; pos < len(body); {
+
+		f, err := readTestFeature(body, pos, h)
+
+		if err != nil {
+			t.Fatalf("Failed to read feature, %v", err)
+		}
+
+		features[uint64(pos-features_offset)] = f
+		names[f.properties["wof:name"].(string)] = true
+
+		pos += 4 + int(binary.LittleEndian.Uint32(body[pos:]))
+	}
+
+	if len(features) != len(test_features) {
+		t.Fatalf("Unexpected number of features %d", len(features))
+	}
+
+	if len(names) != len(test_features) {
+		t.Fatalf("Unexpected feature names %v", names)
+	}
+
+	for _, f := range features {
+
+		if f.properties["wof:name"] == "YUL" {
+
+			if _, ok := f.properties["mz:is_current"]; ok {
+				t.Fatalf("Expected missing property to be omitted")
+			}
+
+			if f.properties["wof:id"] != int64(102535149) || f.properties["geom:latitude"] != 45.468331 {
+				t.Fatalf("Unexpected properties %v", f.properties)
+			}
+		}
+
+		if f.properties["wof:name"] == "Montréal" && f.geometry_type != 6 {
+			t.Fatalf("Unexpected geometry type %d for MultiPolygon", f.geometry_type)
+		}
+	}
+
+	// The root node's bounding box is the extent of all the features.
+
+	root := make([]float64, 4)
+
+	for i := range root {
+		root[i] = math.Float64frombits(binary.LittleEndian.Uint64(index[i*8:]))
+	}
+
+	if !slices.Equal(root, h.envelope) {
+		t.Fatalf("Root node %v does not match envelope %v", root, h.envelope)
+	}
+
+	queries := [][]float64{
+		{-123.0, 37.0, -122.0, 38.0},
+		{-74.0, 40.5, -73.7, 40.7},
+		{-73.55, 45.3, -73.5, 45.32},
+		{-100.0, 30.0, -90.0, 35.0},
+		{-180.0, -90.0, 180.0, 90.0},
+	}
+
+	for _, q := range queries {
+
+		found := searchTestIndex(index, len(test_features), 2, q)
+		slices.Sort(found)
+
+		expected := make([]uint64, 0)
+
+		for offset, f := range features {
+
+			if q[2] < f.bbox[0] || q[3] < f.bbox[1] || q[0] > f.bbox[2] || q[1] > f.bbox[3] {
+				continue
+			}
+
+			expected = append(expected, offset)
+		}
+
+		slices.Sort(expected)
+
+		if !slices.Equal(found, expected) {
+			t.Fatalf("Search for %v returned %v, expected %v", q, found, expected)
+		}
+	}
+}
+
+// TestBuilderWithoutIndex ensures that features immediately follow the header when the index is disabled.
+func TestBuilderWithoutIndex(t *testing.T) {
+
+	body := buildTestFile(t, 0)
+
+	h, offset, err := readTestHeader(body)
+
+	if err != nil {
+		t.Fatalf("Failed to read header, %v", err)
+	}
+
+	if h.index_node_size != 0 {
+		t.Fatalf("Unexpected index node size %d", h.index_node_size)
+	}
+
+	count := 0
+
+	for pos := offset; pos < len(body); count++ {
+
+		f, err := readTestFeature(body, pos, h)
+
+		if err != nil {
+			t.Fatalf("Failed to read feature, %v", err)
+		}
+
+		// Without an index features are written in the order they were added.
+
+		expected := []string{"SFO", "OAK", "JFK", "YUL", "San Francisco", "Montréal", "New York"}[count]
+
+		if f.properties["wof:name"] != expected {
+			t.Fatalf("Unexpected feature %v at position %d, expected %s", f.properties["wof:name"], count, expected)
+		}
+
+		pos += 4 + int(binary.LittleEndian.Uint32(body[pos:]))
+	}
+
+	if uint64(count) != h.features_count {
+		t.Fatalf("Read %d features, expected %d", count, h.features_count)
+	}
+}
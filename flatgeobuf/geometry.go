@@ -0,0 +1,152 @@
+package flatgeobuf
+
+import (
+	"fmt"
+
+	"github.com/paulmach/orb"
+)
+
+// GeometryType is a FlatGeobuf geometry type.
+type GeometryType uint8
+
+const (
+	GEOMETRY_UNKNOWN GeometryType = iota
+	GEOMETRY_POINT
+	GEOMETRY_LINESTRING
+	GEOMETRY_POLYGON
+	GEOMETRY_MULTIPOINT
+	GEOMETRY_MULTILINESTRING
+	GEOMETRY_MULTIPOLYGON
+	GEOMETRY_GEOMETRYCOLLECTION
+)
+
+// Field ids for the FlatGeobuf `Geometry` table.
+const (
+	geometry_ends  = 0
+	geometry_xy    = 1
+	geometry_type  = 6
+	geometry_parts = 7
+	geometry_len   = 8
+)
+
+func geometryType(geom orb.Geometry) (GeometryType, error) {
+
+	switch geom.(type) {
+	case orb.Point:
+		return GEOMETRY_POINT, nil
+	case orb.LineString:
+		return GEOMETRY_LINESTRING, nil
+	case orb.Polygon:
+		return GEOMETRY_POLYGON, nil
+	case orb.MultiPoint:
+		return GEOMETRY_MULTIPOINT, nil
+	case orb.MultiLineString:
+		return GEOMETRY_MULTILINESTRING, nil
+	case orb.MultiPolygon:
+		return GEOMETRY_MULTIPOLYGON, nil
+	case orb.Collection:
+		return GEOMETRY_GEOMETRYCOLLECTION, nil
+	default:
+		return GEOMETRY_UNKNOWN, fmt.Errorf("Unsupported geometry type %s", geom.GeoJSONType())
+	}
+}
+
+// encodeGeometry returns the FlatGeobuf `Geometry` table for 'geom'.
+func encodeGeometry(geom orb.Geometry) (fbTable, error) {
+
+	geom_type, err := geometryType(geom)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := make(fbTable, geometry_len)
+	t[geometry_type] = fbUint8(geom_type)
+
+	switch g := geom.(type) {
+	case orb.Point:
+		t[geometry_xy] = fbFloat64s{g.X(), g.Y()}
+	case orb.LineString:
+		t[geometry_xy] = flattenPoints(nil, g)
+	case orb.MultiPoint:
+		t[geometry_xy] = flattenPoints(nil, g)
+	case orb.Polygon:
+		encodeParts(t, ringsAsLines(g))
+	case orb.MultiLineString:
+		encodeParts(t, g)
+	case orb.MultiPolygon:
+
+		parts := make(fbTables, len(g))
+
+		for idx, poly := range g {
+
+			part, err := encodeGeometry(poly)
+
+			if err != nil {
+				return nil, err
+			}
+
+			parts[idx] = part
+		}
+
+		t[geometry_parts] = parts
+
+	case orb.Collection:
+
+		parts := make(fbTables, len(g))
+
+		for idx, child := range g {
+
+			part, err := encodeGeometry(child)
+
+			if err != nil {
+				return nil, err
+			}
+
+			parts[idx] = part
+		}
+
+		t[geometry_parts] = parts
+	}
+
+	return t, nil
+}
+
+// encodeParts assigns the coordinates of 'lines' to the `xy` field of 't' and, if there is more than
+// one line, the index of the (exclusive) end point of each line to the `ends` field.
+func encodeParts(t fbTable, lines []orb.LineString) {
+
+	xy := make(fbFloat64s, 0)
+	ends := make(fbUint32s, 0)
+
+	for _, ls := range lines {
+		xy = flattenPoints(xy, ls)
+		ends = append(ends, uint32(len(xy)/2))
+	}
+
+	t[geometry_xy] = xy
+
+	if len(ends) > 1 {
+		t[geometry_ends] = ends
+	}
+}
+
+func ringsAsLines(poly orb.Polygon) []orb.LineString {
+
+	lines := make([]orb.LineString, len(poly))
+
+	for idx, r := range poly {
+		lines[idx] = orb.LineString(r)
+	}
+
+	return lines
+}
+
+func flattenPoints[P ~[]orb.Point](xy fbFloat64s, points P) fbFloat64s {
+
+	for _, pt := range points {
+		xy = append(xy, pt.X(), pt.Y())
+	}
+
+	return xy
+}
@@ -0,0 +1,221 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+)
+
+// DEFAULT_INDEX_NODE_SIZE is the default number of items per node in the packed Hilbert R-tree index.
+const DEFAULT_INDEX_NODE_SIZE uint16 = 16
+
+const hilbert_max float64 = float64((1 << 16) - 1)
+
+// nodeItem is a single node in a packed Hilbert R-tree. For leaf nodes 'offset' is the byte offset of
+// the feature in the features section; for all other nodes it is the index of the node's first child.
+type nodeItem struct {
+	minX   float64
+	minY   float64
+	maxX   float64
+	maxY   float64
+	offset uint64
+}
+
+func newNodeItem(offset uint64) nodeItem {
+
+	n := nodeItem{
+		minX:   math.Inf(1),
+		minY:   math.Inf(1),
+		maxX:   math.Inf(-1),
+		maxY:   math.Inf(-1),
+		offset: offset,
+	}
+
+	return n
+}
+
+func (n *nodeItem) expand(other nodeItem) {
+	n.minX = math.Min(n.minX, other.minX)
+	n.minY = math.Min(n.minY, other.minY)
+	n.maxX = math.Max(n.maxX, other.maxX)
+	n.maxY = math.Max(n.maxY, other.maxY)
+}
+
+// levelBounds returns the [start, end) node indices of each level of a packed R-tree containing
+// 'num_items' leaves, from the leaves (first) up to the root (last), along with the total number of nodes.
+func levelBounds(num_items uint64, node_size uint16) ([][2]uint64, uint64) {
+
+	n := num_items
+	num_nodes := n
+
+	level_num_nodes := []uint64{n}
+
+	for {
+		n = (n + uint64(node_size) - 1) / uint64(node_size)
+		num_nodes += n
+		level_num_nodes = append(level_num_nodes, n)
+
+		if n == 1 {
+			break
+		}
+	}
+
+	bounds := make([][2]uint64, len(level_num_nodes))
+	n = num_nodes
+
+	for idx, size := range level_num_nodes {
+		bounds[idx] = [2]uint64{n - size, n}
+		n -= size
+	}
+
+	return bounds, num_nodes
+}
+
+// hilbertSort sorts 'features' by the Hilbert value of the centre of their bounding boxes relative to 'extent'.
+func hilbertSort(features []*feature, extent orb.Bound) {
+
+	width := extent.Max.X() - extent.Min.X()
+	height := extent.Max.Y() - extent.Min.Y()
+
+	values := make(map[*feature]uint32, len(features))
+
+	for _, f := range features {
+
+		x := uint32(0)
+		y := uint32(0)
+
+		if width > 0 {
+			cx := (f.bound.Min.X() + f.bound.Max.X()) / 2
+			x = uint32(math.Floor(hilbert_max * (cx - extent.Min.X()) / width))
+		}
+
+		if height > 0 {
+			cy := (f.bound.Min.Y() + f.bound.Max.Y()) / 2
+			y = uint32(math.Floor(hilbert_max * (cy - extent.Min.Y()) / height))
+		}
+
+		values[f] = hilbert(x, y)
+	}
+
+	sort.SliceStable(features, func(i, j int) bool {
+		return values[features[i]] > values[features[j]]
+	})
+}
+
+// writeIndex writes a packed Hilbert R-tree for 'features', which are assumed to have been sorted
+// by `hilbertSort`, whose encoded sizes are 'sizes'.
+func writeIndex(wr io.Writer, features []*feature, sizes []int, node_size uint16) error {
+
+	bounds, num_nodes := levelBounds(uint64(len(features)), node_size)
+	nodes := make([]nodeItem, num_nodes)
+
+	leaf_start := bounds[0][0]
+	offset := uint64(0)
+
+	for idx, f := range features {
+
+		n := nodeItem{
+			minX:   f.bound.Min.X(),
+			minY:   f.bound.Min.Y(),
+			maxX:   f.bound.Max.X(),
+			maxY:   f.bound.Max.Y(),
+			offset: offset,
+		}
+
+		nodes[leaf_start+uint64(idx)] = n
+		offset += uint64(sizes[idx])
+	}
+
+	for i := 0; i < len(bounds)-1; i++ {
+
+		pos := bounds[i][0]
+		end := bounds[i][1]
+		new_pos := bounds[i+1][0]
+
+		for pos < end {
+
+			n := newNodeItem(pos)
+
+			for j := uint16(0); j < node_size && pos < end; j++ {
+				n.expand(nodes[pos])
+				pos += 1
+			}
+
+			nodes[new_pos] = n
+			new_pos += 1
+		}
+	}
+
+	buf := make([]byte, 40)
+
+	for _, n := range nodes {
+
+		binary.LittleEndian.PutUint64(buf[0:], math.Float64bits(n.minX))
+		binary.LittleEndian.PutUint64(buf[8:], math.Float64bits(n.minY))
+		binary.LittleEndian.PutUint64(buf[16:], math.Float64bits(n.maxX))
+		binary.LittleEndian.PutUint64(buf[24:], math.Float64bits(n.maxY))
+		binary.LittleEndian.PutUint64(buf[32:], n.offset)
+
+		_, err := wr.Write(buf)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hilbert returns the position of (x, y) along a Hilbert curve in a 2^16 x 2^16 grid.
+func hilbert(x uint32, y uint32) uint32 {
+
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
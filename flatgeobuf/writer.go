@@ -0,0 +1,193 @@
+package flatgeobuf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// FlatGeobufWriter implements the `whosonfirst/go-writer.Writer` interface for writing WOF records
+// to a single FlatGeobuf file. Records are accumulated in memory and the file is written, using
+// another `writer.Writer` instance, when the `Close` method is invoked.
+type FlatGeobufWriter struct {
+	writer.Writer
+	writer   writer.Writer
+	filename string
+	builder  *Builder
+	closed   bool
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := writer.RegisterWriter(ctx, "flatgeobuf", NewFlatGeobufWriter)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewFlatGeobufWriter returns a new `FlatGeobufWriter` instance configured by 'uri' in the form of:
+//
+//	flatgeobuf://?writer={WRITER_URI}&filename={FILENAME}&property={PROPERTY}&name={NAME}&index-node-size={SIZE}
+//
+// Where:
+// * {WRITER_URI} is a valid `whosonfirst/go-writer.Writer` URI used to write the final FlatGeobuf file. Required.
+// * {FILENAME} is the key (filename) used to write the final FlatGeobuf file with {WRITER_URI}. Required.
+// * {PROPERTY} is a (relative) property path to store as a typed column. May be passed multiple times.
+// * {NAME} is the name of the layer in the FlatGeobuf file. Default is "whosonfirst".
+// * {SIZE} is the number of items per node in the spatial index, or 0 to disable the index. Default is 16.
+func NewFlatGeobufWriter(ctx context.Context, uri string) (writer.Writer, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	wr_uri := q.Get("writer")
+
+	if wr_uri == "" {
+		return nil, fmt.Errorf("Missing ?writer= parameter")
+	}
+
+	filename := q.Get("filename")
+
+	if filename == "" {
+		return nil, fmt.Errorf("Missing ?filename= parameter")
+	}
+
+	wr, err := writer.NewWriter(ctx, wr_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create writer for '%s', %w", wr_uri, err)
+	}
+
+	opts, err := BuilderOptionsFromQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFlatGeobufWriterWithWriter(ctx, wr, filename, opts)
+}
+
+// NewFlatGeobufWriterWithWriter returns a new `FlatGeobufWriter` instance that will write its
+// output to 'wr', using the key 'filename', and 'opts'.
+func NewFlatGeobufWriterWithWriter(ctx context.Context, wr writer.Writer, filename string, opts *BuilderOptions) (writer.Writer, error) {
+
+	if filename == "" {
+		return nil, fmt.Errorf("Missing filename")
+	}
+
+	b, err := NewBuilder(ctx, opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create builder, %w", err)
+	}
+
+	fgb := &FlatGeobufWriter{
+		writer:   wr,
+		filename: filename,
+		builder:  b,
+	}
+
+	return fgb, nil
+}
+
+// BuilderOptionsFromQuery returns a `BuilderOptions` instance derived from the "property", "name"
+// and "index-node-size" parameters in 'q'.
+func BuilderOptionsFromQuery(q url.Values) (*BuilderOptions, error) {
+
+	opts := DefaultBuilderOptions()
+
+	if q.Has("property") {
+		opts.Properties = q["property"]
+	}
+
+	if q.Has("name") {
+		opts.Name = q.Get("name")
+	}
+
+	if q.Has("index-node-size") {
+
+		sz, err := strconv.ParseUint(q.Get("index-node-size"), 10, 16)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?index-node-size= parameter, %w", err)
+		}
+
+		opts.IndexNodeSize = uint16(sz)
+	}
+
+	return opts, nil
+}
+
+// Write adds the record contained in 'fh' to the FlatGeobuf file. Nothing is written until the `Close`
+// method is invoked.
+func (fgb *FlatGeobufWriter) Write(ctx context.Context, key string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read filehandle, %w", err)
+	}
+
+	err = fgb.builder.AddFeature(ctx, body)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to add feature, %w", err)
+	}
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns 'str_uri' unchanged.
+func (fgb *FlatGeobufWriter) WriterURI(ctx context.Context, str_uri string) string {
+	return str_uri
+}
+
+// Flush is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (fgb *FlatGeobufWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close writes the FlatGeobuf file, for all the records written so far, to the underlying writer.
+func (fgb *FlatGeobufWriter) Close(ctx context.Context) error {
+
+	if fgb.closed {
+		return fmt.Errorf("FlatGeobuf writer has already been closed")
+	}
+
+	var buf bytes.Buffer
+
+	err := fgb.builder.Write(ctx, &buf)
+
+	if err != nil {
+		return fmt.Errorf("Failed to build FlatGeobuf file, %w", err)
+	}
+
+	_, err = fgb.writer.Write(ctx, fgb.filename, bytes.NewReader(buf.Bytes()))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write FlatGeobuf file %s, %w", fgb.filename, err)
+	}
+
+	fgb.closed = true
+
+	return fgb.writer.Close(ctx)
+}
+
+// SetLogger is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (fgb *FlatGeobufWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package geopackage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkb"
+)
+
+// GPKG_MAGIC are the first two bytes of every GeoPackage binary geometry.
+var GPKG_MAGIC = []byte("GP")
+
+const gpkg_flag_little_endian uint8 = 0x01
+
+const gpkg_flag_envelope_xy uint8 = 0x02
+
+const gpkg_flag_empty uint8 = 0x10
+
+const gpkg_flag_envelope_mask uint8 = 0x0E
+
+// EncodeGeometry returns 'geom' encoded as a GeoPackage binary geometry: a header containing the spatial
+// reference system id 'srs_id' and the (x, y) envelope of the geometry followed by its WKB encoding.
+func EncodeGeometry(geom orb.Geometry, srs_id int32) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	buf.Write(GPKG_MAGIC)
+	buf.WriteByte(0) // version 1
+
+	buf.WriteByte(gpkg_flag_little_endian | gpkg_flag_envelope_xy)
+	binary.Write(&buf, binary.LittleEndian, srs_id)
+
+	bound := geom.Bound()
+
+	for _, v := range []float64{bound.Min.X(), bound.Max.X(), bound.Min.Y(), bound.Max.Y()} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	wkb_b, err := wkb.Marshal(geom, binary.LittleEndian)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal geometry as WKB, %w", err)
+	}
+
+	buf.Write(wkb_b)
+	return buf.Bytes(), nil
+}
+
+// DecodeGeometry decodes the GeoPackage binary geometry 'body'. Empty geometries are returned as nil.
+func DecodeGeometry(body []byte) (orb.Geometry, error) {
+
+	if len(body) < 8 || !bytes.Equal(body[0:2], GPKG_MAGIC) {
+		return nil, fmt.Errorf("Invalid GeoPackage geometry header")
+	}
+
+	flags := body[3]
+
+	if flags&gpkg_flag_empty != 0 {
+		return nil, nil
+	}
+
+	var envelope_size int
+
+	switch (flags & gpkg_flag_envelope_mask) >> 1 {
+	case 0:
+		envelope_size = 0
+	case 1:
+		envelope_size = 32
+	case 2, 3:
+		envelope_size = 48
+	case 4:
+		envelope_size = 64
+	default:
+		return nil, fmt.Errorf("Invalid GeoPackage envelope indicator")
+	}
+
+	offset := 8 + envelope_size
+
+	if len(body) < offset {
+		return nil, fmt.Errorf("Invalid GeoPackage geometry, too short")
+	}
+
+	geom, err := wkb.Unmarshal(body[offset:])
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal WKB geometry, %w", err)
+	}
+
+	return geom, nil
+}
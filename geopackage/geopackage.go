@@ -0,0 +1,550 @@
+// Package geopackage provides methods for writing WOF records to, and reading GeoJSON Features from,
+// OGC GeoPackage files.
+package geopackage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/tidwall/gjson"
+)
+
+// DEFAULT_NAME is the default name of the features table (layer) in a GeoPackage file.
+const DEFAULT_NAME string = "whosonfirst"
+
+// GEOMETRY_COLUMN is the name of the geometry column in GeoPackage files produced by this package.
+const GEOMETRY_COLUMN string = "geom"
+
+// PRIMARY_KEY_COLUMN is the name of the primary key column in GeoPackage files produced by this package.
+const PRIMARY_KEY_COLUMN string = "fid"
+
+// Column types for properties stored in a GeoPackage features table.
+const (
+	COLUMN_INTEGER = "INTEGER"
+	COLUMN_DOUBLE  = "DOUBLE"
+	COLUMN_BOOLEAN = "BOOLEAN"
+	COLUMN_TEXT    = "TEXT"
+	// COLUMN_JSON is not a GeoPackage type. JSON columns are stored as TEXT columns and flagged with
+	// the `MIMETYPE_JSON` MIME type in the `gpkg_data_columns` table.
+	COLUMN_JSON = "JSON"
+)
+
+// BuilderOptions defines configuration options for a `Builder` instance.
+type BuilderOptions struct {
+	// The name of the features table (layer) in the GeoPackage file.
+	Name string
+	// The list of (relative) property paths to store as typed columns. For example "wof:name".
+	Properties []string
+}
+
+type feature struct {
+	geometry   orb.Geometry
+	properties []gjson.Result
+}
+
+type statement struct {
+	query string
+	args  []interface{}
+}
+
+type column struct {
+	name string
+	kind string
+}
+
+// Builder accumulates WOF records in memory and writes them as a GeoPackage file. Column types are derived
+// from the property values of all the records so nothing is written until the `Write` method is invoked.
+type Builder struct {
+	options  *BuilderOptions
+	features []*feature
+	mu       *sync.Mutex
+}
+
+// DefaultBuilderOptions returns a `BuilderOptions` instance with a default table name and the "wof:id" and "wof:name"
+// properties, which are needed to merge the features in the GeoPackage file back in to their WOF records.
+func DefaultBuilderOptions() *BuilderOptions {
+
+	opts := &BuilderOptions{
+		Name:       DEFAULT_NAME,
+		Properties: []string{"wof:id", "wof:name"},
+	}
+
+	return opts
+}
+
+// BuilderOptionsFromQuery returns a `BuilderOptions` instance derived from the "property" and "name" parameters in 'q'.
+func BuilderOptionsFromQuery(q url.Values) (*BuilderOptions, error) {
+
+	opts := DefaultBuilderOptions()
+
+	if q.Has("property") {
+		opts.Properties = q["property"]
+	}
+
+	if q.Has("name") {
+		opts.Name = q.Get("name")
+	}
+
+	return opts, nil
+}
+
+// NewBuilder returns a new `Builder` instance configured by 'opts'.
+func NewBuilder(ctx context.Context, opts *BuilderOptions) (*Builder, error) {
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("Missing table name")
+	}
+
+	for _, p := range opts.Properties {
+
+		if p == GEOMETRY_COLUMN || p == PRIMARY_KEY_COLUMN {
+			return nil, fmt.Errorf("Property '%s' conflicts with a reserved column name", p)
+		}
+	}
+
+	b := &Builder{
+		options:  opts,
+		features: make([]*feature, 0),
+		mu:       new(sync.Mutex),
+	}
+
+	return b, nil
+}
+
+// AddFeature adds the GeoJSON Feature 'body' to the list of features to be written.
+func (b *Builder) AddFeature(ctx context.Context, body []byte) error {
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal feature, %w", err)
+	}
+
+	if f.Geometry == nil {
+		return fmt.Errorf("Feature is missing a geometry")
+	}
+
+	properties := make([]gjson.Result, len(b.options.Properties))
+
+	for idx, path := range b.options.Properties {
+		properties[idx] = gjson.GetBytes(body, "properties."+gjson.Escape(path))
+	}
+
+	gpkg_f := &feature{
+		geometry:   f.Geometry,
+		properties: properties,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.features = append(b.features, gpkg_f)
+	return nil
+}
+
+// Write writes all the features added so far to 'wr' as a GeoPackage file. Since GeoPackage files are
+// SQLite databases the file is first written to a temporary location and then copied to 'wr'.
+func (b *Builder) Write(ctx context.Context, wr io.Writer) error {
+
+	tmp, err := os.CreateTemp("", "exportify-*.gpkg")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	tmp_path := tmp.Name()
+	tmp.Close()
+
+	defer os.Remove(tmp_path)
+
+	err = b.WriteFile(ctx, tmp_path)
+
+	if err != nil {
+		return err
+	}
+
+	fh, err := os.Open(tmp_path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s, %w", tmp_path, err)
+	}
+
+	defer fh.Close()
+
+	_, err = io.Copy(wr, fh)
+
+	if err != nil {
+		return fmt.Errorf("Failed to copy GeoPackage file, %w", err)
+	}
+
+	return nil
+}
+
+// WriteFile writes all the features added so far to a new GeoPackage file at 'path'. If 'path'
+// already exists it will be replaced.
+func (b *Builder) WriteFile(ctx context.Context, path string) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := os.Remove(path)
+
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to remove %s, %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create transaction, %w", err)
+	}
+
+	err = b.write(ctx, tx)
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		return fmt.Errorf("Failed to commit transaction, %w", err)
+	}
+
+	return nil
+}
+
+func (b *Builder) write(ctx context.Context, tx *sql.Tx) error {
+
+	pragmas := []string{
+		fmt.Sprintf("PRAGMA application_id = %d", GPKG_APPLICATION_ID),
+		fmt.Sprintf("PRAGMA user_version = %d", GPKG_USER_VERSION),
+	}
+
+	for _, q := range append(pragmas, schema...) {
+
+		_, err := tx.ExecContext(ctx, q)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create GeoPackage schema, %w", err)
+		}
+	}
+
+	table := b.options.Name
+	rtree := fmt.Sprintf("rtree_%s_%s", table, GEOMETRY_COLUMN)
+
+	columns := inferColumns(b.options.Properties, b.features)
+
+	var extent orb.Bound
+	geom_type := ""
+
+	for idx, f := range b.features {
+
+		t := strings.ToUpper(f.geometry.GeoJSONType())
+
+		if idx == 0 {
+			extent = f.geometry.Bound()
+			geom_type = t
+			continue
+		}
+
+		extent = extent.Union(f.geometry.Bound())
+
+		if t != geom_type {
+			geom_type = "GEOMETRY"
+		}
+	}
+
+	if geom_type == "" {
+		geom_type = "GEOMETRY"
+	}
+
+	defs := []string{
+		fmt.Sprintf("%s INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL", quote(PRIMARY_KEY_COLUMN)),
+		fmt.Sprintf("%s %s", quote(GEOMETRY_COLUMN), geom_type),
+	}
+
+	for _, c := range columns {
+
+		kind := c.kind
+
+		if kind == COLUMN_JSON {
+			kind = COLUMN_TEXT
+		}
+
+		defs = append(defs, fmt.Sprintf("%s %s", quote(c.name), kind))
+	}
+
+	q_create := fmt.Sprintf("CREATE TABLE %s (%s)", quote(table), strings.Join(defs, ", "))
+
+	_, err := tx.ExecContext(ctx, q_create)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create %s table, %w", table, err)
+	}
+
+	q_rtree := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING rtree(id, minx, maxx, miny, maxy)", quote(rtree))
+
+	_, err = tx.ExecContext(ctx, q_rtree)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create %s table, %w", rtree, err)
+	}
+
+	var min_x, min_y, max_x, max_y interface{}
+
+	if len(b.features) > 0 {
+		min_x = extent.Min.X()
+		min_y = extent.Min.Y()
+		max_x = extent.Max.X()
+		max_y = extent.Max.Y()
+	}
+
+	metadata := []*statement{
+		{
+			"INSERT INTO gpkg_contents (table_name, data_type, identifier, min_x, min_y, max_x, max_y, srs_id) VALUES (?, 'features', ?, ?, ?, ?, ?, ?)",
+			[]interface{}{table, table, min_x, min_y, max_x, max_y, SRS_ID},
+		},
+		{
+			"INSERT INTO gpkg_geometry_columns (table_name, column_name, geometry_type_name, srs_id, z, m) VALUES (?, ?, ?, ?, 0, 0)",
+			[]interface{}{table, GEOMETRY_COLUMN, geom_type, SRS_ID},
+		},
+		{
+			"INSERT INTO gpkg_extensions (table_name, column_name, extension_name, definition, scope) VALUES (?, ?, 'gpkg_rtree_index', 'http://www.geopackage.org/spec120/#extension_rtree', 'write-only')",
+			[]interface{}{table, GEOMETRY_COLUMN},
+		},
+	}
+
+	for _, c := range columns {
+
+		if c.kind != COLUMN_JSON {
+			continue
+		}
+
+		metadata = append(metadata, &statement{
+			"INSERT INTO gpkg_data_columns (table_name, column_name, name, mime_type) VALUES (?, ?, ?, ?)",
+			[]interface{}{table, c.name, c.name, MIMETYPE_JSON},
+		})
+	}
+
+	for _, m := range metadata {
+
+		_, err := tx.ExecContext(ctx, m.query, m.args...)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write GeoPackage metadata, %w", err)
+		}
+	}
+
+	col_names := []string{
+		quote(GEOMETRY_COLUMN),
+	}
+
+	placeholders := []string{
+		"?",
+	}
+
+	for _, c := range columns {
+		col_names = append(col_names, quote(c.name))
+		placeholders = append(placeholders, "?")
+	}
+
+	q_insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(table), strings.Join(col_names, ", "), strings.Join(placeholders, ", "))
+
+	insert_stmt, err := tx.PrepareContext(ctx, q_insert)
+
+	if err != nil {
+		return fmt.Errorf("Failed to prepare insert statement, %w", err)
+	}
+
+	defer insert_stmt.Close()
+
+	q_index := fmt.Sprintf("INSERT INTO %s (id, minx, maxx, miny, maxy) VALUES (?, ?, ?, ?, ?)", quote(rtree))
+
+	index_stmt, err := tx.PrepareContext(ctx, q_index)
+
+	if err != nil {
+		return fmt.Errorf("Failed to prepare index statement, %w", err)
+	}
+
+	defer index_stmt.Close()
+
+	for _, f := range b.features {
+
+		geom, err := EncodeGeometry(f.geometry, SRS_ID)
+
+		if err != nil {
+			return err
+		}
+
+		args := []interface{}{
+			geom,
+		}
+
+		for idx, c := range columns {
+			args = append(args, columnValue(c, f.properties[idx]))
+		}
+
+		rsp, err := insert_stmt.ExecContext(ctx, args...)
+
+		if err != nil {
+			return fmt.Errorf("Failed to insert feature, %w", err)
+		}
+
+		fid, err := rsp.LastInsertId()
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive feature ID, %w", err)
+		}
+
+		bound := f.geometry.Bound()
+
+		_, err = index_stmt.ExecContext(ctx, fid, bound.Min.X(), bound.Max.X(), bound.Min.Y(), bound.Max.Y())
+
+		if err != nil {
+			return fmt.Errorf("Failed to index feature, %w", err)
+		}
+	}
+
+	return nil
+}
+
+// inferColumns returns the list of columns for 'properties' with types derived from the property
+// values in 'features'. Mixed integer and floating point values are stored as DOUBLE columns, mixed
+// values containing objects or arrays as JSON columns and all other mixed values as TEXT columns.
+func inferColumns(properties []string, features []*feature) []*column {
+
+	columns := make([]*column, len(properties))
+
+	for idx, path := range properties {
+
+		c := &column{
+			name: path,
+		}
+
+		for _, f := range features {
+
+			v := f.properties[idx]
+
+			if !v.Exists() || v.Type == gjson.Null {
+				continue
+			}
+
+			kind := valueKind(v)
+
+			switch {
+			case c.kind == "":
+				c.kind = kind
+			case c.kind == kind:
+				// pass
+			case isNumeric(c.kind) && isNumeric(kind):
+				c.kind = COLUMN_DOUBLE
+			case c.kind == COLUMN_JSON || kind == COLUMN_JSON:
+				c.kind = COLUMN_JSON
+			default:
+				c.kind = COLUMN_TEXT
+			}
+		}
+
+		if c.kind == "" {
+			c.kind = COLUMN_TEXT
+		}
+
+		columns[idx] = c
+	}
+
+	return columns
+}
+
+func valueKind(v gjson.Result) string {
+
+	switch v.Type {
+	case gjson.True, gjson.False:
+		return COLUMN_BOOLEAN
+	case gjson.Number:
+
+		if !strings.ContainsAny(v.Raw, ".eE") {
+
+			_, err := strconv.ParseInt(v.Raw, 10, 64)
+
+			if err == nil {
+				return COLUMN_INTEGER
+			}
+		}
+
+		return COLUMN_DOUBLE
+
+	case gjson.String:
+		return COLUMN_TEXT
+	default:
+		return COLUMN_JSON
+	}
+}
+
+func isNumeric(kind string) bool {
+	return kind == COLUMN_INTEGER || kind == COLUMN_DOUBLE
+}
+
+func columnValue(c *column, v gjson.Result) interface{} {
+
+	if !v.Exists() || v.Type == gjson.Null {
+		return nil
+	}
+
+	switch c.kind {
+	case COLUMN_BOOLEAN:
+		return v.Bool()
+	case COLUMN_INTEGER:
+		return v.Int()
+	case COLUMN_DOUBLE:
+		return v.Float()
+	case COLUMN_JSON:
+		return compact(v.Raw)
+	default:
+
+		if v.IsArray() || v.IsObject() {
+			return compact(v.Raw)
+		}
+
+		return v.String()
+	}
+}
+
+// compact returns the compacted form of the raw JSON value 'raw'.
+func compact(raw string) string {
+
+	var buf bytes.Buffer
+
+	err := json.Compact(&buf, []byte(raw))
+
+	if err != nil {
+		return raw
+	}
+
+	return buf.String()
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,291 @@
+package geopackage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// ReadFeatures reads all the rows in the features table (layer) named 'layer' in the GeoPackage file
+// at 'path' and returns them as GeoJSON Features. If 'layer' is empty and the GeoPackage file contains
+// a single features table that table is read. Each (non-geometry, non-primary key) column is assigned
+// to a property with the same name. BOOLEAN columns are returned as booleans and TEXT columns flagged
+// with the `MIMETYPE_JSON` MIME type in the `gpkg_data_columns` table are returned as JSON values.
+func ReadFeatures(ctx context.Context, path string, layer string) ([][]byte, error) {
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer db.Close()
+
+	if layer == "" {
+
+		layer, err = defaultLayer(ctx, db)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var geom_col string
+
+	row := db.QueryRowContext(ctx, "SELECT column_name FROM gpkg_geometry_columns WHERE table_name = ?", layer)
+	err = row.Scan(&geom_col)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive geometry column for '%s', %w", layer, err)
+	}
+
+	json_cols, err := jsonColumns(ctx, db, layer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pk_col, bool_cols, err := tableInfo(ctx, db, layer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", quote(layer)))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query '%s', %w", layer, err)
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive columns, %w", err)
+	}
+
+	features := make([][]byte, 0)
+
+	for rows.Next() {
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+
+		for idx := range values {
+			pointers[idx] = &values[idx]
+		}
+
+		err := rows.Scan(pointers...)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan row, %w", err)
+		}
+
+		f := geojson.NewFeature(nil)
+
+		for idx, name := range columns {
+
+			v := values[idx]
+
+			if v == nil || name == pk_col {
+				continue
+			}
+
+			if name == geom_col {
+
+				blob, ok := v.([]byte)
+
+				if !ok {
+					return nil, fmt.Errorf("Invalid geometry value")
+				}
+
+				geom, err := DecodeGeometry(blob)
+
+				if err != nil {
+					return nil, fmt.Errorf("Failed to decode geometry, %w", err)
+				}
+
+				f.Geometry = geom
+				continue
+			}
+
+			switch t := v.(type) {
+			case []byte:
+				v = string(t)
+			case time.Time:
+				v = t.Format(time.RFC3339)
+			}
+
+			if bool_cols[name] {
+
+				switch t := v.(type) {
+				case int64:
+					v = t != 0
+				case bool:
+					v = t
+				}
+			}
+
+			if json_cols[name] {
+
+				str_v, ok := v.(string)
+
+				if ok && json.Valid([]byte(str_v)) {
+					v = json.RawMessage(str_v)
+				}
+			}
+
+			f.Properties[name] = v
+		}
+
+		body, err := json.Marshal(f)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal feature, %w", err)
+		}
+
+		features = append(features, body)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to iterate rows, %w", err)
+	}
+
+	return features, nil
+}
+
+func defaultLayer(ctx context.Context, db *sql.DB) (string, error) {
+
+	rows, err := db.QueryContext(ctx, "SELECT table_name FROM gpkg_contents WHERE data_type = 'features'")
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to query features tables, %w", err)
+	}
+
+	defer rows.Close()
+
+	layers := make([]string, 0)
+
+	for rows.Next() {
+
+		var name string
+		err := rows.Scan(&name)
+
+		if err != nil {
+			return "", fmt.Errorf("Failed to scan table name, %w", err)
+		}
+
+		layers = append(layers, name)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to iterate tables, %w", err)
+	}
+
+	switch len(layers) {
+	case 0:
+		return "", fmt.Errorf("GeoPackage does not contain any features tables")
+	case 1:
+		return layers[0], nil
+	default:
+		return "", fmt.Errorf("GeoPackage contains multiple features tables (%s), a layer must be specified", strings.Join(layers, ", "))
+	}
+}
+
+// jsonColumns returns the names of the columns in 'layer' flagged as containing JSON-encoded values.
+func jsonColumns(ctx context.Context, db *sql.DB, layer string) (map[string]bool, error) {
+
+	json_cols := make(map[string]bool)
+
+	var count int
+
+	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'gpkg_data_columns'")
+	err := row.Scan(&count)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to determine whether data columns table exists, %w", err)
+	}
+
+	if count == 0 {
+		return json_cols, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT column_name FROM gpkg_data_columns WHERE table_name = ? AND mime_type = ?", layer, MIMETYPE_JSON)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query data columns, %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var name string
+		err := rows.Scan(&name)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan column name, %w", err)
+		}
+
+		json_cols[name] = true
+	}
+
+	return json_cols, rows.Err()
+}
+
+// tableInfo returns the name of the primary key column in 'layer' and the names of its BOOLEAN columns.
+func tableInfo(ctx context.Context, db *sql.DB, layer string) (string, map[string]bool, error) {
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", quote(layer)))
+
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to query table info, %w", err)
+	}
+
+	defer rows.Close()
+
+	pk_col := ""
+	bool_cols := make(map[string]bool)
+
+	for rows.Next() {
+
+		var cid int
+		var name string
+		var kind string
+		var not_null int
+		var default_value interface{}
+		var pk int
+
+		err := rows.Scan(&cid, &name, &kind, &not_null, &default_value, &pk)
+
+		if err != nil {
+			return "", nil, fmt.Errorf("Failed to scan table info, %w", err)
+		}
+
+		if pk > 0 {
+			pk_col = name
+		}
+
+		if strings.ToUpper(kind) == COLUMN_BOOLEAN {
+			bool_cols[name] = true
+		}
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to iterate table info, %w", err)
+	}
+
+	return pk_col, bool_cols, nil
+}
@@ -0,0 +1,89 @@
+package geopackage
+
+// SRS_ID is the spatial reference system id used for all the geometries in GeoPackage files produced by this package.
+const SRS_ID int32 = 4326
+
+// GPKG_APPLICATION_ID is the SQLite application id ("GPKG") for GeoPackage files.
+const GPKG_APPLICATION_ID int = 0x47504B47
+
+// GPKG_USER_VERSION is the SQLite user version for GeoPackage 1.4 files.
+const GPKG_USER_VERSION int = 10400
+
+// MIMETYPE_JSON is the MIME type recorded in the `gpkg_data_columns` table for columns that contain
+// JSON-encoded objects or arrays.
+const MIMETYPE_JSON string = "application/json"
+
+const wkt_wgs84 string = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AXIS["Latitude",NORTH],AXIS["Longitude",EAST],AUTHORITY["EPSG","4326"]]`
+
+// The core GeoPackage tables as well as the tables required by the "gpkg_schema" extension
+// which is used to flag columns containing JSON-encoded values.
+var schema = []string{
+	`CREATE TABLE gpkg_spatial_ref_sys (
+		srs_name TEXT NOT NULL,
+		srs_id INTEGER PRIMARY KEY,
+		organization TEXT NOT NULL,
+		organization_coordsys_id INTEGER NOT NULL,
+		definition TEXT NOT NULL,
+		description TEXT
+	)`,
+	`INSERT INTO gpkg_spatial_ref_sys VALUES ('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system')`,
+	`INSERT INTO gpkg_spatial_ref_sys VALUES ('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system')`,
+	`INSERT INTO gpkg_spatial_ref_sys VALUES ('WGS 84 geodetic', 4326, 'EPSG', 4326, '` + wkt_wgs84 + `', 'longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid')`,
+	`CREATE TABLE gpkg_contents (
+		table_name TEXT NOT NULL PRIMARY KEY,
+		data_type TEXT NOT NULL,
+		identifier TEXT UNIQUE,
+		description TEXT DEFAULT '',
+		last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+		min_x DOUBLE,
+		min_y DOUBLE,
+		max_x DOUBLE,
+		max_y DOUBLE,
+		srs_id INTEGER,
+		CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id)
+	)`,
+	`CREATE TABLE gpkg_geometry_columns (
+		table_name TEXT NOT NULL,
+		column_name TEXT NOT NULL,
+		geometry_type_name TEXT NOT NULL,
+		srs_id INTEGER NOT NULL,
+		z TINYINT NOT NULL,
+		m TINYINT NOT NULL,
+		CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name),
+		CONSTRAINT uk_gc_table_name UNIQUE (table_name),
+		CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+		CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id)
+	)`,
+	`CREATE TABLE gpkg_extensions (
+		table_name TEXT,
+		column_name TEXT,
+		extension_name TEXT NOT NULL,
+		definition TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		CONSTRAINT ge_tce UNIQUE (table_name, column_name, extension_name)
+	)`,
+	`CREATE TABLE gpkg_data_columns (
+		table_name TEXT NOT NULL,
+		column_name TEXT NOT NULL,
+		name TEXT,
+		title TEXT,
+		description TEXT,
+		mime_type TEXT,
+		constraint_name TEXT,
+		CONSTRAINT pk_gdc PRIMARY KEY (table_name, column_name),
+		CONSTRAINT gdc_tn UNIQUE (table_name, name)
+	)`,
+	`CREATE TABLE gpkg_data_column_constraints (
+		constraint_name TEXT NOT NULL,
+		constraint_type TEXT NOT NULL,
+		value TEXT,
+		min NUMERIC,
+		min_is_inclusive BOOLEAN,
+		max NUMERIC,
+		max_is_inclusive BOOLEAN,
+		description TEXT,
+		CONSTRAINT gdcc_ntv UNIQUE (constraint_name, constraint_type, value)
+	)`,
+	`INSERT INTO gpkg_extensions VALUES ('gpkg_data_columns', NULL, 'gpkg_schema', 'http://www.geopackage.org/spec/#extension_schema', 'read-write')`,
+	`INSERT INTO gpkg_extensions VALUES ('gpkg_data_column_constraints', NULL, 'gpkg_schema', 'http://www.geopackage.org/spec/#extension_schema', 'read-write')`,
+}
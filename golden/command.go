@@ -0,0 +1,362 @@
+package golden
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/whosonfirst/go-whosonfirst-database/sql/tables"
+	"github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-exportify/sqlite"
+)
+
+// READER_URI_PLACEHOLDER is replaced by a whosonfirst/go-reader URI in the arguments passed to `StoreCommandOperation`.
+const READER_URI_PLACEHOLDER string = "{READER_URI}"
+
+// WRITER_URI_PLACEHOLDER is replaced by a whosonfirst/go-writer URI in the arguments passed to `StoreCommandOperation`.
+const WRITER_URI_PLACEHOLDER string = "{WRITER_URI}"
+
+// ROOT_PLACEHOLDER is replaced by the path of the directory containing the records in the arguments passed to `StoreCommandOperation`.
+const ROOT_PLACEHOLDER string = "{ROOT}"
+
+// STORE_FS is the name of the store that passes commands "fs://" URIs for a directory of records.
+const STORE_FS string = "fs"
+
+// STORE_MEM is the name of the store that passes commands "mem://{NAME}?path={PATH}" URIs for a GeoJSON
+// FeatureCollection file of records.
+const STORE_MEM string = "mem"
+
+// STORE_SQLITE is the name of the store that passes commands "sqlite://" URIs for a SQLite database of records.
+const STORE_SQLITE string = "sqlite"
+
+// STORES is the list of valid store names for `StoreCommandOperation`.
+var STORES = []string{
+	STORE_FS,
+	STORE_MEM,
+	STORE_SQLITE,
+}
+
+// CommandOperation returns an `Operation` which runs the executable 'bin' with 'args' against a directory of
+// records. It is the same as calling `StoreCommandOperation` with `STORE_FS`.
+func CommandOperation(bin string, args ...string) Operation {
+	return StoreCommandOperation(STORE_FS, bin, args...)
+}
+
+// StoreCommandOperation returns an `Operation` which runs the executable 'bin' with 'args'. Because commands run
+// in their own process they can not share a `mem.Store` so the documents in the store are written to a temporary
+// directory, the command is run against that directory and then the files it contains are copied back in to the
+// store. How records (documents with a ".geojson" extension) are written is determined by 'store':
+//
+// * `STORE_FS` writes records to the temporary directory and passes commands "fs://" URIs for that directory.
+// * `STORE_MEM` writes records to a GeoJSON FeatureCollection file and passes commands "mem://{NAME}?path={PATH}" URIs for that file.
+// * `STORE_SQLITE` writes records to a SQLite database and passes commands "sqlite://" URIs for that database.
+//
+// The `READER_URI_PLACEHOLDER` and `WRITER_URI_PLACEHOLDER` strings in 'args' are replaced by those URIs and the
+// `ROOT_PLACEHOLDER` string is replaced by the path of the temporary directory. Records written to a FeatureCollection
+// file or a database are copied back in to the store at the paths derived from their IDs. Anything the command writes
+// to standard output is written to the store at `OUTPUT_PATH`.
+func StoreCommandOperation(store string, bin string, args ...string) Operation {
+
+	fn := func(ctx context.Context, store_uri string, root string) ([]byte, error) {
+
+		r := strings.NewReplacer(
+			READER_URI_PLACEHOLDER, store_uri,
+			WRITER_URI_PLACEHOLDER, store_uri,
+			ROOT_PLACEHOLDER, root,
+		)
+
+		cmd_args := make([]string, len(args))
+
+		for idx, a := range args {
+			cmd_args[idx] = r.Replace(a)
+		}
+
+		var stdout bytes.Buffer
+		var stderr bytes.Buffer
+
+		cmd := exec.CommandContext(ctx, bin, cmd_args...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to run %s, %w\n%s", bin, err, stderr.Bytes())
+		}
+
+		return stdout.Bytes(), nil
+	}
+
+	return storeOperation(store, fn)
+}
+
+// DirectoryOperation returns an `Operation` which writes the documents in the store to a temporary directory, invokes
+// 'fn' with an "fs://" URI for that directory and its path and then copies the files it contains back in to the store.
+// It is used to run code, other than tools, which depends on records being stored in local files.
+func DirectoryOperation(fn func(ctx context.Context, uri string, root string) error) Operation {
+
+	store_fn := func(ctx context.Context, store_uri string, root string) ([]byte, error) {
+		return nil, fn(ctx, store_uri, root)
+	}
+
+	return storeOperation(STORE_FS, store_fn)
+}
+
+// storeOperation returns an `Operation` which writes the documents in the store to a temporary directory, and records
+// to 'store', invokes 'fn' with the URI for 'store' and the path of the directory, and then copies the files in the
+// directory and the records in 'store' back in to the store. Any output returned by 'fn' is written to the store at
+// `OUTPUT_PATH`. See `StoreCommandOperation` for details.
+func storeOperation(store string, fn func(ctx context.Context, store_uri string, root string) ([]byte, error)) Operation {
+
+	op := func(ctx context.Context, reader_uri string, writer_uri string) error {
+
+		if !slices.Contains(STORES, store) {
+			return fmt.Errorf("Unsupported store '%s'", store)
+		}
+
+		u, err := url.Parse(writer_uri)
+
+		if err != nil {
+			return fmt.Errorf("Failed to parse writer URI, %w", err)
+		}
+
+		if u.Scheme != "mem" {
+			return fmt.Errorf("Unsupported writer URI '%s', must be a mem:// URI", writer_uri)
+		}
+
+		s := mem.GetStore(u.Host)
+
+		root, err := os.MkdirTemp("", "golden-")
+
+		if err != nil {
+			return fmt.Errorf("Failed to create temporary directory, %w", err)
+		}
+
+		defer os.RemoveAll(root)
+
+		// Records written to a FeatureCollection file or a database are kept outside of 'root' so that they
+		// are not copied back in to the store as files.
+
+		data_root, err := os.MkdirTemp("", "golden-data-")
+
+		if err != nil {
+			return fmt.Errorf("Failed to create temporary directory, %w", err)
+		}
+
+		defer os.RemoveAll(data_root)
+
+		records := mem.NewStore()
+
+		for _, path := range s.Paths() {
+
+			body, err := s.Read(path)
+
+			if err != nil {
+				return err
+			}
+
+			if store != STORE_FS && filepath.Ext(path) == ".geojson" {
+				records.Write(path, body)
+				continue
+			}
+
+			err = writeFile(filepath.Join(root, path), body)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		var store_uri string
+
+		fc_path := filepath.Join(data_root, "records.geojson")
+		db_path := filepath.Join(data_root, "records.db")
+
+		switch store {
+		case STORE_MEM:
+
+			err := records.WriteFeatureCollectionFile(fc_path)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write records, %w", err)
+			}
+
+			store_uri = fmt.Sprintf("mem://golden?path=%s", url.QueryEscape(fc_path))
+
+		case STORE_SQLITE:
+
+			err := writeDatabase(ctx, records, db_path)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write records, %w", err)
+			}
+
+			store_uri = fmt.Sprintf("sqlite://%s", db_path)
+
+		default:
+			store_uri = fmt.Sprintf("fs://%s", root)
+		}
+
+		output, err := fn(ctx, store_uri, root)
+
+		if err != nil {
+			return err
+		}
+
+		for _, path := range s.Paths() {
+			s.Remove(path)
+		}
+
+		if len(output) > 0 {
+			s.Write(OUTPUT_PATH, output)
+		}
+
+		walk_func := func(path string, d fs.DirEntry, err error) error {
+
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			rel_path, err := filepath.Rel(root, path)
+
+			if err != nil {
+				return err
+			}
+
+			body, err := os.ReadFile(path)
+
+			if err != nil {
+				return fmt.Errorf("Failed to read %s, %w", path, err)
+			}
+
+			s.Write(filepath.ToSlash(rel_path), body)
+			return nil
+		}
+
+		err = filepath.WalkDir(root, walk_func)
+
+		if err != nil {
+			return fmt.Errorf("Failed to copy files back in to store, %w", err)
+		}
+
+		switch store {
+		case STORE_MEM:
+
+			_, err := mem.LoadFeatureCollectionFile(s, fc_path)
+
+			if err != nil {
+				return fmt.Errorf("Failed to copy records back in to store, %w", err)
+			}
+
+		case STORE_SQLITE:
+
+			err := readDatabase(ctx, s, db_path)
+
+			if err != nil {
+				return fmt.Errorf("Failed to copy records back in to store, %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return op
+}
+
+// writeFile writes 'body' to 'path', creating its parent directory if necessary.
+func writeFile(path string, body []byte) error {
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create parent directory for %s, %w", path, err)
+	}
+
+	err = os.WriteFile(path, body, 0644)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// writeDatabase indexes each of the records in 's' in a new SQLite database at 'path'.
+func writeDatabase(ctx context.Context, s *mem.Store, path string) error {
+
+	d, err := sqlite.OpenDatabase(ctx, path, sqlite.DefaultDatabaseOptions())
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close(ctx)
+
+	for _, rel_path := range s.Paths() {
+
+		body, err := s.Read(rel_path)
+
+		if err != nil {
+			return err
+		}
+
+		err = d.IndexFeature(ctx, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to index %s, %w", rel_path, err)
+		}
+	}
+
+	return nil
+}
+
+// readDatabase stores each of the records in the "geojson" table of the SQLite database at 'path' in 's'.
+func readDatabase(ctx context.Context, s *mem.Store, path string) error {
+
+	db, err := sql.Open("sqlite3", path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT body FROM %s", tables.GEOJSON_TABLE_NAME))
+
+	if err != nil {
+		return fmt.Errorf("Failed to query %s, %w", path, err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var body string
+
+		err := rows.Scan(&body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to scan row, %w", err)
+		}
+
+		_, err = s.WriteFeature([]byte(body))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,494 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// FLATGEOBUF_MAGIC is the string that every FlatGeobuf (version 3) file starts with.
+const FLATGEOBUF_MAGIC string = "fgb\x03fgb"
+
+// The names of the FlatGeobuf geometry types, indexed by their value in the FlatGeobuf schema.
+var fgb_geometry_types = []string{
+	"Unknown",
+	"Point",
+	"LineString",
+	"Polygon",
+	"MultiPoint",
+	"MultiLineString",
+	"MultiPolygon",
+	"GeometryCollection",
+}
+
+// The names of the FlatGeobuf column types, indexed by their value in the FlatGeobuf schema.
+var fgb_column_types = []string{
+	"Byte",
+	"UByte",
+	"Bool",
+	"Short",
+	"UShort",
+	"Int",
+	"UInt",
+	"Long",
+	"ULong",
+	"Float",
+	"Double",
+	"String",
+	"Json",
+	"DateTime",
+	"Binary",
+}
+
+// fgbTable provides access to the fields of a FlatBuffers table by their id in the FlatGeobuf schema.
+type fgbTable struct {
+	flatbuffers.Table
+}
+
+type fgbColumn struct {
+	name        string
+	column_type byte
+}
+
+func newFgbTable(buf []byte, pos flatbuffers.UOffsetT) *fgbTable {
+
+	t := &fgbTable{}
+	t.Bytes = buf
+	t.Pos = pos
+
+	return t
+}
+
+// rootFgbTable returns the root table of the FlatBuffers buffer 'buf'.
+func rootFgbTable(buf []byte) *fgbTable {
+	return newFgbTable(buf, flatbuffers.GetUOffsetT(buf))
+}
+
+func (t *fgbTable) offset(id int) flatbuffers.UOffsetT {
+	return flatbuffers.UOffsetT(t.Offset(flatbuffers.VOffsetT(4 + 2*id)))
+}
+
+func (t *fgbTable) string(id int) string {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return ""
+	}
+
+	return string(t.ByteVector(o + t.Pos))
+}
+
+func (t *fgbTable) bytes(id int) []byte {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return nil
+	}
+
+	return t.ByteVector(o + t.Pos)
+}
+
+func (t *fgbTable) uint8(id int) byte {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return 0
+	}
+
+	return t.GetByte(o + t.Pos)
+}
+
+func (t *fgbTable) uint16(id int, default_value uint16) uint16 {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return default_value
+	}
+
+	return t.GetUint16(o + t.Pos)
+}
+
+func (t *fgbTable) int32(id int) int32 {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return 0
+	}
+
+	return t.GetInt32(o + t.Pos)
+}
+
+func (t *fgbTable) uint64(id int) uint64 {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return 0
+	}
+
+	return t.GetUint64(o + t.Pos)
+}
+
+func (t *fgbTable) float64s(id int) []float64 {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return nil
+	}
+
+	v := t.Vector(o)
+	count := t.VectorLen(o)
+
+	values := make([]float64, count)
+
+	for idx := range values {
+		values[idx] = t.GetFloat64(v + flatbuffers.UOffsetT(idx*8))
+	}
+
+	return values
+}
+
+func (t *fgbTable) uint32s(id int) []uint32 {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return nil
+	}
+
+	v := t.Vector(o)
+	count := t.VectorLen(o)
+
+	values := make([]uint32, count)
+
+	for idx := range values {
+		values[idx] = t.GetUint32(v + flatbuffers.UOffsetT(idx*4))
+	}
+
+	return values
+}
+
+func (t *fgbTable) table(id int) *fgbTable {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return nil
+	}
+
+	return newFgbTable(t.Bytes, t.Indirect(o+t.Pos))
+}
+
+func (t *fgbTable) tables(id int) []*fgbTable {
+
+	o := t.offset(id)
+
+	if o == 0 {
+		return nil
+	}
+
+	v := t.Vector(o)
+	count := t.VectorLen(o)
+
+	tables := make([]*fgbTable, count)
+
+	for idx := range tables {
+		tables[idx] = newFgbTable(t.Bytes, t.Indirect(v+flatbuffers.UOffsetT(idx*4)))
+	}
+
+	return tables
+}
+
+func isFlatGeobuf(body []byte) bool {
+	return bytes.HasPrefix(body, []byte(FLATGEOBUF_MAGIC))
+}
+
+// summarizeFlatGeobuf returns a text summary of the FlatGeobuf file 'body': its header, columns, spatial index and the
+// geometry type, bounding box and properties of each feature.
+func summarizeFlatGeobuf(body []byte) ([]byte, error) {
+
+	if len(body) < 12 {
+		return nil, fmt.Errorf("File is too short")
+	}
+
+	header_len := int(binary.LittleEndian.Uint32(body[8:]))
+	offset := 12 + header_len
+
+	if offset > len(body) {
+		return nil, fmt.Errorf("Header length exceeds file length")
+	}
+
+	header := rootFgbTable(body[12:offset])
+
+	columns := make([]*fgbColumn, 0)
+
+	for _, c := range header.tables(7) {
+
+		col := &fgbColumn{
+			name:        c.string(0),
+			column_type: c.uint8(1),
+		}
+
+		columns = append(columns, col)
+	}
+
+	features_count := header.uint64(8)
+	index_node_size := header.uint16(9, 16)
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "name=%s\n", header.string(0))
+	fmt.Fprintf(&buf, "geometry_type=%s\n", fgbName(fgb_geometry_types, header.uint8(2)))
+	fmt.Fprintf(&buf, "envelope=%s\n", formatFloats(header.float64s(1)))
+	fmt.Fprintf(&buf, "features_count=%d\n", features_count)
+	fmt.Fprintf(&buf, "index_node_size=%d\n", index_node_size)
+
+	crs := header.table(10)
+
+	if crs != nil {
+		fmt.Fprintf(&buf, "crs=%s:%d\n", crs.string(0), crs.int32(1))
+	}
+
+	buf.WriteString("columns\n")
+
+	for _, col := range columns {
+		fmt.Fprintf(&buf, "\t%s %s\n", col.name, fgbName(fgb_column_types, col.column_type))
+	}
+
+	if index_node_size > 0 && features_count > 0 {
+
+		index_len, err := fgbIndexLength(features_count, uint64(index_node_size))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if offset+index_len > len(body) {
+			return nil, fmt.Errorf("Index length exceeds file length")
+		}
+
+		fmt.Fprintf(&buf, "index bbox=%s\n", formatFloats(fgbNodeBox(body[offset:], 0)))
+		offset += index_len
+	}
+
+	buf.WriteString("features\n")
+
+	for idx := uint64(0); idx < features_count; idx++ {
+
+		if offset+4 > len(body) {
+			return nil, fmt.Errorf("Feature %d is missing", idx)
+		}
+
+		feature_len := int(binary.LittleEndian.Uint32(body[offset:]))
+		start := offset + 4
+		offset = start + feature_len
+
+		if offset > len(body) {
+			return nil, fmt.Errorf("Feature %d length exceeds file length", idx)
+		}
+
+		feature := rootFgbTable(body[start:offset])
+
+		geom_summary := "geometry=null"
+		geom := feature.table(0)
+
+		if geom != nil {
+			geom_summary = summarizeFgbGeometry(geom, header.uint8(2))
+		}
+
+		props, err := decodeFgbProperties(feature.bytes(1), columns)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode properties for feature %d, %w", idx, err)
+		}
+
+		fmt.Fprintf(&buf, "\t%d %s properties=%s\n", idx, geom_summary, props)
+	}
+
+	if offset != len(body) {
+		return nil, fmt.Errorf("Unexpected %d trailing bytes", len(body)-offset)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// summarizeFgbGeometry returns the type, number of coordinates, number of parts and bounding box of 'geom'.
+func summarizeFgbGeometry(geom *fgbTable, default_type byte) string {
+
+	geom_type := geom.uint8(6)
+
+	if geom_type == 0 {
+		geom_type = default_type
+	}
+
+	xy := make([]float64, 0)
+	parts := geom.tables(7)
+
+	if len(parts) == 0 {
+		xy = geom.float64s(1)
+	}
+
+	for _, p := range parts {
+		xy = append(xy, p.float64s(1)...)
+	}
+
+	bbox := []float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	for idx := 0; idx+1 < len(xy); idx += 2 {
+		bbox[0] = min(bbox[0], xy[idx])
+		bbox[1] = min(bbox[1], xy[idx+1])
+		bbox[2] = max(bbox[2], xy[idx])
+		bbox[3] = max(bbox[3], xy[idx+1])
+	}
+
+	return fmt.Sprintf("geometry=%s coords=%d ends=%d parts=%d bbox=%s", fgbName(fgb_geometry_types, geom_type), len(xy)/2, len(geom.uint32s(0)), len(parts), formatFloats(bbox))
+}
+
+// decodeFgbProperties returns the FlatGeobuf encoded 'props' as a JSON object keyed by the names of 'columns'.
+func decodeFgbProperties(props []byte, columns []*fgbColumn) (string, error) {
+
+	values := make(map[string]any)
+	offset := 0
+
+	for offset < len(props) {
+
+		if offset+2 > len(props) {
+			return "", fmt.Errorf("Truncated column index")
+		}
+
+		col_idx := int(binary.LittleEndian.Uint16(props[offset:]))
+		offset += 2
+
+		if col_idx >= len(columns) {
+			return "", fmt.Errorf("Invalid column index %d", col_idx)
+		}
+
+		col := columns[col_idx]
+
+		var size int
+
+		switch fgbName(fgb_column_types, col.column_type) {
+		case "Byte", "UByte", "Bool":
+			size = 1
+		case "Short", "UShort":
+			size = 2
+		case "Int", "UInt", "Float":
+			size = 4
+		case "Long", "ULong", "Double":
+			size = 8
+		default:
+
+			if offset+4 > len(props) {
+				return "", fmt.Errorf("Truncated length for %s", col.name)
+			}
+
+			size = int(binary.LittleEndian.Uint32(props[offset:]))
+			offset += 4
+		}
+
+		if offset+size > len(props) {
+			return "", fmt.Errorf("Truncated value for %s", col.name)
+		}
+
+		v := props[offset : offset+size]
+		offset += size
+
+		switch fgbName(fgb_column_types, col.column_type) {
+		case "Byte":
+			values[col.name] = int8(v[0])
+		case "UByte":
+			values[col.name] = v[0]
+		case "Bool":
+			values[col.name] = v[0] != 0
+		case "Short":
+			values[col.name] = int16(binary.LittleEndian.Uint16(v))
+		case "UShort":
+			values[col.name] = binary.LittleEndian.Uint16(v)
+		case "Int":
+			values[col.name] = int32(binary.LittleEndian.Uint32(v))
+		case "UInt":
+			values[col.name] = binary.LittleEndian.Uint32(v)
+		case "Long":
+			values[col.name] = int64(binary.LittleEndian.Uint64(v))
+		case "ULong":
+			values[col.name] = binary.LittleEndian.Uint64(v)
+		case "Float":
+			values[col.name] = math.Float32frombits(binary.LittleEndian.Uint32(v))
+		case "Double":
+			values[col.name] = math.Float64frombits(binary.LittleEndian.Uint64(v))
+		case "Binary":
+			values[col.name] = fmt.Sprintf("%x", v)
+		default:
+			values[col.name] = string(v)
+		}
+	}
+
+	enc, err := json.Marshal(values)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(enc), nil
+}
+
+// fgbIndexLength returns the length, in bytes, of a packed Hilbert R-tree for 'count' features with nodes of 'node_size' items.
+func fgbIndexLength(count uint64, node_size uint64) (int, error) {
+
+	if node_size < 2 {
+		return 0, fmt.Errorf("Invalid index node size %d", node_size)
+	}
+
+	n := count
+	total := n
+
+	for n != 1 {
+		n = (n + node_size - 1) / node_size
+		total += n
+	}
+
+	return int(total * 40), nil
+}
+
+// fgbNodeBox returns the bounding box of the node at 'idx' in the packed R-tree 'index'.
+func fgbNodeBox(index []byte, idx int) []float64 {
+
+	box := make([]float64, 4)
+
+	for i := range box {
+		box[i] = math.Float64frombits(binary.LittleEndian.Uint64(index[idx*40+i*8:]))
+	}
+
+	return box
+}
+
+func fgbName(names []string, v byte) string {
+
+	if int(v) >= len(names) {
+		return fmt.Sprintf("%d", v)
+	}
+
+	return names[v]
+}
+
+func formatFloats(values []float64) string {
+
+	str_values := make([]string, len(values))
+
+	for idx, v := range values {
+		str_values[idx] = fmt.Sprintf("%g", v)
+	}
+
+	return "[" + strings.Join(str_values, ",") + "]"
+}
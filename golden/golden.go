@@ -0,0 +1,599 @@
+// Package golden provides a harness for running operations against fixture records, loaded in to an in-memory
+// `mem.Store`, and comparing the resulting records with the records in a golden file.
+package golden
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-whosonfirst-exportify/mem"
+)
+
+// DEFAULT_IGNORE is the list of (tidwall/gjson) paths which are always removed from records before they are compared
+// because their values change every time a record is exported.
+var DEFAULT_IGNORE = []string{
+	"properties.wof:lastmodified",
+}
+
+// Operation is a function which reads, and writes, records using the whosonfirst/go-reader and whosonfirst/go-writer
+// URIs 'reader_uri' and 'writer_uri'.
+type Operation func(ctx context.Context, reader_uri string, writer_uri string) error
+
+// Case defines a single golden-file test case.
+type Case struct {
+	// Name is the unique name of the case.
+	Name string `json:"name"`
+	// Command is the name of the tool to run, for example "wof-deprecate". See `StoreCommandOperation` for details.
+	Command string `json:"command,omitempty"`
+	// Args are the arguments to pass to Command.
+	Args []string `json:"args,omitempty"`
+	// Store is the name of the store (one of `STORES`) used to pass records to Command. Default is `STORE_FS`.
+	Store string `json:"store,omitempty"`
+	// Fixtures is the path to a GeoJSON FeatureCollection file containing the records to run the case against.
+	Fixtures string `json:"fixtures"`
+	// Golden is the path to a GeoJSON FeatureCollection file containing the expected records once the case has been run.
+	Golden string `json:"golden"`
+	// Prefix is an optional path prepended to the paths of the fixture records, for example "whosonfirst-data-admin-xy/data"
+	// for tools that operate on repository checkouts rather than directories of records.
+	Prefix string `json:"prefix,omitempty"`
+	// Files is an optional map of additional files to copy in to the store before the case is run. Keys are paths in the
+	// store and values are the paths of the files to copy.
+	Files map[string]string `json:"files,omitempty"`
+	// Output is the optional path to a file containing the expected output of the case (see `OUTPUT_PATH`). Binary outputs
+	// are compared using a text summary of their structure (see `summarize`) which is what is written to this file.
+	Output string `json:"output,omitempty"`
+	// Outputs is an optional map of the files, other than records, written by the case to the paths of files containing their
+	// expected contents. Keys are paths in the store; keys ending in "/" are directories whose files, other than records, are
+	// compared as a single document and the key "/" is all the files in the store other than records. Binary files are
+	// compared in the same way as Output.
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Ignore is an optional list of (tidwall/gjson) paths, in addition to `DEFAULT_IGNORE`, to remove from records before they
+	// are compared. If an output is a SQLite database entries in the form of "{TABLE}.{COLUMN}" are columns to ignore when its
+	// rows are compared.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// OUTPUT_PATH is the path in a `mem.Store` of the output (other than records) produced by an `Operation`. It is compared
+// with the file defined by a case's `Output` property.
+const OUTPUT_PATH string = ".output"
+
+// GOLDEN_PATH is the name of the (top-level) member used to record the path of features in a golden file that are not
+// stored at the path derived from their ID.
+const GOLDEN_PATH string = "golden:path"
+
+var store_count int64
+
+// LoadCases returns the list of `Case` instances defined in the JSON file at 'path'. Relative Fixtures and Golden
+// paths are resolved relative to the directory containing 'path'.
+func LoadCases(path string) ([]*Case, error) {
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	var cases []*Case
+
+	err = json.Unmarshal(body, &cases)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal %s, %w", path, err)
+	}
+
+	root := filepath.Dir(path)
+	seen := make(map[string]bool)
+
+	for idx, c := range cases {
+
+		if c.Name == "" {
+			return nil, fmt.Errorf("Case at offset %d is missing a name", idx)
+		}
+
+		if seen[c.Name] {
+			return nil, fmt.Errorf("Duplicate case name '%s'", c.Name)
+		}
+
+		seen[c.Name] = true
+
+		if c.Golden == "" {
+			return nil, fmt.Errorf("Case '%s' is missing a golden file", c.Name)
+		}
+
+		if c.Store == "" {
+			c.Store = STORE_FS
+		}
+
+		if !slices.Contains(STORES, c.Store) {
+			return nil, fmt.Errorf("Case '%s' has an unsupported store '%s'", c.Name, c.Store)
+		}
+
+		// Records are copied back from FeatureCollection files and databases at the paths derived from
+		// their IDs so a prefix would be lost.
+
+		if c.Store != STORE_FS && c.Prefix != "" {
+			return nil, fmt.Errorf("Case '%s' can not use a prefix with the '%s' store", c.Name, c.Store)
+		}
+
+		if c.Fixtures != "" && !filepath.IsAbs(c.Fixtures) {
+			c.Fixtures = filepath.Join(root, c.Fixtures)
+		}
+
+		if !filepath.IsAbs(c.Golden) {
+			c.Golden = filepath.Join(root, c.Golden)
+		}
+
+		if c.Output != "" && !filepath.IsAbs(c.Output) {
+			c.Output = filepath.Join(root, c.Output)
+		}
+
+		for path, golden_path := range c.Outputs {
+
+			if !filepath.IsAbs(golden_path) {
+				c.Outputs[path] = filepath.Join(root, golden_path)
+			}
+		}
+
+		for path, src := range c.Files {
+
+			if !filepath.IsAbs(src) {
+				c.Files[path] = filepath.Join(root, src)
+			}
+		}
+	}
+
+	return cases, nil
+}
+
+// Run loads the fixtures for 'c' in to a new `mem.Store`, runs 'op' using "mem://" reader and writer URIs for that
+// store and then compares the records in the store with the records in the golden file for 'c', and its outputs with
+// their golden files. If 'update' is true the golden files are (re)written with the records and outputs instead.
+func Run(ctx context.Context, c *Case, op Operation, update bool) error {
+
+	name := fmt.Sprintf("golden-%d", atomic.AddInt64(&store_count, 1))
+
+	s := mem.GetStore(name)
+	defer mem.RemoveStore(name)
+
+	if c.Fixtures != "" {
+
+		fixtures_s := mem.NewStore()
+
+		paths, err := mem.LoadFeatureCollectionFile(fixtures_s, c.Fixtures)
+
+		if err != nil {
+			return fmt.Errorf("Failed to load fixtures, %w", err)
+		}
+
+		for _, path := range paths {
+
+			body, err := fixtures_s.Read(path)
+
+			if err != nil {
+				return err
+			}
+
+			s.Write(filepath.ToSlash(filepath.Join(c.Prefix, path)), body)
+		}
+	}
+
+	for path, src := range c.Files {
+
+		body, err := os.ReadFile(src)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read %s, %w", src, err)
+		}
+
+		s.Write(path, body)
+	}
+
+	store_uri := fmt.Sprintf("mem://%s", name)
+
+	err := op(ctx, store_uri, store_uri)
+
+	if err != nil {
+		return fmt.Errorf("Operation failed, %w", err)
+	}
+
+	outputs, err := readOutputs(s, c)
+
+	if err != nil {
+		return err
+	}
+
+	ignore := slices.Concat(DEFAULT_IGNORE, c.Ignore)
+
+	actual, err := normalizeStore(s, ignore)
+
+	if err != nil {
+		return err
+	}
+
+	golden_paths := slices.Sorted(maps.Keys(outputs))
+
+	if update {
+
+		err := writeGolden(c.Golden, actual)
+
+		if err != nil {
+			return err
+		}
+
+		for _, golden_path := range golden_paths {
+
+			err = os.WriteFile(golden_path, outputs[golden_path], 0644)
+
+			if err != nil {
+				return fmt.Errorf("Failed to write %s, %w", golden_path, err)
+			}
+		}
+
+		return nil
+	}
+
+	for _, golden_path := range golden_paths {
+
+		expected_output, err := os.ReadFile(golden_path)
+
+		if err != nil {
+			return fmt.Errorf("Failed to read output file, %w", err)
+		}
+
+		expected_output, err = summarize(golden_path, expected_output, c.Ignore)
+
+		if err != nil {
+			return fmt.Errorf("Failed to summarize %s, %w", golden_path, err)
+		}
+
+		err = compareOutput(golden_path, expected_output, outputs[golden_path])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	expected_s := mem.NewStore()
+
+	err = loadGolden(expected_s, c.Golden)
+
+	if err != nil {
+		return fmt.Errorf("Failed to load golden file, %w", err)
+	}
+
+	expected, err := normalizeStore(expected_s, ignore)
+
+	if err != nil {
+		return err
+	}
+
+	return compare(expected, actual)
+}
+
+// readOutputs removes the output of 'c' (see `OUTPUT_PATH`) and the files listed in its Outputs property from 's' and
+// returns them, summarized using `summarize`, keyed by the paths of the files containing their expected contents.
+func readOutputs(s *mem.Store, c *Case) (map[string][]byte, error) {
+
+	outputs := make(map[string][]byte)
+
+	output, err := s.Read(OUTPUT_PATH)
+
+	if err != nil {
+		output = nil
+	}
+
+	s.Remove(OUTPUT_PATH)
+
+	if c.Output != "" {
+
+		output, err = summarize(OUTPUT_PATH, output, c.Ignore)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to summarize output, %w", err)
+		}
+
+		outputs[c.Output] = output
+	}
+
+	for path, golden_path := range c.Outputs {
+
+		if strings.HasSuffix(path, "/") {
+
+			files := make(map[string][]byte)
+			prefix := strings.TrimPrefix(path, "/")
+
+			for _, p := range s.Paths() {
+
+				if !strings.HasPrefix(p, prefix) || filepath.Ext(p) == ".geojson" {
+					continue
+				}
+
+				body, err := s.Read(p)
+
+				if err != nil {
+					return nil, err
+				}
+
+				files[p] = body
+				s.Remove(p)
+			}
+
+			body, err := summarizeDirectory(files, prefix, c.Ignore)
+
+			if err != nil {
+				return nil, err
+			}
+
+			outputs[golden_path] = body
+			continue
+		}
+
+		body, err := s.Read(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read output %s, %w", path, err)
+		}
+
+		s.Remove(path)
+
+		body, err = summarize(path, body, c.Ignore)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to summarize %s, %w", path, err)
+		}
+
+		outputs[golden_path] = body
+	}
+
+	return outputs, nil
+}
+
+// normalizeStore returns the records in 's', with the paths in 'ignore' removed, keyed by their paths.
+func normalizeStore(s *mem.Store, ignore []string) (map[string]map[string]any, error) {
+
+	records := make(map[string]map[string]any)
+
+	for _, path := range s.Paths() {
+
+		// Other files, like the files copied in to the store for a case, are not records.
+
+		if filepath.Ext(path) != ".geojson" {
+			continue
+		}
+
+		body, err := s.Read(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range ignore {
+
+			if !gjson.GetBytes(body, p).Exists() {
+				continue
+			}
+
+			body, err = sjson.DeleteBytes(body, p)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to remove %s from %s, %w", p, path, err)
+			}
+		}
+
+		var record map[string]any
+
+		err = json.Unmarshal(body, &record)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal %s, %w", path, err)
+		}
+
+		records[path] = record
+	}
+
+	return records, nil
+}
+
+// loadGolden stores each of the features in the golden file at 'path' in 's', at the path defined by their `GOLDEN_PATH`
+// property if present.
+func loadGolden(s *mem.Store, path string) error {
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to read %s, %w", path, err)
+	}
+
+	features_rsp := gjson.GetBytes(body, "features")
+
+	if !features_rsp.IsArray() {
+		return fmt.Errorf("Missing features array")
+	}
+
+	for idx, f := range features_rsp.Array() {
+
+		f_body := []byte(f.Raw)
+		path_rsp := gjson.GetBytes(f_body, GOLDEN_PATH)
+
+		if !path_rsp.Exists() {
+
+			_, err := s.WriteFeature(f_body)
+
+			if err != nil {
+				return fmt.Errorf("Failed to store feature at offset %d, %w", idx, err)
+			}
+
+			continue
+		}
+
+		f_body, err = sjson.DeleteBytes(f_body, GOLDEN_PATH)
+
+		if err != nil {
+			return fmt.Errorf("Failed to remove path from feature at offset %d, %w", idx, err)
+		}
+
+		s.Write(path_rsp.String(), f_body)
+	}
+
+	return nil
+}
+
+func writeGolden(path string, records map[string]map[string]any) error {
+
+	paths := make([]string, 0, len(records))
+
+	for p := range records {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	features := make([]map[string]any, len(paths))
+
+	for idx, p := range paths {
+
+		record := records[p]
+
+		enc_record, err := json.Marshal(record)
+
+		if err != nil {
+			return fmt.Errorf("Failed to marshal %s, %w", p, err)
+		}
+
+		feature_path, err := mem.FeaturePath(enc_record)
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive path for %s, %w", p, err)
+		}
+
+		// Records that are not stored at the path derived from their ID (for example records in a repository
+		// checkout) record their path so it can be restored by loadGolden.
+
+		if feature_path != p {
+
+			record = maps.Clone(record)
+			record[GOLDEN_PATH] = p
+		}
+
+		features[idx] = record
+	}
+
+	fc := map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	}
+
+	body, err := json.MarshalIndent(fc, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal golden file, %w", err)
+	}
+
+	body = append(body, '\n')
+
+	err = os.WriteFile(path, body, 0644)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", path, err)
+	}
+
+	return nil
+}
+
+// compare returns an error describing any differences between 'expected' and 'actual'.
+func compare(expected map[string]map[string]any, actual map[string]map[string]any) error {
+
+	paths := make([]string, 0)
+
+	for p := range expected {
+		paths = append(paths, p)
+	}
+
+	for p := range actual {
+
+		_, exists := expected[p]
+
+		if !exists {
+			paths = append(paths, p)
+		}
+	}
+
+	sort.Strings(paths)
+
+	diffs := make([]string, 0)
+
+	for _, p := range paths {
+
+		e, e_exists := expected[p]
+		a, a_exists := actual[p]
+
+		switch {
+		case !a_exists:
+			diffs = append(diffs, fmt.Sprintf("%s is missing", p))
+		case !e_exists:
+			diffs = append(diffs, fmt.Sprintf("%s is unexpected", p))
+		default:
+
+			changed := diffKeys(e, a, "")
+
+			if len(changed) > 0 {
+				diffs = append(diffs, fmt.Sprintf("%s differs at %s", p, strings.Join(changed, ", ")))
+			}
+		}
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("Records do not match golden file:\n\t%s", strings.Join(diffs, "\n\t"))
+	}
+
+	return nil
+}
+
+// diffKeys returns the sorted list of the top-level keys, and the keys of the "properties" object, whose values
+// differ between 'e' and 'a'.
+func diffKeys(e map[string]any, a map[string]any, prefix string) []string {
+
+	keys := make(map[string]bool)
+
+	for k := range e {
+		keys[k] = true
+	}
+
+	for k := range a {
+		keys[k] = true
+	}
+
+	changed := make([]string, 0)
+
+	for k := range keys {
+
+		if prefix == "" && k == "properties" {
+
+			e_props, e_ok := e[k].(map[string]any)
+			a_props, a_ok := a[k].(map[string]any)
+
+			if e_ok && a_ok {
+				changed = append(changed, diffKeys(e_props, a_props, "properties.")...)
+				continue
+			}
+		}
+
+		if !reflect.DeepEqual(e[k], a[k]) {
+			changed = append(changed, prefix+k)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,212 @@
+package golden
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tidwall/sjson"
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden file for each test case with the records it produces rather than comparing them.")
+
+// TestCases runs the test cases defined in testdata/golden/cases.json against tools built from the cmd directory.
+func TestCases(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("Skipping golden-file test cases in short mode")
+	}
+
+	go_bin, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("Skipping golden-file test cases because the go tool is not available")
+	}
+
+	// Test cases reference files relative to the root of the repository.
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	err = os.Chdir("..")
+
+	if err != nil {
+		t.Fatalf("Failed to change directory, %v", err)
+	}
+
+	t.Cleanup(func() {
+		os.Chdir(cwd)
+	})
+
+	cases, err := LoadCases("testdata/golden/cases.json")
+
+	if err != nil {
+		t.Fatalf("Failed to load test cases, %v", err)
+	}
+
+	bin_dir := t.TempDir()
+
+	build_args := []string{"build", "-o", bin_dir + string(filepath.Separator)}
+	commands := make([]string, 0)
+
+	for _, c := range cases {
+
+		if c.Command == "" {
+			t.Fatalf("Test case '%s' is missing a command", c.Name)
+		}
+
+		if !slices.Contains(commands, c.Command) {
+			commands = append(commands, c.Command)
+			build_args = append(build_args, "./cmd/"+c.Command)
+		}
+	}
+
+	build_cmd := exec.Command(go_bin, build_args...)
+
+	out, err := build_cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("Failed to build tools, %v\n%s", err, out)
+	}
+
+	ctx := context.Background()
+
+	for _, c := range cases {
+
+		t.Run(c.Name, func(t *testing.T) {
+
+			op := StoreCommandOperation(c.Store, filepath.Join(bin_dir, c.Command), c.Args...)
+
+			err := Run(ctx, c, op, *update)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestConflicts runs cases where a record is changed by another process after it has been read, using the same guarded
+// readers and writers, and the same retry logic, as the tools that update existing records.
+func TestConflicts(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		retries  int
+		expected error
+	}{
+		{"concurrency-conflict", 0, concurrency.ErrConflict},
+		{"concurrency-retry", 1, nil},
+	}
+
+	for _, test := range tests {
+
+		t.Run(test.name, func(t *testing.T) {
+
+			c := &Case{
+				Name:     test.name,
+				Fixtures: "../testdata/golden/fixtures.geojson",
+				Golden:   fmt.Sprintf("../testdata/golden/%s.geojson", test.name),
+			}
+
+			fn := func(ctx context.Context, uri string, root string) error {
+
+				r, err := reader.NewReader(ctx, uri)
+
+				if err != nil {
+					return err
+				}
+
+				wr, err := writer.NewWriter(ctx, uri)
+
+				if err != nil {
+					return err
+				}
+
+				guarded_r, guarded_wr := concurrency.Guard(ctx, r, wr)
+
+				rel_path := "101/736/547/101736547.geojson"
+				attempts := 0
+
+				update := func() error {
+
+					attempts += 1
+
+					fh, err := guarded_r.Read(ctx, rel_path)
+
+					if err != nil {
+						return err
+					}
+
+					body, err := io.ReadAll(fh)
+
+					fh.Close()
+
+					if err != nil {
+						return err
+					}
+
+					// Another process changes the record after it has been read the first time.
+
+					if attempts == 1 {
+
+						other_body, err := sjson.SetBytes(body, "properties.misc:note", "Changed by another process")
+
+						if err != nil {
+							return err
+						}
+
+						err = os.WriteFile(filepath.Join(root, rel_path), other_body, 0644)
+
+						if err != nil {
+							return err
+						}
+					}
+
+					body, err = sjson.SetBytes(body, "properties.misc:level", 1)
+
+					if err != nil {
+						return err
+					}
+
+					_, err = guarded_wr.Write(ctx, rel_path, bytes.NewReader(body))
+					return err
+				}
+
+				err = concurrency.Retry(ctx, test.retries, update)
+
+				if test.expected == nil {
+					return err
+				}
+
+				if !errors.Is(err, test.expected) {
+					return fmt.Errorf("Expected error wrapping '%v', got '%v'", test.expected, err)
+				}
+
+				return nil
+			}
+
+			err := Run(ctx, c, DirectoryOperation(fn), *update)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package golden
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLITE_HEADER is the string that every SQLite database file starts with.
+const SQLITE_HEADER string = "SQLite format 3\x00"
+
+// compareOutput returns an error describing the first line at which 'expected' and 'actual', the output written to
+// 'path', differ. Binary outputs should be summarized using `summarize` before they are compared.
+func compareOutput(path string, expected []byte, actual []byte) error {
+
+	if bytes.Equal(expected, actual) {
+		return nil
+	}
+
+	expected_lines := strings.Split(string(expected), "\n")
+	actual_lines := strings.Split(string(actual), "\n")
+
+	for idx := 0; idx < max(len(expected_lines), len(actual_lines)); idx++ {
+
+		var e string
+		var a string
+
+		if idx < len(expected_lines) {
+			e = expected_lines[idx]
+		}
+
+		if idx < len(actual_lines) {
+			a = actual_lines[idx]
+		}
+
+		if idx < len(expected_lines) && idx < len(actual_lines) && e == a {
+			continue
+		}
+
+		return fmt.Errorf("Output does not match %s at line %d:\n\texpected: %s\n\tactual:   %s", path, idx+1, e, a)
+	}
+
+	return fmt.Errorf("Output does not match %s", path)
+}
+
+func isSQLite(body []byte) bool {
+	return bytes.HasPrefix(body, []byte(SQLITE_HEADER))
+}
+
+// dumpSQLite returns the rows of every table in the SQLite database 'body', ordered by table name and then by their
+// contents, with the columns listed in 'ignore' removed.
+func dumpSQLite(body []byte, ignore []string) ([]byte, error) {
+
+	fh, err := os.CreateTemp("", "golden-*.db")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	defer os.Remove(fh.Name())
+
+	_, err = fh.Write(body)
+
+	fh.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write temporary file, %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", fh.Name())
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open database, %w", err)
+	}
+
+	defer db.Close()
+
+	table_rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list tables, %w", err)
+	}
+
+	tables := make([]string, 0)
+
+	for table_rows.Next() {
+
+		var name string
+
+		err := table_rows.Scan(&name)
+
+		if err != nil {
+			table_rows.Close()
+			return nil, fmt.Errorf("Failed to scan table name, %w", err)
+		}
+
+		tables = append(tables, name)
+	}
+
+	table_rows.Close()
+
+	var buf bytes.Buffer
+
+	for _, table := range tables {
+
+		lines, err := dumpTable(db, table, ignore)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read table %s, %w", table, err)
+		}
+
+		fmt.Fprintf(&buf, "%s\n", table)
+
+		for _, l := range lines {
+			fmt.Fprintf(&buf, "\t%s\n", l)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// dumpTable returns the sorted list of the rows in 'table', with the columns listed in 'ignore' removed, encoded
+// as strings.
+func dumpTable(db *sql.DB, table string, ignore []string) ([]string, error) {
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT * FROM "%s"`, strings.ReplaceAll(table, `"`, `""`)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+
+	for rows.Next() {
+
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+
+		for idx := range values {
+			ptrs[idx] = &values[idx]
+		}
+
+		err := rows.Scan(ptrs...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make([]string, 0, len(columns))
+
+		for idx, col := range columns {
+
+			if slices.Contains(ignore, table+"."+col) {
+				continue
+			}
+
+			switch v := values[idx].(type) {
+			case []byte:
+				fields = append(fields, fmt.Sprintf("%s=%x", col, v))
+			default:
+				fields = append(fields, fmt.Sprintf("%s=%v", col, v))
+			}
+		}
+
+		lines = append(lines, strings.Join(fields, " "))
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(lines)
+	return lines, nil
+}
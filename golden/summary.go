@@ -0,0 +1,544 @@
+package golden
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jonas-p/go-shp"
+	"github.com/paulmach/orb/encoding/mvt"
+)
+
+// ZIP_HEADER is the string that every (non-empty) ZIP archive starts with.
+const ZIP_HEADER string = "PK\x03\x04"
+
+// PMTILES_HEADER is the string that every PMTiles (version 3) archive starts with.
+const PMTILES_HEADER string = "PMTiles\x03"
+
+// summarize returns a text summary of 'body', read from 'path', if it is a binary format or 'body' unchanged otherwise.
+// Binary formats are compared using summaries of their structure (for example the schema, feature count and bounding
+// boxes of a FlatGeobuf file) rather than their bytes so that differences are readable and changes to the way files are
+// encoded, which don't change their contents, don't cause cases to fail. The following formats are summarized:
+//
+// * SQLite databases (see `dumpSQLite`), ignoring the columns listed in 'ignore' as "{TABLE}.{COLUMN}" strings.
+// * FlatGeobuf files (see `summarizeFlatGeobuf`).
+// * ZIP archives containing a Shapefile (see `summarizeShapefile`).
+// * PMTiles archives (see `summarizePMTiles`).
+// * Mapbox Vector Tiles, if 'path' has a ".mvt" extension (see `summarizeMVT`).
+func summarize(path string, body []byte, ignore []string) ([]byte, error) {
+
+	switch {
+	case isSQLite(body):
+		return dumpSQLite(body, ignore)
+	case isFlatGeobuf(body):
+		return summarizeFlatGeobuf(body)
+	case bytes.HasPrefix(body, []byte(ZIP_HEADER)):
+		return summarizeShapefile(body)
+	case bytes.HasPrefix(body, []byte(PMTILES_HEADER)):
+		return summarizePMTiles(body)
+	case filepath.Ext(path) == ".mvt":
+		return summarizeMVT(body)
+	default:
+		return body, nil
+	}
+}
+
+// summarizeShapefile returns a text summary of the zipped Shapefile 'body': the files in the archive, the DBF fields
+// and the shape type, bounding box and attributes of each record. Shapefiles are read using jonas-p/go-shp.
+func summarizeShapefile(body []byte) ([]byte, error) {
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read ZIP archive, %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString("files\n")
+
+	for _, f := range zr.File {
+
+		fmt.Fprintf(&buf, "\t%s\n", f.Name)
+
+		// The sidecar file mapping truncated DBF field names to WOF properties is part of the schema.
+
+		if !strings.HasSuffix(f.Name, ".fields.json") {
+			continue
+		}
+
+		fh, err := f.Open()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s, %w", f.Name, err)
+		}
+
+		mapping, err := io.ReadAll(fh)
+
+		fh.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", f.Name, err)
+		}
+
+		var compact bytes.Buffer
+
+		err = json.Compact(&compact, mapping)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to compact %s, %w", f.Name, err)
+		}
+
+		fmt.Fprintf(&buf, "\t\t%s\n", compact.Bytes())
+	}
+
+	// go-shp reads archives from disk.
+
+	fh, err := os.CreateTemp("", "golden-*.zip")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	defer os.Remove(fh.Name())
+
+	_, err = fh.Write(body)
+
+	fh.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write temporary file, %w", err)
+	}
+
+	shp_r, err := shp.OpenZip(fh.Name())
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open Shapefile, %w", err)
+	}
+
+	defer shp_r.Close()
+
+	fields := shp_r.Fields()
+
+	buf.WriteString("fields\n")
+
+	for _, f := range fields {
+		fmt.Fprintf(&buf, "\t%s %c %d %d\n", f.String(), f.Fieldtype, f.Size, f.Precision)
+	}
+
+	buf.WriteString("records\n")
+
+	for shp_r.Next() {
+
+		idx, shape := shp_r.Shape()
+
+		attrs := make([]string, len(fields))
+
+		for i, f := range fields {
+			attrs[i] = fmt.Sprintf("%s=%q", f.String(), shp_r.Attribute(i))
+		}
+
+		box := shape.BBox()
+		bbox := formatFloats([]float64{box.MinX, box.MinY, box.MaxX, box.MaxY})
+
+		fmt.Fprintf(&buf, "\t%d %s points=%d bbox=%s %s\n", idx, strings.TrimPrefix(fmt.Sprintf("%T", shape), "*shp."), shapePoints(shape), bbox, strings.Join(attrs, " "))
+	}
+
+	err = shp_r.Err()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read Shapefile, %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// shapePoints returns the number of points in 'shape'.
+func shapePoints(shape shp.Shape) int {
+
+	switch s := shape.(type) {
+	case *shp.Point:
+		return 1
+	case *shp.MultiPoint:
+		return int(s.NumPoints)
+	case *shp.PolyLine:
+		return int(s.NumPoints)
+	case *shp.Polygon:
+		return int(s.NumPoints)
+	default:
+		return 0
+	}
+}
+
+// summarizeMVT returns a text summary of the (optionally gzip-compressed) Mapbox Vector Tile 'body': the name, version,
+// extent and feature count of each layer and the ID, geometry type, bounding box (in tile coordinates) and properties of
+// each feature.
+func summarizeMVT(body []byte) ([]byte, error) {
+
+	var layers mvt.Layers
+	var err error
+
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		layers, err = mvt.UnmarshalGzipped(body)
+	} else {
+		layers, err = mvt.Unmarshal(body)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal tile, %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	for _, l := range layers {
+
+		fmt.Fprintf(&buf, "layer %s version=%d extent=%d features=%d\n", l.Name, l.Version, l.Extent, len(l.Features))
+
+		for _, f := range l.Features {
+
+			id, err := json.Marshal(f.ID)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to marshal ID, %w", err)
+			}
+
+			props, err := json.Marshal(f.Properties)
+
+			if err != nil {
+				return nil, fmt.Errorf("Failed to marshal properties, %w", err)
+			}
+
+			geom_type := "null"
+			bbox := "[]"
+
+			if f.Geometry != nil {
+				b := f.Geometry.Bound()
+				geom_type = f.Geometry.GeoJSONType()
+				bbox = formatFloats([]float64{b.Min.X(), b.Min.Y(), b.Max.X(), b.Max.Y()})
+			}
+
+			fmt.Fprintf(&buf, "\tid=%s geometry=%s bbox=%s properties=%s\n", id, geom_type, bbox, props)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+type pmtilesEntry struct {
+	tile_id    uint64
+	offset     uint64
+	length     uint64
+	run_length uint64
+}
+
+// summarizePMTiles returns a text summary of the PMTiles (version 3) archive 'body': its header (excluding the offsets
+// and lengths of its sections), metadata and a summary (see `summarizeMVT`) of each tile, ordered by tile ID.
+func summarizePMTiles(body []byte) ([]byte, error) {
+
+	if len(body) < 127 {
+		return nil, fmt.Errorf("Archive is too short")
+	}
+
+	u64 := func(offset int) uint64 {
+		return binary.LittleEndian.Uint64(body[offset:])
+	}
+
+	e7 := func(offset int) float64 {
+		return float64(int32(binary.LittleEndian.Uint32(body[offset:]))) / 10000000.0
+	}
+
+	root_offset := u64(8)
+	root_length := u64(16)
+	metadata_offset := u64(24)
+	metadata_length := u64(32)
+	leaf_offset := u64(40)
+	tile_data_offset := u64(56)
+
+	internal_compression := body[97]
+	tile_compression := body[98]
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "addressed_tiles=%d tile_entries=%d tile_contents=%d clustered=%d\n", u64(72), u64(80), u64(88), body[96])
+	fmt.Fprintf(&buf, "internal_compression=%d tile_compression=%d tile_type=%d\n", internal_compression, tile_compression, body[99])
+	fmt.Fprintf(&buf, "zoom=%d-%d bounds=%s center=%d/%g/%g\n", body[100], body[101], formatFloats([]float64{e7(102), e7(106), e7(110), e7(114)}), body[118], e7(119), e7(123))
+
+	section := func(offset uint64, length uint64, compression uint8) ([]byte, error) {
+
+		if offset+length > uint64(len(body)) {
+			return nil, fmt.Errorf("Section at %d exceeds archive length", offset)
+		}
+
+		return pmtilesDecompress(body[offset:offset+length], compression)
+	}
+
+	metadata, err := section(metadata_offset, metadata_length, internal_compression)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read metadata, %w", err)
+	}
+
+	var md any
+
+	err = json.Unmarshal(metadata, &md)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal metadata, %w", err)
+	}
+
+	enc_md, err := json.Marshal(md)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal metadata, %w", err)
+	}
+
+	fmt.Fprintf(&buf, "metadata=%s\n", enc_md)
+
+	root, err := section(root_offset, root_length, internal_compression)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read root directory, %w", err)
+	}
+
+	entries, err := pmtilesEntries(root, func(offset uint64, length uint64) ([]byte, error) {
+		return section(leaf_offset+offset, length, internal_compression)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+
+		tile, err := section(tile_data_offset+e.offset, e.length, tile_compression)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read tile %d, %w", e.tile_id, err)
+		}
+
+		tile_summary, err := summarizeMVT(tile)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read tile %d, %w", e.tile_id, err)
+		}
+
+		for id := e.tile_id; id < e.tile_id+e.run_length; id++ {
+
+			z, x, y := pmtilesZXY(id)
+			fmt.Fprintf(&buf, "tile %d/%d/%d\n", z, x, y)
+
+			for _, line := range strings.SplitAfter(string(tile_summary), "\n") {
+
+				if line != "" {
+					buf.WriteString("\t" + line)
+				}
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// pmtilesEntries returns the tile entries in the (decompressed) directory 'dir' and any leaf directories it
+// references, which are read using 'read_leaf'.
+func pmtilesEntries(dir []byte, read_leaf func(uint64, uint64) ([]byte, error)) ([]*pmtilesEntry, error) {
+
+	r := bytes.NewReader(dir)
+
+	count, err := binary.ReadUvarint(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read directory length, %w", err)
+	}
+
+	dir_entries := make([]*pmtilesEntry, count)
+
+	var last_id uint64
+
+	for idx := range dir_entries {
+
+		delta, err := binary.ReadUvarint(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read tile ID, %w", err)
+		}
+
+		last_id += delta
+
+		dir_entries[idx] = &pmtilesEntry{
+			tile_id: last_id,
+		}
+	}
+
+	for _, e := range dir_entries {
+
+		e.run_length, err = binary.ReadUvarint(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read run length, %w", err)
+		}
+	}
+
+	for _, e := range dir_entries {
+
+		e.length, err = binary.ReadUvarint(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read length, %w", err)
+		}
+	}
+
+	for idx, e := range dir_entries {
+
+		v, err := binary.ReadUvarint(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read offset, %w", err)
+		}
+
+		// An offset of 0 means the entry immediately follows the previous one.
+
+		if v == 0 && idx > 0 {
+			e.offset = dir_entries[idx-1].offset + dir_entries[idx-1].length
+		} else {
+			e.offset = v - 1
+		}
+	}
+
+	entries := make([]*pmtilesEntry, 0)
+
+	for _, e := range dir_entries {
+
+		if e.run_length > 0 {
+			entries = append(entries, e)
+			continue
+		}
+
+		leaf, err := read_leaf(e.offset, e.length)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read leaf directory, %w", err)
+		}
+
+		leaf_entries, err := pmtilesEntries(leaf, read_leaf)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, leaf_entries...)
+	}
+
+	return entries, nil
+}
+
+// pmtilesDecompress returns 'body' decompressed using the PMTiles 'compression' type.
+func pmtilesDecompress(body []byte, compression uint8) ([]byte, error) {
+
+	switch compression {
+	case 0, 1:
+		return body, nil
+	case 2:
+
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer gz.Close()
+
+		return io.ReadAll(gz)
+
+	default:
+		return nil, fmt.Errorf("Unsupported compression %d", compression)
+	}
+}
+
+// pmtilesZXY returns the zoom level, column and row of the PMTiles tile 'id'. Tile IDs are the position of the
+// tile along a Hilbert curve for its zoom level plus the number of tiles in all the zoom levels before it.
+func pmtilesZXY(id uint64) (uint8, uint64, uint64) {
+
+	var z uint8
+	var acc uint64
+
+	for {
+
+		count := uint64(1) << (2 * z)
+
+		if id < acc+count {
+			break
+		}
+
+		acc += count
+		z += 1
+	}
+
+	n := uint64(1) << z
+	t := id - acc
+
+	var x, y uint64
+
+	for s := uint64(1); s < n; s *= 2 {
+
+		rx := 1 & (t / 2)
+		ry := 1 & (t ^ rx)
+
+		if ry == 0 {
+
+			if rx == 1 {
+				x = s - 1 - x
+				y = s - 1 - y
+			}
+
+			x, y = y, x
+		}
+
+		x += s * rx
+		y += s * ry
+		t /= 4
+	}
+
+	return z, x, y
+}
+
+// summarizeDirectory returns 'files', keyed by their paths, summarized using `summarize` as a single document ordered
+// by path. Each file is preceded by a line containing its path, relative to 'prefix'.
+func summarizeDirectory(files map[string][]byte, prefix string, ignore []string) ([]byte, error) {
+
+	paths := make([]string, 0)
+
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	slices.Sort(paths)
+
+	var buf bytes.Buffer
+
+	for _, path := range paths {
+
+		body, err := summarize(path, files[path], ignore)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to summarize %s, %w", path, err)
+		}
+
+		fmt.Fprintf(&buf, "== %s\n", strings.TrimPrefix(path, prefix))
+		buf.Write(body)
+
+		if !bytes.HasSuffix(body, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+	}
+
+	return buf.Bytes(), nil
+}
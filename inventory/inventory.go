@@ -0,0 +1,317 @@
+// Package inventory provides methods for compiling an inventory of the properties used by a set of Who's On First
+// records: their JSON types, how often they occur, example values and the placetypes of the records they occur in.
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sfomuseum/go-csvdict"
+	"github.com/tidwall/gjson"
+)
+
+// TYPE_NULL is the type of JSON null values.
+const TYPE_NULL string = "null"
+
+// Property is the inventory of a single (top-level) property.
+type Property struct {
+	// Name is the name of the property.
+	Name string `json:"name"`
+	// Count is the number of records the property occurs in.
+	Count int64 `json:"count"`
+	// Frequency is the fraction of all records the property occurs in.
+	Frequency float64 `json:"frequency"`
+	// Types maps the JSON types of the property's values ("string", "number", "boolean", "array", "object" or "null")
+	// to the number of records with a value of that type.
+	Types map[string]int64 `json:"types"`
+	// Inconsistent is true if the property has values of more than one type, not counting null values.
+	Inconsistent bool `json:"inconsistent"`
+	// Examples maps each JSON type to a list of unique example values of that type.
+	Examples map[string][]json.RawMessage `json:"examples"`
+	// Placetypes maps the placetypes of the records the property occurs in to the number of those records.
+	Placetypes map[string]int64 `json:"placetypes"`
+}
+
+// Inventory is an inventory of the properties used by a set of Who's On First records. It is safe for
+// concurrent use.
+type Inventory struct {
+	// Records is the number of records in the inventory.
+	Records int64
+	// Placetypes maps the placetypes of all the records in the inventory to the number of those records.
+	Placetypes map[string]int64
+	// Properties maps property names to their inventories.
+	Properties map[string]*Property
+	// MaxExamples is the maximum number of example values to keep for each type of each property.
+	MaxExamples int
+	mu          *sync.Mutex
+}
+
+// NewInventory returns a new `Inventory` instance which keeps up to 'max_examples' example values for each type
+// of each property.
+func NewInventory(max_examples int) *Inventory {
+
+	inv := &Inventory{
+		Placetypes:  make(map[string]int64),
+		Properties:  make(map[string]*Property),
+		MaxExamples: max_examples,
+		mu:          new(sync.Mutex),
+	}
+
+	return inv
+}
+
+// Add adds the properties of the Who's On First record 'body' to 'inv'.
+func (inv *Inventory) Add(body []byte) error {
+
+	props_rsp := gjson.GetBytes(body, "properties")
+
+	if !props_rsp.IsObject() {
+		return fmt.Errorf("Record is missing properties")
+	}
+
+	placetype := props_rsp.Get("wof:placetype").String()
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.Records += 1
+	inv.Placetypes[placetype] += 1
+
+	props_rsp.ForEach(func(k gjson.Result, v gjson.Result) bool {
+
+		name := k.String()
+
+		p, ok := inv.Properties[name]
+
+		if !ok {
+
+			p = &Property{
+				Name:       name,
+				Types:      make(map[string]int64),
+				Examples:   make(map[string][]json.RawMessage),
+				Placetypes: make(map[string]int64),
+			}
+
+			inv.Properties[name] = p
+		}
+
+		t := TypeOf(v)
+
+		p.Count += 1
+		p.Types[t] += 1
+		p.Placetypes[placetype] += 1
+
+		if len(p.Examples[t]) < inv.MaxExamples {
+
+			raw := json.RawMessage(compact(v.Raw))
+			is_new := true
+
+			for _, e := range p.Examples[t] {
+
+				if string(e) == string(raw) {
+					is_new = false
+					break
+				}
+			}
+
+			if is_new {
+				p.Examples[t] = append(p.Examples[t], raw)
+			}
+		}
+
+		return true
+	})
+
+	return nil
+}
+
+// List returns the inventories of all the properties in 'inv', sorted by name. If 'inconsistent_only' is true only
+// properties with values of more than one (non-null) type are returned.
+func (inv *Inventory) List(inconsistent_only bool) []*Property {
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	names := make([]string, 0, len(inv.Properties))
+
+	for name := range inv.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	list := make([]*Property, 0)
+
+	for _, name := range names {
+
+		p := inv.Properties[name]
+
+		if inv.Records > 0 {
+			p.Frequency = float64(p.Count) / float64(inv.Records)
+		}
+
+		types := 0
+
+		for t := range p.Types {
+
+			if t != TYPE_NULL {
+				types += 1
+			}
+		}
+
+		p.Inconsistent = types > 1
+
+		if inconsistent_only && !p.Inconsistent {
+			continue
+		}
+
+		list = append(list, p)
+	}
+
+	return list
+}
+
+// WriteJSON writes 'inv' to 'wr' as a JSON document. If 'inconsistent_only' is true only properties with values
+// of more than one (non-null) type are included.
+func (inv *Inventory) WriteJSON(wr io.Writer, inconsistent_only bool) error {
+
+	doc := struct {
+		Records    int64            `json:"records"`
+		Placetypes map[string]int64 `json:"placetypes"`
+		Properties []*Property      `json:"properties"`
+	}{
+		Properties: inv.List(inconsistent_only),
+		Records:    inv.Records,
+		Placetypes: inv.Placetypes,
+	}
+
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+
+	err := enc.Encode(doc)
+
+	if err != nil {
+		return fmt.Errorf("Failed to encode inventory, %w", err)
+	}
+
+	return nil
+}
+
+// WriteCSV writes 'inv' to 'wr' as a CSV document with one row per property. The "types" and "placetypes" columns
+// are encoded as semi-colon separated "{KEY}:{COUNT}" pairs and the "examples" column as a JSON object. If
+// 'inconsistent_only' is true only properties with values of more than one (non-null) type are included.
+func (inv *Inventory) WriteCSV(wr io.Writer, inconsistent_only bool) error {
+
+	fieldnames := []string{"property", "count", "frequency", "types", "inconsistent", "placetypes", "examples"}
+
+	csv_wr, err := csvdict.NewWriter(wr, fieldnames)
+
+	if err != nil {
+		return fmt.Errorf("Failed to create CSV writer, %w", err)
+	}
+
+	err = csv_wr.WriteHeader()
+
+	if err != nil {
+		return fmt.Errorf("Failed to write CSV header, %w", err)
+	}
+
+	for _, p := range inv.List(inconsistent_only) {
+
+		examples, err := json.Marshal(p.Examples)
+
+		if err != nil {
+			return fmt.Errorf("Failed to encode examples for %s, %w", p.Name, err)
+		}
+
+		row := map[string]string{
+			"property":     p.Name,
+			"count":        strconv.FormatInt(p.Count, 10),
+			"frequency":    strconv.FormatFloat(p.Frequency, 'f', 4, 64),
+			"types":        FormatCounts(p.Types),
+			"inconsistent": strconv.FormatBool(p.Inconsistent),
+			"placetypes":   FormatCounts(p.Placetypes),
+			"examples":     string(examples),
+		}
+
+		err = csv_wr.WriteRow(row)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write CSV row for %s, %w", p.Name, err)
+		}
+	}
+
+	csv_wr.Flush()
+	return csv_wr.Error()
+}
+
+// TypeOf returns the JSON type of 'v': "string", "number", "boolean", "array", "object" or "null".
+func TypeOf(v gjson.Result) string {
+
+	switch v.Type {
+	case gjson.String:
+		return "string"
+	case gjson.Number:
+		return "number"
+	case gjson.True, gjson.False:
+		return "boolean"
+	case gjson.JSON:
+
+		if v.IsArray() {
+			return "array"
+		}
+
+		return "object"
+
+	default:
+		return TYPE_NULL
+	}
+}
+
+// FormatCounts encodes 'counts' as a semi-colon separated list of "{KEY}:{COUNT}" pairs, sorted by descending
+// count and then by key.
+func FormatCounts(counts map[string]int64) string {
+
+	keys := make([]string, 0, len(counts))
+
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	pairs := make([]string, len(keys))
+
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s:%d", k, counts[k])
+	}
+
+	return strings.Join(pairs, ";")
+}
+
+// compact returns the compacted form of the raw JSON value 'raw'.
+func compact(raw string) string {
+
+	var buf bytes.Buffer
+
+	err := json.Compact(&buf, []byte(raw))
+
+	if err != nil {
+		return raw
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,56 @@
+package mem
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tidwall/gjson"
+)
+
+// LoadFeatureCollection stores each of the features in the GeoJSON FeatureCollection read from 'r' in 's'
+// and returns the paths they were stored at. Features are stored at the relative paths derived from their
+// "wof:id" property (and alternate geometry label) and must contain a "wof:id" property.
+func LoadFeatureCollection(s *Store, r io.Reader) ([]string, error) {
+
+	body, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read FeatureCollection, %w", err)
+	}
+
+	features_rsp := gjson.GetBytes(body, "features")
+
+	if !features_rsp.IsArray() {
+		return nil, fmt.Errorf("Missing features array")
+	}
+
+	paths := make([]string, 0)
+
+	for idx, f := range features_rsp.Array() {
+
+		rel_path, err := s.WriteFeature([]byte(f.Raw))
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to store feature at offset %d, %w", idx, err)
+		}
+
+		paths = append(paths, rel_path)
+	}
+
+	return paths, nil
+}
+
+// LoadFeatureCollectionFile stores each of the features in the GeoJSON FeatureCollection file at 'path' in 's'.
+func LoadFeatureCollectionFile(s *Store, path string) ([]string, error) {
+
+	fh, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer fh.Close()
+
+	return LoadFeatureCollection(s, fh)
+}
@@ -0,0 +1,74 @@
+package mem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/whosonfirst/go-ioutil"
+	"github.com/whosonfirst/go-reader"
+)
+
+// MemReader implements the `whosonfirst/go-reader.Reader` interface for reading documents from a `Store`.
+type MemReader struct {
+	reader.Reader
+	store *Store
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := reader.RegisterReader(ctx, "mem", NewMemReader)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewMemReader returns a new `MemReader` instance configured by 'uri' in the form of:
+//
+//	mem://{NAME}?path={PATH}
+//
+// Where {NAME} is the optional name of the `Store` to read documents from and {PATH} is the optional path of a
+// GeoJSON FeatureCollection file to load the store from, when it is first used, and which writers save it to.
+// Readers and writers with the same name share the same store.
+func NewMemReader(ctx context.Context, uri string) (reader.Reader, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	s, err := storeFromURL(u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &MemReader{
+		store: s,
+	}
+
+	return r, nil
+}
+
+// Read returns the document stored at 'path'. If there is no document the error returned wraps `os.ErrNotExist`.
+func (r *MemReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+
+	body, err := r.store.Read(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NewReadSeekCloser(bytes.NewReader(body))
+}
+
+// ReaderURI returns 'path' unchanged.
+func (r *MemReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
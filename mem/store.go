@@ -0,0 +1,282 @@
+// Package mem provides in-memory implementations of the whosonfirst/go-reader `Reader` and whosonfirst/go-writer
+// `Writer` interfaces. Readers and writers with the same name share the same `Store` so records written by a
+// writer can be read back by a reader in the same process. Stores can also be backed by a GeoJSON FeatureCollection
+// file, which is loaded when the store is first used and rewritten after every write, so that records can be shared
+// between processes (for example tools run one after the other).
+package mem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/whosonfirst/go-whosonfirst-feature/alt"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+var stores = make(map[string]*Store)
+
+var stores_mu = new(sync.Mutex)
+
+// Store is an in-memory collection of documents keyed by their (relative) paths.
+type Store struct {
+	records map[string][]byte
+	mu      *sync.RWMutex
+}
+
+// NewStore returns a new, empty, `Store` instance which is not shared with any `mem://` readers or writers.
+func NewStore() *Store {
+
+	s := &Store{
+		records: make(map[string][]byte),
+		mu:      new(sync.RWMutex),
+	}
+
+	return s
+}
+
+// GetStore returns the `Store` instance named 'name', creating it if necessary. This is the same store used
+// by "mem://{NAME}" readers and writers.
+func GetStore(name string) *Store {
+
+	stores_mu.Lock()
+	defer stores_mu.Unlock()
+
+	s, exists := stores[name]
+
+	if !exists {
+		s = NewStore()
+		stores[name] = s
+	}
+
+	return s
+}
+
+// LoadStore returns the `Store` instance named 'name', creating it if necessary. When the store is created the
+// features in the GeoJSON FeatureCollection file at 'path' are loaded in to it, if that file exists. This is the
+// same store used by "mem://{NAME}?path={PATH}" readers and writers.
+func LoadStore(name string, path string) (*Store, error) {
+
+	stores_mu.Lock()
+	defer stores_mu.Unlock()
+
+	s, exists := stores[name]
+
+	if exists {
+		return s, nil
+	}
+
+	s = NewStore()
+
+	_, err := os.Stat(path)
+
+	if err == nil {
+
+		_, err = LoadFeatureCollectionFile(s, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("Failed to stat %s, %w", path, err)
+	}
+
+	stores[name] = s
+	return s, nil
+}
+
+// RemoveStore removes the `Store` instance named 'name'. Existing readers and writers for that store will
+// continue to use it but new readers and writers will be given a new, empty, store.
+func RemoveStore(name string) {
+
+	stores_mu.Lock()
+	defer stores_mu.Unlock()
+
+	delete(stores, name)
+}
+
+// Read returns the document stored at 'path'. If there is no document the error returned wraps `os.ErrNotExist`.
+func (s *Store) Read(path string) ([]byte, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	body, exists := s.records[normalizePath(path)]
+
+	if !exists {
+		return nil, fmt.Errorf("%s not found, %w", path, os.ErrNotExist)
+	}
+
+	return slices.Clone(body), nil
+}
+
+// Write stores 'body' at 'path', replacing any existing document.
+func (s *Store) Write(path string, body []byte) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[normalizePath(path)] = slices.Clone(body)
+}
+
+// Remove removes the document stored at 'path', if present.
+func (s *Store) Remove(path string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, normalizePath(path))
+}
+
+// Paths returns the sorted list of paths for all the documents in the store.
+func (s *Store) Paths() []string {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths := make([]string, 0, len(s.records))
+
+	for path := range s.records {
+		paths = append(paths, path)
+	}
+
+	slices.Sort(paths)
+	return paths
+}
+
+// WriteFeature stores the GeoJSON Feature 'body' at the relative path derived from its "wof:id" property
+// (and alternate geometry label) and returns that path.
+func (s *Store) WriteFeature(body []byte) (string, error) {
+
+	rel_path, err := FeaturePath(body)
+
+	if err != nil {
+		return "", err
+	}
+
+	s.Write(rel_path, body)
+	return rel_path, nil
+}
+
+// FeatureCollection returns all the documents in the store, ordered by path, as a GeoJSON FeatureCollection.
+func (s *Store) FeatureCollection() ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":"FeatureCollection","features":[`)
+
+	for idx, path := range s.Paths() {
+
+		body, err := s.Read(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if idx > 0 {
+			buf.WriteString(",")
+		}
+
+		err = json.Compact(&buf, body)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to compact %s, %w", path, err)
+		}
+	}
+
+	buf.WriteString(`]}`)
+	return buf.Bytes(), nil
+}
+
+// WriteFeatureCollectionFile writes all the documents in the store, as a GeoJSON FeatureCollection, to the file
+// at 'path'. The file is written to a temporary file first and then renamed so readers never see a partial file.
+func (s *Store) WriteFeatureCollectionFile(path string) error {
+
+	body, err := s.FeatureCollection()
+
+	if err != nil {
+		return err
+	}
+
+	fh, err := os.CreateTemp(filepath.Dir(path), ".mem-*")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file for %s, %w", path, err)
+	}
+
+	defer os.Remove(fh.Name())
+
+	_, err = fh.Write(body)
+
+	if err != nil {
+		fh.Close()
+		return fmt.Errorf("Failed to write %s, %w", fh.Name(), err)
+	}
+
+	err = fh.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close %s, %w", fh.Name(), err)
+	}
+
+	err = os.Rename(fh.Name(), path)
+
+	if err != nil {
+		return fmt.Errorf("Failed to rename %s, %w", fh.Name(), err)
+	}
+
+	return nil
+}
+
+// FeaturePath returns the relative path for the GeoJSON Feature 'body' derived from its "wof:id" property
+// (and alternate geometry label), for example "101/736/545/101736545.geojson".
+func FeaturePath(body []byte) (string, error) {
+
+	id, err := properties.Id(body)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to derive ID, %w", err)
+	}
+
+	if !alt.IsAlt(body) {
+		return uri.Id2RelPath(id)
+	}
+
+	alt_label, err := properties.AltLabel(body)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to derive alt label, %w", err)
+	}
+
+	uri_args, err := uri.NewAlternateURIArgsFromAltLabel(alt_label)
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to derive URI args from label '%s', %w", alt_label, err)
+	}
+
+	return uri.Id2RelPath(id, uri_args)
+}
+
+// storeFromURL returns the `Store` instance for the "mem://" URI 'u'.
+func storeFromURL(u *url.URL) (*Store, error) {
+
+	path := u.Query().Get("path")
+
+	if path == "" {
+		return GetStore(u.Host), nil
+	}
+
+	return LoadStore(u.Host, path)
+}
+
+func normalizePath(path string) string {
+	return strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,112 @@
+package mem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+func TestLoadStore(t *testing.T) {
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "records.geojson")
+	uri := "mem://load-store?path=" + path
+
+	defer RemoveStore("load-store")
+
+	wr, err := writer.NewWriter(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create writer, %v", err)
+	}
+
+	body := []byte(`{"type":"Feature","properties":{"wof:id":101736545},"geometry":null}`)
+
+	_, err = wr.Write(ctx, "101/736/545/101736545.geojson", bytes.NewReader(body))
+
+	if err != nil {
+		t.Fatalf("Failed to write record, %v", err)
+	}
+
+	_, err = os.Stat(path)
+
+	if err != nil {
+		t.Fatalf("Expected store to be saved to %s, %v", path, err)
+	}
+
+	// A new store, as if in another process, is loaded from the file.
+
+	RemoveStore("load-store")
+
+	r, err := reader.NewReader(ctx, uri)
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	fh, err := r.Read(ctx, "101/736/545/101736545.geojson")
+
+	if err != nil {
+		t.Fatalf("Failed to read record, %v", err)
+	}
+
+	defer fh.Close()
+
+	rsp, err := io.ReadAll(fh)
+
+	if err != nil {
+		t.Fatalf("Failed to read body, %v", err)
+	}
+
+	if !bytes.Equal(rsp, body) {
+		t.Fatalf("Unexpected body: %s", rsp)
+	}
+
+	_, err = r.Read(ctx, "101/736/547/101736547.geojson")
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected missing record to wrap os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestLoadStoreMissingFile(t *testing.T) {
+
+	defer RemoveStore("load-store-missing")
+
+	s, err := LoadStore("load-store-missing", filepath.Join(t.TempDir(), "missing.geojson"))
+
+	if err != nil {
+		t.Fatalf("Failed to load store, %v", err)
+	}
+
+	if len(s.Paths()) != 0 {
+		t.Fatalf("Expected empty store, got %v", s.Paths())
+	}
+}
+
+func TestLoadStoreInvalidFile(t *testing.T) {
+
+	defer RemoveStore("load-store-invalid")
+
+	path := filepath.Join(t.TempDir(), "invalid.geojson")
+
+	err := os.WriteFile(path, []byte(`{"type":"Feature"}`), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+
+	_, err = LoadStore("load-store-invalid", path)
+
+	if err == nil {
+		t.Fatalf("Expected invalid file to fail")
+	}
+}
@@ -0,0 +1,102 @@
+package mem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// MemWriter implements the `whosonfirst/go-writer.Writer` interface for writing documents to a `Store`.
+type MemWriter struct {
+	writer.Writer
+	store *Store
+	path  string
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := writer.RegisterWriter(ctx, "mem", NewMemWriter)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewMemWriter returns a new `MemWriter` instance configured by 'uri' in the form of:
+//
+//	mem://{NAME}?path={PATH}
+//
+// Where {NAME} is the optional name of the `Store` to write documents to and {PATH} is the optional path of a
+// GeoJSON FeatureCollection file to load the store from, when it is first used, and to save it to after every write.
+// Readers and writers with the same name share the same store.
+func NewMemWriter(ctx context.Context, uri string) (writer.Writer, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	s, err := storeFromURL(u)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wr := &MemWriter{
+		store: s,
+		path:  u.Query().Get("path"),
+	}
+
+	return wr, nil
+}
+
+// Write stores the contents of 'fh' at 'key', replacing any existing document. If the writer was created with
+// a path the store is then saved to that path.
+func (wr *MemWriter) Write(ctx context.Context, key string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read filehandle, %w", err)
+	}
+
+	wr.store.Write(key, body)
+
+	if wr.path != "" {
+
+		err := wr.store.WriteFeatureCollectionFile(wr.path)
+
+		if err != nil {
+			return 0, fmt.Errorf("Failed to save store, %w", err)
+		}
+	}
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns 'str_uri' unchanged.
+func (wr *MemWriter) WriterURI(ctx context.Context, str_uri string) string {
+	return str_uri
+}
+
+// Flush is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *MemWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *MemWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+// SetLogger is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *MemWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
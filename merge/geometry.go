@@ -0,0 +1,188 @@
+package merge
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// GeometryEqualBytes unmarshals 'a' and 'b' as GeoJSON geometries and returns a boolean value indicating whether
+// they are equal within 'tolerance'.
+func GeometryEqualBytes(a []byte, b []byte, tolerance float64) (bool, error) {
+
+	geom_a, err := geojson.UnmarshalGeometry(a)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal geometry, %w", err)
+	}
+
+	geom_b, err := geojson.UnmarshalGeometry(b)
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal geometry, %w", err)
+	}
+
+	return GeometryEqual(geom_a.Geometry(), geom_b.Geometry(), tolerance), nil
+}
+
+// GeometryEqual returns a boolean value indicating whether 'a' and 'b' are the same type and have
+// the same number of vertices with each pair of corresponding vertices within 'tolerance' of one another.
+// Rings are compared vertex by vertex so two rings with different starting points are not considered equal.
+func GeometryEqual(a orb.Geometry, b orb.Geometry, tolerance float64) bool {
+
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if a.GeoJSONType() != b.GeoJSONType() {
+		return false
+	}
+
+	switch geom_a := a.(type) {
+	case orb.Point:
+		return pointEqual(geom_a, b.(orb.Point), tolerance)
+	case orb.MultiPoint:
+		return pointsEqual(geom_a, b.(orb.MultiPoint), tolerance)
+	case orb.LineString:
+		return pointsEqual(geom_a, b.(orb.LineString), tolerance)
+	case orb.MultiLineString:
+
+		geom_b := b.(orb.MultiLineString)
+
+		if len(geom_a) != len(geom_b) {
+			return false
+		}
+
+		for i := range geom_a {
+			if !pointsEqual(geom_a[i], geom_b[i], tolerance) {
+				return false
+			}
+		}
+
+		return true
+
+	case orb.Polygon:
+		return polygonEqual(geom_a, b.(orb.Polygon), tolerance)
+	case orb.MultiPolygon:
+
+		geom_b := b.(orb.MultiPolygon)
+
+		if len(geom_a) != len(geom_b) {
+			return false
+		}
+
+		for i := range geom_a {
+			if !polygonEqual(geom_a[i], geom_b[i], tolerance) {
+				return false
+			}
+		}
+
+		return true
+
+	case orb.Collection:
+
+		geom_b := b.(orb.Collection)
+
+		if len(geom_a) != len(geom_b) {
+			return false
+		}
+
+		for i := range geom_a {
+			if !GeometryEqual(geom_a[i], geom_b[i], tolerance) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return orb.Equal(a, b)
+	}
+}
+
+func polygonEqual(a orb.Polygon, b orb.Polygon, tolerance float64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !pointsEqual(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pointsEqual[P ~[]orb.Point](a P, b P, tolerance float64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !pointEqual(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func pointEqual(a orb.Point, b orb.Point, tolerance float64) bool {
+	return math.Abs(a[0]-b[0]) <= tolerance && math.Abs(a[1]-b[1]) <= tolerance
+}
+
+// valueEqual returns a boolean value indicating whether the (decoded) JSON values 'a' and 'b' are equal, treating
+// numbers within 'tolerance' of one another as equal. It is used to compare the members of a geometry, like its
+// coordinates, which can't be decoded as a geometry of their own.
+func valueEqual(a interface{}, b interface{}, tolerance float64) bool {
+
+	switch value_a := a.(type) {
+	case float64:
+
+		value_b, ok := b.(float64)
+		return ok && math.Abs(value_a-value_b) <= tolerance
+
+	case []interface{}:
+
+		value_b, ok := b.([]interface{})
+
+		if !ok || len(value_a) != len(value_b) {
+			return false
+		}
+
+		for i := range value_a {
+			if !valueEqual(value_a[i], value_b[i], tolerance) {
+				return false
+			}
+		}
+
+		return true
+
+	case map[string]interface{}:
+
+		value_b, ok := b.(map[string]interface{})
+
+		if !ok || len(value_a) != len(value_b) {
+			return false
+		}
+
+		for k, v := range value_a {
+
+			v_b, exists := value_b[k]
+
+			if !exists || !valueEqual(v, v_b, tolerance) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return a == b
+	}
+}
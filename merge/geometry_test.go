@@ -0,0 +1,46 @@
+package merge
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// TestIsEqualGeometryPaths ensures that the geometry tolerance applies to the geometry path and the paths inside it,
+// but not to other paths.
+func TestIsEqualGeometryPaths(t *testing.T) {
+
+	wof_body := []byte(`{"type":"Feature","properties":{"src:geom":{"type":"Point","coordinates":[-122.3866,37.6162]}},"geometry":{"type":"Point","coordinates":[-122.3866,37.6162]}}`)
+	new_body := []byte(`{"type":"Feature","properties":{"src:geom":{"type":"Point","coordinates":[-122.38660001,37.6162]}},"geometry":{"type":"Point","coordinates":[-122.38660001,37.6162]}}`)
+
+	opts := &MergeOptions{
+		GeometryTolerance: 0.000001,
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"geometry", true},
+		{"geometry.coordinates", true},
+		{"geometry.coordinates.0", true},
+		{"geometry.type", true},
+		{"properties.src:geom", false},
+		{"properties.src:geom.coordinates", false},
+	}
+
+	for _, test := range tests {
+
+		eq := isEqual(test.path, gjson.GetBytes(wof_body, test.path), gjson.GetBytes(new_body, test.path), opts)
+
+		if eq != test.expected {
+			t.Fatalf("Unexpected result comparing '%s': %t, expected %t", test.path, eq, test.expected)
+		}
+	}
+
+	opts.GeometryTolerance = 0.0
+
+	if isEqual("geometry.coordinates", gjson.GetBytes(wof_body, "geometry.coordinates"), gjson.GetBytes(new_body, "geometry.coordinates"), opts) {
+		t.Fatalf("Expected coordinates to differ without a tolerance")
+	}
+}
@@ -0,0 +1,347 @@
+// Package merge provides methods for merging properties (and geometries) from an arbitrary GeoJSON
+// Feature in to a Who's On First record using per-path merge strategies.
+package merge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// STRATEGY_OVERWRITE replaces the existing value with the new value whenever the two differ.
+const STRATEGY_OVERWRITE string = "overwrite"
+
+// STRATEGY_KEEP_EXISTING never replaces an existing value. If both values are JSON objects then any
+// keys missing from the existing value will be copied from the new value (recursively).
+const STRATEGY_KEEP_EXISTING string = "keep-existing"
+
+// STRATEGY_FILL_MISSING only assigns the new value if the existing value is absent, null or empty.
+const STRATEGY_FILL_MISSING string = "fill-missing"
+
+// STRATEGY_ARRAY_UNION appends any elements of the new value that are not already present in the existing value.
+const STRATEGY_ARRAY_UNION string = "array-union"
+
+// STRATEGY_ARRAY_REPLACE replaces the existing value with the new value which must be an array.
+const STRATEGY_ARRAY_REPLACE string = "array-replace"
+
+// GEOMETRY_PATH is the path that is compared using geometry equality rather than JSON equality. Paths inside it, like
+// "geometry.coordinates", are compared number by number using the same tolerance.
+const GEOMETRY_PATH string = "geometry"
+
+// Strategies returns the list of valid merge strategies.
+func Strategies() []string {
+
+	return []string{
+		STRATEGY_OVERWRITE,
+		STRATEGY_KEEP_EXISTING,
+		STRATEGY_FILL_MISSING,
+		STRATEGY_ARRAY_UNION,
+		STRATEGY_ARRAY_REPLACE,
+	}
+}
+
+// IsValidStrategy returns a boolean value indicating whether 'strategy' is a valid merge strategy.
+func IsValidStrategy(strategy string) bool {
+
+	for _, s := range Strategies() {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergeOptions defines configuration options for the `MergeFeature` method.
+type MergeOptions struct {
+	// Paths is the list of tidwall/gjson paths to merge from the new feature in to the existing record.
+	Paths []string
+	// Strategies is an optional lookup table of merge strategies keyed by path.
+	Strategies map[string]string
+	// DefaultStrategy is the merge strategy to use for paths without an explicit strategy. If empty STRATEGY_OVERWRITE is assumed.
+	DefaultStrategy string
+	// GeometryTolerance is the maximum distance (in coordinate units) between two vertices for them to be considered equal.
+	// It applies to GEOMETRY_PATH and the paths inside it. Other paths, even if they contain geometries, are compared exactly.
+	GeometryTolerance float64
+}
+
+// Strategy returns the merge strategy for 'path'.
+func (opts *MergeOptions) Strategy(path string) string {
+
+	s, exists := opts.Strategies[path]
+
+	if exists {
+		return s
+	}
+
+	if opts.DefaultStrategy != "" {
+		return opts.DefaultStrategy
+	}
+
+	return STRATEGY_OVERWRITE
+}
+
+// Validate ensures that all the merge strategies defined by 'opts' are valid.
+func (opts *MergeOptions) Validate() error {
+
+	if opts.DefaultStrategy != "" && !IsValidStrategy(opts.DefaultStrategy) {
+		return fmt.Errorf("Invalid default strategy '%s'", opts.DefaultStrategy)
+	}
+
+	for path, s := range opts.Strategies {
+
+		if !IsValidStrategy(s) {
+			return fmt.Errorf("Invalid strategy '%s' for path '%s'", s, path)
+		}
+	}
+
+	if opts.GeometryTolerance < 0.0 {
+		return fmt.Errorf("Invalid geometry tolerance")
+	}
+
+	return nil
+}
+
+// MergeFeature merges the values for each path in 'opts.Paths' from 'new_body' in to 'wof_body' and returns the
+// updated record along with the (sorted) list of paths whose values were changed. Paths which are not present in
+// 'new_body' are ignored.
+func MergeFeature(ctx context.Context, wof_body []byte, new_body []byte, opts *MergeOptions) ([]byte, []string, error) {
+
+	err := opts.Validate()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed := make([]string, 0)
+
+	for _, path := range opts.Paths {
+
+		new_rsp := gjson.GetBytes(new_body, path)
+
+		if !new_rsp.Exists() {
+			continue
+		}
+
+		wof_rsp := gjson.GetBytes(wof_body, path)
+
+		value, update, err := mergeValue(path, wof_rsp, new_rsp, opts)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to merge '%s', %w", path, err)
+		}
+
+		if !update {
+			continue
+		}
+
+		wof_body, err = sjson.SetBytes(wof_body, path, value)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to assign '%s', %w", path, err)
+		}
+
+		changed = append(changed, path)
+	}
+
+	sort.Strings(changed)
+	return wof_body, changed, nil
+}
+
+func mergeValue(path string, wof_rsp gjson.Result, new_rsp gjson.Result, opts *MergeOptions) (interface{}, bool, error) {
+
+	new_value := new_rsp.Value()
+
+	strategy := opts.Strategy(path)
+
+	switch strategy {
+	case STRATEGY_OVERWRITE:
+
+		if wof_rsp.Exists() && isEqual(path, wof_rsp, new_rsp, opts) {
+			return nil, false, nil
+		}
+
+		return new_value, true, nil
+
+	case STRATEGY_FILL_MISSING:
+
+		if !isEmpty(wof_rsp) {
+			return nil, false, nil
+		}
+
+		if isEmpty(new_rsp) {
+			return nil, false, nil
+		}
+
+		return new_value, true, nil
+
+	case STRATEGY_KEEP_EXISTING:
+
+		if !wof_rsp.Exists() {
+			return new_value, true, nil
+		}
+
+		if !wof_rsp.IsObject() || !new_rsp.IsObject() {
+			return nil, false, nil
+		}
+
+		merged, update := mergeMissingKeys(wof_rsp, new_rsp)
+		return merged, update, nil
+
+	case STRATEGY_ARRAY_UNION:
+
+		union := make([]interface{}, 0)
+		seen := make(map[string]bool)
+
+		add := func(r gjson.Result) bool {
+
+			enc := encode(r.Value())
+
+			if seen[enc] {
+				return false
+			}
+
+			seen[enc] = true
+			union = append(union, r.Value())
+			return true
+		}
+
+		for _, r := range asArray(wof_rsp) {
+			add(r)
+		}
+
+		update := !wof_rsp.Exists() || !wof_rsp.IsArray()
+
+		for _, r := range asArray(new_rsp) {
+
+			if add(r) {
+				update = true
+			}
+		}
+
+		if !update {
+			return nil, false, nil
+		}
+
+		return union, true, nil
+
+	case STRATEGY_ARRAY_REPLACE:
+
+		if !new_rsp.IsArray() {
+			return nil, false, fmt.Errorf("Value is not an array")
+		}
+
+		if wof_rsp.Exists() && isEqual(path, wof_rsp, new_rsp, opts) {
+			return nil, false, nil
+		}
+
+		return new_value, true, nil
+
+	default:
+		return nil, false, fmt.Errorf("Invalid strategy '%s'", strategy)
+	}
+}
+
+func mergeMissingKeys(wof_rsp gjson.Result, new_rsp gjson.Result) (map[string]interface{}, bool) {
+
+	merged := make(map[string]interface{})
+	update := false
+
+	wof_rsp.ForEach(func(k gjson.Result, v gjson.Result) bool {
+		merged[k.String()] = v.Value()
+		return true
+	})
+
+	new_rsp.ForEach(func(k gjson.Result, v gjson.Result) bool {
+
+		key := k.String()
+		old_v := wof_rsp.Get(gjson.Escape(key))
+
+		if !old_v.Exists() {
+			merged[key] = v.Value()
+			update = true
+			return true
+		}
+
+		if old_v.IsObject() && v.IsObject() {
+
+			sub, sub_update := mergeMissingKeys(old_v, v)
+
+			if sub_update {
+				merged[key] = sub
+				update = true
+			}
+		}
+
+		return true
+	})
+
+	return merged, update
+}
+
+func isEqual(path string, wof_rsp gjson.Result, new_rsp gjson.Result, opts *MergeOptions) bool {
+
+	if path == GEOMETRY_PATH {
+
+		eq, err := GeometryEqualBytes([]byte(wof_rsp.Raw), []byte(new_rsp.Raw), opts.GeometryTolerance)
+
+		if err == nil {
+			return eq
+		}
+	}
+
+	if strings.HasPrefix(path, GEOMETRY_PATH+".") {
+		return valueEqual(wof_rsp.Value(), new_rsp.Value(), opts.GeometryTolerance)
+	}
+
+	enc_old := encode(wof_rsp.Value())
+	enc_new := encode(new_rsp.Value())
+
+	return enc_old == enc_new
+}
+
+func isEmpty(r gjson.Result) bool {
+
+	if !r.Exists() {
+		return true
+	}
+
+	switch r.Type {
+	case gjson.Null:
+		return true
+	case gjson.String:
+		return r.String() == ""
+	case gjson.JSON:
+
+		if r.IsArray() {
+			return len(r.Array()) == 0
+		}
+
+		return len(r.Map()) == 0
+	default:
+		return false
+	}
+}
+
+func asArray(r gjson.Result) []gjson.Result {
+
+	if !r.Exists() || r.Type == gjson.Null {
+		return []gjson.Result{}
+	}
+
+	if r.IsArray() {
+		return r.Array()
+	}
+
+	return []gjson.Result{r}
+}
+
+func encode(v interface{}) string {
+	enc, _ := json.Marshal(v)
+	return string(enc)
+}
@@ -0,0 +1,169 @@
+package merge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// ReconcileOptions defines configuration options for the `Reconcile` method.
+type ReconcileOptions struct {
+	// Paths is the list of tidwall/gjson paths to compare between the original and edited features.
+	Paths []string
+	// GeometryTolerance is the maximum distance (in coordinate units) between two vertices for them to be considered equal.
+	GeometryTolerance float64
+}
+
+// ModifiedFeature is a feature present in both the original and edited FeatureCollections.
+type ModifiedFeature struct {
+	// Id is the Who's On First ID of the feature.
+	Id int64
+	// Feature is the edited feature.
+	Feature gjson.Result
+	// Paths is the list of paths whose values differ between the original and edited features.
+	Paths []string
+}
+
+// Reconciliation describes the differences between an original FeatureCollection (typically produced by
+// wof-as-featurecollection) and a copy of that FeatureCollection which has been edited in a GIS application.
+type Reconciliation struct {
+	// New are the features in the edited FeatureCollection without a (valid) `wof:id` property.
+	New []gjson.Result
+	// Modified are the features in the edited FeatureCollection with one or more changed paths. Features whose
+	// `wof:id` property is not present in the original FeatureCollection are always considered modified.
+	Modified []*ModifiedFeature
+	// Unchanged are the IDs of the features in the edited FeatureCollection without any changed paths.
+	Unchanged []int64
+	// Deleted are the IDs of the features in the original FeatureCollection that are absent from the edited FeatureCollection.
+	Deleted []int64
+}
+
+// Reconcile compares 'original' and 'edited' (both lists of GeoJSON features) and returns a `Reconciliation`
+// instance describing which features have been added, modified or deleted.
+func Reconcile(ctx context.Context, original []gjson.Result, edited []gjson.Result, opts *ReconcileOptions) (*Reconciliation, error) {
+
+	merge_opts := &MergeOptions{
+		GeometryTolerance: opts.GeometryTolerance,
+	}
+
+	err := merge_opts.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := make(map[int64]gjson.Result)
+
+	for idx, f := range original {
+
+		id := featureId(f)
+
+		if id <= 0 {
+			return nil, fmt.Errorf("Original feature at offset %d is missing a valid wof:id property", idx)
+		}
+
+		_, exists := lookup[id]
+
+		if exists {
+			return nil, fmt.Errorf("Original feature %d is defined more than once", id)
+		}
+
+		lookup[id] = f
+	}
+
+	rec := &Reconciliation{
+		New:       make([]gjson.Result, 0),
+		Modified:  make([]*ModifiedFeature, 0),
+		Unchanged: make([]int64, 0),
+		Deleted:   make([]int64, 0),
+	}
+
+	seen := make(map[int64]bool)
+
+	for _, f := range edited {
+
+		id := featureId(f)
+
+		if id <= 0 {
+			rec.New = append(rec.New, f)
+			continue
+		}
+
+		if seen[id] {
+			return nil, fmt.Errorf("Edited feature %d is defined more than once", id)
+		}
+
+		seen[id] = true
+
+		original_f, exists := lookup[id]
+
+		if !exists {
+
+			m := &ModifiedFeature{
+				Id:      id,
+				Feature: f,
+				Paths:   opts.Paths,
+			}
+
+			rec.Modified = append(rec.Modified, m)
+			continue
+		}
+
+		changed := make([]string, 0)
+
+		for _, path := range opts.Paths {
+
+			new_rsp := f.Get(path)
+			old_rsp := original_f.Get(path)
+
+			if !new_rsp.Exists() {
+				continue
+			}
+
+			if old_rsp.Exists() && isEqual(path, old_rsp, new_rsp, merge_opts) {
+				continue
+			}
+
+			changed = append(changed, path)
+		}
+
+		if len(changed) == 0 {
+			rec.Unchanged = append(rec.Unchanged, id)
+			continue
+		}
+
+		m := &ModifiedFeature{
+			Id:      id,
+			Feature: f,
+			Paths:   changed,
+		}
+
+		rec.Modified = append(rec.Modified, m)
+	}
+
+	for id := range lookup {
+
+		if !seen[id] {
+			rec.Deleted = append(rec.Deleted, id)
+		}
+	}
+
+	sort.Slice(rec.Deleted, func(i, j int) bool {
+		return rec.Deleted[i] < rec.Deleted[j]
+	})
+
+	return rec, nil
+}
+
+func featureId(f gjson.Result) int64 {
+
+	id_rsp := f.Get("properties.wof:id")
+
+	if !id_rsp.Exists() {
+		return -1
+	}
+
+	return id_rsp.Int()
+}
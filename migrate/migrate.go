@@ -0,0 +1,470 @@
+// Package migrate provides methods for renaming the properties in one namespace (or matching a pattern) of a
+// Who's On First record to another namespace.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+)
+
+// COLLISION_FAIL causes `Migrate` to return an error if a property is renamed to a property that already exists.
+const COLLISION_FAIL string = "fail"
+
+// COLLISION_PREFER_OLD causes the value of the property being renamed to replace the value of the property that
+// already exists.
+const COLLISION_PREFER_OLD string = "prefer-old"
+
+// COLLISION_PREFER_NEW causes the value of the property that already exists to be kept and the property being
+// renamed to be removed.
+const COLLISION_PREFER_NEW string = "prefer-new"
+
+// COLLISION_MERGE_ARRAY causes the values of both properties to be merged in to a single array, containing the
+// unique elements of the existing value followed by the unique elements of the value being renamed. Values that
+// are not arrays are treated as single-element arrays.
+const COLLISION_MERGE_ARRAY string = "merge-array"
+
+// CollisionStrategies returns the list of valid collision strategies.
+func CollisionStrategies() []string {
+	return []string{COLLISION_FAIL, COLLISION_PREFER_OLD, COLLISION_PREFER_NEW, COLLISION_MERGE_ARRAY}
+}
+
+// Rename maps property names matching a regular expression to new names.
+type Rename struct {
+	pattern     *regexp.Regexp
+	replacement string
+	label       string
+}
+
+// NewPrefixRename returns a new `Rename` instance which replaces 'old_prefix' with 'new_prefix' in property names
+// that start with 'old_prefix'. It is an error for either prefix to match properties that are assigned by the exporter
+// (see `exportify.IsProtectedProperty`).
+func NewPrefixRename(old_prefix string, new_prefix string) (*Rename, error) {
+
+	if old_prefix == "" {
+		return nil, fmt.Errorf("Missing prefix")
+	}
+
+	for _, prefix := range []string{old_prefix, new_prefix} {
+
+		if prefix != "" && isProtectedPrefix(prefix) {
+			return nil, fmt.Errorf("Can not rename properties starting with '%s' because they include properties assigned by the exporter", prefix)
+		}
+	}
+
+	r := &Rename{
+		pattern:     regexp.MustCompile("^" + regexp.QuoteMeta(old_prefix)),
+		replacement: strings.ReplaceAll(new_prefix, "$", "$$"),
+		label:       fmt.Sprintf("%s*=%s*", old_prefix, new_prefix),
+	}
+
+	return r, nil
+}
+
+// NewRegexpRename returns a new `Rename` instance which replaces the first match of the regular expression 'pattern'
+// in property names with 'replacement', which may contain "$1" style references to capture groups. It is an error
+// for 'pattern' to match properties that are assigned by the exporter (see `exportify.IsProtectedProperty`).
+func NewRegexpRename(pattern string, replacement string) (*Rename, error) {
+
+	re, err := regexp.Compile(pattern)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to compile '%s', %w", pattern, err)
+	}
+
+	for _, name := range append(exportify.ProtectedProperties(), exportify.PROTECTED_PREFIX) {
+
+		if re.MatchString(name) {
+			return nil, fmt.Errorf("Can not rename properties matching '%s' because they include '%s' which is assigned by the exporter", pattern, name)
+		}
+	}
+
+	r := &Rename{
+		pattern:     re,
+		replacement: replacement,
+		label:       fmt.Sprintf("%s=%s", pattern, replacement),
+	}
+
+	return r, nil
+}
+
+// ParsePrefixRename parses a string in the form "{OLD_PREFIX}={NEW_PREFIX}" and returns a new `Rename` instance
+// created by `NewPrefixRename`. A trailing "*" is removed from both prefixes so that, for example, "sfomuseum:*=sfo:*"
+// and "sfomuseum:=sfo:" are equivalent. It is an error for only one of the prefixes to end in "*".
+func ParsePrefixRename(str string) (*Rename, error) {
+
+	old_prefix, new_prefix, err := splitRename(str)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(old_prefix, "*") != strings.HasSuffix(new_prefix, "*") {
+		return nil, fmt.Errorf("Invalid rename '%s', either both or neither of the prefixes must end in '*'", str)
+	}
+
+	return NewPrefixRename(strings.TrimSuffix(old_prefix, "*"), strings.TrimSuffix(new_prefix, "*"))
+}
+
+// ParseRegexpRename parses a string in the form "{PATTERN}={REPLACEMENT}" and returns a new `Rename` instance
+// created by `NewRegexpRename`.
+func ParseRegexpRename(str string) (*Rename, error) {
+
+	pattern, replacement, err := splitRename(str)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRegexpRename(pattern, replacement)
+}
+
+// splitRename splits 'str' on its last "=" character, since regular expressions are more likely to contain
+// one than property names.
+func splitRename(str string) (string, string, error) {
+
+	idx := strings.LastIndex(str, "=")
+
+	if idx <= 0 {
+		return "", "", fmt.Errorf("Invalid rename '%s', expected {OLD}={NEW}", str)
+	}
+
+	return str[:idx], str[idx+1:], nil
+}
+
+// isProtectedPrefix returns a boolean value indicating whether any of the properties assigned by the exporter start
+// with 'prefix'.
+func isProtectedPrefix(prefix string) bool {
+
+	if exportify.IsProtectedProperty(prefix) || strings.HasPrefix(exportify.PROTECTED_PREFIX, prefix) {
+		return true
+	}
+
+	for _, name := range exportify.ProtectedProperties() {
+
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Target returns the new name for the property 'name' and a boolean value indicating whether 'name' matches 'r'.
+func (r *Rename) Target(name string) (string, bool) {
+
+	loc := r.pattern.FindStringSubmatchIndex(name)
+
+	if loc == nil {
+		return "", false
+	}
+
+	dst := r.pattern.ExpandString(nil, r.replacement, name, loc)
+	return name[:loc[0]] + string(dst) + name[loc[1]:], true
+}
+
+// String returns a description of 'r'.
+func (r *Rename) String() string {
+	return r.label
+}
+
+// Renames is an ordered list of `Rename` instances. Properties are renamed by the first `Rename` they match.
+type Renames []*Rename
+
+// Target returns the new name for the property 'name' and a boolean value indicating whether 'name' matches any
+// of 'renames'.
+func (renames Renames) Target(name string) (string, bool) {
+
+	for _, r := range renames {
+
+		target, ok := r.Target(name)
+
+		if ok {
+			return target, true
+		}
+	}
+
+	return "", false
+}
+
+// Change describes a single property that was renamed.
+type Change struct {
+	// Old is the original name of the property.
+	Old string
+	// New is the new name of the property.
+	New string
+	// Collision is the collision strategy that was applied if the property being renamed collided with an
+	// existing property, or an empty string if it didn't.
+	Collision string
+}
+
+// CollisionError is the error returned by `Migrate` when a property is renamed to a property that already exists and
+// the COLLISION_FAIL strategy is used.
+type CollisionError struct {
+	// Old is the name of the property being renamed.
+	Old string
+	// New is the name of the property that already exists.
+	New string
+}
+
+// Error returns a description of 'e'.
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("Can not rename '%s' to '%s' because '%s' already exists", e.Old, e.New, e.New)
+}
+
+// MigrateOptions defines options for the `Migrate` method.
+type MigrateOptions struct {
+	// Renames is the list of renames to apply.
+	Renames Renames
+	// Collision is the strategy to apply when a property is renamed to a property that already exists. It must be
+	// one of the COLLISION_ constants.
+	Collision string
+}
+
+// Migrate renames the (top-level) properties of 'body' that match opts.Renames and returns the updated record along
+// with the list of changes. Entries in the record's "wof:controlled" property are renamed too, so the renamed properties
+// remain controlled. Properties that are not renamed, and their order, are left unchanged. It is an error to rename a
+// property to, or from, a property that is assigned by the exporter (see `exportify.IsProtectedProperty`).
+func Migrate(body []byte, opts *MigrateOptions) ([]byte, []*Change, error) {
+
+	switch opts.Collision {
+	case COLLISION_FAIL, COLLISION_PREFER_OLD, COLLISION_PREFER_NEW, COLLISION_MERGE_ARRAY:
+		// pass
+	default:
+		return nil, nil, fmt.Errorf("Invalid collision strategy '%s'", opts.Collision)
+	}
+
+	props_rsp := gjson.GetBytes(body, "properties")
+
+	if !props_rsp.IsObject() {
+		return body, nil, nil
+	}
+
+	// Determine all the renames first so that a property which is itself being renamed doesn't
+	// count as a collision.
+
+	names := make([]string, 0)
+	targets := make(map[string]string)
+
+	var protected_err error
+
+	props_rsp.ForEach(func(k gjson.Result, v gjson.Result) bool {
+
+		name := k.String()
+		names = append(names, name)
+
+		target, ok := opts.Renames.Target(name)
+
+		if !ok || target == name {
+			return true
+		}
+
+		if exportify.IsProtectedProperty(name) || exportify.IsProtectedProperty(target) {
+			protected_err = fmt.Errorf("Can not rename '%s' to '%s' because properties assigned by the exporter can not be renamed", name, target)
+			return false
+		}
+
+		targets[name] = target
+		return true
+	})
+
+	if protected_err != nil {
+		return nil, nil, protected_err
+	}
+
+	if len(targets) == 0 {
+		return body, nil, nil
+	}
+
+	// values maps the names of the properties being assigned to their (raw JSON) values.
+
+	values := make(map[string]string)
+	assigned := make([]string, 0)
+
+	changes := make([]*Change, 0)
+
+	for _, name := range names {
+
+		target, renamed := targets[name]
+
+		if !renamed {
+			continue
+		}
+
+		v := props_rsp.Get(gjson.Escape(name)).Raw
+		c := &Change{Old: name, New: target}
+
+		existing, exists := values[target]
+
+		if !exists {
+
+			_, moving := targets[target]
+			rsp := props_rsp.Get(gjson.Escape(target))
+
+			if rsp.Exists() && !moving {
+				existing = rsp.Raw
+				exists = true
+			}
+		}
+
+		if exists {
+
+			c.Collision = opts.Collision
+
+			switch opts.Collision {
+			case COLLISION_PREFER_OLD:
+				// pass
+			case COLLISION_PREFER_NEW:
+				v = existing
+			case COLLISION_MERGE_ARRAY:
+
+				merged, err := mergeArrays(existing, v)
+
+				if err != nil {
+					return nil, nil, fmt.Errorf("Failed to merge '%s' in to '%s', %w", name, target, err)
+				}
+
+				v = merged
+
+			default:
+				return nil, nil, &CollisionError{Old: name, New: target}
+			}
+		}
+
+		if _, ok := values[target]; !ok {
+			assigned = append(assigned, target)
+		}
+
+		values[target] = v
+		changes = append(changes, c)
+	}
+
+	var err error
+
+	for _, name := range names {
+
+		if _, renamed := targets[name]; !renamed {
+			continue
+		}
+
+		body, err = sjson.DeleteBytes(body, "properties."+gjson.Escape(name))
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to delete '%s', %w", name, err)
+		}
+	}
+
+	for _, name := range assigned {
+
+		body, err = sjson.SetRawBytes(body, "properties."+gjson.Escape(name), []byte(values[name]))
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to assign '%s', %w", name, err)
+		}
+	}
+
+	// Rewrite wof:controlled so that renamed properties remain controlled.
+
+	controlled_rsp := gjson.GetBytes(body, "properties.wof:controlled")
+
+	if controlled_rsp.IsArray() {
+
+		controlled := make([]interface{}, 0)
+		seen := make(map[string]bool)
+		updated := false
+
+		for _, c := range controlled_rsp.Array() {
+
+			if c.Type != gjson.String {
+				controlled = append(controlled, c.Value())
+				continue
+			}
+
+			name := c.String()
+
+			if name != exportify.CONTROLLED_GEOMETRY {
+
+				target, ok := opts.Renames.Target(name)
+
+				if ok && target != name {
+					name = target
+					updated = true
+				}
+			}
+
+			if seen[name] {
+				updated = true
+				continue
+			}
+
+			seen[name] = true
+			controlled = append(controlled, name)
+		}
+
+		if updated {
+
+			body, err = sjson.SetBytes(body, "properties.wof:controlled", controlled)
+
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to assign wof:controlled, %w", err)
+			}
+		}
+	}
+
+	return body, changes, nil
+}
+
+// mergeArrays returns the (raw JSON) array containing the unique elements of the raw JSON value 'a' followed by the
+// unique elements of the raw JSON value 'b' that are not in 'a'.
+func mergeArrays(a string, b string) (string, error) {
+
+	merged := make([]json.RawMessage, 0)
+	seen := make(map[string]bool)
+
+	for _, raw := range []string{a, b} {
+
+		rsp := gjson.Parse(raw)
+
+		items := []gjson.Result{rsp}
+
+		if rsp.IsArray() {
+			items = rsp.Array()
+		}
+
+		for _, item := range items {
+
+			if item.Type == gjson.Null {
+				continue
+			}
+
+			// Encode values in a canonical form so that, for example, objects with the same keys in a different
+			// order are considered equal.
+
+			enc, err := json.Marshal(item.Value())
+
+			if err != nil {
+				return "", err
+			}
+
+			if seen[string(enc)] {
+				continue
+			}
+
+			seen[string(enc)] = true
+			merged = append(merged, json.RawMessage(item.Raw))
+		}
+	}
+
+	enc, err := json.Marshal(merged)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(enc), nil
+}
@@ -0,0 +1,467 @@
+package exportify
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// OPERATION_SET_STRING assigns a string value to a path unless its current value, as a string, is the same.
+const OPERATION_SET_STRING string = "set-string"
+
+// OPERATION_SET_INT64 assigns an int64 value to a path unless its current value, as an int64, is the same.
+const OPERATION_SET_INT64 string = "set-int64"
+
+// OPERATION_SET_FLOAT64 assigns a float64 value to a path unless its current value, as a float64, is the same.
+const OPERATION_SET_FLOAT64 string = "set-float64"
+
+// OPERATION_SET_JSON assigns an arbitrary JSON-encoded value to a path.
+const OPERATION_SET_JSON string = "set-json"
+
+// OPERATION_SET_BOOL assigns a boolean value to a path.
+const OPERATION_SET_BOOL string = "set-bool"
+
+// OPERATION_SET_NULL assigns a null value to a path.
+const OPERATION_SET_NULL string = "set-null"
+
+// OPERATION_DELETE removes a path.
+const OPERATION_DELETE string = "delete"
+
+// OPERATION_ARRAY_APPEND_UNIQUE appends a JSON-encoded value (or each element of a JSON-encoded array) to the
+// array at a path, unless it is already present. If the path does not exist it is created.
+const OPERATION_ARRAY_APPEND_UNIQUE string = "array-append-unique"
+
+// OPERATION_ARRAY_REMOVE removes all the elements equal to a JSON-encoded value (or to any element of a JSON-encoded
+// array) from the array at a path.
+const OPERATION_ARRAY_REMOVE string = "array-remove"
+
+// OPERATION_MERGE_OBJECT assigns each of the keys in a JSON-encoded object to the object at a path. Only the keys
+// in the object being merged are changed.
+const OPERATION_MERGE_OBJECT string = "merge-object"
+
+// Operation is a single change to apply to a record.
+type Operation struct {
+	// Kind is the kind of operation. It must be one of the OPERATION_ constants.
+	Kind string
+	// Path is the tidwall/gjson path the operation is applied to.
+	Path string
+	// Value is the (decoded) value used by the operation. It is ignored by OPERATION_SET_NULL and OPERATION_DELETE.
+	Value interface{}
+}
+
+// Operations is an ordered list of `Operation` instances.
+type Operations []*Operation
+
+// OperationKinds returns the list of valid operation kinds.
+func OperationKinds() []string {
+
+	return []string{
+		OPERATION_SET_STRING,
+		OPERATION_SET_INT64,
+		OPERATION_SET_FLOAT64,
+		OPERATION_SET_JSON,
+		OPERATION_SET_BOOL,
+		OPERATION_SET_NULL,
+		OPERATION_DELETE,
+		OPERATION_ARRAY_APPEND_UNIQUE,
+		OPERATION_ARRAY_REMOVE,
+		OPERATION_MERGE_OBJECT,
+	}
+}
+
+// NewOperation returns a new `Operation` instance of kind 'kind' for 'path' whose value is derived by parsing 'str_value'.
+// 'str_value' is ignored by OPERATION_SET_NULL and OPERATION_DELETE operations.
+func NewOperation(kind string, path string, str_value string) (*Operation, error) {
+
+	if path == "" {
+		return nil, fmt.Errorf("Missing path")
+	}
+
+	op := &Operation{
+		Kind: kind,
+		Path: path,
+	}
+
+	switch kind {
+	case OPERATION_SET_STRING:
+		op.Value = str_value
+	case OPERATION_SET_INT64:
+
+		v, err := strconv.ParseInt(str_value, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse '%s' as int64, %w", str_value, err)
+		}
+
+		op.Value = v
+
+	case OPERATION_SET_FLOAT64:
+
+		v, err := strconv.ParseFloat(str_value, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse '%s' as float64, %w", str_value, err)
+		}
+
+		op.Value = v
+
+	case OPERATION_SET_BOOL:
+
+		v, err := strconv.ParseBool(str_value)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse '%s' as bool, %w", str_value, err)
+		}
+
+		op.Value = v
+
+	case OPERATION_SET_NULL, OPERATION_DELETE:
+		// pass
+	case OPERATION_SET_JSON, OPERATION_ARRAY_APPEND_UNIQUE, OPERATION_ARRAY_REMOVE, OPERATION_MERGE_OBJECT:
+
+		var v interface{}
+
+		err := json.Unmarshal([]byte(str_value), &v)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse '%s' as JSON, %w", str_value, err)
+		}
+
+		if kind == OPERATION_MERGE_OBJECT {
+
+			_, ok := v.(map[string]interface{})
+
+			if !ok {
+				return nil, fmt.Errorf("Value for %s operation must be a JSON object", kind)
+			}
+		}
+
+		op.Value = v
+
+	default:
+		return nil, fmt.Errorf("Invalid operation '%s'", kind)
+	}
+
+	return op, nil
+}
+
+// Apply applies 'op' to 'body' and returns the updated record along with the list of paths whose values were changed.
+func (op *Operation) Apply(body []byte) ([]byte, []string, error) {
+
+	old_rsp := gjson.GetBytes(body, op.Path)
+
+	switch op.Kind {
+	case OPERATION_SET_STRING:
+
+		if old_rsp.Exists() && old_rsp.String() == op.Value.(string) {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, op.Value)
+
+	case OPERATION_SET_INT64:
+
+		if old_rsp.Exists() && old_rsp.Int() == op.Value.(int64) {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, op.Value)
+
+	case OPERATION_SET_FLOAT64:
+
+		if old_rsp.Exists() && old_rsp.Float() == op.Value.(float64) {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, op.Value)
+
+	case OPERATION_SET_JSON, OPERATION_SET_BOOL, OPERATION_SET_NULL:
+
+		if old_rsp.Exists() && encodeValue(old_rsp.Value()) == encodeValue(op.Value) {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, op.Value)
+
+	case OPERATION_DELETE:
+
+		if !old_rsp.Exists() {
+			return body, nil, nil
+		}
+
+		new_body, err := sjson.DeleteBytes(body, op.Path)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to delete '%s', %w", op.Path, err)
+		}
+
+		return new_body, []string{op.Path}, nil
+
+	case OPERATION_ARRAY_APPEND_UNIQUE:
+
+		values, err := op.arrayValues(old_rsp)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		seen := make(map[string]bool)
+
+		for _, v := range values {
+			seen[encodeValue(v)] = true
+		}
+
+		changed := !old_rsp.Exists() || old_rsp.Type == gjson.Null
+
+		for _, v := range asValues(op.Value) {
+
+			enc := encodeValue(v)
+
+			if seen[enc] {
+				continue
+			}
+
+			seen[enc] = true
+			values = append(values, v)
+			changed = true
+		}
+
+		if !changed {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, values)
+
+	case OPERATION_ARRAY_REMOVE:
+
+		if !old_rsp.Exists() || old_rsp.Type == gjson.Null {
+			return body, nil, nil
+		}
+
+		values, err := op.arrayValues(old_rsp)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		remove := make(map[string]bool)
+
+		for _, v := range asValues(op.Value) {
+			remove[encodeValue(v)] = true
+		}
+
+		kept := make([]interface{}, 0)
+
+		for _, v := range values {
+
+			if !remove[encodeValue(v)] {
+				kept = append(kept, v)
+			}
+		}
+
+		if len(kept) == len(values) {
+			return body, nil, nil
+		}
+
+		return op.set(body, op.Path, kept)
+
+	case OPERATION_MERGE_OBJECT:
+
+		if old_rsp.Exists() && old_rsp.Type != gjson.Null && !old_rsp.IsObject() {
+			return nil, nil, fmt.Errorf("Existing value is not an object")
+		}
+
+		obj := op.Value.(map[string]interface{})
+
+		keys := make([]string, 0, len(obj))
+
+		for k := range obj {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		changed := make([]string, 0)
+
+		for _, k := range keys {
+
+			sub_path := fmt.Sprintf("%s.%s", op.Path, gjson.Escape(k))
+			sub_rsp := gjson.GetBytes(body, sub_path)
+
+			if sub_rsp.Exists() && encodeValue(sub_rsp.Value()) == encodeValue(obj[k]) {
+				continue
+			}
+
+			new_body, err := sjson.SetBytes(body, sub_path, obj[k])
+
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to assign '%s', %w", sub_path, err)
+			}
+
+			body = new_body
+			changed = append(changed, sub_path)
+		}
+
+		return body, changed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("Invalid operation '%s'", op.Kind)
+	}
+}
+
+// Paths returns the list of paths that 'op' may change. This is the operation's path except for OPERATION_MERGE_OBJECT
+// operations which may change each of the keys in the object being merged.
+func (op *Operation) Paths() []string {
+
+	if op.Kind != OPERATION_MERGE_OBJECT {
+		return []string{op.Path}
+	}
+
+	obj, _ := op.Value.(map[string]interface{})
+
+	paths := make([]string, 0, len(obj))
+
+	for k := range obj {
+		paths = append(paths, fmt.Sprintf("%s.%s", op.Path, gjson.Escape(k)))
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func (op *Operation) set(body []byte, path string, value interface{}) ([]byte, []string, error) {
+
+	new_body, err := sjson.SetBytes(body, path, value)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to assign '%s', %w", path, err)
+	}
+
+	return new_body, []string{path}, nil
+}
+
+// arrayValues returns the elements of the array in 'rsp' or an error if 'rsp' exists and is not an array.
+func (op *Operation) arrayValues(rsp gjson.Result) ([]interface{}, error) {
+
+	values := make([]interface{}, 0)
+
+	if !rsp.Exists() || rsp.Type == gjson.Null {
+		return values, nil
+	}
+
+	if !rsp.IsArray() {
+		return nil, fmt.Errorf("Existing value is not an array")
+	}
+
+	for _, r := range rsp.Array() {
+		values = append(values, r.Value())
+	}
+
+	return values, nil
+}
+
+// asValues returns 'v' if it is a list or a single-item list containing 'v'.
+func asValues(v interface{}) []interface{} {
+
+	values, ok := v.([]interface{})
+
+	if ok {
+		return values
+	}
+
+	return []interface{}{v}
+}
+
+func encodeValue(v interface{}) string {
+	enc, _ := json.Marshal(v)
+	return string(enc)
+}
+
+// operationFlag implements the `flag.Value` interface for appending operations of a given kind to an `Operations` list.
+type operationFlag struct {
+	kind string
+	ops  *Operations
+}
+
+// String returns the list of paths for the operations of the same kind as 'f'.
+func (f *operationFlag) String() string {
+
+	// flag.PrintDefaults calls String on a zero value to determine the default value.
+
+	if f.ops == nil {
+		return ""
+	}
+
+	paths := make([]string, 0)
+
+	for _, op := range *f.ops {
+
+		if op.Kind == f.kind {
+			paths = append(paths, op.Path)
+		}
+	}
+
+	return strings.Join(paths, ",")
+}
+
+// Set parses 'value' in the form of "{PATH}={VALUE}" (or "{PATH}" for OPERATION_SET_NULL and OPERATION_DELETE)
+// and appends a new operation to the list.
+func (f *operationFlag) Set(value string) error {
+
+	path := value
+	str_value := ""
+
+	switch f.kind {
+	case OPERATION_SET_NULL, OPERATION_DELETE:
+		// pass
+	default:
+
+		k, v, ok := strings.Cut(value, "=")
+
+		if !ok {
+			return fmt.Errorf("Invalid value '%s', expected {PATH}={VALUE}", value)
+		}
+
+		path = k
+		str_value = v
+	}
+
+	op, err := NewOperation(f.kind, path, str_value)
+
+	if err != nil {
+		return err
+	}
+
+	*f.ops = append(*f.ops, op)
+	return nil
+}
+
+// AppendPropertyFlags assigns the -string-property, -int-property and -float-property flags to 'fs' which append
+// set-string, set-int64 and set-float64 operations to 'ops' in the order they are passed on the command line.
+func AppendPropertyFlags(fs *flag.FlagSet, ops *Operations) {
+
+	fs.Var(&operationFlag{OPERATION_SET_STRING, ops}, "string-property", "Zero or more {PATH}={VALUE} flags where {PATH} is a valid tidwall/gjson path and {VALUE} is a string value to assign to it.")
+	fs.Var(&operationFlag{OPERATION_SET_INT64, ops}, "int-property", "Zero or more {PATH}={VALUE} flags where {PATH} is a valid tidwall/gjson path and {VALUE} is an int(64) value to assign to it.")
+	fs.Var(&operationFlag{OPERATION_SET_FLOAT64, ops}, "float-property", "Zero or more {PATH}={VALUE} flags where {PATH} is a valid tidwall/gjson path and {VALUE} is a float(64) value to assign to it.")
+}
+
+// AppendOperationFlags assigns flags to 'fs' for each of the operations (other than the set-string, set-int64 and
+// set-float64 operations which are assigned by `AppendPropertyFlags`) that append to 'ops' in the order they are
+// passed on the command line. Used with `AppendPropertyFlags` and the same 'ops' all the update flags are applied in
+// the order they are passed.
+func AppendOperationFlags(fs *flag.FlagSet, ops *Operations) {
+
+	fs.Var(&operationFlag{OPERATION_SET_JSON, ops}, "set-json", "Zero or more {PATH}={JSON} flags where {PATH} is a valid tidwall/gjson path and {JSON} is a JSON-encoded value to assign to it.")
+	fs.Var(&operationFlag{OPERATION_SET_BOOL, ops}, "set-bool", "Zero or more {PATH}={BOOL} flags where {PATH} is a valid tidwall/gjson path and {BOOL} is a boolean value to assign to it.")
+	fs.Var(&operationFlag{OPERATION_SET_NULL, ops}, "set-null", "Zero or more valid tidwall/gjson paths to assign a null value to.")
+	fs.Var(&operationFlag{OPERATION_DELETE, ops}, "delete", "Zero or more valid tidwall/gjson paths to remove.")
+	fs.Var(&operationFlag{OPERATION_ARRAY_APPEND_UNIQUE, ops}, "append-unique", "Zero or more {PATH}={JSON} flags where {PATH} is a valid tidwall/gjson path to an array and {JSON} is a JSON-encoded value, or array of values, to append to it if not already present.")
+	fs.Var(&operationFlag{OPERATION_ARRAY_REMOVE, ops}, "array-remove", "Zero or more {PATH}={JSON} flags where {PATH} is a valid tidwall/gjson path to an array and {JSON} is a JSON-encoded value, or array of values, to remove from it.")
+	fs.Var(&operationFlag{OPERATION_MERGE_OBJECT, ops}, "merge-object", "Zero or more {PATH}={JSON} flags where {PATH} is a valid tidwall/gjson path to an object and {JSON} is a JSON-encoded object whose keys will be assigned to it.")
+}
@@ -0,0 +1,154 @@
+package exportify
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ExpandPath returns the list of tidwall/gjson paths in 'body' that match 'pattern', in the order they occur in
+// the document. Components of 'pattern' may contain the glob characters supported by `path.Match` (for example
+// "properties.misc:*" or "properties.name:*_x_colloquial") which are matched against the keys of the object
+// at that point in the document. Use "\" to escape glob characters and "." in keys. If 'pattern' does not contain
+// any glob characters it is returned if it exists in 'body'.
+func ExpandPath(body []byte, pattern string) ([]string, error) {
+
+	err := ValidatePathPattern(pattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+	expandPath(gjson.ParseBytes(body), "", splitPath(pattern), &paths)
+
+	return paths, nil
+}
+
+// ValidatePathPattern returns an error if any of the components of 'pattern' is a malformed glob pattern.
+func ValidatePathPattern(pattern string) error {
+
+	for _, c := range splitPath(pattern) {
+
+		if !isGlob(c) {
+			continue
+		}
+
+		_, err := path.Match(c, "")
+
+		if err != nil {
+			return fmt.Errorf("Invalid pattern '%s', %w", c, err)
+		}
+	}
+
+	return nil
+}
+
+// IsPathPattern returns a boolean value indicating whether any of the components of 'pattern' contain (unescaped)
+// glob characters.
+func IsPathPattern(pattern string) bool {
+
+	for _, c := range splitPath(pattern) {
+
+		if isGlob(c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func expandPath(node gjson.Result, prefix string, components []string, paths *[]string) {
+
+	if len(components) == 0 {
+		*paths = append(*paths, prefix)
+		return
+	}
+
+	c := components[0]
+
+	join := func(key string) string {
+
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "." + key
+	}
+
+	if !isGlob(c) {
+
+		child := node.Get(c)
+
+		if child.Exists() {
+			expandPath(child, join(c), components[1:], paths)
+		}
+
+		return
+	}
+
+	if !node.IsObject() {
+		return
+	}
+
+	node.ForEach(func(k gjson.Result, v gjson.Result) bool {
+
+		ok, _ := path.Match(c, k.String())
+
+		if ok {
+			expandPath(v, join(gjson.Escape(k.String())), components[1:], paths)
+		}
+
+		return true
+	})
+}
+
+// splitPath splits the tidwall/gjson path 'p' on unescaped "." characters. Escape characters are preserved.
+func splitPath(p string) []string {
+
+	components := make([]string, 0)
+
+	var sb strings.Builder
+	escaped := false
+
+	for _, r := range p {
+
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '.':
+			components = append(components, sb.String())
+			sb.Reset()
+			continue
+		}
+
+		sb.WriteRune(r)
+	}
+
+	components = append(components, sb.String())
+	return components
+}
+
+// isGlob returns a boolean value indicating whether the path component 'c' contains unescaped glob characters.
+func isGlob(c string) bool {
+
+	escaped := false
+
+	for _, r := range c {
+
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*' || r == '?' || r == '[':
+			return true
+		}
+	}
+
+	return false
+}
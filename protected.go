@@ -0,0 +1,68 @@
+package exportify
+
+import (
+	"strings"
+)
+
+// PROTECTED_PREFIX is the prefix of the (geometry-derived) properties that are assigned by the exporter.
+const PROTECTED_PREFIX string = "geom:"
+
+// protectedProperties are the properties that are assigned, or derived, by the exporter. Renaming or removing
+// them causes the exporter to mint new IDs or to silently recompute their values.
+var protectedProperties = []string{
+	"wof:id",
+	"wof:parent_id",
+	"wof:placetype",
+	"wof:repo",
+	"wof:hierarchy",
+	"wof:belongsto",
+	"wof:lastmodified",
+	"src:alt_label",
+}
+
+// ProtectedProperties returns the list of properties that are assigned, or derived, by the exporter. All the
+// properties starting with PROTECTED_PREFIX are protected too.
+func ProtectedProperties() []string {
+
+	names := make([]string, len(protectedProperties))
+	copy(names, protectedProperties)
+
+	return names
+}
+
+// IsProtectedProperty returns a boolean value indicating whether the (top-level) property 'name' is assigned, or
+// derived, by the exporter.
+func IsProtectedProperty(name string) bool {
+
+	if strings.HasPrefix(name, PROTECTED_PREFIX) {
+		return true
+	}
+
+	for _, p := range protectedProperties {
+
+		if name == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsProtectedPath returns a boolean value indicating whether changing the tidwall/gjson 'path' would change a
+// property that is assigned, or derived, by the exporter.
+func IsProtectedPath(path string) bool {
+
+	// Replacing the entire properties dictionary changes every protected property.
+
+	if path == "properties" {
+		return true
+	}
+
+	components := splitPath(path)
+
+	if len(components) < 2 || components[0] != "properties" {
+		return false
+	}
+
+	return IsProtectedProperty(components[1])
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-whosonfirst-id"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// CHECKED_PROVIDER_MAX_ATTEMPTS is the maximum number of identifiers a `CheckedProvider` will mint
+// before giving up on finding one that doesn't already exist.
+const CHECKED_PROVIDER_MAX_ATTEMPTS int = 100
+
+// CheckedProvider implements the `id.Provider` interface by wrapping another `id.Provider` instance and
+// discarding any identifiers it mints that already exist in a `reader.Reader` instance.
+type CheckedProvider struct {
+	id.Provider
+	provider id.Provider
+	reader   reader.Reader
+}
+
+// NewCheckedProvider returns a new `CheckedProvider` instance that mints identifiers using 'pr' and checks
+// them against the records in 'r'.
+func NewCheckedProvider(ctx context.Context, pr id.Provider, r reader.Reader) (id.Provider, error) {
+
+	checked_pr := &CheckedProvider{
+		provider: pr,
+		reader:   r,
+	}
+
+	return checked_pr, nil
+}
+
+// NewID returns a new identifier which can not be read from the underlying reader. Identifiers which the reader
+// reports as not found, or which are read as empty documents (for example by the null:// reader), are assumed not to
+// exist. Any other error reading an identifier is returned since it doesn't mean the identifier is available.
+func (pr *CheckedProvider) NewID(ctx context.Context) (int64, error) {
+
+	for i := 0; i < CHECKED_PROVIDER_MAX_ATTEMPTS; i++ {
+
+		new_id, err := pr.provider.NewID(ctx)
+
+		if err != nil {
+			return -1, err
+		}
+
+		rel_path, err := uri.Id2RelPath(new_id)
+
+		if err != nil {
+			return -1, fmt.Errorf("Failed to derive path for %d, %w", new_id, err)
+		}
+
+		fh, err := pr.reader.Read(ctx, rel_path)
+
+		if err != nil {
+
+			if isNotFound(ctx, pr.reader, rel_path, err) {
+				return new_id, nil
+			}
+
+			return -1, fmt.Errorf("Failed to determine whether %d already exists, %w", new_id, err)
+		}
+
+		body, err := io.ReadAll(fh)
+		fh.Close()
+
+		if err != nil {
+			return -1, fmt.Errorf("Failed to read %d, %w", new_id, err)
+		}
+
+		if len(body) == 0 {
+			return new_id, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Failed to mint an identifier that doesn't already exist after %d attempts", CHECKED_PROVIDER_MAX_ATTEMPTS)
+}
+
+// isNotFound returns a boolean value indicating whether 'err', returned by 'r' when reading 'rel_path', means that
+// there is no record at 'rel_path'. Not every reader wraps `os.ErrNotExist` (the whosonfirst/go-reader fs:// reader
+// doesn't) so if 'r' resolves 'rel_path' to a local file that file is checked instead.
+func isNotFound(ctx context.Context, r reader.Reader, rel_path string, err error) bool {
+
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+
+	abs_path := r.ReaderURI(ctx, rel_path)
+
+	if !filepath.IsAbs(abs_path) {
+		return false
+	}
+
+	_, err = os.Stat(abs_path)
+	return errors.Is(err, os.ErrNotExist)
+}
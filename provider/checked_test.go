@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/whosonfirst/go-ioutil"
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-whosonfirst-exportify/mem"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// errorReader is a `reader.Reader` whose Read method always fails with 'err'.
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	return nil, r.err
+}
+
+func (r *errorReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
+
+// existsReader is a `reader.Reader` which returns the same (non-empty) record for every path.
+type existsReader struct{}
+
+func (r *existsReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+	return ioutil.NewReadSeekCloser(strings.NewReader(`{"type":"Feature"}`))
+}
+
+func (r *existsReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
+
+func newCheckedSequence(t *testing.T, r reader.Reader) *CheckedProvider {
+
+	ctx := context.Background()
+
+	seq, err := NewProvider(ctx, "sequence://?seed=1360000000")
+
+	if err != nil {
+		t.Fatalf("Failed to create sequence provider, %v", err)
+	}
+
+	pr, err := NewCheckedProvider(ctx, seq, r)
+
+	if err != nil {
+		t.Fatalf("Failed to create checked provider, %v", err)
+	}
+
+	return pr.(*CheckedProvider)
+}
+
+func writeRecord(t *testing.T, root string, id int64) {
+
+	rel_path, err := uri.Id2RelPath(id)
+
+	if err != nil {
+		t.Fatalf("Failed to derive path for %d, %v", id, err)
+	}
+
+	path := filepath.Join(root, rel_path)
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create %s, %v", filepath.Dir(path), err)
+	}
+
+	err = os.WriteFile(path, []byte(`{"type":"Feature"}`), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write %s, %v", path, err)
+	}
+}
+
+func TestCheckedProviderFS(t *testing.T) {
+
+	ctx := context.Background()
+	root := t.TempDir()
+
+	writeRecord(t, root, 1360000000)
+	writeRecord(t, root, 1360000001)
+
+	r, err := reader.NewReader(ctx, "fs://"+root)
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	pr := newCheckedSequence(t, r)
+
+	new_id, err := pr.NewID(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to mint ID, %v", err)
+	}
+
+	if new_id != 1360000002 {
+		t.Fatalf("Expected existing IDs to be skipped, got %d", new_id)
+	}
+}
+
+func TestCheckedProviderMem(t *testing.T) {
+
+	ctx := context.Background()
+
+	s := mem.GetStore(t.Name())
+	defer mem.RemoveStore(t.Name())
+
+	rel_path, err := uri.Id2RelPath(1360000000)
+
+	if err != nil {
+		t.Fatalf("Failed to derive path, %v", err)
+	}
+
+	s.Write(rel_path, []byte(`{"type":"Feature"}`))
+
+	r, err := reader.NewReader(ctx, "mem://"+t.Name())
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	pr := newCheckedSequence(t, r)
+
+	new_id, err := pr.NewID(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to mint ID, %v", err)
+	}
+
+	if new_id != 1360000001 {
+		t.Fatalf("Expected existing ID to be skipped, got %d", new_id)
+	}
+}
+
+func TestCheckedProviderNull(t *testing.T) {
+
+	ctx := context.Background()
+
+	r, err := reader.NewReader(ctx, "null://")
+
+	if err != nil {
+		t.Fatalf("Failed to create reader, %v", err)
+	}
+
+	pr := newCheckedSequence(t, r)
+
+	new_id, err := pr.NewID(ctx)
+
+	if err != nil {
+		t.Fatalf("Failed to mint ID, %v", err)
+	}
+
+	if new_id != 1360000000 {
+		t.Fatalf("Expected empty records to be treated as missing, got %d", new_id)
+	}
+}
+
+func TestCheckedProviderErrors(t *testing.T) {
+
+	ctx := context.Background()
+
+	// Errors other than a record not existing don't mean that an ID is available.
+
+	unavailable := errors.New("Connection refused")
+	pr := newCheckedSequence(t, &errorReader{err: unavailable})
+
+	_, err := pr.NewID(ctx)
+
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("Expected reader error to be returned, got %v", err)
+	}
+
+	pr = newCheckedSequence(t, &errorReader{err: os.ErrNotExist})
+
+	new_id, err := pr.NewID(ctx)
+
+	if err != nil || new_id != 1360000000 {
+		t.Fatalf("Expected not found error to mean the ID is available, got %d, %v", new_id, err)
+	}
+
+	pr = newCheckedSequence(t, &existsReader{})
+
+	_, err = pr.NewID(ctx)
+
+	if err == nil {
+		t.Fatalf("Expected an error after %d existing IDs", CHECKED_PROVIDER_MAX_ATTEMPTS)
+	}
+}
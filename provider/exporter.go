@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/whosonfirst/go-reader"
+	export "github.com/whosonfirst/go-whosonfirst-export/v2"
+	"github.com/whosonfirst/go-whosonfirst-id"
+)
+
+// ProviderExporter implements the whosonfirst/go-whosonfirst-export `Exporter` interface, exporting records the same
+// way as the default "whosonfirst://" exporter but minting new identifiers with a custom `id.Provider` instance.
+type ProviderExporter struct {
+	export.Exporter
+	options *export.Options
+}
+
+// NewExporter returns a new `export.Exporter` instance for 'exporter_uri'. If 'provider_uri' is empty this is the same
+// as calling `export.NewExporter`. Otherwise new identifiers are minted by the `id.Provider` instance derived from
+// 'provider_uri' and wrapped in a `CheckedProvider` so that identifiers which already exist in 'r' are never used.
+// Custom providers are only supported for "whosonfirst://" exporter URIs.
+func NewExporter(ctx context.Context, exporter_uri string, provider_uri string, r reader.Reader) (export.Exporter, error) {
+
+	if provider_uri == "" {
+		return export.NewExporter(ctx, exporter_uri)
+	}
+
+	u, err := url.Parse(exporter_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse exporter URI, %w", err)
+	}
+
+	if u.Scheme != "whosonfirst" {
+		return nil, fmt.Errorf("Custom ID providers are not supported for '%s' exporters", u.Scheme)
+	}
+
+	pr, err := NewProvider(ctx, provider_uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create ID provider for '%s', %w", provider_uri, err)
+	}
+
+	checked_pr, err := NewCheckedProvider(ctx, pr, r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create checked ID provider, %w", err)
+	}
+
+	return NewExporterWithProvider(ctx, checked_pr)
+}
+
+// NewExporterWithProvider returns a new `ProviderExporter` instance that mints new identifiers using 'pr'.
+func NewExporterWithProvider(ctx context.Context, pr id.Provider) (export.Exporter, error) {
+
+	opts, err := export.NewDefaultOptionsWithProvider(ctx, pr)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create export options, %w", err)
+	}
+
+	ex := &ProviderExporter{
+		options: opts,
+	}
+
+	return ex, nil
+}
+
+// ExportFeature encodes 'feature' as JSON and then exports it.
+func (ex *ProviderExporter) ExportFeature(ctx context.Context, feature interface{}) ([]byte, error) {
+
+	body, err := json.Marshal(feature)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ex.Export(ctx, body)
+}
+
+// Export prepares and formats 'feature', assigning a new identifier if necessary.
+func (ex *ProviderExporter) Export(ctx context.Context, feature []byte) ([]byte, error) {
+
+	var err error
+
+	feature, err = export.Prepare(feature, ex.options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	feature, err = export.Format(feature, ex.options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return feature, nil
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+	"github.com/whosonfirst/go-whosonfirst-id"
+)
+
+// RANGE_LOCK_TIMEOUT is the maximum amount of time to wait to acquire the lock file for a range file.
+const RANGE_LOCK_TIMEOUT time.Duration = 10 * time.Second
+
+// RANGE_LOCK_INTERVAL is the amount of time to wait between attempts to acquire the lock file for a range file.
+const RANGE_LOCK_INTERVAL time.Duration = 50 * time.Millisecond
+
+// Range defines a block of reserved identifiers stored in a range file.
+type Range struct {
+	// Min is the first identifier in the range.
+	Min int64 `json:"min"`
+	// Max is the last identifier in the range.
+	Max int64 `json:"max"`
+	// Next is the next identifier to mint. If zero then `Min` is used.
+	Next int64 `json:"next,omitempty"`
+}
+
+// RangeProvider implements the `id.Provider` interface for minting identifiers from a block of reserved
+// identifiers stored in a range file.
+type RangeProvider struct {
+	id.Provider
+	path string
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterProvider(ctx, "range", NewRangeProvider)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewRangeProvider returns a new `RangeProvider` instance configured by 'uri' in the form of:
+//
+//	range://{PATH}
+//
+// Where {PATH} is the path to an existing range file. Absolute paths have three slashes, for example
+// "range:///usr/local/data/ids.json", and relative paths, for example "range://ids.json", are relative to the
+// current working directory. Range files are JSON-encoded `Range` instances, for example:
+//
+//	{"min": 1360000000, "max": 1360000999}
+//
+// Each identifier is allocated by acquiring a "{PATH}.lock" lock file, reading the range file, incrementing its
+// "next" property and atomically replacing the range file so that multiple processes can share the same range.
+func NewRangeProvider(ctx context.Context, uri string) (id.Provider, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	// Relative paths like "range://ids.json" or "range://data/ids.json" are parsed with their first
+	// component as the URI's host.
+
+	path := u.Host + u.Path
+
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("Missing range file path")
+	}
+
+	abs_path, err := filepath.Abs(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive absolute path for %s, %w", path, err)
+	}
+
+	_, err = readRange(abs_path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &RangeProvider{
+		path: abs_path,
+	}
+
+	return pr, nil
+}
+
+// NewID returns the next identifier in the range file, returning an error if the range has been exhausted.
+func (pr *RangeProvider) NewID(ctx context.Context) (int64, error) {
+
+	unlock, err := concurrency.AcquireLock(ctx, pr.path+".lock", RANGE_LOCK_TIMEOUT, RANGE_LOCK_INTERVAL)
+
+	if err != nil {
+		return -1, err
+	}
+
+	defer unlock()
+
+	r, err := readRange(pr.path)
+
+	if err != nil {
+		return -1, err
+	}
+
+	next := r.Next
+
+	if next == 0 {
+		next = r.Min
+	}
+
+	if next > r.Max {
+		return -1, fmt.Errorf("Range in %s has been exhausted", pr.path)
+	}
+
+	r.Next = next + 1
+
+	err = writeRange(pr.path, r)
+
+	if err != nil {
+		return -1, err
+	}
+
+	return next, nil
+}
+
+func readRange(path string) (*Range, error) {
+
+	body, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read range file %s, %w", path, err)
+	}
+
+	var r *Range
+
+	err = json.Unmarshal(body, &r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal range file %s, %w", path, err)
+	}
+
+	if r.Min < 1 || r.Max < r.Min {
+		return nil, fmt.Errorf("Invalid range in %s, min must be greater than zero and less than or equal to max", path)
+	}
+
+	if r.Next != 0 && r.Next < r.Min {
+		return nil, fmt.Errorf("Invalid range in %s, next must be greater than or equal to min", path)
+	}
+
+	return r, nil
+}
+
+// writeRange writes 'r' to a temporary file in the same directory as 'path' and then renames it to 'path'.
+func writeRange(path string, r *Range) error {
+
+	body, err := json.Marshal(r)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal range, %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	tmp_path := tmp.Name()
+
+	_, err = tmp.Write(body)
+
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp_path)
+		return fmt.Errorf("Failed to write temporary file, %w", err)
+	}
+
+	err = tmp.Close()
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return fmt.Errorf("Failed to close temporary file, %w", err)
+	}
+
+	err = os.Rename(tmp_path, path)
+
+	if err != nil {
+		os.Remove(tmp_path)
+		return fmt.Errorf("Failed to replace range file %s, %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/whosonfirst/go-whosonfirst-exportify/concurrency"
+)
+
+func writeRangeFile(t *testing.T, path string, body string) {
+
+	err := os.WriteFile(path, []byte(body), 0644)
+
+	if err != nil {
+		t.Fatalf("Failed to write range file, %v", err)
+	}
+}
+
+func TestRangeProviderURI(t *testing.T) {
+
+	ctx := context.Background()
+
+	root := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(root, "ranges"), 0755)
+
+	if err != nil {
+		t.Fatalf("Failed to create directory, %v", err)
+	}
+
+	writeRangeFile(t, filepath.Join(root, "ids.json"), `{"min": 1, "max": 10}`)
+	writeRangeFile(t, filepath.Join(root, "ranges", "ids.json"), `{"min": 1, "max": 10}`)
+
+	// Relative paths are relative to the current working directory.
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	err = os.Chdir(root)
+
+	if err != nil {
+		t.Fatalf("Failed to change directory, %v", err)
+	}
+
+	t.Cleanup(func() {
+		os.Chdir(cwd)
+	})
+
+	// The temporary directory may be a symlink so derive paths from the current working directory.
+
+	root, err = os.Getwd()
+
+	if err != nil {
+		t.Fatalf("Failed to derive current working directory, %v", err)
+	}
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{uri: "range://" + filepath.Join(root, "ids.json"), expected: filepath.Join(root, "ids.json")},
+		{uri: "range://ids.json", expected: filepath.Join(root, "ids.json")},
+		{uri: "range://ranges/ids.json", expected: filepath.Join(root, "ranges", "ids.json")},
+		{uri: "range://./ranges/ids.json", expected: filepath.Join(root, "ranges", "ids.json")},
+		{uri: "range:ranges/ids.json", expected: filepath.Join(root, "ranges", "ids.json")},
+	}
+
+	for _, test := range tests {
+
+		pr, err := NewProvider(ctx, test.uri)
+
+		if err != nil {
+			t.Errorf("Failed to create provider for %s, %v", test.uri, err)
+			continue
+		}
+
+		path := pr.(*RangeProvider).path
+
+		if path != test.expected {
+			t.Errorf("Expected path for %s to be %s, got %s", test.uri, test.expected, path)
+		}
+	}
+
+	invalid := []string{
+		"range://",
+		"range://missing.json",
+		"range:///missing/ids.json",
+	}
+
+	for _, uri := range invalid {
+
+		_, err := NewProvider(ctx, uri)
+
+		if err == nil {
+			t.Errorf("Expected %s to fail", uri)
+		}
+	}
+}
+
+func TestRangeProviderInvalid(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ids.json")
+
+	invalid := []string{
+		`not json`,
+		`{"min": 0, "max": 10}`,
+		`{"min": 10, "max": 1}`,
+		`{"min": 10, "max": 20, "next": 5}`,
+	}
+
+	for _, body := range invalid {
+
+		writeRangeFile(t, path, body)
+
+		_, err := NewProvider(ctx, "range://"+path)
+
+		if err == nil {
+			t.Errorf("Expected range file %s to be invalid", body)
+		}
+	}
+}
+
+func TestRangeProviderExhausted(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ids.json")
+
+	writeRangeFile(t, path, `{"min": 1360000000, "max": 1360000002, "next": 1360000001}`)
+
+	pr, err := NewProvider(ctx, "range://"+path)
+
+	if err != nil {
+		t.Fatalf("Failed to create provider, %v", err)
+	}
+
+	for _, expected := range []int64{1360000001, 1360000002} {
+
+		new_id, err := pr.NewID(ctx)
+
+		if err != nil {
+			t.Fatalf("Failed to mint ID, %v", err)
+		}
+
+		if new_id != expected {
+			t.Fatalf("Expected %d, got %d", expected, new_id)
+		}
+	}
+
+	_, err = pr.NewID(ctx)
+
+	if err == nil {
+		t.Fatalf("Expected exhausted range to fail")
+	}
+
+	// The range file records the next ID, for other processes, even once it has been exhausted.
+
+	r, err := readRange(path)
+
+	if err != nil {
+		t.Fatalf("Failed to read range file, %v", err)
+	}
+
+	if r.Next != 1360000003 {
+		t.Fatalf("Expected next ID to be 1360000003, got %d", r.Next)
+	}
+}
+
+func TestRangeProviderConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ids.json")
+
+	writeRangeFile(t, path, `{"min": 1, "max": 100}`)
+
+	// Separate providers sharing the same range file behave like separate processes.
+
+	providers := make([]*RangeProvider, 4)
+
+	for i := range providers {
+
+		pr, err := NewRangeProvider(ctx, "range://"+path)
+
+		if err != nil {
+			t.Fatalf("Failed to create provider, %v", err)
+		}
+
+		providers[i] = pr.(*RangeProvider)
+	}
+
+	ids := make([]int64, 0)
+	mu := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+
+	for _, pr := range providers {
+
+		wg.Add(1)
+
+		go func(pr *RangeProvider) {
+
+			defer wg.Done()
+
+			for i := 0; i < 10; i++ {
+
+				new_id, err := pr.NewID(ctx)
+
+				if err != nil {
+					t.Errorf("Failed to mint ID, %v", err)
+					return
+				}
+
+				mu.Lock()
+				ids = append(ids, new_id)
+				mu.Unlock()
+			}
+		}(pr)
+	}
+
+	wg.Wait()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for i, id := range ids {
+
+		if id != int64(i+1) {
+			t.Fatalf("Expected IDs 1 to %d to be minted exactly once, got %v", len(ids), ids)
+		}
+	}
+}
+
+func TestRangeProviderLock(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ids.json")
+
+	writeRangeFile(t, path, `{"min": 1, "max": 10}`)
+
+	pr, err := NewProvider(ctx, "range://"+path)
+
+	if err != nil {
+		t.Fatalf("Failed to create provider, %v", err)
+	}
+
+	unlock, err := concurrency.AcquireLock(ctx, path+".lock", time.Second, 10*time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Failed to acquire lock, %v", err)
+	}
+
+	done := make(chan int64)
+
+	go func() {
+
+		new_id, err := pr.NewID(ctx)
+
+		if err != nil {
+			t.Errorf("Failed to mint ID, %v", err)
+		}
+
+		done <- new_id
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Expected ID not to be minted while the range file is locked")
+	case <-time.After(200 * time.Millisecond):
+		// pass
+	}
+
+	unlock()
+
+	select {
+	case new_id := <-done:
+
+		if new_id != 1 {
+			t.Fatalf("Expected 1, got %d", new_id)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected ID to be minted once the range file is unlocked")
+	}
+
+	// A cancelled context stops waiting for the lock.
+
+	unlock, err = concurrency.AcquireLock(ctx, path+".lock", time.Second, 10*time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("Failed to acquire lock, %v", err)
+	}
+
+	defer unlock()
+
+	cancel_ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err = pr.NewID(cancel_ctx)
+
+	if err == nil {
+		t.Fatalf("Expected cancelled context to fail")
+	}
+}
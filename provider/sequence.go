@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/whosonfirst/go-whosonfirst-id"
+)
+
+// SequenceProvider implements the `id.Provider` interface for minting a deterministic sequence of
+// identifiers starting with a seed value. It is intended for tests and other situations where the
+// identifiers of new records need to be known in advance.
+type SequenceProvider struct {
+	id.Provider
+	next int64
+	step int64
+	mu   *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterProvider(ctx, "sequence", NewSequenceProvider)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSequenceProvider returns a new `SequenceProvider` instance configured by 'uri' in the form of:
+//
+//	sequence://?seed={INT}&step={INT}
+//
+// Where {seed} is the first identifier to mint (default 1) and {step} is the (positive) amount each
+// subsequent identifier is incremented by (default 1).
+func NewSequenceProvider(ctx context.Context, uri string) (id.Provider, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	seed := int64(1)
+	step := int64(1)
+
+	if q.Has("seed") {
+
+		v, err := strconv.ParseInt(q.Get("seed"), 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?seed= parameter, %w", err)
+		}
+
+		seed = v
+	}
+
+	if seed < 1 {
+		return nil, fmt.Errorf("Invalid ?seed= parameter, must be greater than zero")
+	}
+
+	if q.Has("step") {
+
+		v, err := strconv.ParseInt(q.Get("step"), 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?step= parameter, %w", err)
+		}
+
+		step = v
+	}
+
+	if step < 1 {
+		return nil, fmt.Errorf("Invalid ?step= parameter, must be greater than zero")
+	}
+
+	pr := &SequenceProvider{
+		next: seed,
+		step: step,
+		mu:   new(sync.Mutex),
+	}
+
+	return pr, nil
+}
+
+// NewID returns the next identifier in the sequence.
+func (pr *SequenceProvider) NewID(ctx context.Context) (int64, error) {
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	v := pr.next
+	pr.next += pr.step
+
+	return v, nil
+}
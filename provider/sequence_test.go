@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSequenceProvider(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := []struct {
+		uri      string
+		expected []int64
+	}{
+		{uri: "sequence://", expected: []int64{1, 2, 3}},
+		{uri: "sequence://?seed=1360000000", expected: []int64{1360000000, 1360000001, 1360000002}},
+		{uri: "sequence://?seed=10&step=5", expected: []int64{10, 15, 20}},
+	}
+
+	for _, test := range tests {
+
+		pr, err := NewProvider(ctx, test.uri)
+
+		if err != nil {
+			t.Errorf("Failed to create provider for %s, %v", test.uri, err)
+			continue
+		}
+
+		for _, expected := range test.expected {
+
+			new_id, err := pr.NewID(ctx)
+
+			if err != nil {
+				t.Fatalf("Failed to mint ID, %v", err)
+			}
+
+			if new_id != expected {
+				t.Errorf("Expected %s to mint %d, got %d", test.uri, expected, new_id)
+			}
+		}
+	}
+
+	invalid := []string{
+		"sequence://?seed=0",
+		"sequence://?seed=-1",
+		"sequence://?seed=one",
+		"sequence://?step=0",
+		"sequence://?step=-1",
+	}
+
+	for _, uri := range invalid {
+
+		_, err := NewProvider(ctx, uri)
+
+		if err == nil {
+			t.Errorf("Expected %s to fail", uri)
+		}
+	}
+}
+
+func TestSequenceProviderConcurrent(t *testing.T) {
+
+	ctx := context.Background()
+
+	pr, err := NewProvider(ctx, "sequence://")
+
+	if err != nil {
+		t.Fatalf("Failed to create provider, %v", err)
+	}
+
+	ids := make([]int64, 0)
+	mu := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 4; i++ {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			for j := 0; j < 25; j++ {
+
+				new_id, _ := pr.NewID(ctx)
+
+				mu.Lock()
+				ids = append(ids, new_id)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for i, id := range ids {
+
+		if id != int64(i+1) {
+			t.Fatalf("Expected IDs 1 to %d to be minted exactly once, got %v", len(ids), ids)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/whosonfirst/go-whosonfirst-id"
+)
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterProvider(ctx, "whosonfirst", NewWhosOnFirstProvider)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewWhosOnFirstProvider returns the default whosonfirst/go-whosonfirst-id `Provider` instance, which mints
+// identifiers using artisanal integer services, configured by 'uri' in the form of:
+//
+//	whosonfirst://
+func NewWhosOnFirstProvider(ctx context.Context, uri string) (id.Provider, error) {
+	return id.NewProvider(ctx)
+}
@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whosonfirst/go-reader"
+)
+
+// RoutingReader implements the `whosonfirst/go-reader.Reader` interface for reading records from the "data"
+// directories of multiple repositories.
+type RoutingReader struct {
+	reader.Reader
+	routes *Routes
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := reader.RegisterReader(ctx, "routing", NewRoutingReader)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewRoutingReader returns a new `RoutingReader` instance configured by 'uri' in the form of:
+//
+//	routing://?root={ROOT}&repo={NAME}={PATH}&map={MAP}
+//
+// See `RoutesFromURI` for details.
+func NewRoutingReader(ctx context.Context, uri string) (reader.Reader, error) {
+
+	routes, err := RoutesFromURI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RoutingReader{
+		routes: routes,
+	}
+
+	return r, nil
+}
+
+// Read returns the record at 'path' from whichever repository it is found in. It returns an error if the record
+// is not found in any repository, wrapping `os.ErrNotExist`, or if it is found in more than one repository.
+func (r *RoutingReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+
+	repo, abs_path, err := r.find(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fh, err := os.Open(abs_path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s in '%s' repository, %w", path, repo, err)
+	}
+
+	return fh, nil
+}
+
+// ReaderURI returns the absolute path of 'path' in the repository it is found in or 'path' unchanged if it
+// can not be found.
+func (r *RoutingReader) ReaderURI(ctx context.Context, path string) string {
+
+	_, abs_path, err := r.find(path)
+
+	if err != nil {
+		return path
+	}
+
+	return abs_path
+}
+
+// find returns the name of the repository containing 'path' and its absolute path.
+func (r *RoutingReader) find(path string) (string, string, error) {
+
+	names, err := r.routes.Names()
+
+	if err != nil {
+		return "", "", err
+	}
+
+	found := make([]string, 0)
+	var found_path string
+
+	for _, repo := range names {
+
+		data_dir, err := r.routes.DataDir(repo)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		abs_path := filepath.Join(data_dir, path)
+
+		_, err = os.Stat(abs_path)
+
+		if err != nil {
+			continue
+		}
+
+		found = append(found, repo)
+		found_path = abs_path
+	}
+
+	switch len(found) {
+	case 0:
+		return "", "", fmt.Errorf("%s not found in any repository, %w", path, os.ErrNotExist)
+	case 1:
+		return found[0], found_path, nil
+	default:
+		return "", "", fmt.Errorf("%s found in multiple repositories: %s", path, strings.Join(found, ", "))
+	}
+}
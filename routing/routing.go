@@ -0,0 +1,168 @@
+// Package routing provides whosonfirst/go-reader and whosonfirst/go-writer implementations for reading and writing
+// records spread across multiple Who's On First data repositories, dispatching each record to the data directory
+// of the repository named by its "wof:repo" property.
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Routes maps the names of Who's On First data repositories to the paths of their checkouts.
+type Routes struct {
+	// Root is an optional directory containing repository checkouts named after their repositories, for
+	// example "{ROOT}/whosonfirst-data-admin-ca".
+	Root string
+	// Repos is an optional map of repository names to the paths of their checkouts. These take precedence over Root.
+	Repos map[string]string
+}
+
+// RoutesFromURI returns a `Routes` instance derived from 'uri' in the form of:
+//
+//	routing://?root={ROOT}&repo={NAME}={PATH}&map={MAP}
+//
+// Where:
+// * {ROOT} is the path to a directory containing repository checkouts named after their repositories.
+// * {NAME}={PATH} maps the repository {NAME} to the checkout at {PATH}. May be passed multiple times.
+// * {MAP} is the path to a JSON file containing a dictionary of repository names and checkout paths.
+//
+// At least one of "root", "repo" or "map" must be present. Records are read from, and written to, the "data"
+// subdirectory of each checkout.
+func RoutesFromURI(uri string) (*Routes, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	routes := &Routes{
+		Root:  q.Get("root"),
+		Repos: make(map[string]string),
+	}
+
+	if q.Has("map") {
+
+		body, err := os.ReadFile(q.Get("map"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read repo map, %w", err)
+		}
+
+		err = json.Unmarshal(body, &routes.Repos)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal repo map, %w", err)
+		}
+	}
+
+	for _, kv := range q["repo"] {
+
+		name, path, ok := strings.Cut(kv, "=")
+
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("Invalid ?repo= parameter '%s', expected {NAME}={PATH}", kv)
+		}
+
+		routes.Repos[name] = path
+	}
+
+	if routes.Root == "" && len(routes.Repos) == 0 {
+		return nil, fmt.Errorf("Missing ?root=, ?repo= or ?map= parameters")
+	}
+
+	return routes, nil
+}
+
+// DataDir returns the path of the "data" directory for the repository 'repo'. It returns an error if
+// 'repo' is not a known repository or if its data directory does not exist.
+func (r *Routes) DataDir(repo string) (string, error) {
+
+	if repo == "" {
+		return "", fmt.Errorf("Missing repository name")
+	}
+
+	path, exists := r.Repos[repo]
+
+	if !exists {
+
+		if r.Root == "" {
+			return "", fmt.Errorf("Unknown repository '%s'", repo)
+		}
+
+		if repo != filepath.Base(repo) || repo == "." || repo == ".." {
+			return "", fmt.Errorf("Invalid repository name '%s'", repo)
+		}
+
+		path = filepath.Join(r.Root, repo)
+	}
+
+	data_dir, err := filepath.Abs(filepath.Join(path, "data"))
+
+	if err != nil {
+		return "", fmt.Errorf("Failed to derive absolute path for '%s' repository, %w", repo, err)
+	}
+
+	info, err := os.Stat(data_dir)
+
+	if err != nil {
+		return "", fmt.Errorf("Unknown repository '%s', failed to stat %s, %w", repo, data_dir, err)
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("Unknown repository '%s', %s is not a directory", repo, data_dir)
+	}
+
+	return data_dir, nil
+}
+
+// Names returns the sorted list of known repository names. These are the keys in `Repos` and the names of any
+// subdirectories of `Root` that contain a "data" directory.
+func (r *Routes) Names() ([]string, error) {
+
+	seen := make(map[string]bool)
+
+	for name := range r.Repos {
+		seen[name] = true
+	}
+
+	if r.Root != "" {
+
+		entries, err := os.ReadDir(r.Root)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", r.Root, err)
+		}
+
+		for _, e := range entries {
+
+			if !e.IsDir() {
+				continue
+			}
+
+			info, err := os.Stat(filepath.Join(r.Root, e.Name(), "data"))
+
+			if err != nil || !info.IsDir() {
+				continue
+			}
+
+			seen[e.Name()] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,155 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// RoutingWriter implements the `whosonfirst/go-writer.Writer` interface for writing each record to the "data"
+// directory of the repository named by its "wof:repo" property.
+type RoutingWriter struct {
+	writer.Writer
+	routes  *Routes
+	writers map[string]writer.Writer
+	mu      *sync.Mutex
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := writer.RegisterWriter(ctx, "routing", NewRoutingWriter)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewRoutingWriter returns a new `RoutingWriter` instance configured by 'uri' in the form of:
+//
+//	routing://?root={ROOT}&repo={NAME}={PATH}&map={MAP}
+//
+// See `RoutesFromURI` for details. Writing a record whose "wof:repo" property is missing, or names a repository
+// which can not be resolved, returns an error.
+func NewRoutingWriter(ctx context.Context, uri string) (writer.Writer, error) {
+
+	routes, err := RoutesFromURI(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wr := &RoutingWriter{
+		routes:  routes,
+		writers: make(map[string]writer.Writer),
+		mu:      new(sync.Mutex),
+	}
+
+	return wr, nil
+}
+
+// Write writes the record contained in 'fh' to 'key' in the data directory of the repository named by its
+// "wof:repo" property.
+func (wr *RoutingWriter) Write(ctx context.Context, key string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read filehandle, %w", err)
+	}
+
+	repo_rsp := gjson.GetBytes(body, "properties.wof:repo")
+
+	if !repo_rsp.Exists() || repo_rsp.String() == "" {
+		return 0, fmt.Errorf("Failed to route %s, missing wof:repo property", key)
+	}
+
+	repo_wr, err := wr.repoWriter(ctx, repo_rsp.String())
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to route %s, %w", key, err)
+	}
+
+	return repo_wr.Write(ctx, key, bytes.NewReader(body))
+}
+
+// WriterURI returns 'str_uri' unchanged.
+func (wr *RoutingWriter) WriterURI(ctx context.Context, str_uri string) string {
+	return str_uri
+}
+
+// Flush flushes each of the underlying repository writers.
+func (wr *RoutingWriter) Flush(ctx context.Context) error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	for repo, repo_wr := range wr.writers {
+
+		err := repo_wr.Flush(ctx)
+
+		if err != nil {
+			return fmt.Errorf("Failed to flush writer for '%s' repository, %w", repo, err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes each of the underlying repository writers.
+func (wr *RoutingWriter) Close(ctx context.Context) error {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	for repo, repo_wr := range wr.writers {
+
+		err := repo_wr.Close(ctx)
+
+		if err != nil {
+			return fmt.Errorf("Failed to close writer for '%s' repository, %w", repo, err)
+		}
+	}
+
+	return nil
+}
+
+// SetLogger is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *RoutingWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
+
+// repoWriter returns the (cached) "fs://" writer for the data directory of 'repo'.
+func (wr *RoutingWriter) repoWriter(ctx context.Context, repo string) (writer.Writer, error) {
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	repo_wr, exists := wr.writers[repo]
+
+	if exists {
+		return repo_wr, nil
+	}
+
+	data_dir, err := wr.routes.DataDir(repo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	repo_wr, err = writer.NewWriter(ctx, fmt.Sprintf("fs://%s", data_dir))
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create writer for '%s' repository, %w", repo, err)
+	}
+
+	wr.writers[repo] = repo_wr
+	return repo_wr, nil
+}
@@ -0,0 +1,401 @@
+// Package rules provides methods for applying conditional changes, defined in a JSON file, to Who's On First records.
+//
+// A rules file is a JSON array of rules. Each rule has an optional "where" filter expression (see the filter package)
+// and one or more of the following changes, which are applied in this order to the records the filter matches:
+//
+//	rename      An object mapping old property paths to new property paths.
+//	set         An object mapping property paths to (JSON) values.
+//	operations  A list of {"kind", "path", "value"} objects, where "kind" is one of the exportify OPERATION_ constants.
+//	remove      A list of property paths to remove.
+//
+// For example:
+//
+//	[
+//		{ "where": "edtf_before({properties.edtf:cessation}, today())", "set": { "properties.mz:is_current": 0 } },
+//		{ "where": "{properties.wof:placetype} == \"venue\"", "rename": { "properties.sfo:old": "properties.sfo:new" } }
+//	]
+//
+// Rules are applied in order and each rule sees the changes made by the rules before it.
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	exportify "github.com/whosonfirst/go-whosonfirst-exportify"
+	"github.com/whosonfirst/go-whosonfirst-exportify/filter"
+)
+
+// OperationDefinition defines an `exportify.Operation` in a rules file.
+type OperationDefinition struct {
+	// Kind is the kind of operation. It must be one of the exportify OPERATION_ constants.
+	Kind string `json:"kind"`
+	// Path is the tidwall/gjson path the operation is applied to.
+	Path string `json:"path"`
+	// Value is the value used by the operation. It is ignored by "set-null" and "delete" operations.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Rule is a set of changes to apply to the records matching a filter expression.
+type Rule struct {
+	// Name is an optional name for the rule used in error messages.
+	Name string `json:"name,omitempty"`
+	// Where is an optional filter expression. If empty the rule is applied to every record.
+	Where string `json:"where,omitempty"`
+	// Rename maps the paths of properties to rename to their new paths.
+	Rename map[string]string `json:"rename,omitempty"`
+	// Set maps property paths to the values to assign to them.
+	Set map[string]interface{} `json:"set,omitempty"`
+	// Operations is an ordered list of operations to apply.
+	Operations []*OperationDefinition `json:"operations,omitempty"`
+	// Remove is the list of property paths to remove.
+	Remove []string `json:"remove,omitempty"`
+
+	filter     *filter.Filter
+	operations exportify.Operations
+}
+
+// Rules is an ordered list of `Rule` instances.
+type Rules []*Rule
+
+// ApplyOptions defines options for applying rules to a record.
+type ApplyOptions struct {
+	// Force allows properties listed in a record's "wof:controlled" property to be changed.
+	Force bool
+	// Logger is an optional logger used to report changes to controlled properties that have been skipped.
+	Logger *log.Logger
+}
+
+// ReadRulesFile reads and parses the rules file at 'path'.
+func ReadRulesFile(path string) (Rules, error) {
+
+	r, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	defer r.Close()
+
+	rules, err := ReadRules(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read rules from %s, %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// ReadRules reads and parses a rules file from 'r'.
+func ReadRules(r io.Reader) (Rules, error) {
+
+	var rules Rules
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(&rules)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode rules, %w", err)
+	}
+
+	for i, rule := range rules {
+
+		err := rule.Compile()
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rule %s, %w", rule.label(i), err)
+		}
+	}
+
+	return rules, nil
+}
+
+// Compile parses the filter expression and operations for 'rule'. It must be called before a `Rule` which has not been
+// created by `ReadRules` or `ReadRulesFile` is applied.
+func (rule *Rule) Compile() error {
+
+	if len(rule.Rename) == 0 && len(rule.Set) == 0 && len(rule.Operations) == 0 && len(rule.Remove) == 0 {
+		return fmt.Errorf("Rule does not define any changes")
+	}
+
+	err := rule.checkProtected()
+
+	if err != nil {
+		return err
+	}
+
+	if rule.Where != "" {
+
+		f, err := filter.NewFilter(rule.Where)
+
+		if err != nil {
+			return err
+		}
+
+		rule.filter = f
+	}
+
+	ops := make(exportify.Operations, 0)
+
+	for _, path := range sortedKeys(rule.Set) {
+
+		op := &exportify.Operation{
+			Kind:  exportify.OPERATION_SET_JSON,
+			Path:  path,
+			Value: rule.Set[path],
+		}
+
+		ops = append(ops, op)
+	}
+
+	for _, def := range rule.Operations {
+
+		// Values for the string, numeric and boolean operations may be passed as JSON strings or as the
+		// equivalent JSON values.
+
+		str_value := string(def.Value)
+
+		var v string
+
+		if json.Unmarshal(def.Value, &v) == nil {
+
+			switch def.Kind {
+			case exportify.OPERATION_SET_STRING, exportify.OPERATION_SET_INT64, exportify.OPERATION_SET_FLOAT64, exportify.OPERATION_SET_BOOL:
+				str_value = v
+			}
+		}
+
+		op, err := exportify.NewOperation(def.Kind, def.Path, str_value)
+
+		if err != nil {
+			return fmt.Errorf("Invalid %s operation for '%s', %w", def.Kind, def.Path, err)
+		}
+
+		ops = append(ops, op)
+	}
+
+	rule.operations = ops
+	return nil
+}
+
+// checkProtected returns an error if any of the paths renamed, to or from, or removed by 'rule' would change a property
+// that is assigned by the exporter (see `exportify.IsProtectedPath`).
+func (rule *Rule) checkProtected() error {
+
+	for _, old_path := range sortedKeys(rule.Rename) {
+
+		new_path := rule.Rename[old_path]
+
+		if exportify.IsProtectedPath(old_path) || exportify.IsProtectedPath(new_path) {
+			return fmt.Errorf("Can not rename '%s' to '%s' because properties assigned by the exporter can not be renamed", old_path, new_path)
+		}
+	}
+
+	for _, path := range rule.Remove {
+
+		if exportify.IsProtectedPath(path) {
+			return fmt.Errorf("Can not remove '%s' because properties assigned by the exporter can not be removed", path)
+		}
+	}
+
+	return nil
+}
+
+// label returns the name of 'rule' or, if it has no name, its (zero-based) position in the list of rules.
+func (rule *Rule) label(i int) string {
+
+	if rule.Name != "" {
+		return fmt.Sprintf("'%s'", rule.Name)
+	}
+
+	return fmt.Sprintf("#%d", i)
+}
+
+// Apply applies each of 'rules', in order, to 'body' and returns the updated record along with the list of paths
+// whose values were changed.
+func (rules Rules) Apply(ctx context.Context, body []byte, opts *ApplyOptions) ([]byte, []string, error) {
+
+	changed := make([]string, 0)
+
+	for i, rule := range rules {
+
+		new_body, rule_changed, err := rule.Apply(ctx, body, opts)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to apply rule %s, %w", rule.label(i), err)
+		}
+
+		body = new_body
+
+		for _, path := range rule_changed {
+
+			if !contains(changed, path) {
+				changed = append(changed, path)
+			}
+		}
+	}
+
+	return body, changed, nil
+}
+
+// Apply applies 'rule' to 'body', if it matches the rule's filter, and returns the updated record along with the list
+// of paths whose values were changed. It is an error for a rule to rename, or remove, a property that is assigned by
+// the exporter (see `exportify.IsProtectedPath`).
+func (rule *Rule) Apply(ctx context.Context, body []byte, opts *ApplyOptions) ([]byte, []string, error) {
+
+	if rule.filter != nil {
+
+		ok, err := rule.filter.Matches(body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			return body, nil, nil
+		}
+	}
+
+	err := rule.checkProtected()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check controlled properties against the record before the rule was applied so that
+	// changes to wof:controlled itself don't unlock any of the properties it lists.
+
+	controlled_body := body
+	changed := make([]string, 0)
+
+	controlled_opts := &exportify.ControlledOptions{
+		Force:  opts.Force,
+		Logger: opts.Logger,
+	}
+
+	// apply replaces 'body' with 'new_body' unless doing so would change a controlled property.
+
+	apply := func(new_body []byte) (bool, error) {
+
+		_, reverted, err := exportify.RevertControlledChanges(controlled_body, new_body, controlled_opts)
+
+		if err != nil {
+			return false, err
+		}
+
+		if len(reverted) > 0 {
+			return false, nil
+		}
+
+		body = new_body
+		return true, nil
+	}
+
+	for _, old_path := range sortedKeys(rule.Rename) {
+
+		new_path := rule.Rename[old_path]
+		old_rsp := gjson.GetBytes(body, old_path)
+
+		if !old_rsp.Exists() {
+			continue
+		}
+
+		new_body, err := sjson.SetBytes(body, new_path, old_rsp.Value())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to assign '%s', %w", new_path, err)
+		}
+
+		new_body, err = sjson.DeleteBytes(new_body, old_path)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to delete '%s', %w", old_path, err)
+		}
+
+		ok, err := apply(new_body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			changed = append(changed, old_path, new_path)
+		}
+	}
+
+	if len(rule.operations) > 0 {
+
+		update_opts := &exportify.UpdateFeatureOptions{
+			Operations: rule.operations,
+			Force:      opts.Force,
+			Logger:     opts.Logger,
+		}
+
+		new_body, ops_changed, err := exportify.UpdateFeatureWithChanges(ctx, body, update_opts)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body = new_body
+		changed = append(changed, ops_changed...)
+	}
+
+	for _, path := range rule.Remove {
+
+		if !gjson.GetBytes(body, path).Exists() {
+			continue
+		}
+
+		new_body, err := sjson.DeleteBytes(body, path)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to delete '%s', %w", path, err)
+		}
+
+		ok, err := apply(new_body)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			changed = append(changed, path)
+		}
+	}
+
+	return body, changed, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(paths []string, path string) bool {
+
+	for _, p := range paths {
+
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestReadRulesProtected ensures that rules which rename, or remove, properties assigned by the exporter are rejected
+// when they are read.
+func TestReadRulesProtected(t *testing.T) {
+
+	tests := []struct {
+		rules string
+		ok    bool
+	}{
+		{`[{"rename":{"properties.sfo:old":"properties.sfo:new"}}]`, true},
+		{`[{"remove":["properties.sfo:old"]}]`, true},
+		{`[{"rename":{"properties.wof:repo":"properties.sfo:repo"}}]`, false},
+		{`[{"rename":{"properties.sfo:old":"properties.wof:id"}}]`, false},
+		{`[{"rename":{"properties.sfo:old":"properties"}}]`, false},
+		{`[{"remove":["properties.geom:area"]}]`, false},
+		{`[{"where":"{properties.wof:placetype} == \"venue\"","remove":["properties.wof:id"]}]`, false},
+	}
+
+	for _, test := range tests {
+
+		_, err := ReadRules(strings.NewReader(test.rules))
+
+		if test.ok && err != nil {
+			t.Fatalf("Failed to read rules %s, %v", test.rules, err)
+		}
+
+		if !test.ok && err == nil {
+			t.Fatalf("Expected rules %s to be rejected", test.rules)
+		}
+	}
+}
+
+// TestApplyProtected ensures that rules created in code, which have not been compiled, can not rename or remove
+// properties assigned by the exporter either.
+func TestApplyProtected(t *testing.T) {
+
+	ctx := context.Background()
+
+	body := []byte(`{"type":"Feature","properties":{"wof:id":101736545,"wof:repo":"whosonfirst-data-admin-ca","sfo:old":"a"},"geometry":null}`)
+
+	tests := []struct {
+		rule *Rule
+		ok   bool
+	}{
+		{&Rule{Rename: map[string]string{"properties.sfo:old": "properties.sfo:new"}}, true},
+		{&Rule{Rename: map[string]string{"properties.wof:repo": "properties.sfo:repo"}}, false},
+		{&Rule{Remove: []string{"properties.sfo:old"}}, true},
+		{&Rule{Remove: []string{"properties.wof:id"}}, false},
+	}
+
+	for i, test := range tests {
+
+		_, _, err := test.rule.Apply(ctx, body, &ApplyOptions{})
+
+		if test.ok && err != nil {
+			t.Fatalf("Failed to apply rule #%d, %v", i, err)
+		}
+
+		if !test.ok && err == nil {
+			t.Fatalf("Expected rule #%d to be rejected", i)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// Field types for the properties stored in a Shapefile's DBF file. These are the values recorded in the
+// sidecar field mapping file.
+const (
+	FIELD_INTEGER = "integer"
+	FIELD_DOUBLE  = "double"
+	FIELD_BOOLEAN = "boolean"
+	FIELD_STRING  = "string"
+	// FIELD_JSON fields contain JSON-encoded objects or arrays stored as DBF character fields.
+	FIELD_JSON = "json"
+)
+
+// DBF_MAX_FIELD_NAME is the maximum length of a DBF field name.
+const DBF_MAX_FIELD_NAME int = 10
+
+// DBF_MAX_FIELD_WIDTH is the maximum width of a DBF field.
+const DBF_MAX_FIELD_WIDTH int = 254
+
+const dbf_max_decimals int = 15
+
+var re_dbf_invalid = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Field maps a DBF field to the WOF property it was derived from.
+type Field struct {
+	// The (possibly truncated) name of the DBF field.
+	Name string `json:"field"`
+	// The (relative) path of the WOF property. For example "wof:name".
+	Property string `json:"property"`
+	// The type of the property. One of the FIELD_* constants.
+	Type string `json:"type"`
+	// The width of the DBF field.
+	width int
+	// The number of decimal places for numeric DBF fields.
+	decimals int
+}
+
+// fieldNames returns a unique DBF field name for each property in 'properties'. Characters that
+// are not allowed in DBF field names are replaced with underscores and names are truncated to
+// DBF_MAX_FIELD_NAME characters. Names that collide (case-insensitively) are disambiguated by
+// replacing their final characters with a counter.
+func fieldNames(properties []string) []string {
+
+	names := make([]string, len(properties))
+	seen := make(map[string]bool)
+
+	for idx, p := range properties {
+
+		name := re_dbf_invalid.ReplaceAllString(p, "_")
+
+		if name == "" {
+			name = "field"
+		}
+
+		if len(name) > DBF_MAX_FIELD_NAME {
+			name = name[0:DBF_MAX_FIELD_NAME]
+		}
+
+		candidate := name
+
+		for i := 1; seen[strings.ToLower(candidate)]; i++ {
+
+			suffix := fmt.Sprintf("_%d", i)
+			prefix := name
+
+			if len(prefix)+len(suffix) > DBF_MAX_FIELD_NAME {
+				prefix = prefix[0 : DBF_MAX_FIELD_NAME-len(suffix)]
+			}
+
+			candidate = prefix + suffix
+		}
+
+		seen[strings.ToLower(candidate)] = true
+		names[idx] = candidate
+	}
+
+	return names
+}
+
+// inferFields assigns a type, width and (for doubles) number of decimal places to each field in 'fields'
+// derived from the property values in 'features'.
+func inferFields(fields []*Field, features []*feature) {
+
+	for idx, f := range fields {
+
+		for _, feat := range features {
+
+			v := feat.properties[idx]
+
+			if !v.Exists() || v.Type == gjson.Null {
+				continue
+			}
+
+			t := valueType(v)
+
+			switch {
+			case f.Type == "":
+				f.Type = t
+			case f.Type == t:
+				// pass
+			case isNumeric(f.Type) && isNumeric(t):
+				f.Type = FIELD_DOUBLE
+			case f.Type == FIELD_JSON || t == FIELD_JSON:
+				f.Type = FIELD_JSON
+			default:
+				f.Type = FIELD_STRING
+			}
+		}
+
+		if f.Type == "" {
+			f.Type = FIELD_STRING
+		}
+
+		if f.Type == FIELD_DOUBLE {
+
+			for _, feat := range features {
+
+				v := feat.properties[idx]
+
+				if v.Type != gjson.Number {
+					continue
+				}
+
+				str_v := strconv.FormatFloat(v.Float(), 'f', -1, 64)
+				pos := strings.Index(str_v, ".")
+
+				if pos > -1 {
+					f.decimals = max(f.decimals, len(str_v)-pos-1)
+				}
+			}
+
+			f.decimals = min(f.decimals, dbf_max_decimals)
+		}
+
+		switch f.Type {
+		case FIELD_BOOLEAN:
+			f.width = 1
+		default:
+
+			f.width = 1
+
+			for _, feat := range features {
+				f.width = max(f.width, len(formatValue(f, feat.properties[idx])))
+			}
+
+			f.width = min(f.width, DBF_MAX_FIELD_WIDTH)
+		}
+	}
+}
+
+func valueType(v gjson.Result) string {
+
+	switch v.Type {
+	case gjson.True, gjson.False:
+		return FIELD_BOOLEAN
+	case gjson.Number:
+
+		if !strings.ContainsAny(v.Raw, ".eE") {
+
+			_, err := strconv.ParseInt(v.Raw, 10, 64)
+
+			if err == nil {
+				return FIELD_INTEGER
+			}
+		}
+
+		return FIELD_DOUBLE
+
+	case gjson.String:
+		return FIELD_STRING
+	default:
+		return FIELD_JSON
+	}
+}
+
+func isNumeric(t string) bool {
+	return t == FIELD_INTEGER || t == FIELD_DOUBLE
+}
+
+func formatValue(f *Field, v gjson.Result) string {
+
+	if !v.Exists() || v.Type == gjson.Null {
+		return ""
+	}
+
+	switch f.Type {
+	case FIELD_BOOLEAN:
+
+		if v.Bool() {
+			return "T"
+		}
+
+		return "F"
+
+	case FIELD_INTEGER:
+		return strconv.FormatInt(v.Int(), 10)
+	case FIELD_DOUBLE:
+		return strconv.FormatFloat(v.Float(), 'f', f.decimals, 64)
+	case FIELD_JSON:
+		return v.Raw
+	default:
+		return v.String()
+	}
+}
+
+// encodeDBF returns a dBASE III file containing the values of 'fields' for each of 'features'.
+// Character values that are wider than DBF_MAX_FIELD_WIDTH bytes are truncated.
+func encodeDBF(fields []*Field, features []*feature) []byte {
+
+	var buf bytes.Buffer
+
+	record_length := 1
+
+	for _, f := range fields {
+		record_length += f.width
+	}
+
+	now := time.Now()
+
+	header := make([]byte, 32)
+	header[0] = 0x03
+	header[1] = byte(now.Year() - 1900)
+	header[2] = byte(now.Month())
+	header[3] = byte(now.Day())
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(features)))
+	binary.LittleEndian.PutUint16(header[8:], uint16(32+32*len(fields)+1))
+	binary.LittleEndian.PutUint16(header[10:], uint16(record_length))
+
+	buf.Write(header)
+
+	for _, f := range fields {
+
+		desc := make([]byte, 32)
+		copy(desc[0:11], f.Name)
+
+		switch f.Type {
+		case FIELD_INTEGER, FIELD_DOUBLE:
+			desc[11] = 'N'
+			desc[17] = byte(f.decimals)
+		case FIELD_BOOLEAN:
+			desc[11] = 'L'
+		default:
+			desc[11] = 'C'
+		}
+
+		desc[16] = byte(f.width)
+		buf.Write(desc)
+	}
+
+	buf.WriteByte(0x0D)
+
+	for _, feat := range features {
+
+		buf.WriteByte(' ')
+
+		for idx, f := range fields {
+
+			v := truncate(formatValue(f, feat.properties[idx]), f.width)
+			padding := strings.Repeat(" ", f.width-len(v))
+
+			switch f.Type {
+			case FIELD_INTEGER, FIELD_DOUBLE:
+				buf.WriteString(padding + v)
+			case FIELD_BOOLEAN:
+
+				if v == "" {
+					v = "?"
+				}
+
+				buf.WriteString(v)
+
+			default:
+				buf.WriteString(v + padding)
+			}
+		}
+	}
+
+	buf.WriteByte(0x1A)
+
+	return buf.Bytes()
+}
+
+// truncate truncates 'str' to at most 'width' bytes without splitting a multi-byte character.
+func truncate(str string, width int) string {
+
+	if len(str) <= width {
+		return str
+	}
+
+	str = str[0:width]
+
+	for len(str) > 0 && !utf8.ValidString(str) {
+		str = str[0 : len(str)-1]
+	}
+
+	return str
+}
+
+type dbfField struct {
+	name     string
+	kind     byte
+	width    int
+	decimals int
+}
+
+// decodeDBF decodes the records in the DBF file 'body' returning a list of field names and, for each
+// record, a list of values. Values are nil (missing), string, int64, float64 or bool. Deleted records
+// are returned as nil.
+func decodeDBF(body []byte) ([]string, [][]interface{}, error) {
+
+	if len(body) < 32 {
+		return nil, nil, fmt.Errorf("Invalid DBF file header")
+	}
+
+	num_records := int(binary.LittleEndian.Uint32(body[4:]))
+	header_length := int(binary.LittleEndian.Uint16(body[8:]))
+	record_length := int(binary.LittleEndian.Uint16(body[10:]))
+
+	if header_length > len(body) {
+		return nil, nil, fmt.Errorf("Invalid DBF header length")
+	}
+
+	fields := make([]*dbfField, 0)
+
+	for offset := 32; offset+32 <= header_length && body[offset] != 0x0D; offset += 32 {
+
+		desc := body[offset : offset+32]
+		name := string(bytes.TrimRight(desc[0:11], "\x00 "))
+
+		f := &dbfField{
+			name:     name,
+			kind:     desc[11],
+			width:    int(desc[16]),
+			decimals: int(desc[17]),
+		}
+
+		fields = append(fields, f)
+	}
+
+	names := make([]string, len(fields))
+
+	for idx, f := range fields {
+		names[idx] = f.name
+	}
+
+	records := make([][]interface{}, num_records)
+
+	for i := 0; i < num_records; i++ {
+
+		offset := header_length + i*record_length
+
+		if offset+record_length > len(body) {
+			return nil, nil, fmt.Errorf("Invalid DBF record %d, record extends past end of file", i+1)
+		}
+
+		record := body[offset : offset+record_length]
+
+		if record[0] == '*' {
+			continue
+		}
+
+		values := make([]interface{}, len(fields))
+		pos := 1
+
+		for idx, f := range fields {
+
+			if pos+f.width > len(record) {
+				return nil, nil, fmt.Errorf("Invalid DBF record %d, field '%s' extends past end of record", i+1, f.name)
+			}
+
+			values[idx] = decodeValue(f, record[pos:pos+f.width])
+			pos += f.width
+		}
+
+		records[i] = values
+	}
+
+	return names, records, nil
+}
+
+func decodeValue(f *dbfField, raw []byte) interface{} {
+
+	str_v := strings.TrimSpace(decodeString(bytes.TrimRight(raw, "\x00")))
+
+	if str_v == "" {
+		return nil
+	}
+
+	switch f.kind {
+	case 'N', 'F':
+
+		if f.decimals == 0 {
+
+			i, err := strconv.ParseInt(str_v, 10, 64)
+
+			if err == nil {
+				return i
+			}
+		}
+
+		fl, err := strconv.ParseFloat(str_v, 64)
+
+		if err != nil {
+			return nil
+		}
+
+		return fl
+
+	case 'L':
+
+		switch str_v {
+		case "T", "t", "Y", "y":
+			return true
+		case "F", "f", "N", "n":
+			return false
+		default:
+			return nil
+		}
+
+	case 'D':
+
+		if len(str_v) == 8 {
+			return fmt.Sprintf("%s-%s-%s", str_v[0:4], str_v[4:6], str_v[6:8])
+		}
+
+		return str_v
+
+	default:
+		return str_v
+	}
+}
+
+// decodeString decodes 'raw' as UTF-8 if it is valid and as ISO-8859-1 (Latin-1) otherwise.
+func decodeString(raw []byte) string {
+
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+
+	runes := make([]rune, len(raw))
+
+	for idx, b := range raw {
+		runes[idx] = rune(b)
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,275 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// ReadFeatures reads all the records in the Shapefile at 'path' and returns them as GeoJSON Features.
+// 'path' may be the path to a .shp file, in which case the .dbf, .prj, .cpg and field mapping files are
+// expected to be found alongside it, or the path to a ZIP archive containing a single Shapefile.
+//
+// If a .prj file is present it must describe a geographic coordinate reference system using the WGS 84 datum;
+// projected coordinates are not supported. If there is no .prj file WGS 84 is assumed. If a field mapping file
+// (see `FIELDS_EXTENSION`) is present DBF fields are assigned to the WOF properties they were derived from,
+// otherwise each field is assigned to a property with the same name.
+func ReadFeatures(ctx context.Context, path string) ([][]byte, error) {
+
+	var open_func func(ext string) ([]byte, error)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".zip":
+
+		zip_r, err := zip.OpenReader(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+		}
+
+		defer zip_r.Close()
+
+		open_func, err = zipOpener(zip_r)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read %s, %w", path, err)
+		}
+
+	case ".shp":
+
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+
+		open_func = func(ext string) ([]byte, error) {
+
+			for _, candidate := range []string{base + ext, base + strings.ToUpper(ext)} {
+
+				body, err := os.ReadFile(candidate)
+
+				if err == nil {
+					return body, nil
+				}
+
+				if !os.IsNotExist(err) {
+					return nil, err
+				}
+			}
+
+			return nil, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("Unsupported file type, expected a .shp or .zip file")
+	}
+
+	return readFeatures(open_func)
+}
+
+// zipOpener returns a function for reading the files, by extension, of the single Shapefile contained in 'zip_r'.
+func zipOpener(zip_r *zip.ReadCloser) (func(ext string) ([]byte, error), error) {
+
+	files := make(map[string]*zip.File)
+	shapefiles := make([]string, 0)
+
+	for _, f := range zip_r.File {
+
+		name := strings.ToLower(f.Name)
+		files[name] = f
+
+		if filepath.Ext(name) == ".shp" {
+			shapefiles = append(shapefiles, name)
+		}
+	}
+
+	switch len(shapefiles) {
+	case 0:
+		return nil, fmt.Errorf("Archive does not contain a .shp file")
+	case 1:
+		// pass
+	default:
+		return nil, fmt.Errorf("Archive contains multiple .shp files")
+	}
+
+	base := strings.TrimSuffix(shapefiles[0], ".shp")
+
+	open_func := func(ext string) ([]byte, error) {
+
+		f, ok := files[base+ext]
+
+		if !ok {
+			return nil, nil
+		}
+
+		fh, err := f.Open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer fh.Close()
+
+		return io.ReadAll(fh)
+	}
+
+	return open_func, nil
+}
+
+// readFeatures reads a Shapefile using 'open_func' to retrieve the contents of its component files
+// by extension. 'open_func' returns nil (and no error) for files that don't exist.
+func readFeatures(open_func func(ext string) ([]byte, error)) ([][]byte, error) {
+
+	shp_body, err := open_func(".shp")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read .shp file, %w", err)
+	}
+
+	if shp_body == nil {
+		return nil, fmt.Errorf("Missing .shp file")
+	}
+
+	prj_body, err := open_func(".prj")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read .prj file, %w", err)
+	}
+
+	if prj_body != nil {
+
+		err := ensureWGS84(string(prj_body))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shapes, err := decodeShapes(shp_body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dbf_body, err := open_func(".dbf")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read .dbf file, %w", err)
+	}
+
+	var names []string
+	var records [][]interface{}
+
+	if dbf_body != nil {
+
+		names, records, err = decodeDBF(dbf_body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(records) != len(shapes) {
+			return nil, fmt.Errorf("Number of DBF records (%d) does not match number of shapes (%d)", len(records), len(shapes))
+		}
+	}
+
+	mapping_body, err := open_func(FIELDS_EXTENSION)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read field mapping file, %w", err)
+	}
+
+	mapped := make(map[string]*Field)
+
+	if mapping_body != nil {
+
+		var mapping *FieldMapping
+
+		err := json.Unmarshal(mapping_body, &mapping)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal field mapping, %w", err)
+		}
+
+		for _, f := range mapping.Fields {
+			mapped[strings.ToLower(f.Name)] = f
+		}
+	}
+
+	features := make([][]byte, 0)
+
+	for idx, geom := range shapes {
+
+		f := geojson.NewFeature(geom)
+
+		if records != nil {
+
+			values := records[idx]
+
+			if values == nil {
+				continue
+			}
+
+			for i, name := range names {
+
+				v := values[i]
+
+				if v == nil {
+					continue
+				}
+
+				m, ok := mapped[strings.ToLower(name)]
+
+				if !ok {
+					f.Properties[name] = v
+					continue
+				}
+
+				str_v, is_str := v.(string)
+
+				if m.Type == FIELD_JSON && is_str && json.Valid([]byte(str_v)) {
+					v = json.RawMessage(str_v)
+				}
+
+				f.Properties[m.Property] = v
+			}
+		}
+
+		body, err := json.Marshal(f)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to marshal feature, %w", err)
+		}
+
+		features = append(features, body)
+	}
+
+	return features, nil
+}
+
+// ensureWGS84 returns an error if the WKT-encoded coordinate reference system 'prj' is not a geographic
+// coordinate reference system using the WGS 84 datum.
+func ensureWGS84(prj string) error {
+
+	prj = strings.TrimSpace(prj)
+
+	if strings.HasPrefix(strings.ToUpper(prj), "PROJCS") {
+		return fmt.Errorf("Projected coordinate reference systems are not supported, Shapefiles must use WGS 84 (EPSG:4326) coordinates")
+	}
+
+	if !strings.HasPrefix(strings.ToUpper(prj), "GEOGCS") {
+		return fmt.Errorf("Unrecognized coordinate reference system in .prj file")
+	}
+
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "_", "").Replace(prj))
+
+	if !strings.Contains(normalized, "DATUM[\"DWGS1984\"") && !strings.Contains(normalized, "DATUM[\"WGS1984\"") && !strings.Contains(normalized, "DATUM[\"WGS84\"") {
+		return fmt.Errorf("Unsupported datum in .prj file, Shapefiles must use WGS 84 (EPSG:4326) coordinates")
+	}
+
+	return nil
+}
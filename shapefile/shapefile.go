@@ -0,0 +1,266 @@
+// Package shapefile provides methods for writing WOF records to, and reading GeoJSON Features from,
+// ESRI Shapefiles.
+package shapefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/tidwall/gjson"
+)
+
+// DEFAULT_NAME is the default base name of the files in a Shapefile.
+const DEFAULT_NAME string = "whosonfirst"
+
+// FIELDS_EXTENSION is the extension of the sidecar file mapping (truncated) DBF field names to WOF property paths.
+const FIELDS_EXTENSION string = ".fields.json"
+
+// PRJ_WGS84 is the contents of the .prj file written for all Shapefiles produced by this package.
+const PRJ_WGS84 string = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// CPG_UTF8 is the contents of the .cpg file written for all Shapefiles produced by this package.
+const CPG_UTF8 string = "UTF-8"
+
+// FieldMapping is the sidecar file that maps DBF field names to the WOF properties they were derived from.
+type FieldMapping struct {
+	Fields []*Field `json:"fields"`
+}
+
+// BuilderOptions defines configuration options for a `Builder` instance.
+type BuilderOptions struct {
+	// The base name of the files in the Shapefile.
+	Name string
+	// The list of (relative) property paths to store as DBF fields. For example "wof:name".
+	Properties []string
+}
+
+type feature struct {
+	geometry   orb.Geometry
+	properties []gjson.Result
+}
+
+// Builder accumulates WOF records in memory and writes them as a Shapefile. Field types and widths are derived
+// from the property values of all the records so nothing is written until the `Write` method is invoked.
+type Builder struct {
+	options  *BuilderOptions
+	features []*feature
+	mu       *sync.Mutex
+}
+
+// DefaultBuilderOptions returns a `BuilderOptions` instance with a default name and no properties.
+func DefaultBuilderOptions() *BuilderOptions {
+
+	opts := &BuilderOptions{
+		Name:       DEFAULT_NAME,
+		Properties: make([]string, 0),
+	}
+
+	return opts
+}
+
+// BuilderOptionsFromQuery returns a `BuilderOptions` instance derived from the "property" and "name" parameters in 'q'.
+func BuilderOptionsFromQuery(q url.Values) (*BuilderOptions, error) {
+
+	opts := DefaultBuilderOptions()
+
+	if q.Has("property") {
+		opts.Properties = q["property"]
+	}
+
+	if q.Has("name") {
+		opts.Name = q.Get("name")
+	}
+
+	return opts, nil
+}
+
+// NewBuilder returns a new `Builder` instance configured by 'opts'.
+func NewBuilder(ctx context.Context, opts *BuilderOptions) (*Builder, error) {
+
+	if opts.Name == "" {
+		return nil, fmt.Errorf("Missing name")
+	}
+
+	b := &Builder{
+		options:  opts,
+		features: make([]*feature, 0),
+		mu:       new(sync.Mutex),
+	}
+
+	return b, nil
+}
+
+// AddFeature adds the GeoJSON Feature 'body' to the list of features to be written.
+func (b *Builder) AddFeature(ctx context.Context, body []byte) error {
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal feature, %w", err)
+	}
+
+	if f.Geometry == nil {
+		return fmt.Errorf("Feature is missing a geometry")
+	}
+
+	_, err = shapeType(f.Geometry)
+
+	if err != nil {
+		return err
+	}
+
+	properties := make([]gjson.Result, len(b.options.Properties))
+
+	for idx, path := range b.options.Properties {
+		properties[idx] = gjson.GetBytes(body, "properties."+gjson.Escape(path))
+	}
+
+	shp_f := &feature{
+		geometry:   f.Geometry,
+		properties: properties,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.features = append(b.features, shp_f)
+	return nil
+}
+
+// Write writes all the features added so far to 'wr' as a ZIP archive containing the .shp, .shx, .dbf, .prj
+// and .cpg files for a Shapefile, as well as a sidecar file mapping DBF field names to WOF property paths.
+func (b *Builder) Write(ctx context.Context, wr io.Writer) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	files, err := b.files()
+
+	if err != nil {
+		return err
+	}
+
+	zip_wr := zip.NewWriter(wr)
+
+	for _, ext := range []string{".shp", ".shx", ".dbf", ".prj", ".cpg", FIELDS_EXTENSION} {
+
+		fh, err := zip_wr.Create(b.options.Name + ext)
+
+		if err != nil {
+			return fmt.Errorf("Failed to create %s%s, %w", b.options.Name, ext, err)
+		}
+
+		_, err = fh.Write(files[ext])
+
+		if err != nil {
+			return fmt.Errorf("Failed to write %s%s, %w", b.options.Name, ext, err)
+		}
+	}
+
+	err = zip_wr.Close()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close ZIP archive, %w", err)
+	}
+
+	return nil
+}
+
+// files returns the contents of each file in the Shapefile keyed by its extension.
+func (b *Builder) files() (map[string][]byte, error) {
+
+	shape_type := SHAPE_NULL
+	var bound orb.Bound
+
+	for idx, f := range b.features {
+
+		t, _ := shapeType(f.geometry)
+
+		if idx == 0 {
+			shape_type = t
+			bound = f.geometry.Bound()
+			continue
+		}
+
+		file_type, err := fileShapeType(shape_type, t)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shape_type = file_type
+		bound = bound.Union(f.geometry.Bound())
+	}
+
+	var shp bytes.Buffer
+	var shx bytes.Buffer
+
+	records := make([][]byte, len(b.features))
+	shp_length := shp_header_length
+
+	for idx, f := range b.features {
+		records[idx] = encodeShape(f.geometry, shape_type)
+		shp_length += 8 + len(records[idx])
+	}
+
+	shx_length := shp_header_length + 8*len(records)
+
+	shp.Write(encodeHeader(shape_type, shp_length, bound))
+	shx.Write(encodeHeader(shape_type, shx_length, bound))
+
+	offset := shp_header_length
+
+	for idx, content := range records {
+
+		binary.Write(&shp, binary.BigEndian, int32(idx+1))
+		binary.Write(&shp, binary.BigEndian, int32(len(content)/2))
+		shp.Write(content)
+
+		binary.Write(&shx, binary.BigEndian, int32(offset/2))
+		binary.Write(&shx, binary.BigEndian, int32(len(content)/2))
+
+		offset += 8 + len(content)
+	}
+
+	names := fieldNames(b.options.Properties)
+	fields := make([]*Field, len(names))
+
+	for idx, name := range names {
+		fields[idx] = &Field{
+			Name:     name,
+			Property: b.options.Properties[idx],
+		}
+	}
+
+	inferFields(fields, b.features)
+
+	mapping := &FieldMapping{
+		Fields: fields,
+	}
+
+	enc_mapping, err := json.MarshalIndent(mapping, "", "  ")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal field mapping, %w", err)
+	}
+
+	files := map[string][]byte{
+		".shp":           shp.Bytes(),
+		".shx":           shx.Bytes(),
+		".dbf":           encodeDBF(fields, b.features),
+		".prj":           []byte(PRJ_WGS84),
+		".cpg":           []byte(CPG_UTF8),
+		FIELDS_EXTENSION: enc_mapping,
+	}
+
+	return files, nil
+}
@@ -0,0 +1,416 @@
+package shapefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+// Shape types defined by the ESRI Shapefile specification.
+const (
+	SHAPE_NULL        int32 = 0
+	SHAPE_POINT       int32 = 1
+	SHAPE_POLYLINE    int32 = 3
+	SHAPE_POLYGON     int32 = 5
+	SHAPE_MULTIPOINT  int32 = 8
+	SHAPE_POINTZ      int32 = 11
+	SHAPE_POLYLINEZ   int32 = 13
+	SHAPE_POLYGONZ    int32 = 15
+	SHAPE_MULTIPOINTZ int32 = 18
+	SHAPE_POINTM      int32 = 21
+	SHAPE_POLYLINEM   int32 = 23
+	SHAPE_POLYGONM    int32 = 25
+	SHAPE_MULTIPOINTM int32 = 28
+)
+
+const shp_file_code int32 = 9994
+
+const shp_version int32 = 1000
+
+const shp_header_length int = 100
+
+// shapeType returns the shape type used to store 'geom'.
+func shapeType(geom orb.Geometry) (int32, error) {
+
+	switch geom.(type) {
+	case orb.Point:
+		return SHAPE_POINT, nil
+	case orb.MultiPoint:
+		return SHAPE_MULTIPOINT, nil
+	case orb.LineString, orb.MultiLineString:
+		return SHAPE_POLYLINE, nil
+	case orb.Polygon, orb.MultiPolygon:
+		return SHAPE_POLYGON, nil
+	default:
+		return SHAPE_NULL, fmt.Errorf("Unsupported geometry type %s", geom.GeoJSONType())
+	}
+}
+
+// fileShapeType returns the single shape type for a file containing shapes of type 'a' and 'b'. Points
+// and multipoints are stored as multipoints. Any other combination of different types is an error since
+// a Shapefile can only contain one type of shape.
+func fileShapeType(a int32, b int32) (int32, error) {
+
+	switch {
+	case a == b:
+		return a, nil
+	case (a == SHAPE_POINT && b == SHAPE_MULTIPOINT) || (a == SHAPE_MULTIPOINT && b == SHAPE_POINT):
+		return SHAPE_MULTIPOINT, nil
+	default:
+		return SHAPE_NULL, fmt.Errorf("Shapefiles can only contain a single type of geometry")
+	}
+}
+
+// encodeShape returns the record contents for 'geom' encoded as a shape of type 'shape_type'.
+func encodeShape(geom orb.Geometry, shape_type int32) []byte {
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, shape_type)
+
+	switch shape_type {
+	case SHAPE_POINT:
+
+		pt := geom.(orb.Point)
+		binary.Write(&buf, binary.LittleEndian, [2]float64{pt.X(), pt.Y()})
+
+	case SHAPE_MULTIPOINT:
+
+		var points orb.MultiPoint
+
+		switch g := geom.(type) {
+		case orb.Point:
+			points = orb.MultiPoint{g}
+		case orb.MultiPoint:
+			points = g
+		}
+
+		writeBound(&buf, points.Bound())
+		binary.Write(&buf, binary.LittleEndian, int32(len(points)))
+		writePoints(&buf, points)
+
+	case SHAPE_POLYLINE, SHAPE_POLYGON:
+
+		parts := shapeParts(geom)
+
+		count := 0
+
+		for _, p := range parts {
+			count += len(p)
+		}
+
+		writeBound(&buf, geom.Bound())
+		binary.Write(&buf, binary.LittleEndian, int32(len(parts)))
+		binary.Write(&buf, binary.LittleEndian, int32(count))
+
+		offset := 0
+
+		for _, p := range parts {
+			binary.Write(&buf, binary.LittleEndian, int32(offset))
+			offset += len(p)
+		}
+
+		for _, p := range parts {
+			writePoints(&buf, p)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// shapeParts returns the parts of a (multi) linestring or (multi) polygon. Polygon rings are oriented so that
+// exterior rings are clockwise and interior rings (holes) are counter-clockwise, as the Shapefile specification requires.
+func shapeParts(geom orb.Geometry) [][]orb.Point {
+
+	parts := make([][]orb.Point, 0)
+
+	appendPolygon := func(poly orb.Polygon) {
+
+		for idx, r := range poly {
+
+			ring := orb.Clone(r).(orb.Ring)
+			orientation := ring.Orientation()
+
+			if (idx == 0 && orientation == orb.CCW) || (idx > 0 && orientation == orb.CW) {
+				ring.Reverse()
+			}
+
+			parts = append(parts, ring)
+		}
+	}
+
+	switch g := geom.(type) {
+	case orb.LineString:
+		parts = append(parts, g)
+	case orb.MultiLineString:
+		for _, ls := range g {
+			parts = append(parts, ls)
+		}
+	case orb.Polygon:
+		appendPolygon(g)
+	case orb.MultiPolygon:
+		for _, poly := range g {
+			appendPolygon(poly)
+		}
+	}
+
+	return parts
+}
+
+func writeBound(buf *bytes.Buffer, b orb.Bound) {
+	binary.Write(buf, binary.LittleEndian, [4]float64{b.Min.X(), b.Min.Y(), b.Max.X(), b.Max.Y()})
+}
+
+func writePoints[P ~[]orb.Point](buf *bytes.Buffer, points P) {
+
+	for _, pt := range points {
+		binary.Write(buf, binary.LittleEndian, [2]float64{pt.X(), pt.Y()})
+	}
+}
+
+// encodeHeader returns the 100-byte header shared by SHP and SHX files.
+func encodeHeader(shape_type int32, file_length int, bound orb.Bound) []byte {
+
+	buf := make([]byte, shp_header_length)
+
+	binary.BigEndian.PutUint32(buf[0:], uint32(shp_file_code))
+	binary.BigEndian.PutUint32(buf[24:], uint32(file_length/2))
+	binary.LittleEndian.PutUint32(buf[28:], uint32(shp_version))
+	binary.LittleEndian.PutUint32(buf[32:], uint32(shape_type))
+
+	for idx, v := range []float64{bound.Min.X(), bound.Min.Y(), bound.Max.X(), bound.Max.Y()} {
+		binary.LittleEndian.PutUint64(buf[36+idx*8:], math.Float64bits(v))
+	}
+
+	return buf
+}
+
+// decodeShapes decodes all the records in the SHP file 'body'. Null shapes are returned as nil geometries.
+// Z and M values are ignored.
+func decodeShapes(body []byte) ([]orb.Geometry, error) {
+
+	if len(body) < shp_header_length || int32(binary.BigEndian.Uint32(body[0:])) != shp_file_code {
+		return nil, fmt.Errorf("Invalid SHP file header")
+	}
+
+	shapes := make([]orb.Geometry, 0)
+	offset := shp_header_length
+
+	for offset+8 <= len(body) {
+
+		length := int(binary.BigEndian.Uint32(body[offset+4:])) * 2
+		offset += 8
+
+		if offset+length > len(body) {
+			return nil, fmt.Errorf("Invalid SHP record at offset %d, content extends past end of file", offset)
+		}
+
+		geom, err := decodeShape(body[offset : offset+length])
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode SHP record %d, %w", len(shapes)+1, err)
+		}
+
+		shapes = append(shapes, geom)
+		offset += length
+	}
+
+	return shapes, nil
+}
+
+func decodeShape(content []byte) (orb.Geometry, error) {
+
+	r := &shapeReader{
+		body: content,
+	}
+
+	shape_type := r.int32()
+
+	switch shape_type {
+	case SHAPE_NULL:
+		return nil, r.err
+	case SHAPE_POINT, SHAPE_POINTZ, SHAPE_POINTM:
+		pt := r.point()
+		return pt, r.err
+	case SHAPE_MULTIPOINT, SHAPE_MULTIPOINTZ, SHAPE_MULTIPOINTM:
+
+		r.skip(32)
+		count := r.int32()
+
+		points := make(orb.MultiPoint, 0)
+
+		for i := int32(0); i < count && r.err == nil; i++ {
+			points = append(points, r.point())
+		}
+
+		return points, r.err
+
+	case SHAPE_POLYLINE, SHAPE_POLYLINEZ, SHAPE_POLYLINEM, SHAPE_POLYGON, SHAPE_POLYGONZ, SHAPE_POLYGONM:
+
+		r.skip(32)
+		num_parts := r.int32()
+		num_points := r.int32()
+
+		starts := make([]int32, 0)
+
+		for i := int32(0); i < num_parts && r.err == nil; i++ {
+			starts = append(starts, r.int32())
+		}
+
+		points := make([]orb.Point, 0)
+
+		for i := int32(0); i < num_points && r.err == nil; i++ {
+			points = append(points, r.point())
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		parts := make([][]orb.Point, len(starts))
+
+		for idx, start := range starts {
+
+			end := num_points
+
+			if idx+1 < len(starts) {
+				end = starts[idx+1]
+			}
+
+			if start < 0 || start > end || end > num_points {
+				return nil, fmt.Errorf("Invalid part offsets")
+			}
+
+			parts[idx] = points[start:end]
+		}
+
+		switch shape_type {
+		case SHAPE_POLYGON, SHAPE_POLYGONZ, SHAPE_POLYGONM:
+			return partsAsPolygons(parts), nil
+		default:
+			return partsAsLines(parts), nil
+		}
+
+	default:
+		return nil, fmt.Errorf("Unsupported shape type %d", shape_type)
+	}
+}
+
+func partsAsLines(parts [][]orb.Point) orb.Geometry {
+
+	if len(parts) == 1 {
+		return orb.LineString(parts[0])
+	}
+
+	lines := make(orb.MultiLineString, len(parts))
+
+	for idx, p := range parts {
+		lines[idx] = orb.LineString(p)
+	}
+
+	return lines
+}
+
+// partsAsPolygons assembles the rings in 'parts' into one or more polygons. Clockwise rings are exterior
+// rings and counter-clockwise rings are interior rings (holes) which are assigned to the first exterior
+// ring that contains them. Rings are re-oriented to follow the GeoJSON (RFC 7946) right-hand rule.
+func partsAsPolygons(parts [][]orb.Point) orb.Geometry {
+
+	polygons := make([]orb.Polygon, 0)
+	holes := make([]orb.Ring, 0)
+
+	for _, p := range parts {
+
+		ring := orb.Ring(p)
+
+		if ring.Orientation() == orb.CCW {
+			holes = append(holes, ring)
+			continue
+		}
+
+		ring.Reverse()
+		polygons = append(polygons, orb.Polygon{ring})
+	}
+
+	for _, h := range holes {
+
+		h.Reverse()
+		assigned := false
+
+		for idx, poly := range polygons {
+
+			if len(h) > 0 && planar.RingContains(poly[0], h[0]) {
+				polygons[idx] = append(poly, h)
+				assigned = true
+				break
+			}
+		}
+
+		// A counter-clockwise ring that isn't inside any exterior ring is treated as an
+		// exterior ring with the wrong orientation.
+
+		if !assigned {
+			h.Reverse()
+			polygons = append(polygons, orb.Polygon{h})
+		}
+	}
+
+	if len(polygons) == 1 {
+		return polygons[0]
+	}
+
+	return orb.MultiPolygon(polygons)
+}
+
+type shapeReader struct {
+	body   []byte
+	offset int
+	err    error
+}
+
+func (r *shapeReader) skip(n int) {
+
+	if r.err != nil {
+		return
+	}
+
+	if r.offset+n > len(r.body) {
+		r.err = fmt.Errorf("Unexpected end of record")
+		return
+	}
+
+	r.offset += n
+}
+
+func (r *shapeReader) int32() int32 {
+
+	start := r.offset
+	r.skip(4)
+
+	if r.err != nil {
+		return 0
+	}
+
+	return int32(binary.LittleEndian.Uint32(r.body[start:]))
+}
+
+func (r *shapeReader) float64() float64 {
+
+	start := r.offset
+	r.skip(8)
+
+	if r.err != nil {
+		return 0
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.body[start:]))
+}
+
+func (r *shapeReader) point() orb.Point {
+	x := r.float64()
+	y := r.float64()
+	return orb.Point{x, y}
+}
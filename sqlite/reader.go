@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/whosonfirst/go-reader"
+	"github.com/whosonfirst/go-whosonfirst-database/sql/tables"
+	"github.com/whosonfirst/go-whosonfirst-uri"
+)
+
+// SQLiteReader implements the `whosonfirst/go-reader.Reader` interface for reading WOF records from the "geojson"
+// table of a SQLite database.
+type SQLiteReader struct {
+	reader.Reader
+	db *sql.DB
+}
+
+type readSeekCloser struct {
+	*bytes.Reader
+}
+
+func (r *readSeekCloser) Close() error {
+	return nil
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := reader.RegisterReader(ctx, "sqlite", NewSQLiteReader)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSQLiteReader returns a new `SQLiteReader` instance configured by 'uri' in the form of:
+//
+//	sqlite://{PATH}
+//
+// Where {PATH} is the path to an existing SQLite database containing a "geojson" table. The database is opened read-only.
+func NewSQLiteReader(ctx context.Context, uri string) (reader.Reader, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	if u.Path == "" {
+		return nil, fmt.Errorf("Missing database path")
+	}
+
+	_, err = os.Stat(u.Path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to stat %s, %w", u.Path, err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro", u.Path)
+
+	db, err := sql.Open("sqlite3", dsn)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", u.Path, err)
+	}
+
+	r := &SQLiteReader{
+		db: db,
+	}
+
+	return r, nil
+}
+
+// Read returns the body of the record, or alternate geometry file, derived from the WOF URI 'path'. For example
+// "101/736/545/101736545.geojson" or "101736545-alt-quattroshapes.geojson". If the record is not found the error
+// returned wraps `os.ErrNotExist`.
+func (r *SQLiteReader) Read(ctx context.Context, path string) (io.ReadSeekCloser, error) {
+
+	id, uri_args, err := uri.ParseURI(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse %s, %w", path, err)
+	}
+
+	alt_label := ""
+
+	if uri_args.IsAlternate {
+		alt_label = altLabel(uri_args.AltGeom)
+	}
+
+	q := fmt.Sprintf("SELECT body FROM %s WHERE id = ? AND alt_label = ?", tables.GEOJSON_TABLE_NAME)
+
+	var body string
+
+	err = r.db.QueryRowContext(ctx, q, id, alt_label).Scan(&body)
+
+	if err != nil {
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s not found, %w", path, os.ErrNotExist)
+		}
+
+		return nil, fmt.Errorf("Failed to query %s, %w", path, err)
+	}
+
+	rsc := &readSeekCloser{
+		Reader: bytes.NewReader([]byte(body)),
+	}
+
+	return rsc, nil
+}
+
+// ReaderURI returns 'path' unchanged.
+func (r *SQLiteReader) ReaderURI(ctx context.Context, path string) string {
+	return path
+}
+
+// altLabel returns the "src:alt_label" value for 'alt_geom' which is its source, function and any extras joined by dashes.
+func altLabel(alt_geom *uri.AltGeom) string {
+
+	parts := []string{alt_geom.Source}
+
+	if alt_geom.Function != "" {
+		parts = append(parts, alt_geom.Function)
+	}
+
+	parts = append(parts, alt_geom.Extras...)
+
+	return strings.Join(parts, "-")
+}
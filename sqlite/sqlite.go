@@ -0,0 +1,353 @@
+// Package sqlite provides methods for reading WOF records from, and writing WOF records to, SQLite databases
+// using the tables defined by whosonfirst/go-whosonfirst-database.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+	database_sql "github.com/sfomuseum/go-database/sql"
+	"github.com/whosonfirst/go-whosonfirst-database/sql/tables"
+	"github.com/whosonfirst/go-whosonfirst-feature/alt"
+	"github.com/whosonfirst/go-whosonfirst-feature/properties"
+)
+
+// DEFAULT_TABLES is the default list of tables created, and populated, in new databases.
+var DEFAULT_TABLES = []string{
+	tables.GEOJSON_TABLE_NAME,
+	tables.SPR_TABLE_NAME,
+	tables.NAMES_TABLE_NAME,
+	tables.ANCESTORS_TABLE_NAME,
+	tables.CONCORDANCES_TABLE_NAME,
+}
+
+// SUPPORTED_TABLES is the list of tables that can be populated, or updated, by `Database` instances.
+var SUPPORTED_TABLES = append(slices.Clone(DEFAULT_TABLES),
+	tables.RTREE_TABLE_NAME,
+	tables.PROPERTIES_TABLE_NAME,
+	tables.SUPERSEDES_TABLE_NAME,
+)
+
+// UNSUPPORTED_TABLES is the list of tables defined by whosonfirst/go-whosonfirst-database that can not be updated
+// by `Database` instances. Existing databases containing any of these tables can only be updated if the tables to
+// populate are listed explicitly.
+var UNSUPPORTED_TABLES = []string{
+	tables.SEARCH_TABLE_NAME,
+	tables.GEOMETRIES_TABLE_NAME,
+	tables.SPELUNKER_TABLE_NAME,
+	tables.WHOSONFIRST_TABLE_NAME,
+}
+
+// DatabaseOptions defines configuration options for a `Database` instance.
+type DatabaseOptions struct {
+	// The names of the tables to populate, creating them if necessary. Each must be one of the values in
+	// `SUPPORTED_TABLES`. If empty the supported tables already present in the database are updated or,
+	// if the database doesn't contain any of them, the tables in `DEFAULT_TABLES` are created.
+	Tables []string
+	// Index alternate geometry files in the "geojson", "spr", "rtree" and "properties" tables.
+	IndexAltFiles bool
+}
+
+// Database populates a SQLite database with WOF records using the tables defined by whosonfirst/go-whosonfirst-database.
+type Database struct {
+	db     *sql.DB
+	tables []database_sql.Table
+	mu     *sync.Mutex
+}
+
+// DefaultDatabaseOptions returns a `DatabaseOptions` instance for populating the tables already present in a
+// database (or the tables in `DEFAULT_TABLES` for new databases), including alternate geometry files.
+func DefaultDatabaseOptions() *DatabaseOptions {
+
+	opts := &DatabaseOptions{
+		Tables:        make([]string, 0),
+		IndexAltFiles: true,
+	}
+
+	return opts
+}
+
+// DatabaseOptionsFromQuery returns a `DatabaseOptions` instance derived from the "table" and "index-alt-files" parameters in 'q'.
+func DatabaseOptionsFromQuery(q url.Values) (*DatabaseOptions, error) {
+
+	opts := DefaultDatabaseOptions()
+
+	if q.Has("table") {
+		opts.Tables = q["table"]
+	}
+
+	if q.Has("index-alt-files") {
+
+		v, err := strconv.ParseBool(q.Get("index-alt-files"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?index-alt-files= parameter, %w", err)
+		}
+
+		opts.IndexAltFiles = v
+	}
+
+	return opts, nil
+}
+
+// OpenDatabase opens the SQLite database at 'path', creating it if necessary, and creates any tables defined in 'opts'
+// that don't already exist.
+func OpenDatabase(ctx context.Context, path string, opts *DatabaseOptions) (*Database, error) {
+
+	db, err := sql.Open("sqlite3", path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s, %w", path, err)
+	}
+
+	// SQLite only supports a single writer at a time
+	db.SetMaxOpenConns(1)
+
+	d, err := configureDatabase(ctx, db, opts)
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func configureDatabase(ctx context.Context, db *sql.DB, opts *DatabaseOptions) (*Database, error) {
+
+	existing, err := tableNames(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	table_names := opts.Tables
+
+	if len(table_names) == 0 {
+
+		for _, name := range existing {
+
+			if slices.Contains(UNSUPPORTED_TABLES, name) {
+				return nil, fmt.Errorf("Database contains a '%s' table which can not be updated, tables to update must be listed explicitly", name)
+			}
+
+			if slices.Contains(SUPPORTED_TABLES, name) {
+				table_names = append(table_names, name)
+			}
+		}
+	}
+
+	if len(table_names) == 0 {
+		table_names = DEFAULT_TABLES
+	}
+
+	db_tables := make([]database_sql.Table, len(table_names))
+
+	for idx, name := range table_names {
+
+		t, err := newTable(ctx, name, opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		db_tables[idx] = t
+	}
+
+	// The default pragma disables journaling which is fine (and faster) when building a new
+	// database but not when updating an existing database in place.
+
+	if len(existing) == 0 {
+
+		err = database_sql.ConfigureSQLitePragma(ctx, db, database_sql.DefaultSQLitePragma())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db_opts := &database_sql.ConfigureDatabaseOptions{
+		CreateTablesIfNecessary: true,
+		Tables:                  db_tables,
+	}
+
+	err = database_sql.ConfigureDatabase(ctx, db, db_opts)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to configure database, %w", err)
+	}
+
+	d := &Database{
+		db:     db,
+		tables: db_tables,
+		mu:     new(sync.Mutex),
+	}
+
+	return d, nil
+}
+
+// IndexFeature adds (or replaces) the GeoJSON Feature 'body' in each of the tables in 'd'.
+func (d *Database) IndexFeature(ctx context.Context, body []byte) error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, t := range d.tables {
+
+		// Rows in the rtree table are always inserted (rather than replaced) so remove any existing
+		// rows for the record first.
+
+		if t.Name() == tables.RTREE_TABLE_NAME {
+
+			err := d.removeRTreeRows(ctx, t.Name(), body)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err := t.IndexRecord(ctx, d.db, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to index %s table, %w", t.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the underlying database connection.
+func (d *Database) Close(ctx context.Context) error {
+	return d.db.Close()
+}
+
+func (d *Database) removeRTreeRows(ctx context.Context, table_name string, body []byte) error {
+
+	id, err := properties.Id(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to derive ID, %w", err)
+	}
+
+	alt_label := ""
+
+	if alt.IsAlt(body) {
+
+		alt_label, err = properties.AltLabel(body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to derive alt label, %w", err)
+		}
+	}
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE wof_id = ? AND alt_label = ?", table_name)
+
+	_, err = d.db.ExecContext(ctx, q, id, alt_label)
+
+	if err != nil {
+		return fmt.Errorf("Failed to remove existing rows from %s table, %w", table_name, err)
+	}
+
+	return nil
+}
+
+// tableNames returns the names of all the tables in 'db'.
+func tableNames(ctx context.Context, db *sql.DB) ([]string, error) {
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type='table'")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query sqlite_master, %w", err)
+	}
+
+	defer rows.Close()
+
+	names := make([]string, 0)
+
+	for rows.Next() {
+
+		var name string
+		err := rows.Scan(&name)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan table name, %w", err)
+		}
+
+		names = append(names, name)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query sqlite_master, %w", err)
+	}
+
+	return names, nil
+}
+
+func newTable(ctx context.Context, name string, opts *DatabaseOptions) (database_sql.Table, error) {
+
+	switch name {
+	case tables.GEOJSON_TABLE_NAME:
+
+		table_opts, err := tables.DefaultGeoJSONTableOptions()
+
+		if err != nil {
+			return nil, err
+		}
+
+		table_opts.IndexAltFiles = opts.IndexAltFiles
+		return tables.NewGeoJSONTableWithOptions(ctx, table_opts)
+
+	case tables.SPR_TABLE_NAME:
+
+		table_opts, err := tables.DefaultSPRTableOptions()
+
+		if err != nil {
+			return nil, err
+		}
+
+		table_opts.IndexAltFiles = opts.IndexAltFiles
+		return tables.NewSPRTableWithOptions(ctx, table_opts)
+
+	case tables.RTREE_TABLE_NAME:
+
+		table_opts, err := tables.DefaultRTreeTableOptions()
+
+		if err != nil {
+			return nil, err
+		}
+
+		table_opts.IndexAltFiles = opts.IndexAltFiles
+		return tables.NewRTreeTableWithOptions(ctx, table_opts)
+
+	case tables.PROPERTIES_TABLE_NAME:
+
+		table_opts, err := tables.DefaultPropertiesTableOptions()
+
+		if err != nil {
+			return nil, err
+		}
+
+		table_opts.IndexAltFiles = opts.IndexAltFiles
+		return tables.NewPropertiesTableWithOptions(ctx, table_opts)
+
+	case tables.NAMES_TABLE_NAME:
+		return tables.NewNamesTable(ctx)
+	case tables.ANCESTORS_TABLE_NAME:
+		return tables.NewAncestorsTable(ctx)
+	case tables.CONCORDANCES_TABLE_NAME:
+		return tables.NewConcordancesTable(ctx)
+	case tables.SUPERSEDES_TABLE_NAME:
+		return tables.NewSupersedesTable(ctx)
+	default:
+		return nil, fmt.Errorf("Unsupported table '%s', must be one of: %s", name, strings.Join(SUPPORTED_TABLES, ", "))
+	}
+}
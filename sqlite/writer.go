@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// SQLiteWriter implements the `whosonfirst/go-writer.Writer` interface for writing WOF records to a SQLite
+// database using the tables defined by whosonfirst/go-whosonfirst-database.
+type SQLiteWriter struct {
+	writer.Writer
+	database *Database
+}
+
+func init() {
+
+	ctx := context.Background()
+
+	err := writer.RegisterWriter(ctx, "sqlite", NewSQLiteWriter)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSQLiteWriter returns a new `SQLiteWriter` instance configured by 'uri' in the form of:
+//
+//	sqlite://{PATH}?table={TABLE}&index-alt-files={BOOLEAN}
+//
+// Where:
+// * {PATH} is the path to the SQLite database. It will be created if it doesn't already exist.
+// * {TABLE} is the name of a table to populate, creating it if necessary. May be passed multiple times. Default is the
+// supported tables already present in the database or, for new databases, the tables in `DEFAULT_TABLES`.
+// * {BOOLEAN} is whether to index alternate geometry files. Default is true.
+//
+// Records are updated in place, replacing any existing rows for the same ID (and alternate geometry label).
+func NewSQLiteWriter(ctx context.Context, uri string) (writer.Writer, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	if u.Path == "" {
+		return nil, fmt.Errorf("Missing database path")
+	}
+
+	opts, err := DatabaseOptionsFromQuery(u.Query())
+
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := OpenDatabase(ctx, u.Path, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wr := &SQLiteWriter{
+		database: d,
+	}
+
+	return wr, nil
+}
+
+// Write adds (or replaces) the record contained in 'fh' in each of the database tables. 'key' is only used in error messages.
+func (wr *SQLiteWriter) Write(ctx context.Context, key string, fh io.ReadSeeker) (int64, error) {
+
+	body, err := io.ReadAll(fh)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read filehandle, %w", err)
+	}
+
+	err = wr.database.IndexFeature(ctx, body)
+
+	if err != nil {
+		return 0, fmt.Errorf("Failed to index %s, %w", key, err)
+	}
+
+	return int64(len(body)), nil
+}
+
+// WriterURI returns 'str_uri' unchanged.
+func (wr *SQLiteWriter) WriterURI(ctx context.Context, str_uri string) string {
+	return str_uri
+}
+
+// Flush is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *SQLiteWriter) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the underlying database.
+func (wr *SQLiteWriter) Close(ctx context.Context) error {
+	return wr.database.Close(ctx)
+}
+
+// SetLogger is a no-op to conform to the `writer.Writer` interface and returns nil.
+func (wr *SQLiteWriter) SetLogger(ctx context.Context, logger *log.Logger) error {
+	return nil
+}
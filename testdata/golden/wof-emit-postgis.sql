@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS whosonfirst (
+	id BIGINT NOT NULL,
+	alt_label TEXT NOT NULL DEFAULT '',
+	properties JSONB,
+	geom geometry(Geometry, 4326),
+	PRIMARY KEY (id, alt_label)
+);
+
+COPY whosonfirst (id, alt_label, properties, geom) FROM stdin;
+101736545		{"edtf:cessation":"","edtf:inception":"","geom:area":0,"geom:bbox":"-73.500000,45.500000,-73.500000,45.500000","geom:latitude":45.5,"geom:longitude":-73.5,"mz:is_current":1,"src:geom":"whosonfirst","wof:belongsto":[85633041,136251273],"wof:country":"CA","wof:created":1700000000,"wof:hierarchy":[{"country_id":85633041,"region_id":136251273,"locality_id":101736545}],"wof:id":101736545,"wof:lastmodified":1700000000,"wof:name":"Montreal","wof:parent_id":136251273,"wof:placetype":"locality","wof:repo":"whosonfirst-data-admin-ca","wof:superseded_by":[],"wof:supersedes":[],"wof:tags":["island"]}	0101000020e610000000000000006052c00000000000c04640
+101736547		{"edtf:cessation":"","edtf:inception":"","geom:area":0,"geom:bbox":"-73.700000,45.600000,-73.700000,45.600000","geom:latitude":45.6,"geom:longitude":-73.7,"mz:is_current":1,"src:geom":"whosonfirst","wof:belongsto":[85633041,136251273],"wof:country":"CA","wof:created":1700000000,"wof:hierarchy":[{"country_id":85633041,"region_id":136251273,"locality_id":101736547}],"wof:id":101736547,"wof:lastmodified":1700000000,"wof:name":"Laval","wof:parent_id":136251273,"wof:placetype":"locality","wof:repo":"whosonfirst-data-admin-ca","wof:superseded_by":[],"wof:supersedes":[]}	0101000020e6100000cdcccccccc6c52c0cdcccccccccc4640
+136251273		{"edtf:cessation":"","edtf:inception":"","geom:area":0,"geom:bbox":"-71.800000,52.400000,-71.800000,52.400000","geom:latitude":52.4,"geom:longitude":-71.8,"mz:is_current":1,"src:geom":"whosonfirst","wof:belongsto":[85633041],"wof:country":"CA","wof:created":1700000000,"wof:hierarchy":[{"country_id":85633041,"region_id":136251273}],"wof:id":136251273,"wof:lastmodified":1700000000,"wof:name":"Quebec","wof:parent_id":85633041,"wof:placetype":"region","wof:repo":"whosonfirst-data-admin-ca","wof:superseded_by":[],"wof:supersedes":[]}	0101000020e61000003333333333f351c03333333333334a40
+\.
+
+CREATE INDEX IF NOT EXISTS whosonfirst_geom_idx ON whosonfirst USING GIST (geom);
+
+COMMIT;
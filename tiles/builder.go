@@ -0,0 +1,357 @@
+package tiles
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/orb/simplify"
+	"github.com/tidwall/gjson"
+)
+
+// DEFAULT_LAYER is the default name of the layer that features are added to.
+const DEFAULT_LAYER string = "whosonfirst"
+
+// DEFAULT_BUFFER is the default size, in tile extent units, of the buffer around each tile that features are clipped to.
+const DEFAULT_BUFFER float64 = 64
+
+// DEFAULT_TOLERANCE is the default Douglas-Peucker simplification threshold, in tile extent units.
+const DEFAULT_TOLERANCE float64 = 1.0
+
+// MAX_LATITUDE is the maximum latitude representable in the Web Mercator projection.
+const MAX_LATITUDE float64 = 85.05112877980659
+
+// BuilderOptions defines configuration options for a `Builder` instance.
+type BuilderOptions struct {
+	// The name of the layer that features are added to.
+	Layer string
+	// The list of (relative) property paths to include as feature attributes. For example "wof:name".
+	Properties []string
+	// The minimum zoom level to generate tiles for.
+	MinZoom maptile.Zoom
+	// The maximum zoom level to generate tiles for.
+	MaxZoom maptile.Zoom
+	// The Douglas-Peucker simplification threshold, in tile extent units, applied to geometries after they have
+	// been projected to tile coordinates. Because this is measured in tile units the amount of simplification
+	// decreases as the zoom level increases. If 0 geometries are not simplified.
+	Tolerance float64
+	// Optional simplification thresholds for specific zoom levels, overriding `Tolerance`.
+	ZoomTolerances map[maptile.Zoom]float64
+	// The size, in tile extent units, of the buffer around each tile that features are clipped to.
+	Buffer float64
+}
+
+type feature struct {
+	id         int64
+	geometry   orb.Geometry
+	bound      orb.Bound
+	properties geojson.Properties
+}
+
+// Builder accumulates WOF records in memory and generates Mapbox Vector Tiles for them.
+type Builder struct {
+	options  *BuilderOptions
+	features []*feature
+	fields   map[string]string
+	mu       *sync.Mutex
+}
+
+// DefaultBuilderOptions returns a `BuilderOptions` instance with default values for zoom levels 0 through 12.
+func DefaultBuilderOptions() *BuilderOptions {
+
+	opts := &BuilderOptions{
+		Layer:          DEFAULT_LAYER,
+		Properties:     make([]string, 0),
+		MinZoom:        0,
+		MaxZoom:        12,
+		Tolerance:      DEFAULT_TOLERANCE,
+		ZoomTolerances: make(map[maptile.Zoom]float64),
+		Buffer:         DEFAULT_BUFFER,
+	}
+
+	return opts
+}
+
+// NewBuilder returns a new `Builder` instance configured by 'opts'.
+func NewBuilder(ctx context.Context, opts *BuilderOptions) (*Builder, error) {
+
+	if opts.Layer == "" {
+		return nil, fmt.Errorf("Missing layer name")
+	}
+
+	if opts.MinZoom > opts.MaxZoom {
+		return nil, fmt.Errorf("Minimum zoom level (%d) is greater than maximum zoom level (%d)", opts.MinZoom, opts.MaxZoom)
+	}
+
+	if opts.MaxZoom > 24 {
+		return nil, fmt.Errorf("Maximum zoom level (%d) must be less than or equal to 24", opts.MaxZoom)
+	}
+
+	if opts.Tolerance < 0 || opts.Buffer < 0 {
+		return nil, fmt.Errorf("Tolerance and buffer must not be negative")
+	}
+
+	b := &Builder{
+		options:  opts,
+		features: make([]*feature, 0),
+		fields:   make(map[string]string),
+		mu:       new(sync.Mutex),
+	}
+
+	return b, nil
+}
+
+// AddFeature adds the GeoJSON Feature 'body' to the list of features to generate tiles for.
+func (b *Builder) AddFeature(ctx context.Context, body []byte) error {
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal feature, %w", err)
+	}
+
+	if f.Geometry == nil {
+		return fmt.Errorf("Feature is missing a geometry")
+	}
+
+	id_rsp := gjson.GetBytes(body, "properties.wof:id")
+
+	properties := geojson.Properties{}
+	fields := make(map[string]string)
+
+	for _, path := range b.options.Properties {
+
+		rsp := gjson.GetBytes(body, "properties."+gjson.Escape(path))
+
+		switch rsp.Type {
+		case gjson.Null:
+			continue
+		case gjson.True, gjson.False:
+			fields[path] = "Boolean"
+		case gjson.Number:
+			fields[path] = "Number"
+		default:
+			fields[path] = "String"
+		}
+
+		switch {
+		case rsp.IsObject(), rsp.IsArray():
+			properties[path] = rsp.Raw
+		case rsp.Type == gjson.Number && float64(rsp.Int()) == rsp.Float():
+			properties[path] = rsp.Int()
+		default:
+			properties[path] = rsp.Value()
+		}
+	}
+
+	tile_f := &feature{
+		id:         id_rsp.Int(),
+		geometry:   f.Geometry,
+		bound:      f.Geometry.Bound(),
+		properties: properties,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for k, v := range fields {
+
+		existing, ok := b.fields[k]
+
+		if ok && existing != v {
+			v = "String"
+		}
+
+		b.fields[k] = v
+	}
+
+	b.features = append(b.features, tile_f)
+	return nil
+}
+
+// Build generates Mapbox Vector Tiles, for each zoom level, for all the features added so far and writes them
+// to 'wr'. Tiles that don't contain any features are not written. 'wr' is closed once all the tiles have been written.
+func (b *Builder) Build(ctx context.Context, wr TileWriter) error {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var extent orb.Bound
+
+	for idx, f := range b.features {
+
+		if idx == 0 {
+			extent = f.bound
+			continue
+		}
+
+		extent = extent.Union(f.bound)
+	}
+
+	for z := b.options.MinZoom; z <= b.options.MaxZoom; z++ {
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			// pass
+		}
+
+		err := b.buildZoom(ctx, wr, z)
+
+		if err != nil {
+			return fmt.Errorf("Failed to build tiles for zoom %d, %w", z, err)
+		}
+	}
+
+	md := &Metadata{
+		Name:    b.options.Layer,
+		Format:  "pbf",
+		MinZoom: b.options.MinZoom,
+		MaxZoom: b.options.MaxZoom,
+		Bounds:  [4]float64{extent.Min.X(), extent.Min.Y(), extent.Max.X(), extent.Max.Y()},
+		VectorLayers: []*VectorLayer{
+			{
+				Id:      b.options.Layer,
+				Fields:  b.fields,
+				MinZoom: b.options.MinZoom,
+				MaxZoom: b.options.MaxZoom,
+			},
+		},
+	}
+
+	return wr.Close(ctx, md)
+}
+
+func (b *Builder) buildZoom(ctx context.Context, wr TileWriter, z maptile.Zoom) error {
+
+	index := make(map[maptile.Tile][]*feature)
+	buffer := b.options.Buffer / float64(mvt.DefaultExtent)
+
+	for _, f := range b.features {
+
+		for _, t := range coveringTiles(f.bound, z, buffer) {
+			index[t] = append(index[t], f)
+		}
+	}
+
+	tiles := make([]maptile.Tile, 0, len(index))
+
+	for t := range index {
+		tiles = append(tiles, t)
+	}
+
+	sort.Slice(tiles, func(i, j int) bool {
+
+		if tiles[i].X != tiles[j].X {
+			return tiles[i].X < tiles[j].X
+		}
+
+		return tiles[i].Y < tiles[j].Y
+	})
+
+	tolerance := b.options.Tolerance
+
+	zoom_tolerance, ok := b.options.ZoomTolerances[z]
+
+	if ok {
+		tolerance = zoom_tolerance
+	}
+
+	clip_bound := orb.Bound{
+		Min: orb.Point{-b.options.Buffer, -b.options.Buffer},
+		Max: orb.Point{float64(mvt.DefaultExtent) + b.options.Buffer, float64(mvt.DefaultExtent) + b.options.Buffer},
+	}
+
+	for _, t := range tiles {
+
+		fc := geojson.NewFeatureCollection()
+
+		for _, f := range index[t] {
+
+			tile_f := geojson.NewFeature(orb.Clone(f.geometry))
+			tile_f.Properties = f.properties
+
+			if f.id > 0 {
+				tile_f.ID = f.id
+			}
+
+			fc.Append(tile_f)
+		}
+
+		layer := mvt.NewLayer(b.options.Layer, fc)
+		layer.ProjectToTile(t)
+		layer.Clip(clip_bound)
+
+		if tolerance > 0 {
+			layer.Simplify(simplify.DouglasPeucker(tolerance))
+			layer.RemoveEmpty(tolerance, tolerance*tolerance)
+		}
+
+		if len(layer.Features) == 0 {
+			continue
+		}
+
+		body, err := mvt.Marshal(mvt.Layers{layer})
+
+		if err != nil {
+			return fmt.Errorf("Failed to marshal tile %d/%d/%d, %w", t.Z, t.X, t.Y, err)
+		}
+
+		err = wr.WriteTile(ctx, t, body)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write tile %d/%d/%d, %w", t.Z, t.X, t.Y, err)
+		}
+	}
+
+	return nil
+}
+
+// coveringTiles returns the tiles at zoom level 'z' that intersect 'bound', including a buffer of 'buffer'
+// (expressed as a fraction of a tile) around each tile.
+func coveringTiles(bound orb.Bound, z maptile.Zoom, buffer float64) []maptile.Tile {
+
+	min_lat := math.Max(bound.Min.Y(), -MAX_LATITUDE)
+	max_lat := math.Min(bound.Max.Y(), MAX_LATITUDE)
+
+	if min_lat > max_lat {
+		return nil
+	}
+
+	n := float64(uint32(1) << z)
+
+	tileX := func(lon float64) float64 {
+		return (lon + 180.0) / 360.0 * n
+	}
+
+	tileY := func(lat float64) float64 {
+		rad := lat * math.Pi / 180.0
+		return (1.0 - math.Log(math.Tan(rad)+1.0/math.Cos(rad))/math.Pi) / 2.0 * n
+	}
+
+	clamp := func(v float64) uint32 {
+		return uint32(math.Max(0, math.Min(n-1, math.Floor(v))))
+	}
+
+	min_x := clamp(tileX(bound.Min.X()) - buffer)
+	max_x := clamp(tileX(bound.Max.X()) + buffer)
+	min_y := clamp(tileY(max_lat) - buffer)
+	max_y := clamp(tileY(min_lat) + buffer)
+
+	tiles := make([]maptile.Tile, 0)
+
+	for x := min_x; x <= max_x; x++ {
+		for y := min_y; y <= max_y; y++ {
+			tiles = append(tiles, maptile.New(x, y, z))
+		}
+	}
+
+	return tiles
+}
@@ -0,0 +1,104 @@
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/paulmach/orb/maptile"
+	"github.com/whosonfirst/go-writer/v3"
+)
+
+// METADATA_KEY is the key (path) of the file that tileset metadata is written to by `DirectoryTileWriter`.
+const METADATA_KEY string = "metadata.json"
+
+// DirectoryTileWriter implements the `TileWriter` interface for writing tiles as individual files
+// in a {Z}/{X}/{Y}.mvt directory tree using a `whosonfirst/go-writer.Writer` instance.
+type DirectoryTileWriter struct {
+	TileWriter
+	writer writer.Writer
+}
+
+// DirectoryWriterSchemes returns the list of `whosonfirst/go-writer.Writer` URI schemes, for writers that store
+// each key as a separate file, supported by `NewDirectoryTileWriterWithURI`.
+func DirectoryWriterSchemes() []string {
+	return []string{"fs://", "null://", "stdout://"}
+}
+
+// NewDirectoryTileWriterWithURI returns a new `DirectoryTileWriter` instance that writes tiles to the
+// `whosonfirst/go-writer.Writer` instance defined by 'uri'. The scheme of 'uri' must be one of those returned
+// by `DirectoryWriterSchemes`.
+func NewDirectoryTileWriterWithURI(ctx context.Context, uri string) (TileWriter, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	supported := false
+
+	for _, s := range DirectoryWriterSchemes() {
+
+		if s == u.Scheme+"://" {
+			supported = true
+			break
+		}
+	}
+
+	if !supported {
+		return nil, fmt.Errorf("Unsupported writer scheme '%s'", u.Scheme)
+	}
+
+	wr, err := writer.NewWriter(ctx, uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create writer for '%s', %w", uri, err)
+	}
+
+	return NewDirectoryTileWriter(ctx, wr)
+}
+
+// NewDirectoryTileWriter returns a new `DirectoryTileWriter` instance that writes tiles to 'wr'.
+func NewDirectoryTileWriter(ctx context.Context, wr writer.Writer) (TileWriter, error) {
+
+	tile_wr := &DirectoryTileWriter{
+		writer: wr,
+	}
+
+	return tile_wr, nil
+}
+
+// WriteTile writes 'body' to the underlying writer with the key {Z}/{X}/{Y}.mvt.
+func (tile_wr *DirectoryTileWriter) WriteTile(ctx context.Context, t maptile.Tile, body []byte) error {
+
+	key := fmt.Sprintf("%d/%d/%d.mvt", t.Z, t.X, t.Y)
+
+	_, err := tile_wr.writer.Write(ctx, key, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", key, err)
+	}
+
+	return nil
+}
+
+// Close writes 'md' to the underlying writer with the key `METADATA_KEY` and then closes the underlying writer.
+func (tile_wr *DirectoryTileWriter) Close(ctx context.Context, md *Metadata) error {
+
+	enc_md, err := json.MarshalIndent(md, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal metadata, %w", err)
+	}
+
+	_, err = tile_wr.writer.Write(ctx, METADATA_KEY, bytes.NewReader(enc_md))
+
+	if err != nil {
+		return fmt.Errorf("Failed to write %s, %w", METADATA_KEY, err)
+	}
+
+	return tile_wr.writer.Close(ctx)
+}
@@ -0,0 +1,433 @@
+package tiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb/maptile"
+)
+
+// PMTILES_HEADER_LENGTH is the length, in bytes, of a PMTiles (version 3) header.
+const PMTILES_HEADER_LENGTH int = 127
+
+// PMTILES_ROOT_LENGTH is the maximum length, in bytes, of a PMTiles header and root directory combined.
+const PMTILES_ROOT_LENGTH int = 16384
+
+// Compression and tile type values defined by the PMTiles (version 3) specification.
+const (
+	pmtiles_compression_gzip uint8 = 2
+	pmtiles_tile_type_mvt    uint8 = 1
+)
+
+type pmtilesEntry struct {
+	tile_id    uint64
+	offset     uint64
+	length     uint32
+	run_length uint32
+}
+
+// PMTilesWriter implements the `TileWriter` interface for writing tiles to a single PMTiles (version 3)
+// archive. Tiles are gzip-compressed and buffered in a temporary file until the `Close` method is invoked,
+// at which point they are written, ordered by their Hilbert tile ID, to the underlying `io.Writer` instance.
+// Identical tiles are only stored once.
+type PMTilesWriter struct {
+	TileWriter
+	writer   io.Writer
+	tmp      *os.File
+	offset   uint64
+	entries  []*pmtilesEntry
+	contents map[[sha256.Size]byte]*pmtilesEntry
+	mu       *sync.Mutex
+}
+
+// NewPMTilesWriter returns a new `PMTilesWriter` instance that writes a PMTiles archive to 'wr'.
+func NewPMTilesWriter(ctx context.Context, wr io.Writer) (TileWriter, error) {
+
+	tmp, err := os.CreateTemp("", "pmtiles")
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	tile_wr := &PMTilesWriter{
+		writer:   wr,
+		tmp:      tmp,
+		entries:  make([]*pmtilesEntry, 0),
+		contents: make(map[[sha256.Size]byte]*pmtilesEntry),
+		mu:       new(sync.Mutex),
+	}
+
+	return tile_wr, nil
+}
+
+// WriteTile compresses 'body' and appends it to the archive's (temporary) tile data.
+func (tile_wr *PMTilesWriter) WriteTile(ctx context.Context, t maptile.Tile, body []byte) error {
+
+	compressed, err := gzipBytes(body)
+
+	if err != nil {
+		return fmt.Errorf("Failed to compress tile, %w", err)
+	}
+
+	tile_wr.mu.Lock()
+	defer tile_wr.mu.Unlock()
+
+	hash := sha256.Sum256(compressed)
+
+	e := &pmtilesEntry{
+		tile_id:    zxyToID(t.Z, t.X, t.Y),
+		length:     uint32(len(compressed)),
+		run_length: 1,
+	}
+
+	existing, ok := tile_wr.contents[hash]
+
+	if ok {
+		e.offset = existing.offset
+		tile_wr.entries = append(tile_wr.entries, e)
+		return nil
+	}
+
+	_, err = tile_wr.tmp.Write(compressed)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write tile data, %w", err)
+	}
+
+	e.offset = tile_wr.offset
+	tile_wr.offset += uint64(len(compressed))
+
+	tile_wr.contents[hash] = e
+	tile_wr.entries = append(tile_wr.entries, e)
+	return nil
+}
+
+// Close writes the PMTiles archive, including 'md' as its metadata, to the underlying writer and removes
+// the temporary file used to buffer tile data. The underlying writer is not closed.
+func (tile_wr *PMTilesWriter) Close(ctx context.Context, md *Metadata) error {
+
+	tile_wr.mu.Lock()
+	defer tile_wr.mu.Unlock()
+
+	defer os.Remove(tile_wr.tmp.Name())
+	defer tile_wr.tmp.Close()
+
+	sort.Slice(tile_wr.entries, func(i, j int) bool {
+		return tile_wr.entries[i].tile_id < tile_wr.entries[j].tile_id
+	})
+
+	// Rewrite the tile data in tile ID order so that the archive is clustered. Duplicate
+	// tiles continue to point at the first copy of their contents.
+
+	tile_data, err := os.CreateTemp("", "pmtiles")
+
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file, %w", err)
+	}
+
+	defer os.Remove(tile_data.Name())
+	defer tile_data.Close()
+
+	offsets := make(map[uint64]uint64)
+	var tile_data_length uint64
+
+	for _, e := range tile_wr.entries {
+
+		new_offset, ok := offsets[e.offset]
+
+		if !ok {
+
+			_, err := io.Copy(tile_data, io.NewSectionReader(tile_wr.tmp, int64(e.offset), int64(e.length)))
+
+			if err != nil {
+				return fmt.Errorf("Failed to copy tile data, %w", err)
+			}
+
+			new_offset = tile_data_length
+			offsets[e.offset] = new_offset
+			tile_data_length += uint64(e.length)
+		}
+
+		e.offset = new_offset
+	}
+
+	entries := collapseEntries(tile_wr.entries)
+
+	root, leaves, err := buildDirectories(entries)
+
+	if err != nil {
+		return err
+	}
+
+	enc_md, err := json.Marshal(md)
+
+	if err != nil {
+		return fmt.Errorf("Failed to marshal metadata, %w", err)
+	}
+
+	compressed_md, err := gzipBytes(enc_md)
+
+	if err != nil {
+		return fmt.Errorf("Failed to compress metadata, %w", err)
+	}
+
+	root_offset := uint64(PMTILES_HEADER_LENGTH)
+	md_offset := root_offset + uint64(len(root))
+	leaves_offset := md_offset + uint64(len(compressed_md))
+	tile_data_offset := leaves_offset + uint64(len(leaves))
+
+	var addressed uint64
+
+	for _, e := range entries {
+		addressed += uint64(e.run_length)
+	}
+
+	header := make([]byte, PMTILES_HEADER_LENGTH)
+	copy(header[0:7], "PMTiles")
+	header[7] = 3
+
+	binary.LittleEndian.PutUint64(header[8:], root_offset)
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(root)))
+	binary.LittleEndian.PutUint64(header[24:], md_offset)
+	binary.LittleEndian.PutUint64(header[32:], uint64(len(compressed_md)))
+	binary.LittleEndian.PutUint64(header[40:], leaves_offset)
+	binary.LittleEndian.PutUint64(header[48:], uint64(len(leaves)))
+	binary.LittleEndian.PutUint64(header[56:], tile_data_offset)
+	binary.LittleEndian.PutUint64(header[64:], tile_data_length)
+	binary.LittleEndian.PutUint64(header[72:], addressed)
+	binary.LittleEndian.PutUint64(header[80:], uint64(len(entries)))
+	binary.LittleEndian.PutUint64(header[88:], uint64(len(offsets)))
+
+	header[96] = 1
+	header[97] = pmtiles_compression_gzip
+	header[98] = pmtiles_compression_gzip
+	header[99] = pmtiles_tile_type_mvt
+	header[100] = uint8(md.MinZoom)
+	header[101] = uint8(md.MaxZoom)
+
+	binary.LittleEndian.PutUint32(header[102:], uint32(e7(md.Bounds[0])))
+	binary.LittleEndian.PutUint32(header[106:], uint32(e7(md.Bounds[1])))
+	binary.LittleEndian.PutUint32(header[110:], uint32(e7(md.Bounds[2])))
+	binary.LittleEndian.PutUint32(header[114:], uint32(e7(md.Bounds[3])))
+
+	header[118] = uint8(md.MinZoom)
+	binary.LittleEndian.PutUint32(header[119:], uint32(e7((md.Bounds[0]+md.Bounds[2])/2.0)))
+	binary.LittleEndian.PutUint32(header[123:], uint32(e7((md.Bounds[1]+md.Bounds[3])/2.0)))
+
+	for _, section := range [][]byte{header, root, compressed_md, leaves} {
+
+		_, err := tile_wr.writer.Write(section)
+
+		if err != nil {
+			return fmt.Errorf("Failed to write PMTiles archive, %w", err)
+		}
+	}
+
+	_, err = tile_data.Seek(0, io.SeekStart)
+
+	if err != nil {
+		return fmt.Errorf("Failed to rewind tile data, %w", err)
+	}
+
+	_, err = io.Copy(tile_wr.writer, tile_data)
+
+	if err != nil {
+		return fmt.Errorf("Failed to write tile data, %w", err)
+	}
+
+	return nil
+}
+
+// collapseEntries merges consecutive entries, sorted by tile ID, that point to the same tile data into
+// a single entry with a run length greater than 1.
+func collapseEntries(entries []*pmtilesEntry) []*pmtilesEntry {
+
+	collapsed := make([]*pmtilesEntry, 0, len(entries))
+
+	for _, e := range entries {
+
+		if len(collapsed) > 0 {
+
+			last := collapsed[len(collapsed)-1]
+
+			if last.offset == e.offset && last.tile_id+uint64(last.run_length) == e.tile_id {
+				last.run_length += 1
+				continue
+			}
+		}
+
+		collapsed = append(collapsed, &pmtilesEntry{
+			tile_id:    e.tile_id,
+			offset:     e.offset,
+			length:     e.length,
+			run_length: e.run_length,
+		})
+	}
+
+	return collapsed
+}
+
+// buildDirectories returns the compressed root directory and leaf directories for 'entries'. Leaf directories
+// are only created if the root directory would otherwise not fit in the first PMTILES_ROOT_LENGTH bytes of the archive.
+func buildDirectories(entries []*pmtilesEntry) ([]byte, []byte, error) {
+
+	root, err := encodeDirectory(entries)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(root) <= PMTILES_ROOT_LENGTH-PMTILES_HEADER_LENGTH {
+		return root, nil, nil
+	}
+
+	leaf_size := float64(4096)
+
+	for {
+
+		var leaves bytes.Buffer
+		root_entries := make([]*pmtilesEntry, 0)
+
+		for i := 0; i < len(entries); i += int(leaf_size) {
+
+			j := min(i+int(leaf_size), len(entries))
+
+			leaf, err := encodeDirectory(entries[i:j])
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			root_entries = append(root_entries, &pmtilesEntry{
+				tile_id: entries[i].tile_id,
+				offset:  uint64(leaves.Len()),
+				length:  uint32(len(leaf)),
+			})
+
+			leaves.Write(leaf)
+		}
+
+		root, err := encodeDirectory(root_entries)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(root) <= PMTILES_ROOT_LENGTH-PMTILES_HEADER_LENGTH {
+			return root, leaves.Bytes(), nil
+		}
+
+		leaf_size *= 1.2
+	}
+}
+
+// encodeDirectory returns the gzip-compressed PMTiles directory for 'entries'.
+func encodeDirectory(entries []*pmtilesEntry) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	writeUvarint := func(v uint64) {
+		buf.Write(binary.AppendUvarint(nil, v))
+	}
+
+	writeUvarint(uint64(len(entries)))
+
+	var last_id uint64
+
+	for _, e := range entries {
+		writeUvarint(e.tile_id - last_id)
+		last_id = e.tile_id
+	}
+
+	for _, e := range entries {
+		writeUvarint(uint64(e.run_length))
+	}
+
+	for _, e := range entries {
+		writeUvarint(uint64(e.length))
+	}
+
+	for idx, e := range entries {
+
+		if idx > 0 && e.offset == entries[idx-1].offset+uint64(entries[idx-1].length) {
+			writeUvarint(0)
+			continue
+		}
+
+		writeUvarint(e.offset + 1)
+	}
+
+	compressed, err := gzipBytes(buf.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to compress directory, %w", err)
+	}
+
+	return compressed, nil
+}
+
+// zxyToID returns the PMTiles tile ID for the tile at 'z', 'x', 'y'. Tile IDs are the position of the tile
+// along a Hilbert curve for its zoom level plus the number of tiles in all the zoom levels before it.
+func zxyToID(z maptile.Zoom, x uint32, y uint32) uint64 {
+
+	acc := ((uint64(1) << (2 * uint64(z))) - 1) / 3
+
+	if z == 0 {
+		return acc
+	}
+
+	for s := uint32(1) << (z - 1); s > 0; s >>= 1 {
+
+		rx := s & x
+		ry := s & y
+
+		acc += uint64((3*rx)^ry) * uint64(s)
+
+		if ry == 0 {
+
+			if rx != 0 {
+				x = s - 1 - x
+				y = s - 1 - y
+			}
+
+			x, y = y, x
+		}
+	}
+
+	return acc
+}
+
+func e7(v float64) int32 {
+	return int32(math.Round(v * 10000000))
+}
+
+func gzipBytes(body []byte) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+
+	_, err := gz.Write(body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = gz.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
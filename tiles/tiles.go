@@ -0,0 +1,45 @@
+// Package tiles provides methods for generating Mapbox Vector Tiles (MVT) from WOF records and writing them
+// to a directory tree or a single PMTiles archive.
+package tiles
+
+import (
+	"context"
+
+	"github.com/paulmach/orb/maptile"
+)
+
+// TileWriter is an interface for writing Mapbox Vector Tiles.
+type TileWriter interface {
+	// WriteTile writes the (uncompressed) Mapbox Vector Tile 'body' for 'tile'.
+	WriteTile(context.Context, maptile.Tile, []byte) error
+	// Close writes any remaining data, including the tileset's metadata, and closes the writer.
+	Close(context.Context, *Metadata) error
+}
+
+// Metadata describes a set of Mapbox Vector Tiles.
+type Metadata struct {
+	// The name of the tileset.
+	Name string `json:"name"`
+	// The format of the tiles. This is always "pbf".
+	Format string `json:"format"`
+	// The minimum zoom level of the tileset.
+	MinZoom maptile.Zoom `json:"minzoom"`
+	// The maximum zoom level of the tileset.
+	MaxZoom maptile.Zoom `json:"maxzoom"`
+	// The geographic extent of the tileset as (west, south, east, north).
+	Bounds [4]float64 `json:"bounds"`
+	// The layers in the tileset.
+	VectorLayers []*VectorLayer `json:"vector_layers"`
+}
+
+// VectorLayer describes a layer in a set of Mapbox Vector Tiles.
+type VectorLayer struct {
+	// The name of the layer.
+	Id string `json:"id"`
+	// The attributes of the features in the layer mapped to their type ("String", "Number" or "Boolean").
+	Fields map[string]string `json:"fields"`
+	// The minimum zoom level of the layer.
+	MinZoom maptile.Zoom `json:"minzoom"`
+	// The maximum zoom level of the layer.
+	MaxZoom maptile.Zoom `json:"maxzoom"`
+}
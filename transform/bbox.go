@@ -0,0 +1,41 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// BoundingBoxTransformation implements the `Transformation` interface to replace a feature's geometry
+// with the polygon of its bounding box.
+type BoundingBoxTransformation struct {
+	Transformation
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "bbox", NewBoundingBoxTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewBoundingBoxTransformation returns a new `BoundingBoxTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	bbox://
+func NewBoundingBoxTransformation(ctx context.Context, uri string) (Transformation, error) {
+	t := &BoundingBoxTransformation{}
+	return t, nil
+}
+
+// Transform replaces the geometry of 'f' with the polygon of its bounding box.
+func (t *BoundingBoxTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	f.Geometry = f.Geometry.Bound().ToPolygon()
+	f.BBox = nil
+
+	return f, nil
+}
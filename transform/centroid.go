@@ -0,0 +1,44 @@
+package transform
+
+import (
+	"context"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// CentroidTransformation implements the `Transformation` interface to replace a feature's geometry
+// with the (planar) centroid of that geometry.
+type CentroidTransformation struct {
+	Transformation
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "centroid", NewCentroidTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewCentroidTransformation returns a new `CentroidTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	centroid://
+func NewCentroidTransformation(ctx context.Context, uri string) (Transformation, error) {
+	t := &CentroidTransformation{}
+	return t, nil
+}
+
+// Transform replaces the geometry of 'f' with its centroid.
+func (t *CentroidTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	pt, _ := planar.CentroidArea(f.Geometry)
+
+	f.Geometry = pt
+	f.BBox = nil
+
+	return f, nil
+}
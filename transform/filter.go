@@ -0,0 +1,86 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// GeometryTypeFilterTransformation implements the `Transformation` interface to filter out features
+// whose geometry type does not match a list of GeoJSON geometry types.
+type GeometryTypeFilterTransformation struct {
+	Transformation
+	types   map[string]bool
+	exclude bool
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "filter", NewGeometryTypeFilterTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewGeometryTypeFilterTransformation returns a new `GeometryTypeFilterTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	filter://?type={GEOJSON_TYPE}&type={GEOJSON_TYPE}
+//	filter://?exclude={GEOJSON_TYPE}&exclude={GEOJSON_TYPE}
+//
+// Where `type` parameters define the geometry types to keep and `exclude` parameters define the geometry types
+// to filter out. The two parameters can not be combined.
+func NewGeometryTypeFilterTransformation(ctx context.Context, uri string) (Transformation, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	include := q["type"]
+	exclude := q["exclude"]
+
+	if len(include) > 0 && len(exclude) > 0 {
+		return nil, fmt.Errorf("?type= and ?exclude= parameters can not be combined")
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, fmt.Errorf("Missing ?type= or ?exclude= parameter")
+	}
+
+	t := &GeometryTypeFilterTransformation{
+		types: make(map[string]bool),
+	}
+
+	types := include
+
+	if len(exclude) > 0 {
+		types = exclude
+		t.exclude = true
+	}
+
+	for _, gt := range types {
+		t.types[gt] = true
+	}
+
+	return t, nil
+}
+
+// Transform returns nil if the geometry type of 'f' is filtered out, or 'f' otherwise.
+func (t *GeometryTypeFilterTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	gt := f.Geometry.GeoJSONType()
+
+	if t.types[gt] == t.exclude {
+		return nil, nil
+	}
+
+	return f, nil
+}
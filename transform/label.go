@@ -0,0 +1,86 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// LabelTransformation implements the `Transformation` interface to replace a feature's geometry
+// with the point defined by its 'lbl:latitude' and 'lbl:longitude' properties.
+type LabelTransformation struct {
+	Transformation
+	required bool
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "label", NewLabelTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewLabelTransformation returns a new `LabelTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	label://?required={BOOLEAN}
+//
+// If a feature does not have 'lbl:latitude' and 'lbl:longitude' properties then its geometry is replaced
+// by its (planar) centroid unless the optional `required` parameter is true, in which case the feature
+// is filtered out.
+func NewLabelTransformation(ctx context.Context, uri string) (Transformation, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	t := &LabelTransformation{}
+
+	if q.Has("required") {
+
+		required, err := strconv.ParseBool(q.Get("required"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?required= parameter, %w", err)
+		}
+
+		t.required = required
+	}
+
+	return t, nil
+}
+
+// Transform replaces the geometry of 'f' with its label point.
+func (t *LabelTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	lat, lat_ok := f.Properties["lbl:latitude"].(float64)
+	lon, lon_ok := f.Properties["lbl:longitude"].(float64)
+
+	var pt orb.Point
+
+	switch {
+	case lat_ok && lon_ok:
+		pt = orb.Point{lon, lat}
+	case t.required:
+		return nil, nil
+	default:
+		pt, _ = planar.CentroidArea(f.Geometry)
+	}
+
+	f.Geometry = pt
+	f.BBox = nil
+
+	return f, nil
+}
@@ -0,0 +1,93 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
+)
+
+// MultiPointsTransformation implements the `Transformation` interface to replace a feature's geometry
+// with a MultiPoint geometry. (Multi)Polygons and (Multi)LineStrings are replaced by the centroid of each
+// polygon or line.
+type MultiPointsTransformation struct {
+	Transformation
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "multipoints", NewMultiPointsTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewMultiPointsTransformation returns a new `MultiPointsTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	multipoints://
+func NewMultiPointsTransformation(ctx context.Context, uri string) (Transformation, error) {
+	t := &MultiPointsTransformation{}
+	return t, nil
+}
+
+// Transform replaces the geometry of 'f' with a MultiPoint geometry.
+func (t *MultiPointsTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	geom := f.Geometry
+
+	switch geom.GeoJSONType() {
+	case "Point":
+
+		points := []orb.Point{
+			geom.(orb.Point),
+		}
+
+		geom = orb.MultiPoint(points)
+
+	case "MultiPoint":
+		// pass
+	case "MultiPolygon":
+
+		points := make([]orb.Point, 0)
+
+		for _, poly := range geom.(orb.MultiPolygon) {
+			pt, _ := planar.CentroidArea(poly)
+			points = append(points, pt)
+		}
+
+		geom = orb.MultiPoint(points)
+
+	case "Polygon", "LineString":
+
+		pt, _ := planar.CentroidArea(geom)
+		points := []orb.Point{pt}
+
+		geom = orb.MultiPoint(points)
+
+	case "MultiLineString":
+
+		points := make([]orb.Point, 0)
+
+		for _, ls := range geom.(orb.MultiLineString) {
+			pt, _ := planar.CentroidArea(ls)
+			points = append(points, pt)
+		}
+
+		geom = orb.MultiPoint(points)
+
+	default:
+		return nil, fmt.Errorf("Unsupported geometry type %s", geom.GeoJSONType())
+	}
+
+	// The bounding box is left as-is, describing the original geometry, as the wof-as-jsonl and
+	// wof-as-featurecollection tools have always done.
+
+	f.Geometry = geom
+
+	return f, nil
+}
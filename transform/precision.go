@@ -0,0 +1,81 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// PrecisionTransformation implements the `Transformation` interface to round the coordinates of
+// a feature's geometry to a fixed number of decimal places.
+type PrecisionTransformation struct {
+	Transformation
+	factor int
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "precision", NewPrecisionTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewPrecisionTransformation returns a new `PrecisionTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	precision://?decimals={INT}
+//
+// Where {INT} is the number of decimal places to round coordinates to. If omitted the default is 6.
+func NewPrecisionTransformation(ctx context.Context, uri string) (Transformation, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	decimals := 6
+
+	if q.Has("decimals") {
+
+		v, err := strconv.Atoi(q.Get("decimals"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ?decimals= parameter, %w", err)
+		}
+
+		if v < 0 || v > 15 {
+			return nil, fmt.Errorf("Invalid ?decimals= parameter, must be between 0 and 15")
+		}
+
+		decimals = v
+	}
+
+	t := &PrecisionTransformation{
+		factor: int(math.Pow10(decimals)),
+	}
+
+	return t, nil
+}
+
+// Transform rounds the coordinates of the geometry of 'f'.
+func (t *PrecisionTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	f.Geometry = orb.Round(orb.Clone(f.Geometry), t.factor)
+
+	if f.BBox != nil {
+		f.BBox = geojson.NewBBox(f.Geometry.Bound())
+	}
+
+	return f, nil
+}
@@ -0,0 +1,78 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/simplify"
+)
+
+// SimplifyTransformation implements the `Transformation` interface to simplify a feature's geometry
+// using the Douglas-Peucker algorithm.
+type SimplifyTransformation struct {
+	Transformation
+	simplifier *simplify.DouglasPeuckerSimplifier
+}
+
+func init() {
+
+	ctx := context.Background()
+	err := RegisterTransformation(ctx, "simplify", NewSimplifyTransformation)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NewSimplifyTransformation returns a new `SimplifyTransformation` instance configured by 'uri'
+// in the form of:
+//
+//	simplify://?tolerance={FLOAT}
+//
+// Where {FLOAT} is the Douglas-Peucker threshold, in coordinate units.
+func NewSimplifyTransformation(ctx context.Context, uri string) (Transformation, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse URI, %w", err)
+	}
+
+	q := u.Query()
+
+	if !q.Has("tolerance") {
+		return nil, fmt.Errorf("Missing ?tolerance= parameter")
+	}
+
+	tolerance, err := strconv.ParseFloat(q.Get("tolerance"), 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid ?tolerance= parameter, %w", err)
+	}
+
+	if tolerance < 0.0 {
+		return nil, fmt.Errorf("Invalid ?tolerance= parameter, must be greater than or equal to zero")
+	}
+
+	t := &SimplifyTransformation{
+		simplifier: simplify.DouglasPeucker(tolerance),
+	}
+
+	return t, nil
+}
+
+// Transform simplifies the geometry of 'f'. Geometries which are simplified out of existence are left unaltered.
+func (t *SimplifyTransformation) Transform(ctx context.Context, f *geojson.Feature) (*geojson.Feature, error) {
+
+	geom := t.simplifier.Simplify(orb.Clone(f.Geometry))
+
+	if geom != nil {
+		f.Geometry = geom
+	}
+
+	return f, nil
+}
@@ -0,0 +1,187 @@
+// Package transform provides a common interface for transforming the geometries of GeoJSON Features
+// before they are emitted or merged.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aaronland/go-roster"
+	"github.com/paulmach/orb/geojson"
+	"github.com/tidwall/sjson"
+)
+
+// Transformation is an interface for transforming a GeoJSON Feature.
+type Transformation interface {
+	// Transform transforms a `geojson.Feature` instance. If the return value is nil the feature
+	// should be considered to have been filtered out and not be processed any further. Only changes
+	// to the feature's geometry and bounding box are kept by the `TransformFeature` method.
+	Transform(context.Context, *geojson.Feature) (*geojson.Feature, error)
+}
+
+// TransformationInitializationFunc is a function defined by individual transformation package and used to create
+// an instance of that transformation
+type TransformationInitializationFunc func(ctx context.Context, uri string) (Transformation, error)
+
+var transformation_roster roster.Roster
+
+// RegisterTransformation registers 'scheme' as a key pointing to 'init_func' in an internal lookup table
+// used to create new `Transformation` instances by the `NewTransformation` method.
+func RegisterTransformation(ctx context.Context, scheme string, init_func TransformationInitializationFunc) error {
+
+	err := ensureTransformationRoster()
+
+	if err != nil {
+		return err
+	}
+
+	return transformation_roster.Register(ctx, scheme, init_func)
+}
+
+func ensureTransformationRoster() error {
+
+	if transformation_roster == nil {
+
+		r, err := roster.NewDefaultRoster()
+
+		if err != nil {
+			return err
+		}
+
+		transformation_roster = r
+	}
+
+	return nil
+}
+
+// NewTransformation returns a new `Transformation` instance configured by 'uri'. The value of 'uri' is parsed
+// as a `url.URL` and its scheme is used as the key for a corresponding `TransformationInitializationFunc`
+// function used to instantiate the new `Transformation`. It is assumed that the scheme (and initialization
+// function) have been registered by the `RegisterTransformation` method.
+func NewTransformation(ctx context.Context, uri string) (Transformation, error) {
+
+	u, err := url.Parse(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := u.Scheme
+
+	i, err := transformation_roster.Driver(ctx, scheme)
+
+	if err != nil {
+		return nil, err
+	}
+
+	init_func := i.(TransformationInitializationFunc)
+	return init_func(ctx, uri)
+}
+
+// NewTransformations returns a list of `Transformation` instances for each URI in 'uris'.
+func NewTransformations(ctx context.Context, uris ...string) ([]Transformation, error) {
+
+	transformations := make([]Transformation, len(uris))
+
+	for idx, uri := range uris {
+
+		t, err := NewTransformation(ctx, uri)
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create transformation for '%s', %w", uri, err)
+		}
+
+		transformations[idx] = t
+	}
+
+	return transformations, nil
+}
+
+// Schemes returns the list of schemes that have been registered.
+func Schemes() []string {
+
+	ctx := context.Background()
+	schemes := []string{}
+
+	err := ensureTransformationRoster()
+
+	if err != nil {
+		return schemes
+	}
+
+	for _, dr := range transformation_roster.Drivers(ctx) {
+		scheme := fmt.Sprintf("%s://", strings.ToLower(dr))
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
+
+// TransformFeature applies each of 'transformations', in order, to 'body'. It returns the transformed
+// feature and a boolean value indicating whether the feature should be kept (true) or was filtered out (false).
+// If 'transformations' is empty then 'body' is returned unaltered. Only the "geometry" and, if it was changed,
+// "bbox" members of 'body' are updated so that its properties, and their order, are left as they are.
+func TransformFeature(ctx context.Context, body []byte, transformations ...Transformation) ([]byte, bool, error) {
+
+	if len(transformations) == 0 {
+		return body, true, nil
+	}
+
+	f, err := geojson.UnmarshalFeature(body)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to unmarshal feature, %w", err)
+	}
+
+	if f.Geometry == nil {
+		return nil, false, fmt.Errorf("Feature is missing a geometry")
+	}
+
+	bbox := f.BBox
+
+	for _, t := range transformations {
+
+		f, err = t.Transform(ctx, f)
+
+		if err != nil {
+			return nil, false, err
+		}
+
+		if f == nil {
+			return nil, false, nil
+		}
+	}
+
+	body, err = sjson.SetBytes(body, "geometry", geojson.NewGeometry(f.Geometry))
+
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to assign geometry, %w", err)
+	}
+
+	switch {
+	case slices.Equal(f.BBox, bbox):
+		// pass
+	case f.BBox == nil:
+
+		body, err = sjson.DeleteBytes(body, "bbox")
+
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to remove bbox, %w", err)
+		}
+
+	default:
+
+		body, err = sjson.SetBytes(body, "bbox", f.BBox)
+
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to assign bbox, %w", err)
+		}
+	}
+
+	return body, true, nil
+}
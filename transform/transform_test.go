@@ -0,0 +1,56 @@
+package transform
+
+import (
+	"context"
+	"testing"
+)
+
+// TestTransformFeature ensures that only the geometry, and the bounding box if it was changed, of a transformed
+// feature are updated.
+func TestTransformFeature(t *testing.T) {
+
+	ctx := context.Background()
+
+	body := `{"type":"Feature","properties":{"wof:name":"SFO","wof:id":102527513,"geom:latitude":37.6},"bbox":[-122.5,37.5,-122.3,37.7],"geometry":{"type":"Polygon","coordinates":[[[-122.5,37.5],[-122.3,37.5],[-122.3,37.7],[-122.5,37.7],[-122.5,37.5]]]},"id":102527513}`
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{
+			"multipoints://",
+			`{"type":"Feature","properties":{"wof:name":"SFO","wof:id":102527513,"geom:latitude":37.6},"bbox":[-122.5,37.5,-122.3,37.7],"geometry":{"type":"MultiPoint","coordinates":[[-122.4,37.6]]},"id":102527513}`,
+		},
+		{
+			"centroid://",
+			`{"type":"Feature","properties":{"wof:name":"SFO","wof:id":102527513,"geom:latitude":37.6},"geometry":{"type":"Point","coordinates":[-122.4,37.6]},"id":102527513}`,
+		},
+		{
+			"precision://?decimals=0",
+			`{"type":"Feature","properties":{"wof:name":"SFO","wof:id":102527513,"geom:latitude":37.6},"bbox":[-123,38,-122,38],"geometry":{"type":"Polygon","coordinates":[[[-123,38],[-122,38],[-122,38],[-123,38],[-123,38]]]},"id":102527513}`,
+		},
+	}
+
+	for _, test := range tests {
+
+		tr, err := NewTransformation(ctx, test.uri)
+
+		if err != nil {
+			t.Fatalf("Failed to create transformation for %s, %v", test.uri, err)
+		}
+
+		new_body, ok, err := TransformFeature(ctx, []byte(body), tr)
+
+		if err != nil {
+			t.Fatalf("Failed to transform feature with %s, %v", test.uri, err)
+		}
+
+		if !ok {
+			t.Fatalf("Expected feature to be kept by %s", test.uri)
+		}
+
+		if string(new_body) != test.expected {
+			t.Fatalf("Unexpected feature for %s: %s", test.uri, new_body)
+		}
+	}
+}
@@ -2,175 +2,138 @@ package exportify
 
 import (
 	"context"
+	"fmt"
+	"log"
 
 	"github.com/paulmach/orb/geojson"
 	"github.com/sfomuseum/go-flags/multi"
-	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
 type UpdateFeatureOptions struct {
+	// StringProperties, Int64Properties and Float64Properties are applied, in that order, before Operations. Use
+	// set-string, set-int64 and set-float64 operations instead to apply them in the same order as other updates.
 	StringProperties  multi.KeyValueString
 	Int64Properties   multi.KeyValueInt64
 	Float64Properties multi.KeyValueFloat64
-	// JSONProperties multi.KeyValueString
-	Geometry *geojson.Geometry
+	// Operations is an ordered list of operations to apply after StringProperties, Int64Properties and
+	// Float64Properties.
+	Operations Operations
+	Geometry   *geojson.Geometry
+	// Force allows properties (and the geometry) listed in a record's "wof:controlled" property to be updated.
+	Force bool
+	// Logger is an optional logger used to report updates to controlled properties that have been skipped.
+	Logger *log.Logger
 }
 
+// UpdateFeature applies the updates defined by 'opts' to 'body' and returns the updated record along with a
+// boolean value indicating whether it was changed.
 func UpdateFeature(ctx context.Context, body []byte, opts *UpdateFeatureOptions) ([]byte, bool, error) {
 
-	changed := false
+	body, changed, err := UpdateFeatureWithChanges(ctx, body, opts)
 
-	for _, p := range opts.StringProperties {
-
-		path := p.Key()
-		new_value := p.Value()
-
-		update := true
-
-		old_rsp := gjson.GetBytes(body, path)
-
-		if old_rsp.Exists() {
-
-			old_value := old_rsp.String()
-
-			if old_value == new_value {
-				update = false
-			}
-		}
+	if err != nil {
+		return nil, false, err
+	}
 
-		if update {
+	return body, len(changed) > 0, nil
+}
 
-			new_body, err := sjson.SetBytes(body, path, new_value)
+// UpdateFeatureWithChanges applies the updates defined by 'opts' to 'body' and returns the updated record along
+// with the list of paths whose values were changed, in the order they were changed.
+func UpdateFeatureWithChanges(ctx context.Context, body []byte, opts *UpdateFeatureOptions) ([]byte, []string, error) {
 
-			if err != nil {
-				return nil, false, err
-			}
+	ops := make(Operations, 0)
 
-			body = new_body
-			changed = true
-		}
+	for _, p := range opts.StringProperties {
+		ops = append(ops, &Operation{Kind: OPERATION_SET_STRING, Path: p.Key(), Value: p.Value().(string)})
 	}
 
 	for _, p := range opts.Int64Properties {
+		ops = append(ops, &Operation{Kind: OPERATION_SET_INT64, Path: p.Key(), Value: p.Value().(int64)})
+	}
 
-		path := p.Key()
-		new_value := p.Value()
-
-		update := true
+	for _, p := range opts.Float64Properties {
+		ops = append(ops, &Operation{Kind: OPERATION_SET_FLOAT64, Path: p.Key(), Value: p.Value().(float64)})
+	}
 
-		old_rsp := gjson.GetBytes(body, path)
+	ops = append(ops, opts.Operations...)
 
-		if old_rsp.Exists() {
+	controlled_opts := &ControlledOptions{
+		Force:  opts.Force,
+		Logger: opts.Logger,
+	}
 
-			old_value := old_rsp.Int()
+	// apply replaces 'body' with 'new_body' unless doing so would change a controlled property of the record, in
+	// which case the change is skipped in its entirety (and logged). Controlled properties are checked against
+	// the record before any updates were applied so that changes to wof:controlled itself don't unlock any of the
+	// properties it lists.
 
-			if old_value == new_value {
-				update = false
-			}
-		}
+	original_body := body
 
-		if update {
+	apply := func(new_body []byte) (bool, error) {
 
-			new_body, err := sjson.SetBytes(body, path, new_value)
+		_, reverted, err := RevertControlledChanges(original_body, new_body, controlled_opts)
 
-			if err != nil {
-				return nil, false, err
-			}
+		if err != nil {
+			return false, err
+		}
 
-			body = new_body
-			changed = true
+		if len(reverted) > 0 {
+			return false, nil
 		}
-	}
 
-	for _, p := range opts.Float64Properties {
+		body = new_body
+		return true, nil
+	}
 
-		path := p.Key()
-		new_value := p.Value()
+	changed := make([]string, 0)
+	seen := make(map[string]bool)
 
-		update := true
+	for _, op := range ops {
 
-		old_rsp := gjson.GetBytes(body, path)
+		new_body, op_changed, err := op.Apply(body)
 
-		if old_rsp.Exists() {
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to apply %s operation to '%s', %w", op.Kind, op.Path, err)
+		}
 
-			old_value := old_rsp.Float()
+		ok, err := apply(new_body)
 
-			if old_value == new_value {
-				update = false
-			}
+		if err != nil {
+			return nil, nil, err
 		}
 
-		if update {
+		if !ok {
+			continue
+		}
 
-			new_body, err := sjson.SetBytes(body, path, new_value)
+		for _, path := range op_changed {
 
-			if err != nil {
-				return nil, false, err
+			if !seen[path] {
+				seen[path] = true
+				changed = append(changed, path)
 			}
-
-			body = new_body
-			changed = true
 		}
 	}
 
-	// Instinctively this just feels like a bad idea since there's
-	// basically no input validation happening here
-	// (20210430/thisisaaronland)
-
-	/*
-
-		for _, p := range opts.JSONProperties {
-
-			path := p.Key()
-			new_value := p.Value().(string)
-
-			var json_value interface{}
-
-			err := json.Unmarshal([]byte(new_value), &json_value)
-
-			if err != nil {
-				return nil, false, err
-			}
-
-			update := true
-
-			old_rsp := gjson.GetBytes(body, path)
-
-			if old_rsp.Exists() {
-
-				old_value := old_rsp.String()
-
-				if old_value == new_value {
-					update = false
-				}
-			}
-
-			if update {
-
-				new_body, err := sjson.SetBytes(body, path, json_value)
+	if opts.Geometry != nil {
 
-				if err != nil {
-					return nil, false, err
-				}
+		new_body, err := sjson.SetBytes(body, "geometry", opts.Geometry)
 
-				body = new_body
-				changed = true
-			}
+		if err != nil {
+			return nil, nil, err
 		}
 
-	*/
-
-	if opts.Geometry != nil {
-
-		new_body, err := sjson.SetBytes(body, "geometry", opts.Geometry)
+		ok, err := apply(new_body)
 
 		if err != nil {
-			return nil, false, err
+			return nil, nil, err
 		}
 
-		body = new_body
-		changed = true
+		if ok {
+			changed = append(changed, "geometry")
+		}
 	}
 
 	return body, changed, nil
@@ -0,0 +1,62 @@
+package exportify
+
+import (
+	"context"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// TestUpdateFeatureFlagOrder ensures that the property and operation flags are applied in the order they are passed
+// on the command line, regardless of their kind.
+func TestUpdateFeatureFlagOrder(t *testing.T) {
+
+	ctx := context.Background()
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-delete", "properties.wof:name", "-string-property", "properties.wof:name=Montreal"}, `"Montreal"`},
+		{[]string{"-string-property", "properties.wof:name=Montreal", "-delete", "properties.wof:name"}, ``},
+		{[]string{"-set-json", `properties.wof:name=["Montreal"]`, "-string-property", "properties.wof:name=Montréal"}, `"Montréal"`},
+		{[]string{"-int-property", "properties.wof:name=1", "-set-null", "properties.wof:name", "-float-property", "properties.wof:name=1.5"}, `1.5`},
+	}
+
+	body := []byte(`{"type":"Feature","properties":{"wof:id":101736545,"wof:name":"Montréal"},"geometry":null}`)
+
+	for _, test := range tests {
+
+		var ops Operations
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+
+		AppendPropertyFlags(fs, &ops)
+		AppendOperationFlags(fs, &ops)
+
+		err := fs.Parse(test.args)
+
+		if err != nil {
+			t.Fatalf("Failed to parse %v, %v", test.args, err)
+		}
+
+		opts := &UpdateFeatureOptions{
+			Operations: ops,
+		}
+
+		new_body, _, err := UpdateFeatureWithChanges(ctx, body, opts)
+
+		if err != nil {
+			t.Fatalf("Failed to update feature for %v, %v", test.args, err)
+		}
+
+		rsp := gjson.GetBytes(new_body, "properties.wof:name")
+
+		if rsp.Raw != test.expected {
+			t.Fatalf("Unexpected value for %v: '%s', expected '%s'", test.args, rsp.Raw, test.expected)
+		}
+	}
+}